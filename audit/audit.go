@@ -0,0 +1,339 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// audited actions
+const (
+	Create     = "create"
+	Update     = "update"
+	Delete     = "delete"
+	Undelete   = "undelete"
+	Purge      = "purge"
+	Transition = "transition"
+	Import     = "import"
+	Rename     = "rename"
+	Alias      = "alias"
+	Erase      = "erase"
+	Release    = "release"
+	Lock       = "lock"
+	Unlock     = "unlock"
+)
+
+// outcomes of an audited action
+const (
+	Allowed = "allowed"
+	Denied  = "denied"
+)
+
+// Entry is a record of a mutation, or of an attempt denied to the caller.
+// Entries are chained: Hash covers the entry and the Hash of the entry before it.
+type Entry struct {
+	Sequence      uint64    `yaml:"sequence" json:"sequence"`
+	Time          time.Time `yaml:"time" json:"time"`
+	Principal     string    `yaml:"principal" json:"principal"`
+	Action        string    `yaml:"action" json:"action"`
+	ApplicationID string    `yaml:"applicationID" json:"applicationID"`
+	RequestID     string    `yaml:"requestID" json:"requestID"`
+	SourceIP      string    `yaml:"sourceIP" json:"sourceIP"`
+	Outcome       string    `yaml:"outcome" json:"outcome"`
+	// Reason tells why the attempt was denied
+	Reason  string   `yaml:"reason,omitempty" json:"reason,omitempty"`
+	Changes []Change `yaml:"changes,omitempty" json:"changes,omitempty"`
+	// Redactions are the entries redacted by this one, see Log.Redact
+	Redactions []Redaction `yaml:"redactions,omitempty" json:"redactions,omitempty"`
+	// PrevHash is the Hash of the previous entry, empty for the first one
+	PrevHash string `yaml:"prevHash" json:"prevHash"`
+	Hash     string `yaml:"hash" json:"hash"`
+	// Redacted is set when personal data was removed from the entry, so its content no longer matches Hash
+	Redacted bool `yaml:"redacted,omitempty" json:"redacted,omitempty"`
+}
+
+// Redaction records an entry redacted by a later one.  Hash is the hash of its redacted content, which keeps the
+// entry tamper-evident although it no longer matches its own Hash.
+type Redaction struct {
+	Sequence uint64 `yaml:"sequence" json:"sequence"`
+	Hash     string `yaml:"hash" json:"hash"`
+}
+
+// Filter selects entries, zero fields match every entry
+type Filter struct {
+	ApplicationID string
+	Principal     string
+	// Since and Until bound the time of the entries, inclusive
+	Since time.Time
+	Until time.Time
+}
+
+func (f Filter) matches(e *Entry) bool {
+	if f.ApplicationID != "" && e.ApplicationID != f.ApplicationID {
+		return false
+	}
+	if f.Principal != "" && e.Principal != f.Principal {
+		return false
+	}
+	if !f.Since.IsZero() && e.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && e.Time.After(f.Until) {
+		return false
+	}
+	return true
+}
+
+// ChainError is returned by Verify when an entry doesn't match its hash or doesn't follow the previous entry
+type ChainError struct {
+	Sequence uint64
+	Reason   string
+}
+
+func (e *ChainError) Error() string {
+	return fmt.Sprintf("audit log is broken at entry %d: %s", e.Sequence, e.Reason)
+}
+
+// Log is an append-only chain of entries kept in memory, and written as json lines to w when it's set
+type Log struct {
+	mu      sync.RWMutex
+	entries []Entry
+	w       io.Writer
+	now     func() time.Time
+}
+
+// NewLog returns an instance of Log continuing the chain of existing entries, which should be verified first
+func NewLog(existing []Entry, w io.Writer) *Log {
+	return &Log{
+		entries: existing,
+		w:       w,
+		now:     time.Now,
+	}
+}
+
+// Append sets the sequence, time, and hashes of e, and adds it at the end of the chain
+func (l *Log) Append(e Entry) (Entry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e.Sequence = 1
+	e.PrevHash = ""
+	if n := len(l.entries); n > 0 {
+		e.Sequence = l.entries[n-1].Sequence + 1
+		e.PrevHash = l.entries[n-1].Hash
+	}
+	e.Time = l.now().UTC()
+	hash, err := Hash(e)
+	if err != nil {
+		return Entry{}, err
+	}
+	e.Hash = hash
+
+	if l.w != nil {
+		b, err := json.Marshal(e)
+		if err != nil {
+			return Entry{}, err
+		}
+		if _, err := l.w.Write(append(b, '\n')); err != nil {
+			return Entry{}, err
+		}
+	}
+	l.entries = append(l.entries, e)
+	return e, nil
+}
+
+// Redact applies fn to a copy of every entry, and marks the entries it changed as redacted.  Then it appends e
+// listing them in its Redactions with the hash of their redacted content, which lets Verify check them although
+// their content no longer matches their Hash.  The file the log is written to is replaced by a rewritten copy, the
+// log can't be redacted when it's written elsewhere.
+func (l *Log) Redact(fn func(e *Entry) bool, e Entry) (Entry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	f, rewritable := l.w.(*os.File)
+	if l.w != nil && !rewritable {
+		return Entry{}, errors.New("the audit log can't be rewritten")
+	}
+
+	entries := make([]Entry, len(l.entries), len(l.entries)+1)
+	e.Redactions = nil
+	for i := range l.entries {
+		entry := l.entries[i]
+		if fn(&entry) {
+			entry.Redacted = true
+			hash, err := Hash(entry)
+			if err != nil {
+				return Entry{}, err
+			}
+			e.Redactions = append(e.Redactions, Redaction{Sequence: entry.Sequence, Hash: hash})
+		}
+		entries[i] = entry
+	}
+	e.Sequence = 1
+	e.PrevHash = ""
+	if n := len(entries); n > 0 {
+		e.Sequence = entries[n-1].Sequence + 1
+		e.PrevHash = entries[n-1].Hash
+	}
+	e.Time = l.now().UTC()
+	hash, err := Hash(e)
+	if err != nil {
+		return Entry{}, err
+	}
+	e.Hash = hash
+	entries = append(entries, e)
+
+	if l.w != nil {
+		rewritten, err := rewrite(f, entries)
+		if err != nil {
+			return Entry{}, err
+		}
+		f.Close()
+		l.w = rewritten
+	}
+	l.entries = entries
+	return e, nil
+}
+
+// rewrite writes entries to a temporary file next to f, and renames it over f once it's synced, so that the log is
+// either rewritten as a whole or left as it was.  It returns the new file, open for the next entries.
+func rewrite(f *os.File, entries []Entry) (*os.File, error) {
+	var buf bytes.Buffer
+	for i := range entries {
+		b, err := json.Marshal(entries[i])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(append(b, '\n'))
+	}
+
+	path := f.Name()
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	if _, err = tmp.Write(buf.Bytes()); err == nil {
+		err = tmp.Sync()
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return nil, err
+	}
+	// the rename is only durable once the directory is synced, which some platforms don't support
+	if dir, err := os.Open(filepath.Dir(path)); err == nil {
+		dir.Sync()
+		dir.Close()
+	}
+	return tmp, nil
+}
+
+// Query returns the entries matching f in chain order
+func (l *Log) Query(f Filter) []Entry {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	res := []Entry{}
+	for i := range l.entries {
+		if f.matches(&l.entries[i]) {
+			res = append(res, l.entries[i])
+		}
+	}
+	return res
+}
+
+// Verify checks the whole chain kept in memory
+func (l *Log) Verify() (int, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return len(l.entries), Verify(l.entries)
+}
+
+// Hash returns the hex encoded SHA-256 of e without its Hash, which covers PrevHash and so the whole chain before it
+func Hash(e Entry) (string, error) {
+	e.Hash = ""
+	e.Redacted = false
+	b, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Verify checks that every entry matches its hash and links to the entry before it.
+// The content of a redacted entry is checked against the hash recorded by the latest entry listing it in its
+// Redactions instead.
+func Verify(entries []Entry) error {
+	redacted := make(map[uint64]string)
+	for i := range entries {
+		for _, r := range entries[i].Redactions {
+			if r.Sequence >= entries[i].Sequence {
+				return &ChainError{Sequence: entries[i].Sequence, Reason: fmt.Sprintf("redacts entry %d which isn't before it", r.Sequence)}
+			}
+			redacted[r.Sequence] = r.Hash
+		}
+	}
+
+	prev := ""
+	var seq uint64
+	for i := range entries {
+		e := &entries[i]
+		if i > 0 && e.Sequence != seq+1 {
+			return &ChainError{Sequence: e.Sequence, Reason: fmt.Sprintf("expected sequence %d", seq+1)}
+		}
+		if e.PrevHash != prev {
+			return &ChainError{Sequence: e.Sequence, Reason: "previous hash doesn't match"}
+		}
+		expected := e.Hash
+		if e.Redacted {
+			var ok bool
+			if expected, ok = redacted[e.Sequence]; !ok {
+				return &ChainError{Sequence: e.Sequence, Reason: "redacted without a redaction entry"}
+			}
+		}
+		hash, err := Hash(*e)
+		if err != nil {
+			return err
+		}
+		if hash != expected {
+			if e.Redacted {
+				return &ChainError{Sequence: e.Sequence, Reason: "redacted content doesn't match its redaction"}
+			}
+			return &ChainError{Sequence: e.Sequence, Reason: "hash doesn't match the content"}
+		}
+		prev = e.Hash
+		seq = e.Sequence
+	}
+	return nil
+}
+
+// Read decodes the json lines written by a Log
+func Read(r io.Reader) ([]Entry, error) {
+	var entries []Entry
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 16<<20)
+	for sc.Scan() {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("audit entry %d: %v", len(entries)+1, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, sc.Err()
+}
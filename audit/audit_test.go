@@ -0,0 +1,161 @@
+package audit
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/elumbantoruan/app-metadata/metadata"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLog_AppendRead_ResultedVerified(t *testing.T) {
+
+	var buf bytes.Buffer
+	l := NewLog(nil, &buf)
+	changes, err := Diff(nil, &metadata.ApplicationMetadata{ApplicationID: "appID1", Title: "App 1"})
+	assert.Nil(t, err)
+	l.Append(Entry{Principal: "a@example.com", Action: Create, ApplicationID: "appID1", Outcome: Allowed, Changes: changes})
+	l.Append(Entry{Principal: "b@example.com", Action: Purge, ApplicationID: "appID1", Outcome: Denied, Reason: "admin token is required"})
+
+	n, err := l.Verify()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, n)
+
+	entries, err := Read(&buf)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(entries))
+	assert.Nil(t, Verify(entries))
+	assert.Equal(t, entries[0].Hash, entries[1].PrevHash)
+
+	// the chain carries on from the entries read back
+	l = NewLog(entries, &buf)
+	e, err := l.Append(Entry{Action: Delete, ApplicationID: "appID1", Outcome: Allowed})
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(3), e.Sequence)
+	assert.Equal(t, entries[1].Hash, e.PrevHash)
+}
+
+func TestVerify_Tampered_ResultedChainError(t *testing.T) {
+
+	l := NewLog(nil, nil)
+	for _, p := range []string{"a@example.com", "b@example.com", "c@example.com"} {
+		l.Append(Entry{Principal: p, Action: Update, ApplicationID: "appID1", Outcome: Allowed})
+	}
+	entries := l.Query(Filter{})
+
+	tampered := append([]Entry(nil), entries...)
+	tampered[1].Principal = "mallory@example.com"
+	err := Verify(tampered)
+	assert.Equal(t, &ChainError{Sequence: 2, Reason: "hash doesn't match the content"}, err)
+
+	// rehashing the tampered entry breaks the link of the next one
+	tampered[1].Hash, _ = Hash(tampered[1])
+	err = Verify(tampered)
+	assert.Equal(t, &ChainError{Sequence: 3, Reason: "previous hash doesn't match"}, err)
+
+	// and so does dropping an entry
+	err = Verify([]Entry{entries[0], entries[2]})
+	assert.Equal(t, &ChainError{Sequence: 3, Reason: "expected sequence 2"}, err)
+}
+
+func TestLog_Query_ResultedFiltered(t *testing.T) {
+
+	l := NewLog(nil, nil)
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	l.now = func() time.Time { return now }
+	l.Append(Entry{Principal: "a@example.com", ApplicationID: "appID1"})
+	now = now.Add(time.Hour)
+	l.Append(Entry{Principal: "b@example.com", ApplicationID: "appID1"})
+	now = now.Add(time.Hour)
+	l.Append(Entry{Principal: "a@example.com", ApplicationID: "appID2"})
+
+	assert.Equal(t, 3, len(l.Query(Filter{})))
+	assert.Equal(t, 2, len(l.Query(Filter{ApplicationID: "appID1"})))
+	assert.Equal(t, 2, len(l.Query(Filter{Principal: "a@example.com"})))
+	res := l.Query(Filter{Since: now.Add(-90 * time.Minute), Until: now.Add(-time.Hour)})
+	assert.Equal(t, 1, len(res))
+	assert.Equal(t, "b@example.com", res[0].Principal)
+}
+
+func TestDiff_Update_ResultedChangedFields(t *testing.T) {
+
+	before := &metadata.ApplicationMetadata{ApplicationID: "appID1", Title: "App 1", Version: "1.0.0"}
+	after := &metadata.ApplicationMetadata{ApplicationID: "appID1", Title: "App 1", Version: "1.1.0", Labels: map[string]string{"team": "payments"}}
+
+	changes, err := Diff(before, after)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []Change{
+		{Field: "labels", New: map[string]interface{}{"team": "payments"}},
+		{Field: "version", Old: "1.0.0", New: "1.1.0"},
+	}, changes)
+}
+
+func TestLog_Redact_ResultedVerified(t *testing.T) {
+
+	f, err := os.CreateTemp(t.TempDir(), "audit")
+	assert.Nil(t, err)
+	defer f.Close()
+	l := NewLog(nil, f)
+	for _, p := range []string{"a@example.com", "b@example.com", "a@example.com"} {
+		l.Append(Entry{Principal: p, Action: Update, ApplicationID: "appID1", Outcome: Allowed})
+	}
+
+	e, err := l.Redact(func(e *Entry) bool {
+		if e.Principal != "a@example.com" {
+			return false
+		}
+		e.Principal = "erased@erased.invalid"
+		return true
+	}, Entry{Principal: "admin@example.com", Action: Erase, Outcome: Allowed})
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(4), e.Sequence)
+	assert.Equal(t, 2, len(e.Redactions))
+	assert.Equal(t, uint64(1), e.Redactions[0].Sequence)
+	assert.Equal(t, uint64(3), e.Redactions[1].Sequence)
+
+	n, err := l.Verify()
+	assert.Nil(t, err)
+	assert.Equal(t, 4, n)
+
+	// the file is replaced by one with the redacted entries, which the next entries are appended to
+	_, err = l.Append(Entry{Principal: "b@example.com", Action: Update, ApplicationID: "appID1", Outcome: Allowed})
+	assert.Nil(t, err)
+	replaced, err := os.Open(f.Name())
+	assert.Nil(t, err)
+	defer replaced.Close()
+	entries, err := Read(replaced)
+	assert.Nil(t, err)
+	assert.Equal(t, 5, len(entries))
+	assert.Nil(t, Verify(entries))
+	assert.Equal(t, "erased@erased.invalid", entries[0].Principal)
+	assert.True(t, entries[0].Redacted)
+	assert.False(t, entries[1].Redacted)
+
+	// an entry marked redacted without being listed doesn't verify
+	entries[1].Redacted = true
+	assert.Equal(t, &ChainError{Sequence: 2, Reason: "redacted without a redaction entry"}, Verify(entries))
+
+	// nor does an entry which was changed anyway
+	entries[1].Redacted = false
+	entries[1].Principal = "mallory@example.com"
+	assert.Equal(t, &ChainError{Sequence: 2, Reason: "hash doesn't match the content"}, Verify(entries))
+
+	// nor a redacted entry changed after its redaction
+	entries[1].Principal = "b@example.com"
+	entries[2].Principal = "mallory@example.com"
+	assert.Equal(t, &ChainError{Sequence: 3, Reason: "redacted content doesn't match its redaction"}, Verify(entries))
+
+	// no temporary file is left behind
+	files, err := os.ReadDir(filepath.Dir(f.Name()))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(files))
+
+	// a log written elsewhere than a file can't be redacted
+	var buf bytes.Buffer
+	_, err = NewLog(nil, &buf).Redact(func(e *Entry) bool { return false }, Entry{Action: Erase})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,61 @@
+package audit
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+
+	"github.com/elumbantoruan/app-metadata/metadata"
+)
+
+// Change is a field of an application metadata that changed, Old or New is nil when the field was empty
+type Change struct {
+	Field string      `yaml:"field" json:"field"`
+	Old   interface{} `yaml:"old,omitempty" json:"old,omitempty"`
+	New   interface{} `yaml:"new,omitempty" json:"new,omitempty"`
+}
+
+// Diff returns the fields changed from before to after by their json name, either of them may be nil
+func Diff(before, after *metadata.ApplicationMetadata) ([]Change, error) {
+	old, err := fields(before)
+	if err != nil {
+		return nil, err
+	}
+	cur, err := fields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(old)+len(cur))
+	for k := range old {
+		names = append(names, k)
+	}
+	for k := range cur {
+		if _, ok := old[k]; !ok {
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+
+	var changes []Change
+	for _, k := range names {
+		if !reflect.DeepEqual(old[k], cur[k]) {
+			changes = append(changes, Change{Field: k, Old: old[k], New: cur[k]})
+		}
+	}
+	return changes, nil
+}
+
+// fields returns the json fields of app as generic values, so that they're hashed the same once read back
+func fields(app *metadata.ApplicationMetadata) (map[string]interface{}, error) {
+	m := map[string]interface{}{}
+	if app == nil {
+		return m, nil
+	}
+	b, err := json.Marshal(app)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(b, &m)
+	return m, err
+}
@@ -0,0 +1,219 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/elumbantoruan/app-metadata/metadata"
+	"github.com/google/uuid"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// default retry settings
+const (
+	DefaultMaxRetries = 3
+	DefaultBackoff    = 100 * time.Millisecond
+	maxBackoff        = 5 * time.Second
+)
+
+// Client calls the app-metadata REST API
+type Client struct {
+	// BaseURL is the address of the service such as http://localhost:5000
+	BaseURL string
+	// HTTPClient sends the requests, http.DefaultClient when nil
+	HTTPClient *http.Client
+	// APIKey is sent in the X-API-Key header when set
+	APIKey string
+	// Principal is the email of the caller sent in the X-User-Email header when set
+	Principal string
+	// MaxRetries is how many times an idempotent call is retried after a network error,
+	// 429, 502, 503, or 504.  A response asking to retry after more than 5 seconds is returned as an Error instead.
+	MaxRetries int
+	// Backoff is the delay before the first retry, doubled for every following retry
+	Backoff time.Duration
+}
+
+// New returns an instance of Client for the service at baseURL
+func New(baseURL string) *Client {
+	return &Client{
+		BaseURL:    strings.TrimRight(baseURL, "/"),
+		HTTPClient: http.DefaultClient,
+		MaxRetries: DefaultMaxRetries,
+		Backoff:    DefaultBackoff,
+	}
+}
+
+// Create adds an application metadata and returns it with its generated ApplicationID.
+// Every call carries a new Idempotency-Key so that it's safely retried.
+func (c *Client) Create(ctx context.Context, app *metadata.ApplicationMetadata) (*metadata.ApplicationMetadata, error) {
+	header := http.Header{}
+	header.Set("Idempotency-Key", uuid.New().String())
+	var res metadata.ApplicationMetadata
+	if err := c.do(ctx, http.MethodPost, "/app-metadata", header, app, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// Get returns the application metadata for a given appID
+func (c *Client) Get(ctx context.Context, appID string) (*metadata.ApplicationMetadata, error) {
+	var res metadata.ApplicationMetadata
+	if err := c.do(ctx, http.MethodGet, "/app-metadata/"+url.PathEscape(appID), nil, nil, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// Update replaces the application metadata for a given appID
+func (c *Client) Update(ctx context.Context, appID string, app *metadata.ApplicationMetadata) (*metadata.ApplicationMetadata, error) {
+	var res metadata.ApplicationMetadata
+	if err := c.do(ctx, http.MethodPut, "/app-metadata/"+url.PathEscape(appID), nil, app, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// Delete removes the application metadata for a given appID
+func (c *Client) Delete(ctx context.Context, appID string) error {
+	return c.do(ctx, http.MethodDelete, "/app-metadata/"+url.PathEscape(appID), nil, nil, nil)
+}
+
+// Transition moves the application for a given appID to another lifecycle state such as "in-review" or "published"
+func (c *Client) Transition(ctx context.Context, appID, state string) (*metadata.ApplicationMetadata, error) {
+	var res metadata.ApplicationMetadata
+	body := map[string]string{"state": state}
+	if err := c.do(ctx, http.MethodPost, "/app-metadata/"+url.PathEscape(appID)+":transition", nil, body, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// Rename changes the applicationID of an application to newID, the previous one redirecting to it
+func (c *Client) Rename(ctx context.Context, appID, newID string) (*metadata.ApplicationMetadata, error) {
+	var res metadata.ApplicationMetadata
+	body := map[string]string{"applicationID": newID}
+	if err := c.do(ctx, http.MethodPost, "/app-metadata/"+url.PathEscape(appID)+":rename", nil, body, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// do sends a request with body encoded in yaml, retrying idempotent calls, and decodes the response into out
+func (c *Client) do(ctx context.Context, method, path string, header http.Header, body, out interface{}) error {
+	res, err := c.send(ctx, method, path, header, body)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		return newError(res)
+	}
+	if out == nil || res.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if err := yaml.NewDecoder(res.Body).Decode(out); err != nil && err != io.EOF {
+		return fmt.Errorf("decoding response: %v", err)
+	}
+	return nil
+}
+
+// send sends a request, retrying network errors and retryable statuses when the call is idempotent.
+// The caller must close the body of the returned response.
+func (c *Client) send(ctx context.Context, method, path string, header http.Header, body interface{}) (*http.Response, error) {
+	var payload []byte
+	if body != nil {
+		b, err := yaml.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		payload = b
+	}
+	hc := c.HTTPClient
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	retryable := method != http.MethodPost || header.Get("Idempotency-Key") != ""
+
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequest(method, c.BaseURL+path, bytes.NewReader(payload))
+		if err != nil {
+			return nil, err
+		}
+		req = req.WithContext(ctx)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		if body != nil {
+			req.Header.Set("Content-Type", "application/x-yaml")
+		}
+		if c.APIKey != "" {
+			req.Header.Set("X-API-Key", c.APIKey)
+		}
+		if c.Principal != "" {
+			req.Header.Set("X-User-Email", c.Principal)
+		}
+
+		res, err := hc.Do(req)
+		if !retryable || attempt >= c.MaxRetries || (err == nil && !retryStatus(res.StatusCode)) {
+			return res, err
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		wait := c.backoff(attempt)
+		if res != nil {
+			if ra, ok := retryAfter(res); ok {
+				// a longer wait is left to the caller, whose Error carries RetryAfter
+				if ra > maxBackoff {
+					return res, nil
+				}
+				wait = ra
+			}
+			ioutil.ReadAll(res.Body)
+			res.Body.Close()
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// backoff returns the delay before a retry, doubling with every attempt with up to 50% jitter
+func (c *Client) backoff(attempt int) time.Duration {
+	d := c.Backoff << uint(attempt)
+	if d <= 0 || d > maxBackoff {
+		d = maxBackoff
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// retryAfter returns the delay of the Retry-After header of res in seconds, and false when there is none
+func retryAfter(res *http.Response) (time.Duration, bool) {
+	ra, err := strconv.Atoi(res.Header.Get("Retry-After"))
+	if err != nil || ra < 0 {
+		return 0, false
+	}
+	return time.Duration(ra) * time.Second, true
+}
+
+func retryStatus(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,259 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/elumbantoruan/app-metadata/handlers"
+	"github.com/elumbantoruan/app-metadata/metadata"
+	"github.com/elumbantoruan/app-metadata/server"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestServer(t *testing.T) *httptest.Server {
+	cfg := server.DefaultConfig()
+	// the tests send requests faster than the default budgets allow
+	cfg.ReadLimit = handlers.RateLimit{}
+	cfg.WriteLimit = handlers.RateLimit{}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	m, err := server.RegisterHandlers(ctx, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return httptest.NewServer(m)
+}
+
+func TestClient_CreateGetUpdateDelete_ResultedOK(t *testing.T) {
+
+	ts := newTestServer(t)
+	defer ts.Close()
+	c := New(ts.URL)
+	ctx := context.Background()
+
+	created, err := c.Create(ctx, createValidApp("Valid App 1"))
+	assert.Nil(t, err)
+	assert.NotEmpty(t, created.ApplicationID)
+
+	got, err := c.Get(ctx, created.ApplicationID)
+	assert.Nil(t, err)
+	assert.Equal(t, "Valid App 1", got.Title)
+
+	got.Company = "updated company"
+	updated, err := c.Update(ctx, created.ApplicationID, got)
+	assert.Nil(t, err)
+	assert.Equal(t, "updated company", updated.Company)
+
+	assert.Nil(t, c.Delete(ctx, created.ApplicationID))
+
+	_, err = c.Get(ctx, created.ApplicationID)
+	assert.True(t, errors.Is(err, ErrNotFound))
+	err = c.Delete(ctx, created.ApplicationID)
+	assert.True(t, errors.Is(err, ErrNotFound))
+	assert.True(t, errors.Is(err, ErrConflict))
+}
+
+func TestClient_CreateInvalid_ResultedValidationError(t *testing.T) {
+
+	ts := newTestServer(t)
+	defer ts.Close()
+	c := New(ts.URL)
+
+	app := createValidApp("Valid App 1")
+	app.Version = ""
+	_, err := c.Create(context.Background(), app)
+
+	var verr *ValidationError
+	assert.True(t, errors.As(err, &verr))
+	assert.Equal(t, "version is empty", verr.Validation.Description)
+	assert.Equal(t, http.StatusBadRequest, verr.Err.StatusCode)
+}
+
+func TestClient_List_ResultedAllPages(t *testing.T) {
+
+	ts := newTestServer(t)
+	defer ts.Close()
+	c := New(ts.URL)
+	ctx := context.Background()
+
+	for i := 0; i < 5; i++ {
+		app := createValidApp(fmt.Sprintf("Valid App %d", i))
+		if i%2 == 0 {
+			app.Labels = map[string]string{"team": "payments"}
+		}
+		_, err := c.Create(ctx, app)
+		assert.Nil(t, err)
+	}
+
+	var titles []string
+	// new applications are drafts, which aren't listed by default
+	it := c.List(ctx, ListOptions{PageSize: 2})
+	assert.False(t, it.Next())
+
+	it = c.List(ctx, ListOptions{State: "all", PageSize: 2})
+	for it.Next() {
+		titles = append(titles, it.Value().Title)
+	}
+	assert.Nil(t, it.Err())
+	assert.Equal(t, 5, len(titles))
+
+	count := 0
+	it = c.List(ctx, ListOptions{LabelSelector: "team=payments", State: "draft", PageSize: 2})
+	for it.Next() {
+		count++
+	}
+	assert.Nil(t, it.Err())
+	assert.Equal(t, 3, count)
+
+	it = c.List(ctx, ListOptions{LabelSelector: "team=search"})
+	assert.False(t, it.Next())
+	assert.Nil(t, it.Err())
+}
+
+func TestClient_GetUnavailable_ResultedRetried(t *testing.T) {
+
+	ts := newTestServer(t)
+	defer ts.Close()
+
+	var calls int32
+	flaky := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		ts.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer flaky.Close()
+
+	c := New(flaky.URL)
+	c.Backoff = time.Millisecond
+	_, err := c.Get(context.Background(), "notfound")
+
+	assert.True(t, errors.Is(err, ErrNotFound))
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestClient_GetUnavailable_ResultedError(t *testing.T) {
+
+	unavailable := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer unavailable.Close()
+
+	c := New(unavailable.URL)
+	c.Backoff = time.Millisecond
+	c.MaxRetries = 1
+	_, err := c.Get(context.Background(), "appID1")
+
+	var e *Error
+	assert.True(t, errors.As(err, &e))
+	assert.Equal(t, http.StatusServiceUnavailable, e.StatusCode)
+}
+
+func TestClient_GetRetryAfterLong_ResultedError(t *testing.T) {
+
+	var calls int32
+	limited := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set("Retry-After", "3600")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer limited.Close()
+
+	c := New(limited.URL)
+	c.Backoff = time.Millisecond
+	start := time.Now()
+	_, err := c.Get(context.Background(), "appID1")
+
+	// the client doesn't sleep for an hour, it returns the error with the delay the server asked for
+	assert.True(t, time.Since(start) < time.Second)
+	assert.True(t, errors.Is(err, ErrRateLimited))
+	var e *Error
+	assert.True(t, errors.As(err, &e))
+	assert.Equal(t, time.Hour, e.RetryAfter)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestClient_UpdateLockedReadOnly_ResultedRefused(t *testing.T) {
+
+	cfg := server.DefaultConfig()
+	cfg.ReadLimit = handlers.RateLimit{}
+	cfg.WriteLimit = handlers.RateLimit{}
+	cfg.AdminToken = "secret"
+	cfg.ReadOnlyRetryAfter = time.Minute
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m, err := server.RegisterHandlers(ctx, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(m)
+	defer ts.Close()
+	c := New(ts.URL)
+	c.MaxRetries = 0
+
+	admin := func(method, path, body string) *http.Response {
+		req, _ := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+		req.Header.Set(handlers.AdminTokenHeader, "secret")
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		return res
+	}
+
+	app, err := c.Create(ctx, createValidApp("Valid App 1"))
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, admin("POST", "/app-metadata/"+app.ApplicationID+":lock", "reason: migrating").StatusCode)
+	assert.Equal(t, http.StatusConflict, admin("POST", "/app-metadata/"+app.ApplicationID+":lock", "").StatusCode)
+
+	app.Company = "updated company"
+	_, err = c.Update(ctx, app.ApplicationID, app)
+	assert.True(t, errors.Is(err, ErrLocked))
+	assert.Contains(t, err.Error(), "application is locked until an admin unlocks it: migrating")
+	assert.True(t, errors.Is(c.Delete(ctx, app.ApplicationID), ErrLocked))
+	got, err := c.Get(ctx, app.ApplicationID)
+	assert.Nil(t, err)
+	assert.Equal(t, "migrating", got.Lock.Reason)
+
+	// writes are refused in read-only mode, but turning it off
+	assert.Equal(t, http.StatusOK, admin("PUT", "/read-only", "enabled: true\nreason: maintenance").StatusCode)
+	req, _ := http.NewRequest("POST", ts.URL+"/app-metadata", strings.NewReader("title: Valid App 2"))
+	res, err := http.DefaultClient.Do(req)
+	assert.Nil(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+	assert.Equal(t, "60", res.Header.Get("Retry-After"))
+	assert.Equal(t, http.StatusServiceUnavailable, admin("POST", "/app-metadata/"+app.ApplicationID+":unlock", "").StatusCode)
+	_, err = c.Get(ctx, app.ApplicationID)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, admin("PUT", "/read-only", "enabled: false").StatusCode)
+
+	assert.Equal(t, http.StatusOK, admin("POST", "/app-metadata/"+app.ApplicationID+":unlock", "").StatusCode)
+	updated, err := c.Update(ctx, app.ApplicationID, app)
+	assert.Nil(t, err)
+	assert.Equal(t, "updated company", updated.Company)
+	assert.Nil(t, updated.Lock)
+}
+
+func createValidApp(title string) *metadata.ApplicationMetadata {
+	return &metadata.ApplicationMetadata{
+		Title:   title,
+		Version: "1.0.1",
+		Maintainers: []metadata.Maintainer{
+			{Name: "First Maintainer App1", Email: "firstmaintainer@hotmail.com"},
+		},
+		Company: "pellucid Computing",
+		Website: "http://pellucidcomputing.com",
+		Source:  "https://github.com/elumbantoruan/app-metadata",
+		License: "Apache-2.0",
+	}
+}
@@ -0,0 +1,95 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/elumbantoruan/app-metadata/metadata"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+var (
+	// ErrNotFound matches errors about an application that doesn't exist
+	ErrNotFound = errors.New("not found")
+	// ErrConflict matches 409 responses
+	ErrConflict = errors.New("conflict")
+	// ErrRateLimited matches 429 responses that were still rejected after retrying, or that asked to wait longer than
+	// the client waits between retries
+	ErrRateLimited = errors.New("rate limited")
+	// ErrLocked matches 423 responses about an application an admin locked
+	ErrLocked = errors.New("locked")
+)
+
+// serverIDNotFound is the message the service returns when updating or deleting an unknown appID
+const serverIDNotFound = "id not found"
+
+// Error is a response of the service with an unexpected status
+type Error struct {
+	StatusCode int
+	// Message is the error message returned by the service, if any
+	Message string
+	// RetryAfter is how long the service asked to wait before retrying, zero when it didn't
+	RetryAfter time.Duration
+}
+
+// Error returns the description of the error
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("app-metadata: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("app-metadata: %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// Is matches ErrNotFound, ErrConflict, ErrRateLimited, and ErrLocked.
+// The service reports an unknown appID on update and delete with 409, so it matches both ErrConflict and ErrNotFound.
+func (e *Error) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound || (e.StatusCode == http.StatusConflict && e.Message == serverIDNotFound)
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrLocked:
+		return e.StatusCode == http.StatusLocked
+	}
+	return false
+}
+
+// ValidationError is a 400 response carrying the validation message of the payload
+type ValidationError struct {
+	Err        *Error
+	Validation metadata.ValidationMessage
+}
+
+// Error returns the description of the error
+func (e *ValidationError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying Error
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// newError reads the body of an unexpected response into an error
+func newError(res *http.Response) error {
+	b, _ := ioutil.ReadAll(res.Body)
+	e := &Error{StatusCode: res.StatusCode}
+	e.RetryAfter, _ = retryAfter(res)
+
+	var vm metadata.ValidationMessage
+	if res.StatusCode == http.StatusBadRequest && yaml.Unmarshal(b, &vm) == nil && vm.Description != "" {
+		e.Message = vm.Description
+		return &ValidationError{Err: e, Validation: vm}
+	}
+	var msg string
+	if yaml.Unmarshal(b, &msg) == nil {
+		e.Message = msg
+	}
+	return e
+}
@@ -0,0 +1,124 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/elumbantoruan/app-metadata/metadata"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// DefaultPageSize is the number of applications fetched per request by List
+const DefaultPageSize = 100
+
+// ListOptions filters and pages the applications returned by List
+type ListOptions struct {
+	// LabelSelector such as "team=payments,tier in (1,2)"
+	LabelSelector string
+	// FieldSelector such as "company=acme,extensions.oncall.rotation=weekly"
+	FieldSelector string
+	// State is a comma separated list of lifecycle states, or "all".  Only published applications are listed when empty.
+	State string
+	// PageSize is the number of applications fetched per request, DefaultPageSize when zero
+	PageSize int
+}
+
+// Iterator walks the applications returned by List, fetching a page at a time
+//
+//	it := c.List(ctx, client.ListOptions{})
+//	for it.Next() {
+//		app := it.Value()
+//	}
+//	if err := it.Err(); err != nil {
+//	}
+type Iterator struct {
+	c     *Client
+	ctx   context.Context
+	opts  ListOptions
+	page  []metadata.ApplicationMetadata
+	index int
+	token string
+	done  bool
+	err   error
+}
+
+// List returns an iterator over the applications matching opts, ordered by appID
+func (c *Client) List(ctx context.Context, opts ListOptions) *Iterator {
+	if opts.PageSize <= 0 {
+		opts.PageSize = DefaultPageSize
+	}
+	return &Iterator{c: c, ctx: ctx, opts: opts, index: -1}
+}
+
+// Next advances to the next application, it returns false when there are no more or an error occurred
+func (it *Iterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+	it.index++
+	for it.index >= len(it.page) {
+		if it.done {
+			return false
+		}
+		if it.err = it.fetch(); it.err != nil {
+			return false
+		}
+		it.index = 0
+	}
+	return true
+}
+
+// Value returns the current application
+func (it *Iterator) Value() metadata.ApplicationMetadata {
+	return it.page[it.index]
+}
+
+// Err returns the error that stopped the iteration, if any
+func (it *Iterator) Err() error {
+	return it.err
+}
+
+// fetch gets the next page
+func (it *Iterator) fetch() error {
+	q := url.Values{}
+	q.Set("limit", strconv.Itoa(it.opts.PageSize))
+	if it.opts.LabelSelector != "" {
+		q.Set("labelSelector", it.opts.LabelSelector)
+	}
+	if it.opts.FieldSelector != "" {
+		q.Set("fieldSelector", it.opts.FieldSelector)
+	}
+	if it.opts.State != "" {
+		q.Set("state", it.opts.State)
+	}
+	if it.token != "" {
+		q.Set("continue", it.token)
+	}
+
+	res, err := it.c.send(it.ctx, http.MethodGet, "/app-metadata?"+q.Encode(), nil, nil)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	it.page = nil
+	if res.StatusCode >= 300 {
+		err := newError(res)
+		// the service returns 404 when nothing matches
+		if errors.Is(err, ErrNotFound) {
+			it.done = true
+			return nil
+		}
+		return err
+	}
+	if err := yaml.NewDecoder(res.Body).Decode(&it.page); err != nil {
+		return err
+	}
+	it.token = res.Header.Get("X-Continue")
+	it.done = it.token == ""
+	return nil
+}
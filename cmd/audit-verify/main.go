@@ -0,0 +1,38 @@
+// Command audit-verify checks the hash chain of an audit log file written with -audit-log
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/elumbantoruan/app-metadata/audit"
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s <audit log file>\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	f, err := os.Open(flag.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+
+	entries, err := audit.Read(f)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := audit.Verify(entries); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("ok: %d entries\n", len(entries))
+}
@@ -0,0 +1,107 @@
+package dedupe
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/elumbantoruan/app-metadata/metadata"
+)
+
+// DefaultTitleSimilarity is the similarity from which two titles are considered the same
+const DefaultTitleSimilarity = 0.85
+
+// reasons for a near-duplicate
+const (
+	SimilarTitle = "similar title"
+	SameSource   = "same source repository"
+)
+
+// Match is an application which may be a duplicate of another one
+type Match struct {
+	ApplicationID string   `yaml:"applicationID" json:"applicationID"`
+	Title         string   `yaml:"title" json:"title"`
+	Reasons       []string `yaml:"reasons" json:"reasons"`
+}
+
+// Find returns the candidates which may be duplicates of app: their normalized title is at least
+// threshold similar, or their source is the same repository, see metadata.SourceKey
+func Find(app *metadata.ApplicationMetadata, candidates []metadata.ApplicationMetadata, threshold float64) []Match {
+	title := NormalizeTitle(app.Title)
+	source := metadata.SourceKey(app.Source)
+
+	var matches []Match
+	for i := range candidates {
+		c := &candidates[i]
+		if c.ApplicationID == app.ApplicationID {
+			continue
+		}
+		var reasons []string
+		if title != "" && TitleSimilarity(title, NormalizeTitle(c.Title)) >= threshold {
+			reasons = append(reasons, SimilarTitle)
+		}
+		if source != "" && source == metadata.SourceKey(c.Source) {
+			reasons = append(reasons, SameSource)
+		}
+		if len(reasons) > 0 {
+			matches = append(matches, Match{ApplicationID: c.ApplicationID, Title: c.Title, Reasons: reasons})
+		}
+	}
+	return matches
+}
+
+// NormalizeTitle returns a title in normalization form C and lowercase, with punctuation replaced by spaces and
+// spaces collapsed
+func NormalizeTitle(title string) string {
+	title = metadata.NormalizeText(title)
+	mapped := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return ' '
+	}, title)
+	return strings.Join(strings.Fields(mapped), " ")
+}
+
+// TitleSimilarity returns 1 minus the edit distance of two titles relative to the longest one,
+// 1 meaning they're the same and 0 that they have nothing in common
+func TitleSimilarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longest := len(ra)
+	if len(rb) > longest {
+		longest = len(rb)
+	}
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = minInt(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func minInt(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
@@ -0,0 +1,33 @@
+package dedupe
+
+import (
+	"testing"
+
+	"github.com/elumbantoruan/app-metadata/metadata"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFind_NearDuplicates_ResultedMatches(t *testing.T) {
+
+	candidates := []metadata.ApplicationMetadata{
+		{ApplicationID: "appID1", Title: "Payments API", Source: "https://github.com/acme/payments"},
+		{ApplicationID: "appID2", Title: "Search", Source: "git@github.com:acme/payments-api.git"},
+		{ApplicationID: "appID3", Title: "Billing", Source: "https://gitlab.com/acme/payments"},
+		{ApplicationID: "appID4", Title: "Payment API", Source: "https://github.com/acme/payment-api"},
+	}
+	app := &metadata.ApplicationMetadata{Title: "payments-api", Source: "http://www.GitHub.com/acme/payments.git/tree/main"}
+
+	matches := Find(app, candidates, DefaultTitleSimilarity)
+
+	assert.Equal(t, []Match{
+		{ApplicationID: "appID1", Title: "Payments API", Reasons: []string{SimilarTitle, SameSource}},
+		{ApplicationID: "appID4", Title: "Payment API", Reasons: []string{SimilarTitle}},
+	}, matches)
+}
+
+func TestTitleSimilarity_ResultedRatio(t *testing.T) {
+
+	assert.Equal(t, 1.0, TitleSimilarity(NormalizeTitle("Payments-API!"), NormalizeTitle("payments api")))
+	assert.Equal(t, 0.75, TitleSimilarity("abcd", "abce"))
+	assert.Equal(t, 0.0, TitleSimilarity("abc", "xyz"))
+}
@@ -0,0 +1,222 @@
+package dependency
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/elumbantoruan/app-metadata/metadata"
+)
+
+// Lookup returns the application metadata for a given appID, or nil when it doesn't exist
+type Lookup func(appID string) (*metadata.ApplicationMetadata, error)
+
+// Node is an application reached while walking the dependency graph
+type Node struct {
+	ApplicationID string `yaml:"applicationID" json:"applicationID"`
+	Title         string `yaml:"title" json:"title"`
+	Version       string `yaml:"version" json:"version"`
+	// Constraint is the version constraint declared on the edge leading to the application
+	Constraint string `yaml:"constraint,omitempty" json:"constraint,omitempty"`
+	// Depth is 1 for a direct dependency (or dependent), 2 for the next level, etc
+	Depth int `yaml:"depth" json:"depth"`
+}
+
+// Validate checks that every dependency of app exists, satisfies its version constraint,
+// and that none of them leads back to app
+func Validate(app *metadata.ApplicationMetadata, lookup Lookup) (*metadata.ValidationMessage, error) {
+	for _, d := range app.Dependencies {
+		dep, err := lookup(d.ApplicationID)
+		if err != nil {
+			return nil, err
+		}
+		if dep == nil {
+			return &metadata.ValidationMessage{
+				Description: fmt.Sprintf("dependency %s is not found", d.ApplicationID),
+			}, nil
+		}
+		if len(d.Version) > 0 {
+			c, err := metadata.ParseVersionConstraint(d.Version)
+			if err != nil {
+				return &metadata.ValidationMessage{Description: err.Error()}, nil
+			}
+			v, err := metadata.ParseSemVer(dep.Version)
+			if err != nil || !c.Check(v) {
+				return &metadata.ValidationMessage{
+					Description: fmt.Sprintf("dependency %s version %s doesn't satisfy %s", d.ApplicationID, dep.Version, d.Version),
+				}, nil
+			}
+		}
+	}
+
+	path, err := findCycle(app, lookup)
+	if err != nil {
+		return nil, err
+	}
+	if path != nil {
+		return &metadata.ValidationMessage{
+			Description: fmt.Sprintf("dependency cycle %v", path),
+		}, nil
+	}
+	return nil, nil
+}
+
+// ValidationError is returned by Check when the dependencies of an application aren't valid
+type ValidationError struct {
+	Validation metadata.ValidationMessage
+}
+
+func (e *ValidationError) Error() string {
+	return e.Validation.Description
+}
+
+// Check validates the dependencies of app like Validate, returning a ValidationError when they aren't valid.
+// It only checks an application whose dependencies changed from previous, so that it can check every write of a
+// repository without refusing the writes of other fields.  lookup is a Lookup, so that Check is a repository.CheckFunc.
+func Check(app, previous *metadata.ApplicationMetadata, lookup func(appID string) (*metadata.ApplicationMetadata, error)) error {
+	if previous != nil && reflect.DeepEqual(app.Dependencies, previous.Dependencies) {
+		return nil
+	}
+	desc, err := Validate(app, lookup)
+	if err != nil {
+		return err
+	}
+	if desc != nil {
+		return &ValidationError{Validation: *desc}
+	}
+	return nil
+}
+
+// findCycle returns the path leading from app back to itself, or nil when there is none.
+// The applications are compared by the applicationID lookup returns, so that a cycle through an alias is found.
+func findCycle(app *metadata.ApplicationMetadata, lookup Lookup) ([]string, error) {
+	visited := make(map[string]bool)
+	var visit func(id string, path []string) ([]string, error)
+	visit = func(id string, path []string) ([]string, error) {
+		if id == app.ApplicationID {
+			return append(path, id), nil
+		}
+		if visited[id] {
+			return nil, nil
+		}
+		visited[id] = true
+		cur, err := lookup(id)
+		if err != nil || cur == nil {
+			return nil, err
+		}
+		if canonical := cur.ApplicationID; canonical != "" && canonical != id {
+			// id is an alias
+			if canonical == app.ApplicationID {
+				return append(path, id), nil
+			}
+			if visited[canonical] {
+				return nil, nil
+			}
+			visited[canonical] = true
+		}
+		for _, d := range cur.Dependencies {
+			found, err := visit(d.ApplicationID, append(path, id))
+			if found != nil || err != nil {
+				return found, err
+			}
+		}
+		return nil, nil
+	}
+
+	for _, d := range app.Dependencies {
+		found, err := visit(d.ApplicationID, []string{app.ApplicationID})
+		if found != nil || err != nil {
+			return found, err
+		}
+	}
+	return nil, nil
+}
+
+// Dependencies walks the applications app depends on breadth first, up to maxDepth levels.
+// A maxDepth lower than 1 walks the whole transitive closure.  Missing applications are skipped.
+func Dependencies(app *metadata.ApplicationMetadata, lookup Lookup, maxDepth int) ([]Node, error) {
+	var nodes []Node
+	visited := map[string]bool{app.ApplicationID: true}
+	level := []*metadata.ApplicationMetadata{app}
+	for depth := 1; len(level) > 0 && (maxDepth < 1 || depth <= maxDepth); depth++ {
+		var next []*metadata.ApplicationMetadata
+		for _, cur := range level {
+			for _, d := range cur.Dependencies {
+				if visited[d.ApplicationID] {
+					continue
+				}
+				visited[d.ApplicationID] = true
+				dep, err := lookup(d.ApplicationID)
+				if err != nil {
+					return nil, err
+				}
+				if dep == nil {
+					continue
+				}
+				nodes = append(nodes, Node{
+					ApplicationID: d.ApplicationID,
+					Title:         dep.Title,
+					Version:       dep.Version,
+					Constraint:    d.Version,
+					Depth:         depth,
+				})
+				next = append(next, dep)
+			}
+		}
+		level = next
+	}
+	return nodes, nil
+}
+
+// Dependents walks the applications depending on appID breadth first, up to maxDepth levels.
+// A maxDepth lower than 1 walks the whole transitive closure.
+func Dependents(appID string, all []metadata.ApplicationMetadata, maxDepth int) []Node {
+	// reverse edges: dependency -> applications depending on it
+	reverse := make(map[string][]int)
+	for i, a := range all {
+		for _, d := range a.Dependencies {
+			reverse[d.ApplicationID] = append(reverse[d.ApplicationID], i)
+		}
+	}
+
+	var nodes []Node
+	visited := map[string]bool{appID: true}
+	level := []string{appID}
+	for depth := 1; len(level) > 0 && (maxDepth < 1 || depth <= maxDepth); depth++ {
+		var next []string
+		for _, id := range level {
+			for _, i := range reverse[id] {
+				a := all[i]
+				if visited[a.ApplicationID] {
+					continue
+				}
+				visited[a.ApplicationID] = true
+				nodes = append(nodes, Node{
+					ApplicationID: a.ApplicationID,
+					Title:         a.Title,
+					Version:       a.Version,
+					Constraint:    constraintOn(a, id),
+					Depth:         depth,
+				})
+				next = append(next, a.ApplicationID)
+			}
+		}
+		level = next
+	}
+	sort.SliceStable(nodes, func(i, j int) bool {
+		if nodes[i].Depth != nodes[j].Depth {
+			return nodes[i].Depth < nodes[j].Depth
+		}
+		return nodes[i].ApplicationID < nodes[j].ApplicationID
+	})
+	return nodes
+}
+
+func constraintOn(app metadata.ApplicationMetadata, appID string) string {
+	for _, d := range app.Dependencies {
+		if d.ApplicationID == appID {
+			return d.Version
+		}
+	}
+	return ""
+}
@@ -0,0 +1,120 @@
+package dependency
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/elumbantoruan/app-metadata/metadata"
+	"github.com/stretchr/testify/assert"
+)
+
+// createGraph returns a lookup of the applications, each depending on the ones listed, and aliases resolving
+// to the applications
+func createGraph(deps map[string][]string, aliases map[string]string) Lookup {
+	apps := make(map[string]*metadata.ApplicationMetadata)
+	for id, ds := range deps {
+		app := &metadata.ApplicationMetadata{ApplicationID: id, Title: "App " + id, Version: "1.2.0"}
+		for _, d := range ds {
+			app.Dependencies = append(app.Dependencies, metadata.Dependency{ApplicationID: d})
+		}
+		apps[id] = app
+	}
+	return func(appID string) (*metadata.ApplicationMetadata, error) {
+		if canonical, ok := aliases[appID]; ok {
+			appID = canonical
+		}
+		return apps[appID], nil
+	}
+}
+
+func TestValidate_Dependencies_ResultedValidationMessage(t *testing.T) {
+
+	// c -> b -> a, d -> a and b
+	lookup := createGraph(map[string][]string{"a": nil, "b": {"a"}, "c": {"b"}, "d": {"a", "b"}}, map[string]string{"old-c": "c"})
+
+	tests := []struct {
+		name     string
+		app      *metadata.ApplicationMetadata
+		expected string
+	}{
+		{name: "no dependency", app: &metadata.ApplicationMetadata{ApplicationID: "x"}},
+		{name: "diamond", app: &metadata.ApplicationMetadata{ApplicationID: "x",
+			Dependencies: []metadata.Dependency{{ApplicationID: "d"}, {ApplicationID: "c"}}}},
+		{name: "missing", app: &metadata.ApplicationMetadata{ApplicationID: "x",
+			Dependencies: []metadata.Dependency{{ApplicationID: "b"}, {ApplicationID: "missing"}}},
+			expected: "dependency missing is not found"},
+		{name: "satisfied constraint", app: &metadata.ApplicationMetadata{ApplicationID: "x",
+			Dependencies: []metadata.Dependency{{ApplicationID: "a", Version: "^1.0.0"}}}},
+		{name: "unsatisfied constraint", app: &metadata.ApplicationMetadata{ApplicationID: "x",
+			Dependencies: []metadata.Dependency{{ApplicationID: "a", Version: ">=2.0.0"}}},
+			expected: "dependency a version 1.2.0 doesn't satisfy >=2.0.0"},
+		{name: "self", app: &metadata.ApplicationMetadata{ApplicationID: "a",
+			Dependencies: []metadata.Dependency{{ApplicationID: "a"}}},
+			expected: "dependency cycle [a a]"},
+		{name: "cycle", app: &metadata.ApplicationMetadata{ApplicationID: "a",
+			Dependencies: []metadata.Dependency{{ApplicationID: "c"}}},
+			expected: "dependency cycle [a c b a]"},
+		{name: "cycle through an alias", app: &metadata.ApplicationMetadata{ApplicationID: "a",
+			Dependencies: []metadata.Dependency{{ApplicationID: "old-c"}}},
+			expected: "dependency cycle [a old-c b a]"},
+		{name: "alias of itself", app: &metadata.ApplicationMetadata{ApplicationID: "c",
+			Dependencies: []metadata.Dependency{{ApplicationID: "old-c"}}},
+			expected: "dependency cycle [c old-c]"},
+	}
+	for _, tt := range tests {
+		desc, err := Validate(tt.app, lookup)
+		assert.Nil(t, err, tt.name)
+		if tt.expected == "" {
+			assert.Nil(t, desc, tt.name)
+			continue
+		}
+		if assert.NotNil(t, desc, tt.name) {
+			assert.Equal(t, tt.expected, desc.Description, tt.name)
+		}
+	}
+}
+
+func TestValidate_LookupError_ResultedError(t *testing.T) {
+
+	errLookup := errors.New("storage is unavailable")
+	app := &metadata.ApplicationMetadata{ApplicationID: "x", Dependencies: []metadata.Dependency{{ApplicationID: "a"}}}
+	_, err := Validate(app, func(appID string) (*metadata.ApplicationMetadata, error) {
+		return nil, errLookup
+	})
+
+	assert.Equal(t, errLookup, err)
+}
+
+func TestCheck_Dependencies_ResultedValidationError(t *testing.T) {
+
+	lookup := createGraph(map[string][]string{"a": nil, "b": {"a"}}, nil)
+	app := &metadata.ApplicationMetadata{ApplicationID: "a", Dependencies: []metadata.Dependency{{ApplicationID: "b"}}}
+
+	err := Check(app, &metadata.ApplicationMetadata{ApplicationID: "a"}, lookup)
+	var ve *ValidationError
+	assert.True(t, errors.As(err, &ve))
+	assert.Equal(t, "dependency cycle [a b a]", ve.Validation.Description)
+	assert.Equal(t, "dependency cycle [a b a]", err.Error())
+
+	// a new application is always checked, one whose dependencies didn't change isn't
+	assert.NotNil(t, Check(app, nil, lookup))
+	assert.Nil(t, Check(app, app.DeepCopy(), lookup))
+}
+
+func TestDependents_Graph_ResultedByDepth(t *testing.T) {
+
+	all := []metadata.ApplicationMetadata{
+		{ApplicationID: "a"},
+		{ApplicationID: "b", Dependencies: []metadata.Dependency{{ApplicationID: "a", Version: "^1.0.0"}}},
+		{ApplicationID: "c", Dependencies: []metadata.Dependency{{ApplicationID: "b"}}},
+		{ApplicationID: "d", Dependencies: []metadata.Dependency{{ApplicationID: "a"}, {ApplicationID: "c"}}},
+	}
+
+	nodes := Dependents("a", all, 0)
+	assert.Equal(t, []Node{
+		{ApplicationID: "b", Constraint: "^1.0.0", Depth: 1},
+		{ApplicationID: "d", Depth: 1},
+		{ApplicationID: "c", Depth: 2},
+	}, nodes)
+	assert.Len(t, Dependents("a", all, 1), 2)
+}
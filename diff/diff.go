@@ -0,0 +1,195 @@
+package diff
+
+import (
+	"reflect"
+	"sort"
+
+	"github.com/elumbantoruan/app-metadata/metadata"
+)
+
+// kinds of change
+const (
+	Added   = "added"
+	Removed = "removed"
+	Changed = "changed"
+)
+
+// Change is a difference between two application metadata.  Path is the json name of the field, with the
+// email of a maintainer, the applicationID of a dependency, the version of a release, the key of a label, or the
+// namespace of an extension, such as maintainers[a@example.com].name
+type Change struct {
+	Path string      `yaml:"path" json:"path"`
+	Kind string      `yaml:"kind" json:"kind"`
+	Old  interface{} `yaml:"old,omitempty" json:"old,omitempty"`
+	New  interface{} `yaml:"new,omitempty" json:"new,omitempty"`
+}
+
+// Compare returns the changes from a to b ordered by path.  The ApplicationID and the fields
+// managed by the service besides the lifecycle state aren't compared.
+// Maintainers are compared as a set keyed by email, dependencies as a set keyed by applicationID, and releases
+// as a set keyed by version.
+func Compare(a, b *metadata.ApplicationMetadata) []Change {
+	var changes []Change
+	scalar := func(path, old, cur string) {
+		switch {
+		case old == cur:
+		case old == "":
+			changes = append(changes, Change{Path: path, Kind: Added, New: cur})
+		case cur == "":
+			changes = append(changes, Change{Path: path, Kind: Removed, Old: old})
+		default:
+			changes = append(changes, Change{Path: path, Kind: Changed, Old: old, New: cur})
+		}
+	}
+
+	scalar("company", a.Company, b.Company)
+	scalar("description", a.Description, b.Description)
+	scalar("license", a.License, b.License)
+	scalar("source", a.Source, b.Source)
+	scalar("state", a.State, b.State)
+	scalar("title", a.Title, b.Title)
+	scalar("version", a.Version, b.Version)
+	scalar("website", a.Website, b.Website)
+
+	oldMaintainers, curMaintainers := maintainersByEmail(a), maintainersByEmail(b)
+	for _, email := range keys(oldMaintainers, curMaintainers) {
+		old, inOld := oldMaintainers[email]
+		cur, inCur := curMaintainers[email]
+		path := "maintainers[" + email + "]"
+		switch {
+		case !inOld:
+			changes = append(changes, Change{Path: path, Kind: Added, New: cur})
+		case !inCur:
+			changes = append(changes, Change{Path: path, Kind: Removed, Old: old})
+		default:
+			scalar(path+".name", old.Name, cur.Name)
+		}
+	}
+
+	oldDependencies, curDependencies := dependenciesByID(a), dependenciesByID(b)
+	for _, id := range keys(oldDependencies, curDependencies) {
+		old, inOld := oldDependencies[id]
+		cur, inCur := curDependencies[id]
+		path := "dependencies[" + id + "]"
+		switch {
+		case !inOld:
+			changes = append(changes, Change{Path: path, Kind: Added, New: cur})
+		case !inCur:
+			changes = append(changes, Change{Path: path, Kind: Removed, Old: old})
+		default:
+			scalar(path+".version", old.Version, cur.Version)
+		}
+	}
+
+	oldReleases, curReleases := releasesByVersion(a), releasesByVersion(b)
+	for _, v := range keys(oldReleases, curReleases) {
+		old, inOld := oldReleases[v]
+		cur, inCur := curReleases[v]
+		path := "releases[" + v + "]"
+		switch {
+		case !inOld:
+			changes = append(changes, Change{Path: path, Kind: Added, New: cur})
+		case !inCur:
+			changes = append(changes, Change{Path: path, Kind: Removed, Old: old})
+		default:
+			if !old.ReleasedAt.Equal(cur.ReleasedAt) {
+				changes = append(changes, Change{Path: path + ".releasedAt", Kind: Changed, Old: old.ReleasedAt, New: cur.ReleasedAt})
+			}
+			scalar(path+".notes", old.Notes, cur.Notes)
+			if !reflect.DeepEqual(old.Artifacts, cur.Artifacts) {
+				changes = append(changes, Change{Path: path + ".artifacts", Kind: Changed, Old: old.Artifacts, New: cur.Artifacts})
+			}
+		}
+	}
+
+	for _, k := range keys(a.Labels, b.Labels) {
+		old, inOld := a.Labels[k]
+		cur, inCur := b.Labels[k]
+		path := "labels." + k
+		switch {
+		case !inOld:
+			changes = append(changes, Change{Path: path, Kind: Added, New: cur})
+		case !inCur:
+			changes = append(changes, Change{Path: path, Kind: Removed, Old: old})
+		case old != cur:
+			changes = append(changes, Change{Path: path, Kind: Changed, Old: old, New: cur})
+		}
+	}
+
+	for _, ns := range keys(a.Extensions, b.Extensions) {
+		old, inOld := a.Extensions[ns]
+		cur, inCur := b.Extensions[ns]
+		path := "extensions." + ns
+		switch {
+		case !inOld:
+			changes = append(changes, Change{Path: path, Kind: Added, New: cur})
+		case !inCur:
+			changes = append(changes, Change{Path: path, Kind: Removed, Old: old})
+		case !reflect.DeepEqual(old, cur):
+			changes = append(changes, Change{Path: path, Kind: Changed, Old: old, New: cur})
+		}
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes
+}
+
+// maintainersByEmail keys the maintainers by their email, which is case insensitive
+func maintainersByEmail(app *metadata.ApplicationMetadata) map[string]metadata.Maintainer {
+	m := make(map[string]metadata.Maintainer, len(app.Maintainers))
+	for _, mt := range app.Maintainers {
+		m[metadata.NormalizeEmail(mt.Email)] = mt
+	}
+	return m
+}
+
+func dependenciesByID(app *metadata.ApplicationMetadata) map[string]metadata.Dependency {
+	m := make(map[string]metadata.Dependency, len(app.Dependencies))
+	for _, d := range app.Dependencies {
+		m[d.ApplicationID] = d
+	}
+	return m
+}
+
+func releasesByVersion(app *metadata.ApplicationMetadata) map[string]metadata.Release {
+	m := make(map[string]metadata.Release, len(app.Releases))
+	for _, r := range app.Releases {
+		m[r.Version] = r
+	}
+	return m
+}
+
+// keys returns the sorted union of the keys of two maps of the same type
+func keys(a, b interface{}) []string {
+	set := make(map[string]bool)
+	for _, m := range []interface{}{a, b} {
+		switch m := m.(type) {
+		case map[string]string:
+			for k := range m {
+				set[k] = true
+			}
+		case map[string]metadata.Maintainer:
+			for k := range m {
+				set[k] = true
+			}
+		case map[string]metadata.Dependency:
+			for k := range m {
+				set[k] = true
+			}
+		case map[string]metadata.Release:
+			for k := range m {
+				set[k] = true
+			}
+		case metadata.Extensions:
+			for k := range m {
+				set[k] = true
+			}
+		}
+	}
+	res := make([]string, 0, len(set))
+	for k := range set {
+		res = append(res, k)
+	}
+	sort.Strings(res)
+	return res
+}
@@ -0,0 +1,141 @@
+package diff
+
+import (
+	"testing"
+	"time"
+
+	"github.com/elumbantoruan/app-metadata/metadata"
+	"github.com/stretchr/testify/assert"
+)
+
+func createApp() *metadata.ApplicationMetadata {
+	return &metadata.ApplicationMetadata{
+		ApplicationID: "appID1",
+		Title:         "Valid App 1",
+		Version:       "1.0.1",
+		Maintainers: []metadata.Maintainer{
+			{Name: "First Maintainer", Email: "first@example.com"},
+			{Name: "Second Maintainer", Email: "second@example.com"},
+		},
+		Company:     "pellucid Computing",
+		License:     "Apache-2.0",
+		Description: "line 1\nline 2\nline 3\nline 4\nline 5\nline 6\nline 7\nline 8",
+		Labels:      map[string]string{"team": "payments"},
+		Revision:    1,
+	}
+}
+
+func TestCompare_Maintainers_ResultedComparedAsSet(t *testing.T) {
+
+	a := createApp()
+	b := createApp()
+	b.Revision = 2
+	// reordered, with a different case, which isn't a change
+	b.Maintainers = []metadata.Maintainer{
+		{Name: "Second Maintainer", Email: "Second@Example.com"},
+		{Name: "First M.", Email: "first@example.com"},
+		{Name: "Third Maintainer", Email: "third@example.com"},
+	}
+
+	changes := Compare(a, b)
+
+	assert.Equal(t, []Change{
+		{Path: "maintainers[first@example.com].name", Kind: Changed, Old: "First Maintainer", New: "First M."},
+		{Path: "maintainers[third@example.com]", Kind: Added, New: metadata.Maintainer{Name: "Third Maintainer", Email: "third@example.com"}},
+	}, changes)
+}
+
+func TestCompare_Fields_ResultedChanges(t *testing.T) {
+
+	a := createApp()
+	b := createApp()
+	b.ApplicationID = "appID2"
+	b.Version = "1.1.0"
+	b.Company = ""
+	b.Labels = map[string]string{"team": "search", "tier": "1"}
+	b.Dependencies = []metadata.Dependency{{ApplicationID: "appID3", Version: "^1.0.0"}}
+
+	changes := Compare(a, b)
+
+	assert.Equal(t, []Change{
+		{Path: "company", Kind: Removed, Old: "pellucid Computing"},
+		{Path: "dependencies[appID3]", Kind: Added, New: metadata.Dependency{ApplicationID: "appID3", Version: "^1.0.0"}},
+		{Path: "labels.team", Kind: Changed, Old: "payments", New: "search"},
+		{Path: "labels.tier", Kind: Added, New: "1"},
+		{Path: "version", Kind: Changed, Old: "1.0.1", New: "1.1.0"},
+	}, changes)
+	assert.Empty(t, Compare(a, createApp()))
+}
+
+func TestCompare_Releases_ResultedComparedAsSet(t *testing.T) {
+
+	released := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	a := createApp()
+	a.SetReleases([]metadata.Release{
+		{Version: "1.0.0", ReleasedAt: released},
+		{Version: "1.0.1", ReleasedAt: released, Notes: "Fixes"},
+	})
+	b := createApp()
+	b.SetReleases([]metadata.Release{
+		{Version: "1.0.1", ReleasedAt: released, Notes: "Fixes the login"},
+		{Version: "1.0.0", ReleasedAt: released},
+		{Version: "1.1.0-rc.1", ReleasedAt: released},
+	})
+
+	changes := Compare(a, b)
+
+	assert.Equal(t, []Change{
+		{Path: "releases[1.0.1].notes", Kind: Changed, Old: "Fixes", New: "Fixes the login"},
+		{Path: "releases[1.1.0-rc.1]", Kind: Added, New: metadata.Release{Version: "1.1.0-rc.1", ReleasedAt: released}},
+	}, changes)
+}
+
+func TestUnified_Description_ResultedHunk(t *testing.T) {
+
+	a := createApp()
+	b := createApp()
+	b.Description = "line 1\nline 2\nline 3\nline 4\nline five\nline 6\nline 7\nline 8"
+	b.Maintainers = []metadata.Maintainer{a.Maintainers[1], a.Maintainers[0]}
+
+	u, err := Unified(a, b, "appID1@1", "appID1@2")
+
+	assert.Nil(t, err)
+	assert.Equal(t, `--- appID1@1
++++ appID1@2
+@@ -15,7 +15,7 @@
+   line 2
+   line 3
+   line 4
+-  line 5
++  line five
+   line 6
+   line 7
+   line 8
+`, u)
+
+	u, err = Unified(a, createApp(), "a", "b")
+	assert.Nil(t, err)
+	assert.Equal(t, "", u)
+}
+
+func TestEditScript_ResultedShortest(t *testing.T) {
+
+	ops := editScript([]string{"a", "b", "c", "a", "b", "b", "a"}, []string{"c", "b", "a", "b", "a", "c"})
+
+	changed := 0
+	var a, b []string
+	for _, o := range ops {
+		if o.kind != ' ' {
+			changed++
+		}
+		if o.kind != '+' {
+			a = append(a, o.line)
+		}
+		if o.kind != '-' {
+			b = append(b, o.line)
+		}
+	}
+	assert.Equal(t, 5, changed)
+	assert.Equal(t, []string{"a", "b", "c", "a", "b", "b", "a"}, a)
+	assert.Equal(t, []string{"c", "b", "a", "b", "a", "c"}, b)
+}
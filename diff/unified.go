@@ -0,0 +1,229 @@
+package diff
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/elumbantoruan/app-metadata/metadata"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// context is the number of unchanged lines around each hunk
+const context = 3
+
+// Unified returns a unified diff from a to b, named nameA and nameB in the header, comparing their yaml
+// representation with the same fields and set semantics as Compare.  It's empty when there's no change.
+func Unified(a, b *metadata.ApplicationMetadata, nameA, nameB string) (string, error) {
+	la, err := canonicalLines(a)
+	if err != nil {
+		return "", err
+	}
+	lb, err := canonicalLines(b)
+	if err != nil {
+		return "", err
+	}
+
+	ops := editScript(la, lb)
+	var sb strings.Builder
+	for _, h := range hunks(ops) {
+		if sb.Len() == 0 {
+			fmt.Fprintf(&sb, "--- %s\n+++ %s\n", nameA, nameB)
+		}
+		fmt.Fprintf(&sb, "@@ -%s +%s @@\n", span(h.aStart, h.aCount), span(h.bStart, h.bCount))
+		for _, o := range ops[h.from:h.to] {
+			sb.WriteByte(o.kind)
+			sb.WriteString(o.line)
+			sb.WriteByte('\n')
+		}
+	}
+	return sb.String(), nil
+}
+
+// canonicalLines returns the yaml lines of app without the fields Compare ignores,
+// with the maintainers ordered by email and the dependencies by applicationID
+func canonicalLines(app *metadata.ApplicationMetadata) ([]string, error) {
+	c := *app
+	c.ApplicationID = ""
+	c.DeletedAt = nil
+	c.Review = nil
+	c.Lock = nil
+	c.Revision = 0
+	c.Maintainers = append([]metadata.Maintainer(nil), app.Maintainers...)
+	sort.SliceStable(c.Maintainers, func(i, j int) bool {
+		return metadata.NormalizeEmail(c.Maintainers[i].Email) < metadata.NormalizeEmail(c.Maintainers[j].Email)
+	})
+	c.Dependencies = append([]metadata.Dependency(nil), app.Dependencies...)
+	sort.SliceStable(c.Dependencies, func(i, j int) bool {
+		return c.Dependencies[i].ApplicationID < c.Dependencies[j].ApplicationID
+	})
+
+	b, err := yaml.Marshal(&c)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Split(strings.TrimSuffix(string(b), "\n"), "\n"), nil
+}
+
+// op is a line of an edit script, kind being ' ' when it's unchanged, '-' when it's removed, and '+' when it's added
+type op struct {
+	kind byte
+	line string
+}
+
+// editScript returns the shortest edit script turning a into b, using the Myers algorithm
+func editScript(a, b []string) []op {
+	// the common prefix and suffix are kept out of the search
+	pre := 0
+	for pre < len(a) && pre < len(b) && a[pre] == b[pre] {
+		pre++
+	}
+	suf := 0
+	for suf < len(a)-pre && suf < len(b)-pre && a[len(a)-1-suf] == b[len(b)-1-suf] {
+		suf++
+	}
+
+	var ops []op
+	for _, l := range a[:pre] {
+		ops = append(ops, op{' ', l})
+	}
+	ops = append(ops, myers(a[pre:len(a)-suf], b[pre:len(b)-suf])...)
+	for _, l := range a[len(a)-suf:] {
+		ops = append(ops, op{' ', l})
+	}
+	return ops
+}
+
+func myers(a, b []string) []op {
+	n, m := len(a), len(b)
+	offset := n + m
+	v := make([]int, 2*offset+2)
+	var trace [][]int
+
+search:
+	for d := 0; d <= n+m; d++ {
+		trace = append(trace, append([]int(nil), v...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				break search
+			}
+		}
+	}
+
+	// walk the trace back from the end, collecting the operations in reverse
+	var rev []op
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			rev = append(rev, op{' ', a[x-1]})
+			x--
+			y--
+		}
+		if d > 0 {
+			if x == prevX {
+				rev = append(rev, op{'+', b[y-1]})
+			} else {
+				rev = append(rev, op{'-', a[x-1]})
+			}
+		}
+		x, y = prevX, prevY
+	}
+
+	ops := make([]op, len(rev))
+	for i := range rev {
+		ops[i] = rev[len(rev)-1-i]
+	}
+	return ops
+}
+
+// hunk is the range [from, to) of an edit script, starting at line aStart of a and bStart of b
+type hunk struct {
+	from, to       int
+	aStart, aCount int
+	bStart, bCount int
+}
+
+// hunks groups the changed lines of an edit script with their surrounding context
+func hunks(ops []op) []hunk {
+	var res []hunk
+	aLine, bLine := 0, 0
+	// positions of each op in a and b
+	aPos := make([]int, len(ops)+1)
+	bPos := make([]int, len(ops)+1)
+	for i, o := range ops {
+		aPos[i], bPos[i] = aLine, bLine
+		if o.kind != '+' {
+			aLine++
+		}
+		if o.kind != '-' {
+			bLine++
+		}
+	}
+	aPos[len(ops)], bPos[len(ops)] = aLine, bLine
+
+	for i := 0; i < len(ops); i++ {
+		if ops[i].kind == ' ' {
+			continue
+		}
+		from := i - context
+		if from < 0 {
+			from = 0
+		}
+		// extend while the next change is close enough for the context to overlap
+		to := i
+		for j := i; j < len(ops) && j <= to+2*context; j++ {
+			if ops[j].kind != ' ' {
+				to = j
+			}
+		}
+		i = to
+		to += context + 1
+		if to > len(ops) {
+			to = len(ops)
+		}
+		if n := len(res); n > 0 && res[n-1].to >= from {
+			from = res[n-1].from
+			res = res[:n-1]
+		}
+		res = append(res, hunk{
+			from: from, to: to,
+			aStart: aPos[from], aCount: aPos[to] - aPos[from],
+			bStart: bPos[from], bCount: bPos[to] - bPos[from],
+		})
+	}
+	return res
+}
+
+// span formats the range of a hunk, lines being numbered from 1
+func span(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
@@ -0,0 +1,108 @@
+// Package emailpolicy restricts the email domains maintainers may use
+package emailpolicy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/elumbantoruan/app-metadata/metadata"
+)
+
+// DefaultDisposableDomains are well known providers of throwaway mailboxes
+var DefaultDisposableDomains = []string{
+	"10minutemail.com",
+	"dispostable.com",
+	"getnada.com",
+	"guerrillamail.com",
+	"maildrop.cc",
+	"mailinator.com",
+	"sharklasers.com",
+	"temp-mail.org",
+	"trashmail.com",
+	"yopmail.com",
+}
+
+// Policy restricts the email domains of the maintainers of an application.
+// A domain matches itself and its subdomains, so that acme.com allows eng.acme.com.
+type Policy struct {
+	// CompanyDomains are the domains the maintainers of a company's applications must use, keyed by lowercase company
+	CompanyDomains map[string][]string
+	// Blocklist are the domains no maintainer may use
+	Blocklist []string
+}
+
+// New returns a Policy with domains of each company, such as parsed by ParseCompanyDomains, and blocked domains
+func New(companyDomains map[string][]string, blocked []string) *Policy {
+	p := &Policy{CompanyDomains: make(map[string][]string, len(companyDomains))}
+	for company, domains := range companyDomains {
+		company = strings.ToLower(company)
+		for _, d := range domains {
+			p.CompanyDomains[company] = append(p.CompanyDomains[company], metadata.NormalizeDomain(d))
+		}
+	}
+	for _, d := range blocked {
+		p.Blocklist = append(p.Blocklist, metadata.NormalizeDomain(d))
+	}
+	return p
+}
+
+// ParseCompanyDomains parses company domains separated by ';', each being a company, '=', and a comma separated
+// list of domains, such as "Acme=acme.com,acme.io;Globex=globex.com"
+func ParseCompanyDomains(s string) (map[string][]string, error) {
+	res := make(map[string][]string)
+	for _, c := range strings.Split(s, ";") {
+		if strings.TrimSpace(c) == "" {
+			continue
+		}
+		company, domains, ok := strings.Cut(c, "=")
+		company = strings.TrimSpace(company)
+		if !ok || company == "" {
+			return nil, fmt.Errorf("invalid company domains %q, expected company=domain,...", c)
+		}
+		for _, d := range strings.Split(domains, ",") {
+			d = metadata.NormalizeDomain(d)
+			if d == "" || strings.Contains(d, "@") {
+				return nil, fmt.Errorf("invalid domain %q for company %s", d, company)
+			}
+			res[company] = append(res[company], d)
+		}
+	}
+	return res, nil
+}
+
+// Check returns an error describing the first maintainer of app whose email breaks the policy
+func (p *Policy) Check(app *metadata.ApplicationMetadata) error {
+	allowed := p.CompanyDomains[strings.ToLower(strings.TrimSpace(app.Company))]
+	for _, m := range app.Maintainers {
+		domain := Domain(m.Email)
+		for _, b := range p.Blocklist {
+			if matches(domain, b) {
+				return fmt.Errorf("%s uses the disposable domain %s", m.Email, b)
+			}
+		}
+		if len(allowed) == 0 {
+			continue
+		}
+		ok := false
+		for _, d := range allowed {
+			ok = ok || matches(domain, d)
+		}
+		if !ok {
+			sorted := append([]string(nil), allowed...)
+			sort.Strings(sorted)
+			return fmt.Errorf("%s isn't allowed for company %s, expected a domain among %s", m.Email, app.Company, strings.Join(sorted, ", "))
+		}
+	}
+	return nil
+}
+
+// Domain returns the domain of an email address in lowercase punycode, see metadata.NormalizeDomain
+func Domain(email string) string {
+	i := strings.LastIndex(email, "@")
+	return metadata.NormalizeDomain(email[i+1:])
+}
+
+func matches(domain, d string) bool {
+	return domain == d || strings.HasSuffix(domain, "."+d)
+}
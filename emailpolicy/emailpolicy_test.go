@@ -0,0 +1,38 @@
+package emailpolicy
+
+import (
+	"testing"
+
+	"github.com/elumbantoruan/app-metadata/metadata"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCompanyDomains_ResultedParsed(t *testing.T) {
+
+	domains, err := ParseCompanyDomains("Acme=acme.com, ACME.io.;Globex=globex.com")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string][]string{"Acme": {"acme.com", "acme.io"}, "Globex": {"globex.com"}}, domains)
+
+	_, err = ParseCompanyDomains("acme.com")
+	assert.NotNil(t, err)
+	_, err = ParseCompanyDomains("Acme=")
+	assert.NotNil(t, err)
+}
+
+func TestPolicy_Check_ResultedRejected(t *testing.T) {
+
+	p := New(map[string][]string{"Acme": {"acme.com"}}, DefaultDisposableDomains)
+	app := func(company, email string) *metadata.ApplicationMetadata {
+		return &metadata.ApplicationMetadata{Company: company, Maintainers: []metadata.Maintainer{{Name: "M", Email: email}}}
+	}
+
+	assert.Nil(t, p.Check(app("acme", "first@Acme.com")))
+	assert.Nil(t, p.Check(app("Acme", "first@eng.acme.com")))
+	assert.Nil(t, p.Check(app("Globex", "first@gmail.com")))
+	assert.EqualError(t, p.Check(app("Acme", "first@gmail.com")),
+		"first@gmail.com isn't allowed for company Acme, expected a domain among acme.com")
+	assert.EqualError(t, p.Check(app("Acme", "first@notacme.com")),
+		"first@notacme.com isn't allowed for company Acme, expected a domain among acme.com")
+	assert.EqualError(t, p.Check(app("Globex", "first@Mailinator.com")),
+		"first@Mailinator.com uses the disposable domain mailinator.com")
+}
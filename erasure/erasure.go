@@ -0,0 +1,183 @@
+// Package erasure removes the personal data of a maintainer from application metadata and audit entries
+package erasure
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/elumbantoruan/app-metadata/audit"
+	"github.com/elumbantoruan/app-metadata/metadata"
+)
+
+// what happens to the maintainer in the application metadata, audit entries are always pseudonymized
+const (
+	// Pseudonymize replaces the name and email of the maintainer, keeping the number of maintainers
+	Pseudonymize = "pseudonymize"
+	// Remove drops the maintainer from the applications
+	Remove = "remove"
+)
+
+// ErasedName replaces the name of an erased maintainer
+const ErasedName = "Erased Maintainer"
+
+// ErrUnknownMode is returned by New for a mode other than Pseudonymize or Remove
+var ErrUnknownMode = errors.New("unknown erasure mode")
+
+// Erasure replaces a maintainer by a random pseudonym, the same for every record it's applied to
+type Erasure struct {
+	// Email is the normalized email of the maintainer
+	Email string
+	Mode  string
+	// Pseudonym replaces the email, it can't be traced back to it
+	Pseudonym string
+	re        *regexp.Regexp
+}
+
+// New returns an Erasure of the maintainer with a given email, in Pseudonymize mode when mode is empty
+func New(email, mode string) (*Erasure, error) {
+	if mode == "" {
+		mode = Pseudonymize
+	}
+	if mode != Pseudonymize && mode != Remove {
+		return nil, fmt.Errorf("%w %q, expected %s or %s", ErrUnknownMode, mode, Pseudonymize, Remove)
+	}
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	email = metadata.NormalizeEmail(email)
+	return &Erasure{
+		Email:     email,
+		Mode:      mode,
+		Pseudonym: "erased-" + hex.EncodeToString(b) + "@erased.invalid",
+		re:        regexp.MustCompile("(?i)" + regexp.QuoteMeta(email)),
+	}, nil
+}
+
+// Application erases the maintainer from the maintainers and the review of app, and returns true when it changed.
+// It assigns new slices rather than changing the ones of app, which may be shared with other copies.
+func (e *Erasure) Application(app *metadata.ApplicationMetadata) bool {
+	changed := false
+	var maintainers []metadata.Maintainer
+	for _, m := range app.Maintainers {
+		if metadata.NormalizeEmail(m.Email) != e.Email {
+			maintainers = append(maintainers, m)
+			continue
+		}
+		changed = true
+		if e.Mode == Pseudonymize {
+			maintainers = append(maintainers, metadata.Maintainer{Name: ErasedName, Email: e.Pseudonym})
+		}
+	}
+	if changed {
+		app.Maintainers = maintainers
+	}
+	if app.Review != nil && (e.matches(app.Review.SubmittedBy) || e.matches(app.Review.ApprovedBy)) {
+		review := *app.Review
+		if e.matches(review.SubmittedBy) {
+			review.SubmittedBy = e.Pseudonym
+		}
+		if e.matches(review.ApprovedBy) {
+			review.ApprovedBy = e.Pseudonym
+		}
+		app.Review = &review
+		changed = true
+	}
+	return changed
+}
+
+// AuditEntry pseudonymizes the maintainer in entry, and returns true when it changed.  The source IP of the entries
+// made by the maintainer is dropped as well.
+func (e *Erasure) AuditEntry(entry *audit.Entry) bool {
+	changed := false
+	if e.matches(entry.Principal) {
+		entry.Principal = e.Pseudonym
+		entry.SourceIP = ""
+		changed = true
+	}
+	if reason := e.re.ReplaceAllLiteralString(entry.Reason, e.Pseudonym); reason != entry.Reason {
+		entry.Reason = reason
+		changed = true
+	}
+	if len(entry.Changes) > 0 {
+		changes := make([]audit.Change, len(entry.Changes))
+		for i, c := range entry.Changes {
+			var oldChanged, newChanged bool
+			c.Old, oldChanged = e.value(c.Old)
+			c.New, newChanged = e.value(c.New)
+			changes[i] = c
+			changed = changed || oldChanged || newChanged
+		}
+		entry.Changes = changes
+	}
+	return changed
+}
+
+// value pseudonymizes the maintainer in a generic json value, the maintainer objects by their email and any other
+// string containing the email, and returns a copy when it changed
+func (e *Erasure) value(v interface{}) (interface{}, bool) {
+	switch v := v.(type) {
+	case string:
+		s := e.re.ReplaceAllLiteralString(v, e.Pseudonym)
+		return s, s != v
+	case []interface{}:
+		res := make([]interface{}, len(v))
+		changed := false
+		for i, item := range v {
+			var c bool
+			res[i], c = e.value(item)
+			changed = changed || c
+		}
+		return res, changed
+	case map[string]interface{}:
+		res := make(map[string]interface{}, len(v))
+		changed := false
+		for k, item := range v {
+			var c bool
+			res[k], c = e.value(item)
+			changed = changed || c
+		}
+		if email, ok := v["email"].(string); ok && e.matches(email) {
+			if _, ok := v["name"]; ok {
+				res["name"] = ErasedName
+			}
+		}
+		return res, changed
+	}
+	return v, false
+}
+
+// References returns true when b, such as a stored response, contains the email of the maintainer in any case
+func (e *Erasure) References(b []byte) bool {
+	return e.re.Match(b)
+}
+
+func (e *Erasure) matches(email string) bool {
+	return email != "" && metadata.NormalizeEmail(email) == e.Email
+}
+
+// ReferencesApplication returns true when app lists the maintainer with a given email or records their review
+func ReferencesApplication(app *metadata.ApplicationMetadata, email string) bool {
+	email = metadata.NormalizeEmail(email)
+	for _, m := range app.Maintainers {
+		if metadata.NormalizeEmail(m.Email) == email {
+			return true
+		}
+	}
+	return app.Review != nil &&
+		(metadata.NormalizeEmail(app.Review.SubmittedBy) == email || metadata.NormalizeEmail(app.Review.ApprovedBy) == email)
+}
+
+// ReferencesAuditEntry returns true when the email appears anywhere in entry
+func ReferencesAuditEntry(entry *audit.Entry, email string) bool {
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return false
+	}
+	return strings.Contains(strings.ToLower(string(b)), metadata.NormalizeEmail(email))
+}
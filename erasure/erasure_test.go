@@ -0,0 +1,85 @@
+package erasure
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/elumbantoruan/app-metadata/audit"
+	"github.com/elumbantoruan/app-metadata/metadata"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErasure_Application_ResultedErased(t *testing.T) {
+
+	maintainers := []metadata.Maintainer{
+		{Name: "First Maintainer", Email: "First@Example.com"},
+		{Name: "Second Maintainer", Email: "second@example.com"},
+	}
+	app := metadata.ApplicationMetadata{
+		Maintainers: maintainers,
+		Review:      &metadata.Review{SubmittedBy: "first@example.com", ApprovedBy: "second@example.com"},
+	}
+
+	e, err := New("first@example.com", "")
+	assert.Nil(t, err)
+	assert.Equal(t, Pseudonymize, e.Mode)
+	assert.Regexp(t, `^erased-[0-9a-f]{16}@erased\.invalid$`, e.Pseudonym)
+
+	erased := app
+	assert.True(t, e.Application(&erased))
+	assert.Equal(t, []metadata.Maintainer{
+		{Name: ErasedName, Email: e.Pseudonym},
+		{Name: "Second Maintainer", Email: "second@example.com"},
+	}, erased.Maintainers)
+	assert.Equal(t, e.Pseudonym, erased.Review.SubmittedBy)
+	assert.Equal(t, "second@example.com", erased.Review.ApprovedBy)
+	assert.False(t, e.Application(&erased))
+
+	// the original slices are untouched
+	assert.Equal(t, "First Maintainer", maintainers[0].Name)
+	assert.Equal(t, "first@example.com", app.Review.SubmittedBy)
+	assert.True(t, ReferencesApplication(&app, "FIRST@example.com"))
+	assert.False(t, ReferencesApplication(&erased, "first@example.com"))
+
+	e, _ = New("first@example.com", Remove)
+	removed := app
+	assert.True(t, e.Application(&removed))
+	assert.Equal(t, maintainers[1:], removed.Maintainers)
+
+	_, err = New("first@example.com", "shred")
+	assert.NotNil(t, err)
+}
+
+func TestErasure_AuditEntry_ResultedPseudonymized(t *testing.T) {
+
+	e, _ := New("first@example.com", "")
+	entry := audit.Entry{
+		Principal: "first@example.com",
+		SourceIP:  "10.0.0.1",
+		Reason:    "denied to First@Example.com",
+		Changes: []audit.Change{{
+			Field: "maintainers",
+			New:   []interface{}{map[string]interface{}{"name": "First Maintainer", "email": "first@example.com"}},
+		}},
+	}
+	assert.True(t, ReferencesAuditEntry(&entry, "first@example.com"))
+
+	assert.True(t, e.AuditEntry(&entry))
+	assert.Equal(t, e.Pseudonym, entry.Principal)
+	assert.Equal(t, "", entry.SourceIP)
+	assert.Equal(t, "denied to "+e.Pseudonym, entry.Reason)
+	assert.Equal(t, []interface{}{map[string]interface{}{"name": ErasedName, "email": e.Pseudonym}}, entry.Changes[0].New)
+	assert.False(t, ReferencesAuditEntry(&entry, "first@example.com"))
+	assert.False(t, e.AuditEntry(&entry))
+}
+
+func TestNew_UnknownMode_ResultedError(t *testing.T) {
+
+	_, err := New("first@example.com", "shred")
+	assert.True(t, errors.Is(err, ErrUnknownMode))
+
+	er, err := New("First@Example.com", "")
+	assert.Nil(t, err)
+	assert.Equal(t, Pseudonymize, er.Mode)
+	assert.Equal(t, "first@example.com", er.Email)
+}
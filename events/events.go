@@ -0,0 +1,127 @@
+package events
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/elumbantoruan/app-metadata/metadata"
+	"github.com/elumbantoruan/app-metadata/repository"
+)
+
+// event types
+const (
+	Created = repository.Created
+	Updated = repository.Updated
+	Deleted = repository.Deleted
+)
+
+// Event is a change of an application metadata
+type Event struct {
+	// ResourceVersion increases monotonically with every event
+	ResourceVersion uint64 `yaml:"resourceVersion" json:"resourceVersion"`
+	Type            string `yaml:"type" json:"type"`
+	ApplicationID   string `yaml:"applicationID" json:"applicationID"`
+	// Object is the application after the change, or its last state when it's deleted
+	Object *metadata.ApplicationMetadata `yaml:"object,omitempty" json:"object,omitempty"`
+	Time   time.Time                     `yaml:"time" json:"time"`
+}
+
+var (
+	// ErrGone is returned when the requested resource version was dropped from the log
+	ErrGone = errors.New("resource version is too old")
+)
+
+// Log keeps the latest events in memory, dropping the oldest ones beyond its capacity
+type Log struct {
+	mu       sync.Mutex
+	capacity int
+	events   []Event
+	version  uint64
+	// dropped is the resource version of the latest event dropped from the log
+	dropped uint64
+	// changed is closed and replaced whenever an event is appended
+	changed chan struct{}
+	now     func() time.Time
+}
+
+// NewLog returns an instance of Log keeping up to capacity events
+func NewLog(capacity int) *Log {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &Log{
+		capacity: capacity,
+		changed:  make(chan struct{}),
+		now:      time.Now,
+	}
+}
+
+// Append records a change and wakes up the watchers
+func (l *Log) Append(eventType, appID string, obj *metadata.ApplicationMetadata) Event {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.version++
+	e := Event{
+		ResourceVersion: l.version,
+		Type:            eventType,
+		ApplicationID:   appID,
+		Object:          obj,
+		Time:            l.now().UTC(),
+	}
+	if len(l.events) == l.capacity {
+		l.dropped = l.events[0].ResourceVersion
+		copy(l.events, l.events[1:])
+		l.events = l.events[:len(l.events)-1]
+	}
+	l.events = append(l.events, e)
+
+	close(l.changed)
+	l.changed = make(chan struct{})
+	return e
+}
+
+// Version returns the resource version of the latest event
+func (l *Log) Version() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.version
+}
+
+// Since returns the events after the given resource version, and a channel closed on the next Append.
+// It returns ErrGone when some of those events were already dropped.
+func (l *Log) Since(version uint64) ([]Event, <-chan struct{}, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if version < l.dropped {
+		return nil, nil, ErrGone
+	}
+	var res []Event
+	for _, e := range l.events {
+		if e.ResourceVersion > version {
+			res = append(res, e)
+		}
+	}
+	return res, l.changed, nil
+}
+
+// Scrub rewrites the objects of the events kept in the log with fn, such as to erase personal data.
+// fn is given a copy of each object, which replaces it when fn returns true, since the watchers may be
+// writing the previous one to their clients.
+func (l *Log) Scrub(fn func(obj *metadata.ApplicationMetadata) bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for i := range l.events {
+		if l.events[i].Object == nil {
+			continue
+		}
+		obj := l.events[i].Object.DeepCopy()
+		if fn(obj) {
+			l.events[i].Object = obj
+		}
+	}
+}
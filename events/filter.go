@@ -0,0 +1,49 @@
+package events
+
+import (
+	"github.com/elumbantoruan/app-metadata/labels"
+	"github.com/elumbantoruan/app-metadata/metadata"
+)
+
+// Filter selects the events sent to a watcher by the labels and fields of the application
+type Filter struct {
+	Selector labels.Selector
+	fields   []metadata.FieldRequirement
+}
+
+// NewFilter parses a label selector and a field selector, a comma separated list of field=value or field!=value
+// where field is type or one of the fields supported by metadata.ParseFieldSelector
+func NewFilter(labelSelector, fieldSelector string) (Filter, error) {
+	var f Filter
+	sel, err := labels.Parse(labelSelector)
+	if err != nil {
+		return f, err
+	}
+	f.Selector = sel
+	f.fields, err = metadata.ParseFieldSelector(fieldSelector, "type")
+	return f, err
+}
+
+// Matches returns true when the event satisfies every label and field requirement
+func (f Filter) Matches(e Event) bool {
+	obj := e.Object
+	if obj == nil {
+		obj = &metadata.ApplicationMetadata{ApplicationID: e.ApplicationID}
+	}
+	if !f.Selector.Matches(obj.Labels) {
+		return false
+	}
+	for _, req := range f.fields {
+		value := obj.FieldValue(req.Field)
+		switch req.Field {
+		case "type":
+			value = e.Type
+		case "applicationID":
+			value = e.ApplicationID
+		}
+		if !req.Matches(value) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,38 @@
+package events
+
+import (
+	"github.com/elumbantoruan/app-metadata/metadata"
+	"github.com/elumbantoruan/app-metadata/repository"
+)
+
+// RecordingRepository is a MetadataRepository appending an event to a Log for every write of the repository it
+// wraps.  The events are appended by the change function of the wrapped repository, under its write lock, so that
+// they're recorded in the order the writes are applied.
+type RecordingRepository struct {
+	repository.MetadataRepository
+	Log *Log
+}
+
+// NewRecordingRepository returns an instance of RecordingRepository.
+// Undelete is recorded as created, Rename as deleted then created, and Purge only when the application wasn't
+// already in the trash.
+func NewRecordingRepository(repo repository.MetadataRepository, log *Log) repository.MetadataRepository {
+	repo.OnChange(func(changeType, appID string, data *metadata.ApplicationMetadata) {
+		log.Append(changeType, appID, data)
+	})
+	return &RecordingRepository{
+		MetadataRepository: repo,
+		Log:                log,
+	}
+}
+
+// Scrub rewrites every application metadata, along with the objects of the events still in the log.
+// The live applications changed are recorded as updated.
+func (rr *RecordingRepository) Scrub(fn func(data *metadata.ApplicationMetadata) bool) ([]string, error) {
+	ids, err := rr.MetadataRepository.Scrub(fn)
+	if err != nil {
+		return ids, err
+	}
+	rr.Log.Scrub(fn)
+	return ids, nil
+}
@@ -0,0 +1,57 @@
+// Package extensions validates the extensions of an application against the JSON Schema registered for each namespace
+package extensions
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/elumbantoruan/app-metadata/metadata"
+)
+
+// MaxNamespaceLength is the maximum length of an extension namespace
+const MaxNamespaceLength = 63
+
+var namespaceRe = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+// ValidateNamespace checks an extension namespace, lowercase alphanumeric characters or '-'
+// starting and ending with an alphanumeric, so that it can be used in a URL and a field selector
+func ValidateNamespace(namespace string) error {
+	if len(namespace) == 0 || len(namespace) > MaxNamespaceLength || !namespaceRe.MatchString(namespace) {
+		return fmt.Errorf("extension namespace %q must be at most %d lowercase alphanumeric characters or '-', starting and ending with an alphanumeric", namespace, MaxNamespaceLength)
+	}
+	return nil
+}
+
+// NoSchemaError is returned for an extension whose namespace has no schema registered
+type NoSchemaError struct {
+	Namespace string
+}
+
+func (e *NoSchemaError) Error() string {
+	return fmt.Sprintf("no schema is registered for extension namespace %s", e.Namespace)
+}
+
+// Validate checks the document of every namespace of ext against the schema returned by lookup,
+// which returns nil when the namespace has no schema.  The path of a *ValidationError starts with extensions.
+func Validate(ext metadata.Extensions, lookup func(namespace string) (*Schema, error)) error {
+	namespaces := make([]string, 0, len(ext))
+	for ns := range ext {
+		namespaces = append(namespaces, ns)
+	}
+	// report the first error in a stable order
+	sort.Strings(namespaces)
+	for _, ns := range namespaces {
+		s, err := lookup(ns)
+		if err != nil {
+			return err
+		}
+		if s == nil {
+			return &NoSchemaError{Namespace: ns}
+		}
+		if err := s.Validate(ext[ns], "extensions."+ns); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package extensions
+
+import (
+	"testing"
+
+	"github.com/elumbantoruan/app-metadata/metadata"
+	"github.com/stretchr/testify/assert"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+const oncallSchema = `
+type: object
+required: [rotation]
+additionalProperties: false
+properties:
+  rotation:
+    enum: [daily, weekly]
+  pager:
+    type: string
+    pattern: "^https://"
+  escalation:
+    type: array
+    minItems: 1
+    items:
+      type: object
+      required: [email]
+      properties:
+        email: {type: string}
+        after: {type: integer, minimum: 0}
+`
+
+func compileYAML(t *testing.T, doc string) *Schema {
+	var v interface{}
+	assert.Nil(t, yaml.Unmarshal([]byte(doc), &v))
+	s, err := Compile(metadata.Normalize(v))
+	assert.Nil(t, err)
+	return s
+}
+
+func TestValidate_Extensions_ResultedErrors(t *testing.T) {
+
+	schema := compileYAML(t, oncallSchema)
+	lookup := func(namespace string) (*Schema, error) {
+		if namespace == "oncall" {
+			return schema, nil
+		}
+		return nil, nil
+	}
+
+	tests := []struct {
+		name     string
+		payload  string
+		expected string
+	}{
+		{
+			name:    "valid",
+			payload: "oncall:\n  rotation: weekly\n  escalation:\n  - email: a@example.com\n    after: 15\n",
+		},
+		{
+			name:     "not in enum",
+			payload:  "oncall:\n  rotation: monthly\n",
+			expected: "extensions.oncall.rotation: must be one of daily, weekly",
+		},
+		{
+			name:     "missing required",
+			payload:  "oncall:\n  pager: https://pager.example.com\n",
+			expected: "extensions.oncall: rotation is required",
+		},
+		{
+			name:     "additional property",
+			payload:  "oncall:\n  rotation: daily\n  team: payments\n",
+			expected: "extensions.oncall: team isn't an allowed property",
+		},
+		{
+			name:     "nested item",
+			payload:  "oncall:\n  rotation: daily\n  escalation:\n  - email: a@example.com\n    after: 1.5\n",
+			expected: "extensions.oncall.escalation[0].after: must be integer",
+		},
+		{
+			name:     "pattern",
+			payload:  "oncall:\n  rotation: daily\n  pager: http://pager.example.com\n",
+			expected: "extensions.oncall.pager: must match ^https://",
+		},
+		{
+			name:     "unregistered namespace",
+			payload:  "cost:\n  center: 42\n",
+			expected: "no schema is registered for extension namespace cost",
+		},
+	}
+	for _, tt := range tests {
+		var ext metadata.Extensions
+		assert.Nil(t, yaml.Unmarshal([]byte(tt.payload), &ext), tt.name)
+		err := Validate(ext, lookup)
+		if tt.expected == "" {
+			assert.Nil(t, err, tt.name)
+		} else if assert.NotNil(t, err, tt.name) {
+			assert.Equal(t, tt.expected, err.Error(), tt.name)
+		}
+	}
+}
+
+func TestCompile_InvalidSchema_ResultedError(t *testing.T) {
+
+	for _, doc := range []interface{}{
+		"object",
+		map[string]interface{}{"type": "text"},
+		map[string]interface{}{"$ref": "#/definitions/oncall"},
+		map[string]interface{}{"minLength": -1},
+		map[string]interface{}{"properties": map[string]interface{}{"rotation": 1}},
+	} {
+		_, err := Compile(doc)
+		assert.NotNil(t, err, "%v", doc)
+	}
+}
+
+func TestValidateNamespace_ResultedError(t *testing.T) {
+
+	assert.Nil(t, ValidateNamespace("oncall"))
+	assert.Nil(t, ValidateNamespace("cost-center"))
+	assert.NotNil(t, ValidateNamespace("Oncall"))
+	assert.NotNil(t, ValidateNamespace("on.call"))
+	assert.NotNil(t, ValidateNamespace(""))
+}
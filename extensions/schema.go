@@ -0,0 +1,466 @@
+package extensions
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// unsupported are the JSON Schema keywords which would be silently ignored, so a schema using them is rejected
+var unsupported = []string{
+	"$ref", "$defs", "definitions", "patternProperties", "propertyNames", "dependencies", "dependentRequired",
+	"dependentSchemas", "if", "then", "else", "prefixItems", "contains", "unevaluatedProperties", "unevaluatedItems",
+}
+
+var jsonTypes = map[string]bool{
+	"null": true, "boolean": true, "object": true, "array": true, "number": true, "integer": true, "string": true,
+}
+
+// Schema is a compiled JSON Schema document.  It supports type, enum, const, the numeric, string, array, and
+// object constraints, and the allOf, anyOf, oneOf, and not combinations.  format and annotations such as
+// title and description are ignored.
+type Schema struct {
+	// always is set for the true and false schemas
+	always *bool
+
+	types                []string
+	enum                 []interface{}
+	constant             interface{}
+	hasConst             bool
+	minimum, maximum     *float64
+	exclusiveMinimum     *float64
+	exclusiveMaximum     *float64
+	multipleOf           *float64
+	minLength, maxLength *int
+	pattern              *regexp.Regexp
+	items                *Schema
+	minItems, maxItems   *int
+	uniqueItems          bool
+	properties           map[string]*Schema
+	required             []string
+	additional           *Schema
+	minProps, maxProps   *int
+	allOf, anyOf, oneOf  []*Schema
+	not                  *Schema
+}
+
+// Compile compiles a JSON Schema document decoded into generic values, see metadata.Normalize
+func Compile(doc interface{}) (*Schema, error) {
+	return compile(doc, "")
+}
+
+func compile(doc interface{}, path string) (*Schema, error) {
+	if b, ok := doc.(bool); ok {
+		return &Schema{always: &b}, nil
+	}
+	m, ok := doc.(map[string]interface{})
+	if !ok {
+		return nil, schemaError(path, "a schema must be an object or a boolean")
+	}
+	for _, k := range unsupported {
+		if _, ok := m[k]; ok {
+			return nil, schemaError(path, fmt.Sprintf("keyword %s isn't supported", k))
+		}
+	}
+
+	s := &Schema{}
+	var err error
+	switch t := m["type"].(type) {
+	case nil:
+	case string:
+		s.types = []string{t}
+	case []interface{}:
+		for _, v := range t {
+			name, _ := v.(string)
+			s.types = append(s.types, name)
+		}
+	default:
+		return nil, schemaError(path, "type must be a string or an array of strings")
+	}
+	for _, t := range s.types {
+		if !jsonTypes[t] {
+			return nil, schemaError(path, fmt.Sprintf("unknown type %q", t))
+		}
+	}
+	if e, ok := m["enum"]; ok {
+		if s.enum, ok = e.([]interface{}); !ok {
+			return nil, schemaError(path, "enum must be an array")
+		}
+	}
+	s.constant, s.hasConst = m["const"]
+
+	for keyword, dst := range map[string]**float64{
+		"minimum": &s.minimum, "maximum": &s.maximum, "exclusiveMinimum": &s.exclusiveMinimum,
+		"exclusiveMaximum": &s.exclusiveMaximum, "multipleOf": &s.multipleOf,
+	} {
+		if *dst, err = number(m, keyword, path); err != nil {
+			return nil, err
+		}
+	}
+	for keyword, dst := range map[string]**int{
+		"minLength": &s.minLength, "maxLength": &s.maxLength, "minItems": &s.minItems, "maxItems": &s.maxItems,
+		"minProperties": &s.minProps, "maxProperties": &s.maxProps,
+	} {
+		if *dst, err = count(m, keyword, path); err != nil {
+			return nil, err
+		}
+	}
+	if p, ok := m["pattern"]; ok {
+		expr, _ := p.(string)
+		if s.pattern, err = regexp.Compile(expr); err != nil {
+			return nil, schemaError(path, fmt.Sprintf("invalid pattern: %v", err))
+		}
+	}
+	if u, ok := m["uniqueItems"]; ok {
+		if s.uniqueItems, ok = u.(bool); !ok {
+			return nil, schemaError(path, "uniqueItems must be a boolean")
+		}
+	}
+
+	if items, ok := m["items"]; ok {
+		if s.items, err = compile(items, path+"/items"); err != nil {
+			return nil, err
+		}
+	}
+	if props, ok := m["properties"]; ok {
+		pm, ok := props.(map[string]interface{})
+		if !ok {
+			return nil, schemaError(path, "properties must be an object")
+		}
+		s.properties = make(map[string]*Schema, len(pm))
+		for name, p := range pm {
+			if s.properties[name], err = compile(p, path+"/properties/"+name); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if req, ok := m["required"]; ok {
+		list, ok := req.([]interface{})
+		if !ok {
+			return nil, schemaError(path, "required must be an array of strings")
+		}
+		for _, r := range list {
+			name, ok := r.(string)
+			if !ok {
+				return nil, schemaError(path, "required must be an array of strings")
+			}
+			s.required = append(s.required, name)
+		}
+	}
+	if add, ok := m["additionalProperties"]; ok {
+		if s.additional, err = compile(add, path+"/additionalProperties"); err != nil {
+			return nil, err
+		}
+	}
+	for keyword, dst := range map[string]*[]*Schema{"allOf": &s.allOf, "anyOf": &s.anyOf, "oneOf": &s.oneOf} {
+		v, ok := m[keyword]
+		if !ok {
+			continue
+		}
+		list, ok := v.([]interface{})
+		if !ok || len(list) == 0 {
+			return nil, schemaError(path, keyword+" must be a non-empty array of schemas")
+		}
+		for i, sub := range list {
+			c, err := compile(sub, fmt.Sprintf("%s/%s/%d", path, keyword, i))
+			if err != nil {
+				return nil, err
+			}
+			*dst = append(*dst, c)
+		}
+	}
+	if not, ok := m["not"]; ok {
+		if s.not, err = compile(not, path+"/not"); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func number(m map[string]interface{}, keyword, path string) (*float64, error) {
+	v, ok := m[keyword]
+	if !ok {
+		return nil, nil
+	}
+	f, ok := toFloat(v)
+	if !ok {
+		return nil, schemaError(path, keyword+" must be a number")
+	}
+	return &f, nil
+}
+
+func count(m map[string]interface{}, keyword, path string) (*int, error) {
+	v, ok := m[keyword]
+	if !ok {
+		return nil, nil
+	}
+	f, ok := toFloat(v)
+	if !ok || f < 0 || f != math.Trunc(f) {
+		return nil, schemaError(path, keyword+" must be a non-negative integer")
+	}
+	n := int(f)
+	return &n, nil
+}
+
+func schemaError(path, msg string) error {
+	if path == "" {
+		return fmt.Errorf("invalid schema: %s", msg)
+	}
+	return fmt.Errorf("invalid schema at %s: %s", path, msg)
+}
+
+// ValidationError is a value not matching its schema
+type ValidationError struct {
+	// Path is the dotted path of the value, such as oncall.rotation
+	Path    string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Path + ": " + e.Message
+}
+
+// Validate returns a *ValidationError when v, decoded into generic values, doesn't match the schema.
+// path prefixes the path of the error.
+func (s *Schema) Validate(v interface{}, path string) error {
+	invalid := func(format string, args ...interface{}) error {
+		return &ValidationError{Path: path, Message: fmt.Sprintf(format, args...)}
+	}
+	if s.always != nil {
+		if *s.always {
+			return nil
+		}
+		return invalid("no value is allowed")
+	}
+
+	if len(s.types) > 0 && !matchesType(v, s.types) {
+		return invalid("must be %s", strings.Join(s.types, " or "))
+	}
+	if s.enum != nil {
+		found := false
+		for _, e := range s.enum {
+			if equal(v, e) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return invalid("must be one of %s", formatValues(s.enum))
+		}
+	}
+	if s.hasConst && !equal(v, s.constant) {
+		return invalid("must be %v", s.constant)
+	}
+
+	if f, ok := toFloat(v); ok {
+		switch {
+		case s.minimum != nil && f < *s.minimum:
+			return invalid("must be at least %v", *s.minimum)
+		case s.maximum != nil && f > *s.maximum:
+			return invalid("must be at most %v", *s.maximum)
+		case s.exclusiveMinimum != nil && f <= *s.exclusiveMinimum:
+			return invalid("must be greater than %v", *s.exclusiveMinimum)
+		case s.exclusiveMaximum != nil && f >= *s.exclusiveMaximum:
+			return invalid("must be less than %v", *s.exclusiveMaximum)
+		case s.multipleOf != nil && *s.multipleOf != 0 && math.Abs(math.Remainder(f, *s.multipleOf)) > 1e-9:
+			return invalid("must be a multiple of %v", *s.multipleOf)
+		}
+	}
+
+	switch v := v.(type) {
+	case string:
+		n := len([]rune(v))
+		switch {
+		case s.minLength != nil && n < *s.minLength:
+			return invalid("must be at least %d characters long", *s.minLength)
+		case s.maxLength != nil && n > *s.maxLength:
+			return invalid("must be at most %d characters long", *s.maxLength)
+		case s.pattern != nil && !s.pattern.MatchString(v):
+			return invalid("must match %s", s.pattern)
+		}
+	case []interface{}:
+		switch {
+		case s.minItems != nil && len(v) < *s.minItems:
+			return invalid("must have at least %d items", *s.minItems)
+		case s.maxItems != nil && len(v) > *s.maxItems:
+			return invalid("must have at most %d items", *s.maxItems)
+		}
+		for i, item := range v {
+			if s.uniqueItems {
+				for _, prev := range v[:i] {
+					if equal(item, prev) {
+						return invalid("must have unique items")
+					}
+				}
+			}
+			if s.items != nil {
+				if err := s.items.Validate(item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	case map[string]interface{}:
+		switch {
+		case s.minProps != nil && len(v) < *s.minProps:
+			return invalid("must have at least %d properties", *s.minProps)
+		case s.maxProps != nil && len(v) > *s.maxProps:
+			return invalid("must have at most %d properties", *s.maxProps)
+		}
+		for _, r := range s.required {
+			if _, ok := v[r]; !ok {
+				return invalid("%s is required", r)
+			}
+		}
+		names := make([]string, 0, len(v))
+		for k := range v {
+			names = append(names, k)
+		}
+		// report the first error in a stable order
+		sort.Strings(names)
+		for _, k := range names {
+			sub, ok := s.properties[k]
+			if !ok {
+				sub = s.additional
+			}
+			if sub == nil {
+				continue
+			}
+			if err := sub.Validate(v[k], path+"."+k); err != nil {
+				if !ok && sub.always != nil && !*sub.always {
+					return invalid("%s isn't an allowed property", k)
+				}
+				return err
+			}
+		}
+	}
+
+	for _, sub := range s.allOf {
+		if err := sub.Validate(v, path); err != nil {
+			return err
+		}
+	}
+	if len(s.anyOf) > 0 {
+		matched := false
+		for _, sub := range s.anyOf {
+			if sub.Validate(v, path) == nil {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return invalid("must match at least one of the anyOf schemas")
+		}
+	}
+	if len(s.oneOf) > 0 {
+		matched := 0
+		for _, sub := range s.oneOf {
+			if sub.Validate(v, path) == nil {
+				matched++
+			}
+		}
+		if matched != 1 {
+			return invalid("must match exactly one of the oneOf schemas, it matches %d", matched)
+		}
+	}
+	if s.not != nil && s.not.Validate(v, path) == nil {
+		return invalid("must not match the not schema")
+	}
+	return nil
+}
+
+// matchesType returns true when v is of one of the JSON types
+func matchesType(v interface{}, types []string) bool {
+	for _, t := range types {
+		switch t {
+		case "null":
+			if v == nil {
+				return true
+			}
+		case "boolean":
+			if _, ok := v.(bool); ok {
+				return true
+			}
+		case "object":
+			if _, ok := v.(map[string]interface{}); ok {
+				return true
+			}
+		case "array":
+			if _, ok := v.([]interface{}); ok {
+				return true
+			}
+		case "number":
+			if _, ok := toFloat(v); ok {
+				return true
+			}
+		case "integer":
+			if f, ok := toFloat(v); ok && f == math.Trunc(f) {
+				return true
+			}
+		case "string":
+			if _, ok := v.(string); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// toFloat returns the value of a number decoded from yaml or json
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+// equal compares two generic values, numbers by value whether they were decoded from yaml or json
+func equal(a, b interface{}) bool {
+	fa, okA := toFloat(a)
+	fb, okB := toFloat(b)
+	if okA || okB {
+		return okA && okB && fa == fb
+	}
+	switch a := a.(type) {
+	case []interface{}:
+		b, ok := b.([]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !equal(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]interface{}:
+		b, ok := b.(map[string]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for k, v := range a {
+			if !equal(v, b[k]) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func formatValues(values []interface{}) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = fmt.Sprint(v)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -6,7 +6,10 @@ require (
 	github.com/google/uuid v1.1.0
 	github.com/gorilla/mux v1.7.0
 	github.com/stretchr/testify v1.2.2
+	golang.org/x/net v0.17.0
+	golang.org/x/text v0.14.0
 	gopkg.in/yaml.v2 v2.2.8
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
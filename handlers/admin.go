@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// AdminTokenHeader is the request header carrying the token authorizing admin operations
+const AdminTokenHeader = "X-Admin-Token"
+
+// isAdmin returns true when the request carries the admin token
+func (mh *MetadataHandler) isAdmin(r *http.Request) bool {
+	token := r.Header.Get(AdminTokenHeader)
+	return mh.AdminToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(mh.AdminToken)) == 1
+}
+
+// requireAdmin writes 403 and returns false when the request doesn't carry the admin token
+func (mh *MetadataHandler) requireAdmin(w http.ResponseWriter, r *http.Request) bool {
+	if mh.isAdmin(r) {
+		return true
+	}
+	w.WriteHeader(http.StatusForbidden) // 403
+	yaml.NewEncoder(w).Encode("admin token is required")
+	return false
+}
@@ -0,0 +1,259 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/elumbantoruan/app-metadata/audit"
+	"github.com/elumbantoruan/app-metadata/metadata"
+	"github.com/elumbantoruan/app-metadata/repository"
+	"github.com/gorilla/mux"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// RenameRequest is the payload of a rename, ApplicationID being the new slug of the application
+type RenameRequest struct {
+	ApplicationID string `yaml:"applicationID" json:"applicationID"`
+}
+
+// ResolveAliases is a middleware resolving an alias in the appID route variable to the application it stands for.
+// GET and HEAD requests are redirected permanently to the canonical URL, other requests are served transparently.
+func (mh *MetadataHandler) ResolveAliases(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		appID, ok := vars["appID"]
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		canonical, err := mh.Repository.ResolveAlias(appID)
+		if err != nil || canonical == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			u := *r.URL
+			u.Path = strings.Replace(u.Path, "/app-metadata/"+appID, "/app-metadata/"+canonical, 1)
+			u.RawPath = ""
+			http.Redirect(w, r, u.String(), http.StatusMovedPermanently) // 301
+			return
+		}
+		resolved := make(map[string]string, len(vars))
+		for k, v := range vars {
+			resolved[k] = v
+		}
+		resolved["appID"] = canonical
+		next.ServeHTTP(w, mux.SetURLVars(r, resolved))
+	})
+}
+
+// lookup returns the application metadata for appID or the application it's an alias of,
+// so that dependencies on a renamed application are still found
+func (mh *MetadataHandler) lookup(appID string) (*metadata.ApplicationMetadata, error) {
+	app, err := mh.Repository.Get(appID)
+	if err != nil || app != nil {
+		return app, err
+	}
+	canonical, err := mh.Repository.ResolveAlias(appID)
+	if err != nil || canonical == "" {
+		return nil, err
+	}
+	return mh.Repository.Get(canonical)
+}
+
+// resolveDependencies rewrites the dependencies of app on an alias to the application it stands for, so that the
+// applications are linked by their applicationID
+func (mh *MetadataHandler) resolveDependencies(app *metadata.ApplicationMetadata) error {
+	if len(app.Dependencies) == 0 {
+		return nil
+	}
+	deps := make([]metadata.Dependency, len(app.Dependencies))
+	for i, d := range app.Dependencies {
+		canonical, err := mh.Repository.ResolveAlias(d.ApplicationID)
+		if err != nil {
+			return err
+		}
+		if canonical != "" {
+			d.ApplicationID = canonical
+		}
+		deps[i] = d
+	}
+	app.Dependencies = deps
+	return nil
+}
+
+// HandleRenameMetadata handles POST operation changing the applicationID of an application to another slug.
+// The previous applicationID is kept as an alias redirecting to the new one.
+func (mh *MetadataHandler) HandleRenameMetadata(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	appID, ok := mux.Vars(r)["appID"]
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest) // 400
+		return
+	}
+	b, ok := mh.readBody(w, r)
+	if !ok {
+		return
+	}
+	var req RenameRequest
+	if err := mh.unmarshal(b, &req); err != nil {
+		w.WriteHeader(http.StatusBadRequest) // 400
+		yaml.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	if err := metadata.ValidateSlug(req.ApplicationID); err != nil {
+		w.WriteHeader(http.StatusBadRequest) // 400
+		yaml.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	existing, err := mh.Repository.Get(appID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError) // 500
+		yaml.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	if existing == nil {
+		w.WriteHeader(http.StatusNotFound) // 404
+		return
+	}
+	if !mh.checkUnlocked(w, r, audit.Rename, appID, existing) {
+		return
+	}
+
+	err = mh.Repository.Rename(appID, req.ApplicationID)
+	if !mh.checkLockError(w, r, audit.Rename, err) {
+		return
+	}
+	if err != nil {
+		switch err {
+		case repository.ErrIDNotFound:
+			w.WriteHeader(http.StatusNotFound) // 404
+		case repository.ErrIDConflict:
+			w.WriteHeader(http.StatusConflict) // 409
+		default:
+			w.WriteHeader(http.StatusInternalServerError) // 500
+		}
+		yaml.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	res, err := mh.Repository.Get(req.ApplicationID)
+	if err != nil || res == nil {
+		w.WriteHeader(http.StatusInternalServerError) // 500
+		return
+	}
+	mh.auditAllowed(r, audit.Rename, appID, existing, res)
+
+	w.WriteHeader(http.StatusOK) // 200
+	yaml.NewEncoder(w).Encode(res)
+}
+
+// HandleGetAliases handles GET operation returning the aliases of an application
+func (mh *MetadataHandler) HandleGetAliases(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	appID, ok := mux.Vars(r)["appID"]
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest) // 400
+		return
+	}
+	app, err := mh.Repository.Get(appID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError) // 500
+		yaml.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	if app == nil {
+		w.WriteHeader(http.StatusNotFound) // 404
+		return
+	}
+
+	aliases, err := mh.Repository.Aliases(appID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError) // 500
+		yaml.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusOK) // 200
+	yaml.NewEncoder(w).Encode(aliases)
+}
+
+// HandlePutAlias handles PUT operation adding an alias to an application
+func (mh *MetadataHandler) HandlePutAlias(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	vars := mux.Vars(r)
+	appID, alias := vars["appID"], vars["alias"]
+	if appID == "" || alias == "" {
+		w.WriteHeader(http.StatusBadRequest) // 400
+		return
+	}
+	if err := metadata.ValidateSlug(alias); err != nil {
+		w.WriteHeader(http.StatusBadRequest) // 400
+		yaml.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	// the aliases of a locked application can't change
+	err := mh.Repository.AddAlias(appID, alias)
+	if !mh.checkLockError(w, r, audit.Alias, err) {
+		return
+	}
+	if err != nil {
+		switch err {
+		case repository.ErrIDNotFound:
+			w.WriteHeader(http.StatusNotFound) // 404
+		case repository.ErrIDConflict:
+			w.WriteHeader(http.StatusConflict) // 409
+		default:
+			w.WriteHeader(http.StatusInternalServerError) // 500
+		}
+		yaml.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	mh.auditAlias(r, appID, audit.Change{Field: "aliases", New: alias})
+
+	w.WriteHeader(http.StatusNoContent) // 204
+}
+
+// HandleDeleteAlias handles DELETE operation removing an alias of an application
+func (mh *MetadataHandler) HandleDeleteAlias(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	vars := mux.Vars(r)
+	appID, alias := vars["appID"], vars["alias"]
+	if appID == "" || alias == "" {
+		w.WriteHeader(http.StatusBadRequest) // 400
+		return
+	}
+
+	err := mh.Repository.RemoveAlias(appID, alias)
+	if !mh.checkLockError(w, r, audit.Alias, err) {
+		return
+	}
+	if err != nil {
+		if err == repository.ErrIDNotFound {
+			w.WriteHeader(http.StatusNotFound) // 404
+		} else {
+			w.WriteHeader(http.StatusInternalServerError) // 500
+		}
+		yaml.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	mh.auditAlias(r, appID, audit.Change{Field: "aliases", Old: alias})
+
+	w.WriteHeader(http.StatusNoContent) // 204
+}
+
+// auditAlias records an alias added to or removed from appID
+func (mh *MetadataHandler) auditAlias(r *http.Request, appID string, change audit.Change) {
+	if mh.Audit == nil {
+		return
+	}
+	e := mh.auditEntry(r, audit.Alias, appID, audit.Allowed)
+	e.Changes = []audit.Change{change}
+	mh.appendAudit(e)
+}
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/elumbantoruan/app-metadata/metadata"
+	"github.com/elumbantoruan/app-metadata/repository"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestMetadataHandler_HandlePostMetadataSlug_ResultedCreated(t *testing.T) {
+
+	mh := NewMetadataHandler(repository.NewInMemoryMetadataRepository())
+
+	tests := []struct {
+		slug string
+		code int
+	}{
+		{slug: "payments-api", code: http.StatusCreated},
+		{slug: "payments-api", code: http.StatusConflict},
+		{slug: "Payments_API", code: http.StatusBadRequest},
+		{slug: "payments-", code: http.StatusBadRequest},
+		{slug: "events", code: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		request, _ := http.NewRequest("POST", "app-metadata", strings.NewReader("applicationID: "+tt.slug+createValidPayload()))
+		responseRecorder := httptest.NewRecorder()
+		mh.HandlePostMetadata(responseRecorder, request)
+
+		assert.Equal(t, tt.code, responseRecorder.Code, tt.slug)
+	}
+
+	res, _ := mh.Repository.Get("payments-api")
+	assert.NotNil(t, res)
+}
+
+func TestMetadataHandler_HandleRenameMetadata_ResultedRedirect(t *testing.T) {
+
+	im := repository.NewInMemoryMetadataRepository()
+	var mtd metadata.ApplicationMetadata
+	yaml.Unmarshal([]byte(createValidPayload()), &mtd)
+	mtd.ApplicationID = "payments"
+	im.Create("payments", &mtd)
+	im.Create("other-app", &metadata.ApplicationMetadata{ApplicationID: "other-app"})
+	mh := NewMetadataHandler(im)
+
+	rename := func(appID, newID string) *httptest.ResponseRecorder {
+		request, _ := http.NewRequest("POST", "/app-metadata/"+appID+":rename", strings.NewReader("applicationID: "+newID))
+		request = mux.SetURLVars(request, map[string]string{"appID": appID})
+		responseRecorder := httptest.NewRecorder()
+		mh.HandleRenameMetadata(responseRecorder, request)
+		return responseRecorder
+	}
+
+	assert.Equal(t, http.StatusConflict, rename("payments", "other-app").Code)
+	assert.Equal(t, http.StatusBadRequest, rename("payments", "Payments").Code)
+	assert.Equal(t, http.StatusNotFound, rename("missing", "missing-app").Code)
+
+	responseRecorder := rename("payments", "payments-api")
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	res, _ := im.Get("payments-api")
+	assert.Equal(t, "payments-api", res.ApplicationID)
+	assert.Equal(t, 2, res.Revision)
+	old, _ := im.Get("payments")
+	assert.Nil(t, old)
+
+	// the previous slug redirects to the new one through the router
+	m := mux.NewRouter()
+	m.Use(mh.ResolveAliases)
+	m.HandleFunc("/app-metadata/{appID}", mh.HandleGetMetadata).Methods("GET")
+	m.HandleFunc("/app-metadata/{appID}", mh.HandlePutMetadata).Methods("PUT")
+
+	request, _ := http.NewRequest("GET", "/app-metadata/payments", strings.NewReader(""))
+	responseRecorder = httptest.NewRecorder()
+	m.ServeHTTP(responseRecorder, request)
+	assert.Equal(t, http.StatusMovedPermanently, responseRecorder.Code)
+	assert.Equal(t, "/app-metadata/payments-api", responseRecorder.Header().Get("Location"))
+
+	// and writes are applied transparently
+	request, _ = http.NewRequest("PUT", "/app-metadata/payments", strings.NewReader(createValidPayload2()))
+	responseRecorder = httptest.NewRecorder()
+	m.ServeHTTP(responseRecorder, request)
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	res, _ = im.Get("payments-api")
+	assert.Equal(t, "Valid App 2", res.Title)
+
+	// renaming back to the previous slug is allowed
+	assert.Equal(t, http.StatusOK, rename("payments-api", "payments").Code)
+	aliases, _ := im.Aliases("payments")
+	assert.Equal(t, []string{"payments-api"}, aliases)
+}
+
+func TestMetadataHandler_HandlePutAlias_ResultedAliases(t *testing.T) {
+
+	im := repository.NewInMemoryMetadataRepository()
+	im.Create("appID1", &metadata.ApplicationMetadata{ApplicationID: "appID1"})
+	im.Create("appID2", &metadata.ApplicationMetadata{ApplicationID: "appID2"})
+	mh := NewMetadataHandler(im)
+
+	tests := []struct {
+		method string
+		appID  string
+		alias  string
+		code   int
+	}{
+		{method: "PUT", appID: "appID1", alias: "payments", code: http.StatusNoContent},
+		{method: "PUT", appID: "appID1", alias: "billing", code: http.StatusNoContent},
+		{method: "PUT", appID: "appID2", alias: "payments", code: http.StatusConflict},
+		{method: "PUT", appID: "appID2", alias: "appID1", code: http.StatusBadRequest},
+		{method: "PUT", appID: "appID3", alias: "search", code: http.StatusNotFound},
+		{method: "DELETE", appID: "appID2", alias: "billing", code: http.StatusNotFound},
+		{method: "DELETE", appID: "appID1", alias: "billing", code: http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		request, _ := http.NewRequest(tt.method, "app-metadata/"+tt.appID+"/aliases/"+tt.alias, strings.NewReader(""))
+		request = mux.SetURLVars(request, map[string]string{"appID": tt.appID, "alias": tt.alias})
+		responseRecorder := httptest.NewRecorder()
+		if tt.method == "PUT" {
+			mh.HandlePutAlias(responseRecorder, request)
+		} else {
+			mh.HandleDeleteAlias(responseRecorder, request)
+		}
+		assert.Equal(t, tt.code, responseRecorder.Code, tt.method+" "+tt.appID+" "+tt.alias)
+	}
+
+	request, _ := http.NewRequest("GET", "app-metadata/appID1/aliases", strings.NewReader(""))
+	request = mux.SetURLVars(request, map[string]string{"appID": "appID1"})
+	responseRecorder := httptest.NewRecorder()
+	mh.HandleGetAliases(responseRecorder, request)
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	var aliases []string
+	yaml.NewDecoder(responseRecorder.Body).Decode(&aliases)
+	assert.Equal(t, []string{"payments"}, aliases)
+
+	// an alias can't be taken by a new application
+	err := im.Create("payments", &metadata.ApplicationMetadata{})
+	assert.Equal(t, repository.ErrIDConflict, err)
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/elumbantoruan/app-metadata/audit"
+	"github.com/elumbantoruan/app-metadata/metadata"
+	"github.com/google/uuid"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// RequestIDHeader is the request header correlating a request with its audit entries, generated when it's missing
+const RequestIDHeader = "X-Request-ID"
+
+// RequestIDMiddleware makes sure every request carries a request ID, and echoes it in the response
+func RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if id == "" {
+			id = uuid.New().String()
+			r.Header.Set(RequestIDHeader, id)
+		}
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// AuditVerification is the result of verifying the audit log
+type AuditVerification struct {
+	Entries int    `yaml:"entries" json:"entries"`
+	Valid   bool   `yaml:"valid" json:"valid"`
+	Error   string `yaml:"error,omitempty" json:"error,omitempty"`
+}
+
+// auditAllowed records a mutation of appID from before to after, either of them may be nil
+func (mh *MetadataHandler) auditAllowed(r *http.Request, action, appID string, before, after *metadata.ApplicationMetadata) {
+	if mh.Audit == nil {
+		return
+	}
+	changes, err := audit.Diff(before, after)
+	if err != nil {
+		log.Printf("audit: diff of %s: %v", appID, err)
+	}
+	e := mh.auditEntry(r, action, appID, audit.Allowed)
+	e.Changes = changes
+	mh.appendAudit(e)
+}
+
+// auditDenied records an attempt to mutate appID denied to the caller
+func (mh *MetadataHandler) auditDenied(r *http.Request, action, appID, reason string) {
+	if mh.Audit == nil {
+		return
+	}
+	e := mh.auditEntry(r, action, appID, audit.Denied)
+	e.Reason = reason
+	mh.appendAudit(e)
+}
+
+func (mh *MetadataHandler) auditEntry(r *http.Request, action, appID, outcome string) audit.Entry {
+	return audit.Entry{
+		Principal:     r.Header.Get(PrincipalHeader),
+		Action:        action,
+		ApplicationID: appID,
+		RequestID:     r.Header.Get(RequestIDHeader),
+		SourceIP:      clientIP(r, mh.TrustForwardedFor),
+		Outcome:       outcome,
+	}
+}
+
+func (mh *MetadataHandler) appendAudit(e audit.Entry) {
+	// the mutation already happened, so a failure to record it is only reported
+	if _, err := mh.Audit.Append(e); err != nil {
+		log.Printf("audit: %s of %s by %q: %v", e.Action, e.ApplicationID, e.Principal, err)
+	}
+}
+
+// HandleGetAudit handles the admin GET operation querying the audit log by applicationID, actor,
+// and time range (since and until in RFC 3339)
+func (mh *MetadataHandler) HandleGetAudit(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	if !mh.requireAdmin(w, r) {
+		return
+	}
+	if mh.Audit == nil {
+		w.WriteHeader(http.StatusNotFound) // 404
+		yaml.NewEncoder(w).Encode("audit log is disabled")
+		return
+	}
+
+	q := r.URL.Query()
+	filter := audit.Filter{
+		ApplicationID: q.Get("applicationID"),
+		Principal:     q.Get("actor"),
+	}
+	var err error
+	if s := q.Get("since"); s != "" {
+		if filter.Since, err = time.Parse(time.RFC3339, s); err != nil {
+			w.WriteHeader(http.StatusBadRequest) // 400
+			yaml.NewEncoder(w).Encode("invalid since: " + err.Error())
+			return
+		}
+	}
+	if s := q.Get("until"); s != "" {
+		if filter.Until, err = time.Parse(time.RFC3339, s); err != nil {
+			w.WriteHeader(http.StatusBadRequest) // 400
+			yaml.NewEncoder(w).Encode("invalid until: " + err.Error())
+			return
+		}
+	}
+
+	format := responseFormat(r)
+	w.Header().Set("Content-Type", mediaTypes[format])
+	w.WriteHeader(http.StatusOK) // 200
+	encode(w, format, mh.Audit.Query(filter))
+}
+
+// HandleVerifyAudit handles the admin GET operation checking the hash chain of the audit log.
+// It returns 500 along with the result when the chain is broken.
+func (mh *MetadataHandler) HandleVerifyAudit(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	if !mh.requireAdmin(w, r) {
+		return
+	}
+	if mh.Audit == nil {
+		w.WriteHeader(http.StatusNotFound) // 404
+		yaml.NewEncoder(w).Encode("audit log is disabled")
+		return
+	}
+
+	n, err := mh.Audit.Verify()
+	res := AuditVerification{Entries: n, Valid: err == nil}
+	format := responseFormat(r)
+	w.Header().Set("Content-Type", mediaTypes[format])
+	if err != nil {
+		// the log was altered, the result tells which entry breaks the chain
+		res.Error = err.Error()
+		w.WriteHeader(http.StatusInternalServerError) // 500
+		encode(w, format, res)
+		return
+	}
+	w.WriteHeader(http.StatusOK) // 200
+	encode(w, format, res)
+}
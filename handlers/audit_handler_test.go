@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/elumbantoruan/app-metadata/audit"
+	"github.com/elumbantoruan/app-metadata/repository"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestMetadataHandler_Audit_ResultedEntries(t *testing.T) {
+
+	mh := NewMetadataHandler(repository.NewInMemoryMetadataRepository())
+	mh.Audit = audit.NewLog(nil, nil)
+	mh.AdminToken = "secret"
+
+	request, _ := http.NewRequest("POST", "app-metadata", strings.NewReader(createValidPayload()))
+	request.Header.Set(PrincipalHeader, "firstmaintainer@hotmail.com")
+	request.Header.Set(RequestIDHeader, "request1")
+	request.RemoteAddr = "10.0.0.1:1234"
+	responseRecorder := httptest.NewRecorder()
+	mh.HandlePostMetadata(responseRecorder, request)
+	assert.Equal(t, http.StatusCreated, responseRecorder.Code)
+	appID := mh.Audit.Query(audit.Filter{})[0].ApplicationID
+
+	request, _ = http.NewRequest("PUT", "app-metadata/"+appID, strings.NewReader(createValidPayload2()))
+	request = mux.SetURLVars(request, map[string]string{"appID": appID})
+	request.Header.Set(PrincipalHeader, "secondmaitainer@gmail.com")
+	responseRecorder = httptest.NewRecorder()
+	mh.HandlePutMetadata(responseRecorder, request)
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+
+	request, _ = http.NewRequest("POST", "app-metadata/"+appID+":purge", strings.NewReader(""))
+	request = mux.SetURLVars(request, map[string]string{"appID": appID})
+	request.Header.Set(PrincipalHeader, "secondmaitainer@gmail.com")
+	responseRecorder = httptest.NewRecorder()
+	mh.HandlePurgeMetadata(responseRecorder, request)
+	assert.Equal(t, http.StatusForbidden, responseRecorder.Code)
+
+	entries := mh.Audit.Query(audit.Filter{ApplicationID: appID})
+	assert.Equal(t, 3, len(entries))
+
+	assert.Equal(t, audit.Create, entries[0].Action)
+	assert.Equal(t, audit.Allowed, entries[0].Outcome)
+	assert.Equal(t, "firstmaintainer@hotmail.com", entries[0].Principal)
+	assert.Equal(t, "request1", entries[0].RequestID)
+	assert.Equal(t, "10.0.0.1", entries[0].SourceIP)
+
+	assert.Equal(t, audit.Update, entries[1].Action)
+	assert.Equal(t, []audit.Change{
+		{Field: "revision", Old: float64(1), New: float64(2)},
+		{Field: "title", Old: "Valid App 1", New: "Valid App 2"},
+	}, entries[1].Changes)
+
+	assert.Equal(t, audit.Purge, entries[2].Action)
+	assert.Equal(t, audit.Denied, entries[2].Outcome)
+
+	// query by actor
+	request, _ = http.NewRequest("GET", "audit?actor=secondmaitainer@gmail.com", strings.NewReader(""))
+	request.Header.Set(AdminTokenHeader, "secret")
+	responseRecorder = httptest.NewRecorder()
+	mh.HandleGetAudit(responseRecorder, request)
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	var res []audit.Entry
+	yaml.NewDecoder(responseRecorder.Body).Decode(&res)
+	assert.Equal(t, 2, len(res))
+
+	request, _ = http.NewRequest("GET", "audit:verify", strings.NewReader(""))
+	request.Header.Set(AdminTokenHeader, "secret")
+	responseRecorder = httptest.NewRecorder()
+	mh.HandleVerifyAudit(responseRecorder, request)
+	var verification AuditVerification
+	yaml.NewDecoder(responseRecorder.Body).Decode(&verification)
+	assert.Equal(t, AuditVerification{Entries: 3, Valid: true}, verification)
+}
+
+func TestMetadataHandler_HandleVerifyAuditBrokenChain_ResultedInternalServerError(t *testing.T) {
+
+	log := audit.NewLog(nil, nil)
+	log.Append(audit.Entry{Action: audit.Create, ApplicationID: "appID1", Outcome: audit.Allowed})
+	log.Append(audit.Entry{Action: audit.Delete, ApplicationID: "appID1", Outcome: audit.Allowed})
+	entries := log.Query(audit.Filter{})
+	// the deletion is rewritten as denied
+	entries[1].Outcome = audit.Denied
+
+	mh := NewMetadataHandler(repository.NewInMemoryMetadataRepository())
+	mh.Audit = audit.NewLog(entries, nil)
+	mh.AdminToken = "secret"
+
+	request, _ := http.NewRequest("GET", "audit:verify", strings.NewReader(""))
+	request.Header.Set(AdminTokenHeader, "secret")
+	request.Header.Set("Accept", "application/json")
+	responseRecorder := httptest.NewRecorder()
+	mh.HandleVerifyAudit(responseRecorder, request)
+
+	assert.Equal(t, http.StatusInternalServerError, responseRecorder.Code) // 500
+	assert.Equal(t, "application/json", responseRecorder.Header().Get("Content-Type"))
+	var verification AuditVerification
+	assert.Nil(t, json.NewDecoder(responseRecorder.Body).Decode(&verification))
+	assert.Equal(t, 2, verification.Entries)
+	assert.False(t, verification.Valid)
+	assert.NotEmpty(t, verification.Error)
+}
+
+func TestMetadataHandler_HandleGetAudit_ResultedBadRequest(t *testing.T) {
+
+	mh := NewMetadataHandler(repository.NewInMemoryMetadataRepository())
+	mh.Audit = audit.NewLog(nil, nil)
+	mh.AdminToken = "secret"
+
+	request, _ := http.NewRequest("GET", "audit?since=yesterday", strings.NewReader(""))
+	request.Header.Set(AdminTokenHeader, "secret")
+	responseRecorder := httptest.NewRecorder()
+	mh.HandleGetAudit(responseRecorder, request)
+	assert.Equal(t, http.StatusBadRequest, responseRecorder.Code) // 400
+
+	request, _ = http.NewRequest("GET", "audit", strings.NewReader(""))
+	responseRecorder = httptest.NewRecorder()
+	mh.HandleGetAudit(responseRecorder, request)
+	assert.Equal(t, http.StatusForbidden, responseRecorder.Code) // 403
+}
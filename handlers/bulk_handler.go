@@ -0,0 +1,445 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/elumbantoruan/app-metadata/audit"
+	"github.com/elumbantoruan/app-metadata/dedupe"
+	"github.com/elumbantoruan/app-metadata/dependency"
+	"github.com/elumbantoruan/app-metadata/lifecycle"
+	"github.com/elumbantoruan/app-metadata/metadata"
+	"github.com/elumbantoruan/app-metadata/repository"
+	"github.com/elumbantoruan/app-metadata/strictyaml"
+	"github.com/google/uuid"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// import status for each document
+const (
+	importCreated = "created"
+	importUpdated = "updated"
+	importFailed  = "failed"
+	importAborted = "aborted"
+)
+
+// ImportResult describes the outcome of importing a single document
+type ImportResult struct {
+	Index         int    `yaml:"index" json:"index"`
+	ApplicationID string `yaml:"applicationID,omitempty" json:"applicationID,omitempty"`
+	Status        string `yaml:"status" json:"status"`
+	Error         string `yaml:"error,omitempty" json:"error,omitempty"`
+	// Warnings are the near-duplicates of the document, see DuplicatePolicy
+	Warnings []string `yaml:"warnings,omitempty" json:"warnings,omitempty"`
+}
+
+// HandleImportMetadata handles POST operation of a multi-document payload.
+// The payload is a yaml stream, a json array, or newline delimited json depending on Content-Type.
+// Query parameter atomic=true imports all documents or none, and upsert=true replaces
+// existing applications with the same applicationID instead of reporting a conflict.
+func (mh *MetadataHandler) HandleImportMetadata(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	format := requestFormat(r)
+	atomic, _ := strconv.ParseBool(r.URL.Query().Get("atomic"))
+	upsert, _ := strconv.ParseBool(r.URL.Query().Get("upsert"))
+
+	// read the whole bounded body first, the yaml decoder doesn't preserve reader errors
+	if mh.MaxImportSize > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, mh.MaxImportSize)
+	}
+	b, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		if isBodyTooLarge(err) {
+			w.WriteHeader(http.StatusRequestEntityTooLarge) // 413
+		} else {
+			w.WriteHeader(http.StatusBadRequest) // 400
+		}
+		encode(w, format, err.Error())
+		return
+	}
+	docs, err := mh.decodeDocuments(bytes.NewReader(b), format)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest) // 400
+		encode(w, format, err.Error())
+		return
+	}
+
+	admin := mh.isAdmin(r)
+	results := make([]ImportResult, len(docs))
+	var (
+		batch   []*metadata.ApplicationMetadata
+		indexes []int
+		invalid bool
+		// previous content of the upserted applications, for the audit log
+		previous = make(map[int]*metadata.ApplicationMetadata)
+		// emails sent a verification token once written
+		pending = make(map[int][]string)
+	)
+	for i := range docs {
+		doc := &docs[i]
+		results[i].Index = i
+		doc.Normalize()
+		if valid, desc := doc.IsValid(); !valid {
+			results[i].Status = importFailed
+			results[i].Error = desc.Description
+			invalid = true
+			continue
+		}
+		if err := mh.checkExtensions(doc.Extensions); err != nil {
+			if !isExtensionError(err) {
+				w.WriteHeader(http.StatusInternalServerError) // 500
+				encode(w, format, err.Error())
+				return
+			}
+			results[i].Status = importFailed
+			results[i].Error = err.Error()
+			invalid = true
+			continue
+		}
+		if err := mh.checkEmailPolicy(doc); err != nil {
+			results[i].Status = importFailed
+			results[i].Error = err.Error()
+			invalid = true
+			continue
+		}
+		// the releases are kept so that an export can be imported back as is, and so is the lifecycle state when an
+		// admin imports it.  Otherwise new applications start as drafts, like POST, and the others follow the
+		// lifecycle of the application they replace, like PUT.
+		state, releases := doc.State, doc.Releases
+		doc.ClearServerFields()
+		doc.SetReleases(releases)
+		if state != "" && !lifecycle.Valid(state) {
+			results[i].Status = importFailed
+			results[i].Error = lifecycle.ErrInvalidState.Error()
+			invalid = true
+			continue
+		}
+		keepState := admin && state != ""
+		doc.State = lifecycle.Draft
+		if keepState {
+			doc.State = state
+		}
+		results[i].Status = importCreated
+		if doc.ApplicationID != "" {
+			if err := metadata.ValidateSlug(doc.ApplicationID); err != nil {
+				results[i].Status = importFailed
+				results[i].Error = err.Error()
+				invalid = true
+				continue
+			}
+		}
+		if doc.ApplicationID == "" {
+			doc.ApplicationID = uuid.New().String()
+		} else if upsert {
+			existing, err := mh.Repository.Get(doc.ApplicationID)
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError) // 500
+				encode(w, format, err.Error())
+				return
+			}
+			if existing != nil && existing.Lock != nil {
+				results[i].Status = importFailed
+				results[i].Error = lockedMessage(existing.Lock)
+				invalid = true
+				continue
+			}
+			if existing != nil && !keepState {
+				if err := lifecycle.Edit(doc, existing); err != nil {
+					mh.auditDenied(r, audit.Import, doc.ApplicationID, err.Error())
+					results[i].Status = importFailed
+					results[i].Error = err.Error()
+					invalid = true
+					continue
+				}
+			}
+			if existing != nil {
+				results[i].Status = importUpdated
+				previous[i] = existing
+			}
+		}
+		if pending[i], err = mh.markVerification(doc, previous[i]); err != nil {
+			w.WriteHeader(http.StatusInternalServerError) // 500
+			encode(w, format, err.Error())
+			return
+		}
+		results[i].ApplicationID = doc.ApplicationID
+		batch = append(batch, doc)
+		indexes = append(indexes, i)
+	}
+
+	batch, indexes, failed, err := mh.validateBatchDependencies(batch, indexes, results)
+	for _, d := range batch {
+		if err == nil {
+			err = mh.resolveDependencies(d)
+		}
+	}
+	if err == nil {
+		err = mh.warnBatchDuplicates(batch, indexes, results)
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError) // 500
+		encode(w, format, err.Error())
+		return
+	}
+	invalid = invalid || failed
+
+	if invalid && atomic {
+		abortResults(results)
+		w.WriteHeader(http.StatusBadRequest) // 400
+		encode(w, format, results)
+		return
+	}
+
+	errs, err := mh.Repository.PutBatch(batch, repository.BatchOptions{Atomic: atomic, Upsert: upsert})
+	if err != nil && err != repository.ErrBatchAborted {
+		w.WriteHeader(http.StatusInternalServerError) // 500
+		encode(w, format, err.Error())
+		return
+	}
+	for j, e := range errs {
+		if e != nil {
+			results[indexes[j]].Status = importFailed
+			results[indexes[j]].Error = e.Error()
+			if le := lockError(e); le != nil {
+				results[indexes[j]].Error = lockedMessage(&le.Lock)
+			}
+		}
+	}
+	if err == repository.ErrBatchAborted {
+		abortResults(results)
+		w.WriteHeader(http.StatusConflict) // 409
+		encode(w, format, results)
+		return
+	}
+	for j, d := range batch {
+		if i := indexes[j]; results[i].Status != importFailed {
+			mh.auditAllowed(r, audit.Import, d.ApplicationID, previous[i], d)
+			mh.sendVerifications(pending[i])
+		}
+	}
+
+	w.WriteHeader(http.StatusOK) // 200
+	encode(w, format, results)
+}
+
+// HandleExportMetadata handles GET operation streaming every application metadata
+// as a yaml stream, a json array, or newline delimited json
+func (mh *MetadataHandler) HandleExportMetadata(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	format := responseFormat(r)
+	w.Header().Set("Content-Type", mediaTypes[format])
+	w.WriteHeader(http.StatusOK) // 200
+
+	var err error
+	switch format {
+	case formatJSON:
+		err = exportJSON(w, mh.Repository)
+	case formatNDJSON:
+		enc := json.NewEncoder(w)
+		err = mh.Repository.ForEach(func(d *metadata.ApplicationMetadata) error {
+			return flushAfter(w, enc.Encode(d))
+		})
+	default:
+		enc := yaml.NewEncoder(w)
+		err = mh.Repository.ForEach(func(d *metadata.ApplicationMetadata) error {
+			return flushAfter(w, enc.Encode(d))
+		})
+		if err == nil {
+			err = enc.Close()
+		}
+	}
+	if err != nil {
+		// the status is already sent, so the best we can do is to cut the stream short
+		panic(http.ErrAbortHandler)
+	}
+}
+
+func exportJSON(w io.Writer, repo repository.MetadataRepository) error {
+	if _, err := io.WriteString(w, "["); err != nil {
+		return err
+	}
+	first := true
+	err := repo.ForEach(func(d *metadata.ApplicationMetadata) error {
+		if !first {
+			if _, err := io.WriteString(w, ","); err != nil {
+				return err
+			}
+		}
+		first = false
+		b, err := json.Marshal(d)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(b)
+		return flushAfter(w, err)
+	})
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "]\n")
+	return err
+}
+
+// validateBatchDependencies validates the dependencies of every document of a batch, which may reference
+// each other or applications already stored.  Failing documents are marked in results and removed from the
+// batch, until the remaining documents only reference applications that will exist.
+func (mh *MetadataHandler) validateBatchDependencies(batch []*metadata.ApplicationMetadata, indexes []int, results []ImportResult) ([]*metadata.ApplicationMetadata, []int, bool, error) {
+	failed := false
+	for {
+		pending := make(map[string]*metadata.ApplicationMetadata)
+		for _, d := range batch {
+			pending[d.ApplicationID] = d
+		}
+		lookup := func(appID string) (*metadata.ApplicationMetadata, error) {
+			if d, ok := pending[appID]; ok {
+				return d, nil
+			}
+			return mh.lookup(appID)
+		}
+
+		var (
+			keep        []*metadata.ApplicationMetadata
+			keepIndexes []int
+		)
+		for j, d := range batch {
+			desc, err := dependency.Validate(d, lookup)
+			if err != nil {
+				return nil, nil, false, err
+			}
+			if desc != nil {
+				results[indexes[j]].Status = importFailed
+				results[indexes[j]].Error = desc.Description
+				continue
+			}
+			keep = append(keep, d)
+			keepIndexes = append(keepIndexes, indexes[j])
+		}
+		if len(keep) == len(batch) {
+			return batch, indexes, failed, nil
+		}
+		failed = true
+		batch, indexes = keep, keepIndexes
+	}
+}
+
+// flushAfter flushes w to the client when it supports it, unless err is set
+func flushAfter(w io.Writer, err error) error {
+	if err != nil {
+		return err
+	}
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+	return nil
+}
+
+// abortResults marks every document that did not fail as aborted
+func abortResults(results []ImportResult) {
+	for i := range results {
+		if results[i].Status != importFailed {
+			results[i].Status = importAborted
+		}
+	}
+}
+
+// documentDecoder decodes the documents of a yaml stream, see decodeDocuments
+type documentDecoder interface {
+	Decode(v interface{}) error
+}
+
+// warnBatchDuplicates sets the warnings of the results of the batch documents with their near-duplicates, among the
+// live applications and the documents before them
+func (mh *MetadataHandler) warnBatchDuplicates(batch []*metadata.ApplicationMetadata, indexes []int, results []ImportResult) error {
+	if mh.DuplicatePolicy == "" || mh.DuplicatePolicy == DuplicatesOff {
+		return nil
+	}
+	candidates, err := mh.Repository.GetAll()
+	if err != nil {
+		return err
+	}
+	for j, d := range batch {
+		for _, m := range dedupe.Find(d, candidates, dedupe.DefaultTitleSimilarity) {
+			results[indexes[j]].Warnings = append(results[indexes[j]].Warnings, duplicateWarning(m))
+		}
+		candidates = append(candidates, *d)
+	}
+	return nil
+}
+
+// decodeDocuments reads every application metadata document from r in the given format.
+// Each document is decoded strictly unless StrictDecoding is off, like the body of a write operation.
+func (mh *MetadataHandler) decodeDocuments(r io.Reader, format string) ([]metadata.ApplicationMetadata, error) {
+	var docs []metadata.ApplicationMetadata
+	switch format {
+	case formatJSON:
+		dec := json.NewDecoder(r)
+		if mh.StrictDecoding {
+			dec.DisallowUnknownFields()
+		}
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+			return nil, errors.New("expected a json array")
+		}
+		for dec.More() {
+			var d metadata.ApplicationMetadata
+			if err := dec.Decode(&d); err != nil {
+				return nil, fmt.Errorf("document %d: %v", len(docs), err)
+			}
+			docs = append(docs, d)
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+	case formatNDJSON:
+		dec := json.NewDecoder(r)
+		if mh.StrictDecoding {
+			dec.DisallowUnknownFields()
+		}
+		for {
+			var d metadata.ApplicationMetadata
+			err := dec.Decode(&d)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("document %d: %v", len(docs), err)
+			}
+			docs = append(docs, d)
+		}
+	default:
+		var dec documentDecoder
+		if mh.StrictDecoding {
+			dec = strictyaml.NewDecoder(r, mh.DecodeLimits)
+		} else {
+			dec = yaml.NewDecoder(r)
+		}
+		for {
+			var d *metadata.ApplicationMetadata
+			err := dec.Decode(&d)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("document %d: %v", len(docs), err)
+			}
+			if d == nil {
+				// skip empty documents such as a leading "---"
+				continue
+			}
+			docs = append(docs, *d)
+		}
+	}
+	return docs, nil
+}
@@ -0,0 +1,291 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/elumbantoruan/app-metadata/lifecycle"
+	"github.com/elumbantoruan/app-metadata/metadata"
+	"github.com/elumbantoruan/app-metadata/repository"
+	"github.com/stretchr/testify/assert"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestMetadataHandler_HandleImportMetadataYamlStream_ResultedOK(t *testing.T) {
+
+	payload := createValidPayload() + "---\n" + createValidPayload2()
+	request, _ := http.NewRequest("POST", "app-metadata:import", strings.NewReader(payload))
+	responseRecorder := httptest.NewRecorder()
+
+	im := repository.NewInMemoryMetadataRepository()
+	mh := NewMetadataHandler(im)
+	mh.HandleImportMetadata(responseRecorder, request)
+
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	var results []ImportResult
+	yaml.NewDecoder(responseRecorder.Body).Decode(&results)
+	assert.Equal(t, 2, len(results))
+	assert.Equal(t, importCreated, results[0].Status)
+	assert.Equal(t, importCreated, results[1].Status)
+
+	all, _ := im.GetAll()
+	assert.Equal(t, 2, len(all))
+}
+
+func TestMetadataHandler_HandleImportMetadataPartiallyInvalid_ResultedOK(t *testing.T) {
+
+	payload := createValidPayload() + "---\n" + createInvalidPayloadMissingVersion()
+	request, _ := http.NewRequest("POST", "app-metadata:import", strings.NewReader(payload))
+	responseRecorder := httptest.NewRecorder()
+
+	im := repository.NewInMemoryMetadataRepository()
+	mh := NewMetadataHandler(im)
+	mh.HandleImportMetadata(responseRecorder, request)
+
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	var results []ImportResult
+	yaml.NewDecoder(responseRecorder.Body).Decode(&results)
+	assert.Equal(t, importCreated, results[0].Status)
+	assert.Equal(t, importFailed, results[1].Status)
+	assert.Equal(t, "version is empty", results[1].Error)
+
+	all, _ := im.GetAll()
+	assert.Equal(t, 1, len(all))
+}
+
+func TestMetadataHandler_HandleImportMetadataAtomicInvalid_ResultedBadRequest(t *testing.T) {
+
+	payload := createValidPayload() + "---\n" + createInvalidPayloadMissingVersion()
+	request, _ := http.NewRequest("POST", "app-metadata:import?atomic=true", strings.NewReader(payload))
+	responseRecorder := httptest.NewRecorder()
+
+	im := repository.NewInMemoryMetadataRepository()
+	mh := NewMetadataHandler(im)
+	mh.HandleImportMetadata(responseRecorder, request)
+
+	assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+	var results []ImportResult
+	yaml.NewDecoder(responseRecorder.Body).Decode(&results)
+	assert.Equal(t, importAborted, results[0].Status)
+	assert.Equal(t, importFailed, results[1].Status)
+
+	all, _ := im.GetAll()
+	assert.Equal(t, 0, len(all))
+}
+
+func TestMetadataHandler_HandleImportMetadataAtomicConflict_ResultedConflict(t *testing.T) {
+
+	im := repository.NewInMemoryMetadataRepository()
+	var mtd metadata.ApplicationMetadata
+	yaml.Unmarshal([]byte(createValidPayload()), &mtd)
+	mtd.ApplicationID = "app-id1"
+	im.Create("app-id1", &mtd)
+
+	payload := "applicationID: app-id1\n" + createValidPayload() + "---\n" + createValidPayload2()
+	request, _ := http.NewRequest("POST", "app-metadata:import?atomic=true", strings.NewReader(payload))
+	responseRecorder := httptest.NewRecorder()
+
+	mh := NewMetadataHandler(im)
+	mh.HandleImportMetadata(responseRecorder, request)
+
+	assert.Equal(t, http.StatusConflict, responseRecorder.Code)
+	all, _ := im.GetAll()
+	assert.Equal(t, 1, len(all))
+}
+
+func TestMetadataHandler_HandleImportMetadataInvalidID_ResultedBadRequest(t *testing.T) {
+
+	im := repository.NewInMemoryMetadataRepository()
+	payload := "applicationID: App_1\n" + createValidPayload()
+	request, _ := http.NewRequest("POST", "app-metadata:import", strings.NewReader(payload))
+	responseRecorder := httptest.NewRecorder()
+
+	mh := NewMetadataHandler(im)
+	mh.HandleImportMetadata(responseRecorder, request)
+
+	var results []ImportResult
+	yaml.NewDecoder(responseRecorder.Body).Decode(&results)
+	assert.Equal(t, importFailed, results[0].Status)
+	all, _ := im.GetAll()
+	assert.Equal(t, 0, len(all))
+}
+
+func TestMetadataHandler_HandleImportMetadataUnknownField_ResultedBadRequest(t *testing.T) {
+
+	tests := []struct {
+		name        string
+		contentType string
+		payload     string
+	}{
+		{name: "yaml", payload: createValidPayload() + "---\n" + createValidPayload2() + "licence: Apache-2.0\n"},
+		{name: "json", contentType: "application/json", payload: `[{"title": "App", "licence": "Apache-2.0"}]`},
+		{name: "ndjson", contentType: "application/x-ndjson", payload: `{"title": "App", "licence": "Apache-2.0"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			im := repository.NewInMemoryMetadataRepository()
+			request, _ := http.NewRequest("POST", "app-metadata:import", strings.NewReader(tt.payload))
+			if tt.contentType != "" {
+				request.Header.Set("Content-Type", tt.contentType)
+			}
+			responseRecorder := httptest.NewRecorder()
+
+			mh := NewMetadataHandler(im)
+			mh.HandleImportMetadata(responseRecorder, request)
+
+			assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+			assert.Contains(t, responseRecorder.Body.String(), "licence")
+			all, _ := im.GetAll()
+			assert.Equal(t, 0, len(all))
+		})
+	}
+}
+
+func TestMetadataHandler_HandleImportMetadataUpsertJSON_ResultedOK(t *testing.T) {
+
+	im := repository.NewInMemoryMetadataRepository()
+	var mtd metadata.ApplicationMetadata
+	yaml.Unmarshal([]byte(createValidPayload()), &mtd)
+	mtd.ApplicationID = "app-id1"
+	im.Create("app-id1", &mtd)
+
+	updated := mtd
+	updated.Company = "updated company"
+	b, _ := json.Marshal([]metadata.ApplicationMetadata{updated})
+
+	request, _ := http.NewRequest("POST", "app-metadata:import?upsert=true", strings.NewReader(string(b)))
+	request.Header.Set("Content-Type", "application/json")
+	responseRecorder := httptest.NewRecorder()
+
+	mh := NewMetadataHandler(im)
+	mh.HandleImportMetadata(responseRecorder, request)
+
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	var results []ImportResult
+	json.NewDecoder(responseRecorder.Body).Decode(&results)
+	assert.Equal(t, importUpdated, results[0].Status)
+
+	res, _ := im.Get("app-id1")
+	assert.Equal(t, "updated company", res.Company)
+}
+
+func TestMetadataHandler_HandleImportMetadataState_ResultedLifecycle(t *testing.T) {
+
+	im := repository.NewInMemoryMetadataRepository()
+	im.Create("review", &metadata.ApplicationMetadata{ApplicationID: "review", State: lifecycle.InReview, Review: &metadata.Review{SubmittedBy: "first@example.com"}})
+	im.Create("retired", &metadata.ApplicationMetadata{ApplicationID: "retired", State: lifecycle.Retired})
+	mh := NewMetadataHandler(im)
+	mh.AdminToken = "secret"
+
+	importDocs := func(token string, ids ...string) []ImportResult {
+		var docs []metadata.ApplicationMetadata
+		for _, id := range ids {
+			var mtd metadata.ApplicationMetadata
+			yaml.Unmarshal([]byte(createValidPayload()), &mtd)
+			mtd.ApplicationID = id
+			mtd.State = lifecycle.Published
+			docs = append(docs, mtd)
+		}
+		b, _ := json.Marshal(docs)
+		request, _ := http.NewRequest("POST", "app-metadata:import?upsert=true", strings.NewReader(string(b)))
+		request.Header.Set("Content-Type", "application/json")
+		request.Header.Set(AdminTokenHeader, token)
+		responseRecorder := httptest.NewRecorder()
+		mh.HandleImportMetadata(responseRecorder, request)
+		var results []ImportResult
+		json.NewDecoder(responseRecorder.Body).Decode(&results)
+		return results
+	}
+
+	// the state of the documents is ignored, so the review can't be skipped
+	results := importDocs("", "new", "review", "retired")
+	assert.Equal(t, importCreated, results[0].Status)
+	assert.Equal(t, importUpdated, results[1].Status)
+	assert.Equal(t, importFailed, results[2].Status)
+	assert.Equal(t, lifecycle.ErrRetired.Error(), results[2].Error)
+	app, _ := im.Get("new")
+	assert.Equal(t, lifecycle.Draft, app.State)
+	app, _ = im.Get("review")
+	assert.Equal(t, lifecycle.Draft, app.State)
+	assert.Nil(t, app.Review)
+	app, _ = im.Get("retired")
+	assert.Equal(t, "", app.Title)
+
+	// an admin restores the state as exported
+	results = importDocs("secret", "new", "restored")
+	assert.Equal(t, importUpdated, results[0].Status)
+	assert.Equal(t, importCreated, results[1].Status)
+	for _, id := range []string{"new", "restored"} {
+		app, _ = im.Get(id)
+		assert.Equal(t, lifecycle.Published, app.State, id)
+	}
+}
+
+func TestMetadataHandler_HandleImportMetadataBadYamlFormat_ResultedBadRequest(t *testing.T) {
+
+	payload := createInvalidPayloadBadYamlFormat()
+	request, _ := http.NewRequest("POST", "app-metadata:import", strings.NewReader(payload))
+	responseRecorder := httptest.NewRecorder()
+
+	mh := NewMetadataHandler(repository.NewInMemoryMetadataRepository())
+	mh.HandleImportMetadata(responseRecorder, request)
+
+	assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+}
+
+func TestMetadataHandler_HandleExportMetadataNDJSON_ResultedOK(t *testing.T) {
+
+	im := repository.NewInMemoryMetadataRepository()
+	for _, id := range []string{"app-id1", "app-id2"} {
+		var mtd metadata.ApplicationMetadata
+		yaml.Unmarshal([]byte(createValidPayload()), &mtd)
+		im.Create(id, &mtd)
+	}
+
+	request, _ := http.NewRequest("GET", "app-metadata:export?format=ndjson", strings.NewReader(""))
+	responseRecorder := httptest.NewRecorder()
+
+	mh := NewMetadataHandler(im)
+	mh.HandleExportMetadata(responseRecorder, request)
+
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	assert.Equal(t, "application/x-ndjson", responseRecorder.Header().Get("Content-Type"))
+	dec := json.NewDecoder(responseRecorder.Body)
+	var ids []string
+	for {
+		var mtd metadata.ApplicationMetadata
+		if err := dec.Decode(&mtd); err == io.EOF {
+			break
+		}
+		ids = append(ids, mtd.ApplicationID)
+	}
+	assert.Equal(t, []string{"app-id1", "app-id2"}, ids)
+}
+
+func TestMetadataHandler_HandleExportMetadataRoundTrip_ResultedOK(t *testing.T) {
+
+	im := repository.NewInMemoryMetadataRepository()
+	var mtd metadata.ApplicationMetadata
+	yaml.Unmarshal([]byte(createValidPayload()), &mtd)
+	im.Create("app-id1", &mtd)
+
+	request, _ := http.NewRequest("GET", "app-metadata:export", strings.NewReader(""))
+	responseRecorder := httptest.NewRecorder()
+	NewMetadataHandler(im).HandleExportMetadata(responseRecorder, request)
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+
+	// import the yaml stream into an empty repository
+	target := repository.NewInMemoryMetadataRepository()
+	request, _ = http.NewRequest("POST", "app-metadata:import", responseRecorder.Body)
+	responseRecorder = httptest.NewRecorder()
+	NewMetadataHandler(target).HandleImportMetadata(responseRecorder, request)
+
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	res, _ := target.Get("app-id1")
+	assert.NotNil(t, res)
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/elumbantoruan/app-metadata/dependency"
+	"github.com/elumbantoruan/app-metadata/metadata"
+	"github.com/gorilla/mux"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// HandleGetDependencies handles GET operation returning the applications the specified application depends on.
+// Only direct dependencies are returned unless depth=N or transitive=true is set.
+func (mh *MetadataHandler) HandleGetDependencies(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	app, depth, ok := mh.dependencyRequest(w, r)
+	if !ok {
+		return
+	}
+
+	nodes, err := dependency.Dependencies(app, mh.lookup, depth)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError) // 500
+		yaml.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusOK) // 200
+	yaml.NewEncoder(w).Encode(nodes)
+}
+
+// HandleGetDependents handles GET operation returning the applications depending on the specified application.
+// Only direct dependents are returned unless depth=N or transitive=true is set.
+func (mh *MetadataHandler) HandleGetDependents(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	app, depth, ok := mh.dependencyRequest(w, r)
+	if !ok {
+		return
+	}
+
+	all, err := mh.Repository.GetAll()
+	// the dependencies written before a rename name the previous applicationID, now an alias
+	for i := range all {
+		if err == nil {
+			err = mh.resolveDependencies(&all[i])
+		}
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError) // 500
+		yaml.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusOK) // 200
+	yaml.NewEncoder(w).Encode(dependency.Dependents(app.ApplicationID, all, depth))
+}
+
+// dependencyRequest returns the application and the walk depth of a dependency request,
+// it writes the error response and returns false when they can't be resolved
+func (mh *MetadataHandler) dependencyRequest(w http.ResponseWriter, r *http.Request) (*metadata.ApplicationMetadata, int, bool) {
+	appID, ok := mux.Vars(r)["appID"]
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest) // 400
+		return nil, 0, false
+	}
+
+	depth := 1
+	q := r.URL.Query()
+	if transitive, _ := strconv.ParseBool(q.Get("transitive")); transitive {
+		depth = 0
+	} else if d := q.Get("depth"); d != "" {
+		n, err := strconv.Atoi(d)
+		if err != nil || n < 1 {
+			w.WriteHeader(http.StatusBadRequest) // 400
+			yaml.NewEncoder(w).Encode("depth must be a positive number")
+			return nil, 0, false
+		}
+		depth = n
+	}
+
+	app, err := mh.Repository.Get(appID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError) // 500
+		return nil, 0, false
+	}
+	if app == nil {
+		w.WriteHeader(http.StatusNotFound) // 404
+		return nil, 0, false
+	}
+	found := *app
+	found.ApplicationID = appID
+	return &found, depth, true
+}
+
+// validateDependencies checks the dependencies of payload against the applications returned by lookup.
+// It writes the error response and returns false when they're not valid.
+func (mh *MetadataHandler) validateDependencies(w http.ResponseWriter, payload *metadata.ApplicationMetadata, lookup dependency.Lookup) bool {
+	desc, err := dependency.Validate(payload, lookup)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError) // 500
+		yaml.NewEncoder(w).Encode(err.Error())
+		return false
+	}
+	if desc != nil {
+		w.WriteHeader(http.StatusBadRequest) // 400
+		yaml.NewEncoder(w).Encode(desc)
+		return false
+	}
+	return true
+}
+
+// checkDependencyError writes 400 and returns false when err is the dependency ValidationError of a write the
+// repository refused, such as when a concurrent write made the dependencies a cycle after validateDependencies
+func checkDependencyError(w http.ResponseWriter, err error) bool {
+	var ve *dependency.ValidationError
+	if !errors.As(err, &ve) {
+		return true
+	}
+	w.WriteHeader(http.StatusBadRequest) // 400
+	yaml.NewEncoder(w).Encode(ve.Validation)
+	return false
+}
@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/elumbantoruan/app-metadata/dependency"
+	"github.com/elumbantoruan/app-metadata/metadata"
+	"github.com/elumbantoruan/app-metadata/repository"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestMetadataHandler_HandlePostMetadataMissingDependency_ResultedBadRequest(t *testing.T) {
+
+	payload := createValidPayload() + "dependencies:\n- applicationID: notfound\n"
+	request, _ := http.NewRequest("POST", "app-metadata", strings.NewReader(payload))
+	responseRecorder := httptest.NewRecorder()
+
+	mh := NewMetadataHandler(repository.NewInMemoryMetadataRepository())
+	mh.HandlePostMetadata(responseRecorder, request)
+
+	assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+	var vm metadata.ValidationMessage
+	yaml.NewDecoder(responseRecorder.Body).Decode(&vm)
+	assert.Equal(t, "dependency notfound is not found", vm.Description)
+}
+
+func TestMetadataHandler_HandlePostMetadataUnsatisfiedConstraint_ResultedBadRequest(t *testing.T) {
+
+	im := createDependencyChain()
+	payload := createValidPayload() + "dependencies:\n- applicationID: appID1\n  version: '>=2.0.0'\n"
+	request, _ := http.NewRequest("POST", "app-metadata", strings.NewReader(payload))
+	responseRecorder := httptest.NewRecorder()
+
+	mh := NewMetadataHandler(im)
+	mh.HandlePostMetadata(responseRecorder, request)
+
+	assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+}
+
+func TestMetadataHandler_HandlePostMetadataSatisfiedConstraint_ResultedCreated(t *testing.T) {
+
+	im := createDependencyChain()
+	payload := createValidPayload() + "dependencies:\n- applicationID: appID1\n  version: '^1.0.0'\n"
+	request, _ := http.NewRequest("POST", "app-metadata", strings.NewReader(payload))
+	responseRecorder := httptest.NewRecorder()
+
+	mh := NewMetadataHandler(im)
+	mh.HandlePostMetadata(responseRecorder, request)
+
+	assert.Equal(t, http.StatusCreated, responseRecorder.Code)
+}
+
+func TestMetadataHandler_HandlePutMetadataDependencyCycle_ResultedBadRequest(t *testing.T) {
+
+	// appID3 -> appID2 -> appID1, so appID1 can't depend on appID3
+	im := createDependencyChain()
+	payload := createValidPayload() + "dependencies:\n- applicationID: appID3\n"
+	request, _ := http.NewRequest("PUT", "app-metadata/appID1", strings.NewReader(payload))
+	request = mux.SetURLVars(request, map[string]string{"appID": "appID1"})
+	responseRecorder := httptest.NewRecorder()
+
+	mh := NewMetadataHandler(im)
+	mh.HandlePutMetadata(responseRecorder, request)
+
+	assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+	var vm metadata.ValidationMessage
+	yaml.NewDecoder(responseRecorder.Body).Decode(&vm)
+	assert.Equal(t, "dependency cycle [appID1 appID3 appID2 appID1]", vm.Description)
+}
+
+func TestMetadataHandler_HandlePutMetadataAliasCycle_ResultedBadRequest(t *testing.T) {
+
+	// appID3 is also known as third, so appID1 can't depend on third either
+	im := createDependencyChain()
+	im.AddAlias("appID3", "third")
+	payload := createValidPayload() + "dependencies:\n- applicationID: third\n"
+	request, _ := http.NewRequest("PUT", "app-metadata/appID1", strings.NewReader(payload))
+	request = mux.SetURLVars(request, map[string]string{"appID": "appID1"})
+	responseRecorder := httptest.NewRecorder()
+
+	mh := NewMetadataHandler(im)
+	mh.HandlePutMetadata(responseRecorder, request)
+
+	assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+	res, _ := im.Get("appID1")
+	assert.Equal(t, 0, len(res.Dependencies))
+}
+
+func TestMetadataHandler_HandlePostMetadataAliasDependency_ResultedCanonical(t *testing.T) {
+
+	im := createDependencyChain()
+	im.AddAlias("appID1", "first")
+	payload := "applicationID: app-id4\n" + createValidPayload() + "dependencies:\n- applicationID: first\n"
+	request, _ := http.NewRequest("POST", "app-metadata", strings.NewReader(payload))
+	responseRecorder := httptest.NewRecorder()
+
+	mh := NewMetadataHandler(im)
+	mh.HandlePostMetadata(responseRecorder, request)
+
+	assert.Equal(t, http.StatusCreated, responseRecorder.Code)
+	res, _ := im.Get("app-id4")
+	assert.Equal(t, "appID1", res.Dependencies[0].ApplicationID)
+}
+
+func TestMetadataHandler_HandleGetDependentsRenamed_ResultedOK(t *testing.T) {
+
+	im := createDependencyChain()
+	im.Rename("appID1", "app-id1")
+	request, _ := http.NewRequest("GET", "app-metadata/app-id1/dependents?depth=2", strings.NewReader(""))
+	request = mux.SetURLVars(request, map[string]string{"appID": "app-id1"})
+	responseRecorder := httptest.NewRecorder()
+
+	mh := NewMetadataHandler(im)
+	mh.HandleGetDependents(responseRecorder, request)
+
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	var nodes []dependency.Node
+	yaml.NewDecoder(responseRecorder.Body).Decode(&nodes)
+	assert.Equal(t, 2, len(nodes))
+	assert.Equal(t, "appID2", nodes[0].ApplicationID)
+}
+
+func TestMetadataHandler_HandleGetDependencies_ResultedOK(t *testing.T) {
+
+	im := createDependencyChain()
+	mh := NewMetadataHandler(im)
+
+	request, _ := http.NewRequest("GET", "app-metadata/appID3/dependencies", strings.NewReader(""))
+	request = mux.SetURLVars(request, map[string]string{"appID": "appID3"})
+	responseRecorder := httptest.NewRecorder()
+	mh.HandleGetDependencies(responseRecorder, request)
+
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	var nodes []dependency.Node
+	yaml.NewDecoder(responseRecorder.Body).Decode(&nodes)
+	assert.Equal(t, 1, len(nodes))
+	assert.Equal(t, "appID2", nodes[0].ApplicationID)
+
+	request, _ = http.NewRequest("GET", "app-metadata/appID3/dependencies?transitive=true", strings.NewReader(""))
+	request = mux.SetURLVars(request, map[string]string{"appID": "appID3"})
+	responseRecorder = httptest.NewRecorder()
+	mh.HandleGetDependencies(responseRecorder, request)
+
+	nodes = nil
+	yaml.NewDecoder(responseRecorder.Body).Decode(&nodes)
+	assert.Equal(t, 2, len(nodes))
+	assert.Equal(t, "appID1", nodes[1].ApplicationID)
+	assert.Equal(t, 2, nodes[1].Depth)
+}
+
+func TestMetadataHandler_HandleGetDependents_ResultedOK(t *testing.T) {
+
+	im := createDependencyChain()
+	request, _ := http.NewRequest("GET", "app-metadata/appID1/dependents?depth=2", strings.NewReader(""))
+	request = mux.SetURLVars(request, map[string]string{"appID": "appID1"})
+	responseRecorder := httptest.NewRecorder()
+
+	mh := NewMetadataHandler(im)
+	mh.HandleGetDependents(responseRecorder, request)
+
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	var nodes []dependency.Node
+	yaml.NewDecoder(responseRecorder.Body).Decode(&nodes)
+	assert.Equal(t, 2, len(nodes))
+	assert.Equal(t, "appID2", nodes[0].ApplicationID)
+	assert.Equal(t, "appID3", nodes[1].ApplicationID)
+}
+
+func TestMetadataHandler_HandleGetDependentsNotFound_ResultedNotFound(t *testing.T) {
+
+	request, _ := http.NewRequest("GET", "app-metadata/notfound/dependents", strings.NewReader(""))
+	request = mux.SetURLVars(request, map[string]string{"appID": "notfound"})
+	responseRecorder := httptest.NewRecorder()
+
+	mh := NewMetadataHandler(repository.NewInMemoryMetadataRepository())
+	mh.HandleGetDependents(responseRecorder, request)
+
+	assert.Equal(t, http.StatusNotFound, responseRecorder.Code)
+}
+
+// createDependencyChain returns a repository where appID3 depends on appID2, which depends on appID1
+func TestInMemoryMetadataRepository_UpdateDependencyCycle_ResultedValidationError(t *testing.T) {
+
+	im := createDependencyChain()
+	im.OnWrite(dependency.Check)
+
+	app, _ := im.Get("appID1")
+	update := app.DeepCopy()
+	update.Dependencies = []metadata.Dependency{{ApplicationID: "appID3"}}
+	err := im.Update("appID1", update)
+
+	var ve *dependency.ValidationError
+	assert.True(t, errors.As(err, &ve))
+	assert.Equal(t, "dependency cycle [appID1 appID3 appID2 appID1]", ve.Validation.Description)
+
+	// the writes leaving the dependencies as they are aren't checked
+	app, _ = im.Get("appID3")
+	update = app.DeepCopy()
+	update.Title = "Valid App 3"
+	assert.Nil(t, im.Update("appID3", update))
+}
+
+func createDependencyChain() repository.MetadataRepository {
+	im := repository.NewInMemoryMetadataRepository()
+	prev := ""
+	for _, id := range []string{"appID1", "appID2", "appID3"} {
+		var mtd metadata.ApplicationMetadata
+		yaml.Unmarshal([]byte(createValidPayload()), &mtd)
+		mtd.ApplicationID = id
+		if prev != "" {
+			mtd.Dependencies = []metadata.Dependency{{ApplicationID: prev}}
+		}
+		im.Create(id, &mtd)
+		prev = id
+	}
+	return im
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/elumbantoruan/app-metadata/markdown"
+	"github.com/gorilla/mux"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// formatHTML requests the description as an HTML fragment
+const formatHTML = "html"
+
+// HandleGetDescription handles GET operation returning the description of an application rendered to sanitized HTML,
+// along with its excerpt, headings, and links.  The HTML alone is returned for text/html.
+func (mh *MetadataHandler) HandleGetDescription(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	appID, ok := mux.Vars(r)["appID"]
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest) // 400
+		return
+	}
+	app, err := mh.Repository.Get(appID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError) // 500
+		yaml.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	if app == nil {
+		w.WriteHeader(http.StatusNotFound) // 404
+		return
+	}
+
+	doc := markdown.Render(app.Description)
+	if wantsHTML(r) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		// the fragment is meant to be embedded, it runs nothing when opened by itself
+		w.Header().Set("Content-Security-Policy", "default-src 'none'; img-src https: http:; style-src 'unsafe-inline'")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.WriteHeader(http.StatusOK) // 200
+		io.WriteString(w, doc.HTML)
+		return
+	}
+	format := responseFormat(r)
+	w.Header().Set("Content-Type", mediaTypes[format])
+	w.WriteHeader(http.StatusOK) // 200
+	encode(w, format, doc)
+}
+
+// wantsHTML tells whether the client asks for text/html, through format=html or the Accept header
+func wantsHTML(r *http.Request) bool {
+	if f := r.URL.Query().Get("format"); f != "" {
+		return f == formatHTML
+	}
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		if mt, _, err := mime.ParseMediaType(strings.TrimSpace(accept)); err == nil && mt == "text/html" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/elumbantoruan/app-metadata/markdown"
+	"github.com/elumbantoruan/app-metadata/repository"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestMetadataHandler_HandlePostMetadataDescription_ResultedValidated(t *testing.T) {
+
+	mh := NewMetadataHandler(repository.NewInMemoryMetadataRepository())
+
+	post := func(payload string) *httptest.ResponseRecorder {
+		request, _ := http.NewRequest("POST", "app-metadata", strings.NewReader(payload))
+		responseRecorder := httptest.NewRecorder()
+		mh.HandlePostMetadata(responseRecorder, request)
+		return responseRecorder
+	}
+
+	responseRecorder := post("applicationID: unsafe" + strings.Replace(createValidPayload(), "Some application content", "<script>alert(1)</script>", 1))
+	assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+	assert.Contains(t, responseRecorder.Body.String(), "raw HTML <script> is not allowed in description")
+
+	responseRecorder = post("applicationID: large" + createValidPayload() + "  " + strings.Repeat("a", 64<<10) + "\n")
+	assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+	assert.Contains(t, responseRecorder.Body.String(), "description is longer than 65536 bytes")
+
+	assert.Equal(t, http.StatusCreated, post("applicationID: payments"+createValidPayload()).Code)
+	res, _ := mh.Repository.Get("payments")
+	assert.Equal(t, "Some application content", res.Excerpt)
+}
+
+func TestMetadataHandler_HandleGetDescription_ResultedRendered(t *testing.T) {
+
+	mh := NewMetadataHandler(repository.NewInMemoryMetadataRepository())
+	request, _ := http.NewRequest("POST", "app-metadata", strings.NewReader("applicationID: payments"+createValidPayload()+
+		"  [Docs](https://docs.example.com) [x](javascript:alert(1))\n"))
+	mh.HandlePostMetadata(httptest.NewRecorder(), request)
+
+	get := func(appID, accept string) *httptest.ResponseRecorder {
+		request, _ := http.NewRequest("GET", "/app-metadata/"+appID+"/description", strings.NewReader(""))
+		request.Header.Set("Accept", accept)
+		request = mux.SetURLVars(request, map[string]string{"appID": appID})
+		responseRecorder := httptest.NewRecorder()
+		mh.HandleGetDescription(responseRecorder, request)
+		return responseRecorder
+	}
+
+	assert.Equal(t, http.StatusNotFound, get("missing", "").Code)
+
+	responseRecorder := get("payments", "")
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	var doc markdown.Document
+	yaml.Unmarshal(responseRecorder.Body.Bytes(), &doc)
+	assert.Equal(t, []markdown.Heading{{Level: 3, Text: "Interesting title", ID: "interesting-title"}}, doc.Headings)
+	assert.Equal(t, []markdown.Link{{Text: "Docs", URL: "https://docs.example.com"}}, doc.Links)
+	assert.Equal(t, "Some application content Docs x", doc.Excerpt)
+
+	responseRecorder = get("payments", "text/html")
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	assert.Equal(t, "text/html; charset=utf-8", responseRecorder.Header().Get("Content-Type"))
+	assert.Equal(t, "<h3 id=\"interesting-title\">Interesting title</h3>\n<p>Some application content\n"+
+		"<a href=\"https://docs.example.com\" rel=\"nofollow noopener noreferrer\">Docs</a> x</p>\n", responseRecorder.Body.String())
+}
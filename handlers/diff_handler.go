@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/elumbantoruan/app-metadata/diff"
+	"github.com/elumbantoruan/app-metadata/metadata"
+	"github.com/gorilla/mux"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// formatDiff is the unified text diff format of the diff endpoints
+const formatDiff = "diff"
+
+// MetadataDiff is the field level difference between two application metadata,
+// From and To naming them as appID@revision
+type MetadataDiff struct {
+	From    string        `yaml:"from" json:"from"`
+	To      string        `yaml:"to" json:"to"`
+	Changes []diff.Change `yaml:"changes" json:"changes"`
+}
+
+// HandleDiffRevisions handles GET operation comparing two revisions of an application.
+// to defaults to the current revision and from to the revision before to.
+func (mh *MetadataHandler) HandleDiffRevisions(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	appID, ok := mux.Vars(r)["appID"]
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest) // 400
+		return
+	}
+	q := r.URL.Query()
+	from, errFrom := parseRevision(q.Get("from"))
+	to, errTo := parseRevision(q.Get("to"))
+	if errFrom != nil || errTo != nil {
+		w.WriteHeader(http.StatusBadRequest) // 400
+		yaml.NewEncoder(w).Encode("from and to must be positive revision numbers")
+		return
+	}
+
+	current, err := mh.Repository.Get(appID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError) // 500
+		yaml.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	if current == nil {
+		w.WriteHeader(http.StatusNotFound) // 404
+		return
+	}
+	if to == 0 {
+		to = current.Revision
+	}
+	if from == 0 {
+		from = to - 1
+	}
+
+	a, err := mh.Repository.GetRevision(appID, from)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError) // 500
+		yaml.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	b, err := mh.Repository.GetRevision(appID, to)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError) // 500
+		yaml.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	if a == nil || b == nil {
+		w.WriteHeader(http.StatusNotFound) // 404
+		yaml.NewEncoder(w).Encode("revision not found")
+		return
+	}
+	writeDiff(w, r, a, b)
+}
+
+// HandleDiffApplications handles GET operation comparing the current revision of two applications a and b
+func (mh *MetadataHandler) HandleDiffApplications(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	q := r.URL.Query()
+	idA, idB := q.Get("a"), q.Get("b")
+	if idA == "" || idB == "" {
+		w.WriteHeader(http.StatusBadRequest) // 400
+		yaml.NewEncoder(w).Encode("a and b parameters are required")
+		return
+	}
+
+	apps := make([]*metadata.ApplicationMetadata, 2)
+	for i, id := range []string{idA, idB} {
+		app, err := mh.lookup(id)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError) // 500
+			yaml.NewEncoder(w).Encode(err.Error())
+			return
+		}
+		if app == nil {
+			w.WriteHeader(http.StatusNotFound) // 404
+			yaml.NewEncoder(w).Encode(id + " not found")
+			return
+		}
+		d := *app
+		d.ApplicationID = id
+		apps[i] = &d
+	}
+	writeDiff(w, r, apps[0], apps[1])
+}
+
+// writeDiff writes the difference from a to b, as a unified diff when format=diff or Accept is text/x-diff,
+// or as a MetadataDiff otherwise
+func writeDiff(w http.ResponseWriter, r *http.Request, a, b *metadata.ApplicationMetadata) {
+	from := a.ApplicationID + "@" + strconv.Itoa(a.Revision)
+	to := b.ApplicationID + "@" + strconv.Itoa(b.Revision)
+
+	if wantsUnifiedDiff(r) {
+		u, err := diff.Unified(a, b, from, to)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError) // 500
+			yaml.NewEncoder(w).Encode(err.Error())
+			return
+		}
+		w.Header().Set("Content-Type", "text/x-diff; charset=utf-8")
+		w.WriteHeader(http.StatusOK) // 200
+		io.WriteString(w, u)
+		return
+	}
+
+	res := MetadataDiff{From: from, To: to, Changes: diff.Compare(a, b)}
+	if res.Changes == nil {
+		res.Changes = []diff.Change{}
+	}
+	format := responseFormat(r)
+	w.Header().Set("Content-Type", mediaTypes[format])
+	w.WriteHeader(http.StatusOK) // 200
+	encode(w, format, res)
+}
+
+func wantsUnifiedDiff(r *http.Request) bool {
+	if f := r.URL.Query().Get("format"); f != "" {
+		return f == formatDiff
+	}
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		if mt, _, err := mime.ParseMediaType(strings.TrimSpace(accept)); err == nil && mt == "text/x-diff" {
+			return true
+		}
+	}
+	return false
+}
+
+// parseRevision parses a revision number, zero when it's empty
+func parseRevision(s string) (int, error) {
+	if s == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err == nil && n < 1 {
+		err = strconv.ErrRange
+	}
+	return n, err
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/elumbantoruan/app-metadata/diff"
+	"github.com/elumbantoruan/app-metadata/metadata"
+	"github.com/elumbantoruan/app-metadata/repository"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestMetadataHandler_HandleDiffRevisions_ResultedOK(t *testing.T) {
+
+	im := repository.NewInMemoryMetadataRepository()
+	var mtd metadata.ApplicationMetadata
+	yaml.Unmarshal([]byte(createValidPayload()), &mtd)
+	im.Create("appID1", &mtd)
+	updated := mtd
+	updated.Title = "Valid App 1 renamed"
+	updated.Maintainers = []metadata.Maintainer{mtd.Maintainers[1], mtd.Maintainers[0]}
+	im.Update("appID1", &updated)
+	mh := NewMetadataHandler(im)
+
+	request, _ := http.NewRequest("GET", "app-metadata/appID1/diff", strings.NewReader(""))
+	request = mux.SetURLVars(request, map[string]string{"appID": "appID1"})
+	responseRecorder := httptest.NewRecorder()
+	mh.HandleDiffRevisions(responseRecorder, request)
+
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	var res MetadataDiff
+	yaml.NewDecoder(responseRecorder.Body).Decode(&res)
+	// reordering the maintainers isn't a change
+	assert.Equal(t, MetadataDiff{
+		From: "appID1@1",
+		To:   "appID1@2",
+		Changes: []diff.Change{
+			{Path: "title", Kind: diff.Changed, Old: "Valid App 1", New: "Valid App 1 renamed"},
+		},
+	}, res)
+
+	request, _ = http.NewRequest("GET", "app-metadata/appID1/diff?from=1&to=2&format=diff", strings.NewReader(""))
+	request = mux.SetURLVars(request, map[string]string{"appID": "appID1"})
+	responseRecorder = httptest.NewRecorder()
+	mh.HandleDiffRevisions(responseRecorder, request)
+
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	assert.Equal(t, "text/x-diff; charset=utf-8", responseRecorder.Header().Get("Content-Type"))
+	assert.Contains(t, responseRecorder.Body.String(), "-title: Valid App 1\n+title: Valid App 1 renamed\n")
+}
+
+func TestMetadataHandler_HandleDiffRevisionsSharedSlices_ResultedHistoryKept(t *testing.T) {
+
+	im := repository.NewInMemoryMetadataRepository()
+	var mtd metadata.ApplicationMetadata
+	yaml.Unmarshal([]byte(createValidPayload()), &mtd)
+	mtd.Labels = map[string]string{"team": "payments"}
+	im.Create("appID1", &mtd)
+	// the update shares the slices and maps of the first revision
+	updated := mtd
+	updated.Maintainers[0].Name = "Renamed Maintainer"
+	updated.Labels["team"] = "billing"
+	im.Update("appID1", &updated)
+	mh := NewMetadataHandler(im)
+
+	request, _ := http.NewRequest("GET", "app-metadata/appID1/diff", strings.NewReader(""))
+	request = mux.SetURLVars(request, map[string]string{"appID": "appID1"})
+	responseRecorder := httptest.NewRecorder()
+	mh.HandleDiffRevisions(responseRecorder, request)
+
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	var res MetadataDiff
+	yaml.NewDecoder(responseRecorder.Body).Decode(&res)
+	paths := []string{}
+	for _, c := range res.Changes {
+		paths = append(paths, c.Path)
+	}
+	assert.Equal(t, []string{"labels.team", "maintainers[firstmaintainer@hotmail.com].name"}, paths)
+}
+
+func TestMetadataHandler_HandleDiffRevisions_ResultedError(t *testing.T) {
+
+	im := repository.NewInMemoryMetadataRepository()
+	im.Create("appID1", &metadata.ApplicationMetadata{ApplicationID: "appID1"})
+	mh := NewMetadataHandler(im)
+
+	tests := []struct {
+		appID string
+		query string
+		code  int
+	}{
+		{appID: "appID1", query: "?from=0", code: http.StatusBadRequest},
+		{appID: "appID1", query: "?to=abc", code: http.StatusBadRequest},
+		{appID: "appID1", query: "?from=1&to=2", code: http.StatusNotFound},
+		// there's no revision before the first one
+		{appID: "appID1", query: "", code: http.StatusNotFound},
+		{appID: "appID2", query: "", code: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		request, _ := http.NewRequest("GET", "app-metadata/"+tt.appID+"/diff"+tt.query, strings.NewReader(""))
+		request = mux.SetURLVars(request, map[string]string{"appID": tt.appID})
+		responseRecorder := httptest.NewRecorder()
+		mh.HandleDiffRevisions(responseRecorder, request)
+
+		assert.Equal(t, tt.code, responseRecorder.Code, tt.appID+tt.query)
+	}
+}
+
+func TestMetadataHandler_HandleDiffApplications_ResultedOK(t *testing.T) {
+
+	im := repository.NewInMemoryMetadataRepository()
+	var app1, app2 metadata.ApplicationMetadata
+	yaml.Unmarshal([]byte(createValidPayload()), &app1)
+	yaml.Unmarshal([]byte(createValidPayload2()), &app2)
+	app2.Maintainers = app2.Maintainers[:1]
+	im.Create("appID1", &app1)
+	im.Create("appID2", &app2)
+	mh := NewMetadataHandler(im)
+
+	request, _ := http.NewRequest("GET", "app-metadata:diff?a=appID1&b=appID2&format=json", strings.NewReader(""))
+	responseRecorder := httptest.NewRecorder()
+	mh.HandleDiffApplications(responseRecorder, request)
+
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	assert.Equal(t, "application/json", responseRecorder.Header().Get("Content-Type"))
+	var res MetadataDiff
+	yaml.NewDecoder(responseRecorder.Body).Decode(&res)
+	assert.Equal(t, "appID1@1", res.From)
+	assert.Equal(t, "appID2@1", res.To)
+	assert.Equal(t, 2, len(res.Changes))
+	assert.Equal(t, "maintainers[secondmaitainer@gmail.com]", res.Changes[0].Path)
+	assert.Equal(t, diff.Removed, res.Changes[0].Kind)
+	assert.Equal(t, "title", res.Changes[1].Path)
+
+	request, _ = http.NewRequest("GET", "app-metadata:diff?a=appID1&b=appID3", strings.NewReader(""))
+	responseRecorder = httptest.NewRecorder()
+	mh.HandleDiffApplications(responseRecorder, request)
+	assert.Equal(t, http.StatusNotFound, responseRecorder.Code)
+
+	request, _ = http.NewRequest("GET", "app-metadata:diff?a=appID1", strings.NewReader(""))
+	responseRecorder = httptest.NewRecorder()
+	mh.HandleDiffApplications(responseRecorder, request)
+	assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/elumbantoruan/app-metadata/dedupe"
+	"github.com/elumbantoruan/app-metadata/metadata"
+	"github.com/elumbantoruan/app-metadata/repository"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// policies for the near-duplicates of an application being created
+const (
+	// DuplicatesOff doesn't look for near-duplicates
+	DuplicatesOff = "off"
+	// DuplicatesWarn creates the application with a Warning header for each near-duplicate
+	DuplicatesWarn = "warn"
+	// DuplicatesBlock rejects the application with 409, unless an admin sets force=true.  Updates and imports are
+	// warned as with DuplicatesWarn.
+	DuplicatesBlock = "block"
+)
+
+// DuplicatesResponse is the body of a creation blocked by near-duplicates
+type DuplicatesResponse struct {
+	Description string         `yaml:"description" json:"description"`
+	Duplicates  []dedupe.Match `yaml:"duplicates" json:"duplicates"`
+}
+
+// checkDuplicates looks for near-duplicates of payload according to DuplicatePolicy.
+// It writes the error response and returns false when the creation must not go on.
+func (mh *MetadataHandler) checkDuplicates(w http.ResponseWriter, r *http.Request, payload *metadata.ApplicationMetadata) bool {
+	if mh.DuplicatePolicy == "" || mh.DuplicatePolicy == DuplicatesOff {
+		return true
+	}
+	force, _ := strconv.ParseBool(r.URL.Query().Get("force"))
+	if force && !mh.requireAdmin(w, r) {
+		return false
+	}
+
+	matches, err := mh.findDuplicates(payload)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError) // 500
+		yaml.NewEncoder(w).Encode(err.Error())
+		return false
+	}
+	if len(matches) == 0 {
+		return true
+	}
+
+	if mh.DuplicatePolicy == DuplicatesBlock && !force {
+		w.WriteHeader(http.StatusConflict) // 409
+		yaml.NewEncoder(w).Encode(DuplicatesResponse{
+			Description: "the application may be a duplicate, an admin can create it anyway with force=true",
+			Duplicates:  matches,
+		})
+		return false
+	}
+	warnDuplicates(w, matches)
+	return true
+}
+
+// findDuplicates returns the near-duplicates of payload among the live applications, none when DuplicatePolicy is off
+func (mh *MetadataHandler) findDuplicates(payload *metadata.ApplicationMetadata) ([]dedupe.Match, error) {
+	if mh.DuplicatePolicy == "" || mh.DuplicatePolicy == DuplicatesOff {
+		return nil, nil
+	}
+	all, err := mh.Repository.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	return dedupe.Find(payload, all, dedupe.DefaultTitleSimilarity), nil
+}
+
+// warnDuplicates adds a Warning header for each near-duplicate
+func warnDuplicates(w http.ResponseWriter, matches []dedupe.Match) {
+	for _, m := range matches {
+		w.Header().Add("Warning", fmt.Sprintf("299 - %q", duplicateWarning(m)))
+	}
+}
+
+func duplicateWarning(m dedupe.Match) string {
+	return fmt.Sprintf("possible duplicate of %s: %s", m.ApplicationID, strings.Join(m.Reasons, ", "))
+}
+
+// isConflict returns true when err is a conflict with another application: the ID or unique values are taken
+func isConflict(err error) bool {
+	var ce *repository.ConstraintError
+	return err == repository.ErrIDConflict || errors.As(err, &ce)
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/elumbantoruan/app-metadata/metadata"
+	"github.com/elumbantoruan/app-metadata/repository"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestMetadataHandler_HandlePostMetadataDuplicate_ResultedWarning(t *testing.T) {
+
+	mh := NewMetadataHandler(repository.NewInMemoryMetadataRepository())
+
+	request, _ := http.NewRequest("POST", "app-metadata", strings.NewReader(createValidPayload()))
+	responseRecorder := httptest.NewRecorder()
+	mh.HandlePostMetadata(responseRecorder, request)
+	assert.Equal(t, http.StatusCreated, responseRecorder.Code)
+	assert.Empty(t, responseRecorder.Header().Get("Warning"))
+
+	request, _ = http.NewRequest("POST", "app-metadata", strings.NewReader(createValidPayload2()))
+	responseRecorder = httptest.NewRecorder()
+	mh.HandlePostMetadata(responseRecorder, request)
+	assert.Equal(t, http.StatusCreated, responseRecorder.Code)
+	assert.Contains(t, responseRecorder.Header().Get("Warning"), "similar title, same source repository")
+}
+
+func TestMetadataHandler_HandlePostMetadataDuplicate_ResultedBlocked(t *testing.T) {
+
+	mh := NewMetadataHandler(repository.NewInMemoryMetadataRepository())
+	mh.DuplicatePolicy = DuplicatesBlock
+	mh.AdminToken = "secret"
+
+	post := func(query, token string) *httptest.ResponseRecorder {
+		request, _ := http.NewRequest("POST", "app-metadata"+query, strings.NewReader(createValidPayload()))
+		if token != "" {
+			request.Header.Set(AdminTokenHeader, token)
+		}
+		responseRecorder := httptest.NewRecorder()
+		mh.HandlePostMetadata(responseRecorder, request)
+		return responseRecorder
+	}
+
+	assert.Equal(t, http.StatusCreated, post("", "").Code)
+
+	responseRecorder := post("", "")
+	assert.Equal(t, http.StatusConflict, responseRecorder.Code) // 409
+	var res DuplicatesResponse
+	yaml.NewDecoder(responseRecorder.Body).Decode(&res)
+	assert.Equal(t, 1, len(res.Duplicates))
+	assert.Equal(t, "Valid App 1", res.Duplicates[0].Title)
+
+	// only admins can force the creation
+	assert.Equal(t, http.StatusForbidden, post("?force=true", "").Code)
+	assert.Equal(t, http.StatusCreated, post("?force=true", "secret").Code)
+}
+
+func TestMetadataHandler_UniqueConstraints_ResultedConflict(t *testing.T) {
+
+	constraints, err := repository.ParseUniqueConstraints("title,company;source")
+	assert.Nil(t, err)
+	im := repository.NewInMemoryMetadataRepository(constraints...)
+	mh := NewMetadataHandler(im)
+	mh.DuplicatePolicy = DuplicatesOff
+
+	request, _ := http.NewRequest("POST", "app-metadata", strings.NewReader("applicationID: app1"+createValidPayload()))
+	responseRecorder := httptest.NewRecorder()
+	mh.HandlePostMetadata(responseRecorder, request)
+	assert.Equal(t, http.StatusCreated, responseRecorder.Code)
+
+	// the same source written differently
+	payload := strings.Replace(createValidPayload2(), "https://github.com/elumbantoruan/app-metadata", "HTTPS://github.com/elumbantoruan/app-metadata.git/", 1)
+	request, _ = http.NewRequest("POST", "app-metadata", strings.NewReader(payload))
+	responseRecorder = httptest.NewRecorder()
+	mh.HandlePostMetadata(responseRecorder, request)
+	assert.Equal(t, http.StatusConflict, responseRecorder.Code)
+	assert.Contains(t, responseRecorder.Body.String(), "source must be unique, they're the same as app1")
+
+	// the same title in another company is fine once the source differs
+	im.Create("app2", &metadata.ApplicationMetadata{ApplicationID: "app2", Title: "Other", Company: "pellucid Computing", Source: "https://github.com/acme/other"})
+	request, _ = http.NewRequest("PUT", "app-metadata/app2", strings.NewReader(strings.Replace(payload, "source: HTTPS://github.com/elumbantoruan/app-metadata.git/", "source: https://github.com/acme/other", 1)))
+	request = mux.SetURLVars(request, map[string]string{"appID": "app2"})
+	responseRecorder = httptest.NewRecorder()
+	mh.HandlePutMetadata(responseRecorder, request)
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+
+	// but not in the same company
+	request, _ = http.NewRequest("PUT", "app-metadata/app2", strings.NewReader(strings.Replace(createValidPayload(), "source: https://github.com/elumbantoruan/app-metadata", "source: https://github.com/acme/other", 1)))
+	request = mux.SetURLVars(request, map[string]string{"appID": "app2"})
+	responseRecorder = httptest.NewRecorder()
+	mh.HandlePutMetadata(responseRecorder, request)
+	assert.Equal(t, http.StatusConflict, responseRecorder.Code)
+	assert.Contains(t, responseRecorder.Body.String(), "company and title must be unique")
+
+	// nor within a batch
+	errs, err := im.PutBatch([]*metadata.ApplicationMetadata{
+		{ApplicationID: "app3", Source: "https://github.com/acme/batch"},
+		{ApplicationID: "app4", Source: "https://github.com/acme/batch/"},
+	}, repository.BatchOptions{})
+	assert.Nil(t, err)
+	assert.Nil(t, errs[0])
+	assert.IsType(t, &repository.ConstraintError{}, errs[1])
+}
+
+func TestMetadataHandler_HandlePutMetadataDuplicate_ResultedWarning(t *testing.T) {
+
+	im := repository.NewInMemoryMetadataRepository()
+	im.Create("app1", &metadata.ApplicationMetadata{ApplicationID: "app1", Title: "Valid App 1"})
+	im.Create("app2", &metadata.ApplicationMetadata{ApplicationID: "app2", Title: "Search"})
+	mh := NewMetadataHandler(im)
+	mh.DuplicatePolicy = DuplicatesBlock
+
+	request, _ := http.NewRequest("PUT", "app-metadata/app2", strings.NewReader(createValidPayload2()))
+	request = mux.SetURLVars(request, map[string]string{"appID": "app2"})
+	responseRecorder := httptest.NewRecorder()
+	mh.HandlePutMetadata(responseRecorder, request)
+
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	assert.Equal(t, []string{`299 - "possible duplicate of app1: similar title"`}, responseRecorder.Header().Values("Warning"))
+}
+
+func TestMetadataHandler_HandleImportMetadataDuplicate_ResultedWarning(t *testing.T) {
+
+	im := repository.NewInMemoryMetadataRepository()
+	mh := NewMetadataHandler(im)
+
+	payload := "applicationID: app1\n" + createValidPayload() + "---\napplicationID: app2\n" + createValidPayload2()
+	request, _ := http.NewRequest("POST", "app-metadata:import", strings.NewReader(payload))
+	responseRecorder := httptest.NewRecorder()
+	mh.HandleImportMetadata(responseRecorder, request)
+
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	var results []ImportResult
+	yaml.NewDecoder(responseRecorder.Body).Decode(&results)
+	assert.Empty(t, results[0].Warnings)
+	assert.Equal(t, []string{"possible duplicate of app1: similar title, same source repository"}, results[1].Warnings)
+}
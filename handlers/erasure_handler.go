@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/elumbantoruan/app-metadata/audit"
+	"github.com/elumbantoruan/app-metadata/erasure"
+	"github.com/elumbantoruan/app-metadata/metadata"
+	"github.com/elumbantoruan/app-metadata/repository"
+	"github.com/gorilla/mux"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// ErasureResult is the response of an erase operation
+type ErasureResult struct {
+	Email string `yaml:"email" json:"email"`
+	Mode  string `yaml:"mode" json:"mode"`
+	// Pseudonym replaces the email in the records kept
+	Pseudonym string `yaml:"pseudonym" json:"pseudonym"`
+	// Applications are the appIDs changed, live, in the trash, or in their revision history
+	Applications []string `yaml:"applications" json:"applications"`
+	// AuditEntries is the number of audit entries redacted
+	AuditEntries int `yaml:"auditEntries" json:"auditEntries"`
+	// IdempotentResponses is the number of stored responses dropped, so that retries no longer replay them
+	IdempotentResponses int `yaml:"idempotentResponses" json:"idempotentResponses"`
+}
+
+// MaintainerExport is every record referencing a maintainer
+type MaintainerExport struct {
+	Email        string                         `yaml:"email" json:"email"`
+	Applications []metadata.ApplicationMetadata `yaml:"applications" json:"applications"`
+	// Deleted are the applications in the trash
+	Deleted []metadata.ApplicationMetadata `yaml:"deleted" json:"deleted"`
+	// Revisions are the past revisions of the applications, live or in the trash
+	Revisions []metadata.ApplicationMetadata `yaml:"revisions" json:"revisions"`
+	Audit     []audit.Entry                  `yaml:"audit" json:"audit"`
+}
+
+// HandleEraseMaintainer handles the admin POST operation erasing a maintainer from every application, its revision
+// history, the audit log, and the stored idempotent responses.  The mode query parameter removes the maintainer from the applications or replaces
+// them by a pseudonym (the default).  Audit entries are always kept, with the maintainer pseudonymized, and the
+// erasure itself is audited.
+func (mh *MetadataHandler) HandleEraseMaintainer(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	email, ok := mux.Vars(r)["email"]
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest) // 400
+		return
+	}
+	if !mh.isAdmin(r) {
+		mh.auditDenied(r, audit.Erase, "", "admin token is required")
+		mh.requireAdmin(w, r)
+		return
+	}
+	er, err := erasure.New(email, r.URL.Query().Get("mode"))
+	if err != nil {
+		if errors.Is(err, erasure.ErrUnknownMode) {
+			w.WriteHeader(http.StatusBadRequest) // 400
+		} else {
+			w.WriteHeader(http.StatusInternalServerError) // 500
+		}
+		yaml.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	ids, err := mh.Repository.Scrub(er.Application)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError) // 500
+		yaml.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	res := ErasureResult{Email: er.Email, Mode: er.Mode, Pseudonym: er.Pseudonym, Applications: ids}
+	if mh.Idempotency != nil {
+		res.IdempotentResponses, err = mh.Idempotency.Purge(func(rec *repository.IdempotencyRecord) bool {
+			return er.References(rec.Body)
+		})
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError) // 500
+			yaml.NewEncoder(w).Encode(err.Error())
+			return
+		}
+	}
+	if len(ids) == 0 && res.IdempotentResponses == 0 && !mh.auditReferences(er.Email) {
+		w.WriteHeader(http.StatusNotFound) // 404
+		yaml.NewEncoder(w).Encode(fmt.Sprintf("no record references %s", er.Email))
+		return
+	}
+	if mh.Audit != nil {
+		e := mh.auditEntry(r, audit.Erase, "", audit.Allowed)
+		e.Reason = "maintainer erased as " + er.Pseudonym
+		if len(ids) > 0 {
+			e.Changes = []audit.Change{{Field: "applications", New: stringsValue(ids)}}
+		}
+		// an admin erasing themselves isn't recorded under their own email
+		er.AuditEntry(&e)
+		recorded, err := mh.Audit.Redact(er.AuditEntry, e)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError) // 500
+			yaml.NewEncoder(w).Encode(err.Error())
+			return
+		}
+		res.AuditEntries = len(recorded.Redactions)
+	}
+	w.WriteHeader(http.StatusOK) // 200
+	encode(w, responseFormat(r), res)
+}
+
+// HandleExportMaintainer handles GET operation returning every record referencing a maintainer: the applications,
+// live or in the trash, their revisions, and the audit entries.  Only the maintainer (X-User-Email header) or an
+// admin can export them.
+func (mh *MetadataHandler) HandleExportMaintainer(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	email, ok := mux.Vars(r)["email"]
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest) // 400
+		return
+	}
+	email = metadata.NormalizeEmail(email)
+	if !mh.isAdmin(r) {
+		principal := r.Header.Get(PrincipalHeader)
+		if principal == "" {
+			w.WriteHeader(http.StatusUnauthorized) // 401
+			yaml.NewEncoder(w).Encode(PrincipalHeader + " header is required")
+			return
+		}
+		if metadata.NormalizeEmail(principal) != email {
+			w.WriteHeader(http.StatusForbidden) // 403
+			yaml.NewEncoder(w).Encode("only the maintainer or an admin can export their records")
+			return
+		}
+	}
+
+	res := MaintainerExport{
+		Email:        email,
+		Applications: []metadata.ApplicationMetadata{},
+		Deleted:      []metadata.ApplicationMetadata{},
+		Revisions:    []metadata.ApplicationMetadata{},
+		Audit:        []audit.Entry{},
+	}
+	live, err := mh.Repository.List(repository.Query{})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError) // 500
+		yaml.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	deleted, err := mh.Repository.List(repository.Query{Deleted: true})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError) // 500
+		yaml.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	for _, apps := range []struct {
+		apps []metadata.ApplicationMetadata
+		res  *[]metadata.ApplicationMetadata
+	}{{live, &res.Applications}, {deleted, &res.Deleted}} {
+		for i := range apps.apps {
+			app := &apps.apps[i]
+			if erasure.ReferencesApplication(app, email) {
+				*apps.res = append(*apps.res, *app)
+			}
+			// the current revision is the application itself
+			for rev := 1; rev < app.Revision; rev++ {
+				d, err := mh.Repository.GetRevision(app.ApplicationID, rev)
+				if err != nil {
+					w.WriteHeader(http.StatusInternalServerError) // 500
+					yaml.NewEncoder(w).Encode(err.Error())
+					return
+				}
+				if d != nil && erasure.ReferencesApplication(d, email) {
+					d.ApplicationID = app.ApplicationID
+					res.Revisions = append(res.Revisions, *d)
+				}
+			}
+		}
+	}
+	if mh.Audit != nil {
+		for _, e := range mh.Audit.Query(audit.Filter{}) {
+			if erasure.ReferencesAuditEntry(&e, email) {
+				res.Audit = append(res.Audit, e)
+			}
+		}
+	}
+	w.WriteHeader(http.StatusOK) // 200
+	encode(w, responseFormat(r), res)
+}
+
+// auditReferences returns true when an audit entry references the email
+func (mh *MetadataHandler) auditReferences(email string) bool {
+	if mh.Audit == nil {
+		return false
+	}
+	for _, e := range mh.Audit.Query(audit.Filter{}) {
+		if erasure.ReferencesAuditEntry(&e, email) {
+			return true
+		}
+	}
+	return false
+}
+
+// stringsValue converts ids to the generic json value of audit changes
+func stringsValue(ids []string) []interface{} {
+	res := make([]interface{}, len(ids))
+	for i, id := range ids {
+		res[i] = id
+	}
+	return res
+}
@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/elumbantoruan/app-metadata/audit"
+	"github.com/elumbantoruan/app-metadata/metadata"
+	"github.com/elumbantoruan/app-metadata/repository"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestMetadataHandler_HandleEraseMaintainer_ResultedErased(t *testing.T) {
+
+	im := repository.NewInMemoryMetadataRepository()
+	mh := NewMetadataHandler(im)
+	mh.Audit = audit.NewLog(nil, nil)
+	mh.AdminToken = "secret"
+
+	im.Create("app1", &metadata.ApplicationMetadata{ApplicationID: "app1", Maintainers: []metadata.Maintainer{
+		{Name: "First Maintainer", Email: "first@example.com"},
+		{Name: "Second Maintainer", Email: "second@example.com"},
+	}})
+	im.Create("app2", &metadata.ApplicationMetadata{ApplicationID: "app2", Maintainers: []metadata.Maintainer{
+		{Name: "First Maintainer", Email: "first@example.com"},
+	}})
+	im.Update("app2", &metadata.ApplicationMetadata{ApplicationID: "app2", Maintainers: []metadata.Maintainer{
+		{Name: "Second Maintainer", Email: "second@example.com"},
+	}})
+	im.Create("app3", &metadata.ApplicationMetadata{ApplicationID: "app3", Maintainers: []metadata.Maintainer{
+		{Name: "First Maintainer", Email: "first@example.com"},
+	}})
+	im.Delete("app3")
+	mh.Audit.Append(audit.Entry{Principal: "first@example.com", Action: audit.Create, ApplicationID: "app1", SourceIP: "10.0.0.1"})
+
+	export := func(principal string) *httptest.ResponseRecorder {
+		request, _ := http.NewRequest("GET", "maintainers/first@example.com:export", strings.NewReader(""))
+		request = mux.SetURLVars(request, map[string]string{"email": "first@example.com"})
+		if principal != "" {
+			request.Header.Set(PrincipalHeader, principal)
+		}
+		responseRecorder := httptest.NewRecorder()
+		mh.HandleExportMaintainer(responseRecorder, request)
+		return responseRecorder
+	}
+	erase := func(email, mode, token string) *httptest.ResponseRecorder {
+		request, _ := http.NewRequest("POST", "maintainers/"+email+":erase?mode="+mode, strings.NewReader(""))
+		request = mux.SetURLVars(request, map[string]string{"email": email})
+		request.Header.Set(PrincipalHeader, "admin@example.com")
+		if token != "" {
+			request.Header.Set(AdminTokenHeader, token)
+		}
+		responseRecorder := httptest.NewRecorder()
+		mh.HandleEraseMaintainer(responseRecorder, request)
+		return responseRecorder
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, export("").Code)
+	assert.Equal(t, http.StatusForbidden, export("second@example.com").Code)
+	responseRecorder := export("First@Example.com")
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	var exported MaintainerExport
+	yaml.NewDecoder(responseRecorder.Body).Decode(&exported)
+	assert.Equal(t, 1, len(exported.Applications))
+	assert.Equal(t, "app1", exported.Applications[0].ApplicationID)
+	assert.Equal(t, 1, len(exported.Deleted))
+	assert.Equal(t, 1, len(exported.Revisions))
+	assert.Equal(t, "app2", exported.Revisions[0].ApplicationID)
+	assert.Equal(t, 1, len(exported.Audit))
+
+	assert.Equal(t, http.StatusForbidden, erase("first@example.com", "", "").Code)
+	responseRecorder = erase("First@Example.com", "", "secret")
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	var res ErasureResult
+	yaml.NewDecoder(responseRecorder.Body).Decode(&res)
+	assert.Equal(t, []string{"app1", "app2", "app3"}, res.Applications)
+	assert.Equal(t, 1, res.AuditEntries)
+
+	app1, _ := im.Get("app1")
+	assert.Equal(t, metadata.Maintainer{Name: "Erased Maintainer", Email: res.Pseudonym}, app1.Maintainers[0])
+	assert.Equal(t, 1, app1.Revision)
+	revision, _ := im.GetRevision("app2", 1)
+	assert.Equal(t, res.Pseudonym, revision.Maintainers[0].Email)
+
+	// the erasure is audited, and the chain still verifies
+	entries := mh.Audit.Query(audit.Filter{})
+	assert.Equal(t, res.Pseudonym, entries[0].Principal)
+	assert.Equal(t, "", entries[0].SourceIP)
+	assert.True(t, entries[0].Redacted)
+	assert.Equal(t, audit.Erase, entries[len(entries)-1].Action)
+	assert.Equal(t, "admin@example.com", entries[len(entries)-1].Principal)
+	_, err := mh.Audit.Verify()
+	assert.Nil(t, err)
+
+	responseRecorder = export("first@example.com")
+	exported = MaintainerExport{}
+	yaml.NewDecoder(responseRecorder.Body).Decode(&exported)
+	assert.Equal(t, 0, len(exported.Applications)+len(exported.Deleted)+len(exported.Revisions)+len(exported.Audit))
+	assert.Equal(t, http.StatusNotFound, erase("first@example.com", "", "secret").Code)
+
+	// removing drops the maintainer instead
+	responseRecorder = erase("second@example.com", "remove", "secret")
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	app1, _ = im.Get("app1")
+	assert.Equal(t, 1, len(app1.Maintainers))
+	assert.Equal(t, "Erased Maintainer", app1.Maintainers[0].Name)
+	assert.Equal(t, http.StatusBadRequest, erase("x@example.com", "shred", "secret").Code)
+}
+
+func TestMetadataHandler_HandleEraseMaintainerIdempotentResponse_ResultedPurged(t *testing.T) {
+
+	im := repository.NewInMemoryMetadataRepository()
+	mh := NewMetadataHandler(im)
+	mh.AdminToken = "secret"
+
+	post := func() *httptest.ResponseRecorder {
+		request, _ := http.NewRequest("POST", "app-metadata", strings.NewReader(createValidPayload()))
+		request.Header.Set(IdempotencyKeyHeader, "key1")
+		responseRecorder := httptest.NewRecorder()
+		mh.HandlePostMetadata(responseRecorder, request)
+		return responseRecorder
+	}
+	assert.Equal(t, http.StatusCreated, post().Code)
+
+	request, _ := http.NewRequest("POST", "maintainers/firstmaintainer@hotmail.com:erase", strings.NewReader(""))
+	request = mux.SetURLVars(request, map[string]string{"email": "firstmaintainer@hotmail.com"})
+	request.Header.Set(AdminTokenHeader, "secret")
+	responseRecorder := httptest.NewRecorder()
+	mh.HandleEraseMaintainer(responseRecorder, request)
+
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	var res ErasureResult
+	yaml.NewDecoder(responseRecorder.Body).Decode(&res)
+	assert.Equal(t, 1, res.IdempotentResponses)
+
+	// the retry is processed again rather than replaying the erased email
+	retry := post()
+	assert.Equal(t, "", retry.Header().Get("Idempotent-Replayed"))
+}
+
+func TestInMemoryMetadataRepository_Scrub_ResultedCopiesReplaced(t *testing.T) {
+
+	im := repository.NewInMemoryMetadataRepository()
+	for _, id := range []string{"app1", "app2"} {
+		im.Create(id, &metadata.ApplicationMetadata{ApplicationID: id,
+			Maintainers: []metadata.Maintainer{{Name: "First Maintainer", Email: "first@example.com"}}})
+	}
+	im.Delete("app2")
+	// a reader may still hold the stored application
+	held, _ := im.Get("app1")
+
+	ids, _ := im.Scrub(func(data *metadata.ApplicationMetadata) bool {
+		data.Maintainers[0].Name = "Erased Maintainer"
+		return true
+	})
+
+	assert.Equal(t, []string{"app1", "app2"}, ids)
+	assert.Equal(t, "First Maintainer", held.Maintainers[0].Name)
+	app1, _ := im.Get("app1")
+	assert.Equal(t, "Erased Maintainer", app1.Maintainers[0].Name)
+	revision, _ := im.GetRevision("app1", 1)
+	assert.Equal(t, "Erased Maintainer", revision.Maintainers[0].Name)
+	deleted, _ := im.List(repository.Query{Deleted: true})
+	assert.Equal(t, "Erased Maintainer", deleted[0].Maintainers[0].Name)
+}
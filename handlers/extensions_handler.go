@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/elumbantoruan/app-metadata/extensions"
+	"github.com/elumbantoruan/app-metadata/metadata"
+	"github.com/elumbantoruan/app-metadata/repository"
+	"github.com/gorilla/mux"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// HandleGetExtensionSchemas handles GET operation returning the schemas of every extension namespace
+func (mh *MetadataHandler) HandleGetExtensionSchemas(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	res, err := mh.Schemas.ListSchemas()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError) // 500
+		yaml.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusOK) // 200
+	encode(w, responseFormat(r), res)
+}
+
+// HandleGetExtensionSchema handles GET operation returning the schema of an extension namespace
+func (mh *MetadataHandler) HandleGetExtensionSchema(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	namespace, ok := mux.Vars(r)["namespace"]
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest) // 400
+		return
+	}
+	res, err := mh.Schemas.GetSchema(namespace)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError) // 500
+		yaml.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	if res == nil {
+		w.WriteHeader(http.StatusNotFound) // 404
+		return
+	}
+	w.WriteHeader(http.StatusOK) // 200
+	encode(w, responseFormat(r), res)
+}
+
+// HandlePutExtensionSchema handles PUT operation registering the JSON Schema of an extension namespace,
+// in json or yaml.  It's an admin operation, and the applications already using the namespace aren't revalidated.
+func (mh *MetadataHandler) HandlePutExtensionSchema(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	if !mh.requireAdmin(w, r) {
+		return
+	}
+	namespace, ok := mux.Vars(r)["namespace"]
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest) // 400
+		return
+	}
+	if err := extensions.ValidateNamespace(namespace); err != nil {
+		w.WriteHeader(http.StatusBadRequest) // 400
+		yaml.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	b, ok := mh.readBody(w, r)
+	if !ok {
+		return
+	}
+	var doc interface{}
+	if err := mh.unmarshal(b, &doc); err != nil {
+		w.WriteHeader(http.StatusBadRequest) // 400
+		yaml.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	doc = metadata.Normalize(doc)
+	if _, err := extensions.Compile(doc); err != nil {
+		w.WriteHeader(http.StatusBadRequest) // 400
+		yaml.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	schema := &repository.ExtensionSchema{Namespace: namespace, Schema: doc, UpdatedAt: time.Now().UTC()}
+	created, err := mh.Schemas.PutSchema(schema)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError) // 500
+		yaml.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	if created {
+		w.WriteHeader(http.StatusCreated) // 201
+	} else {
+		w.WriteHeader(http.StatusOK) // 200
+	}
+	encode(w, responseFormat(r), schema)
+}
+
+// HandleDeleteExtensionSchema handles DELETE operation removing the schema of an extension namespace.
+// It's an admin operation, and the applications using the namespace can't be updated until it's registered again.
+func (mh *MetadataHandler) HandleDeleteExtensionSchema(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	if !mh.requireAdmin(w, r) {
+		return
+	}
+	namespace, ok := mux.Vars(r)["namespace"]
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest) // 400
+		return
+	}
+	err := mh.Schemas.DeleteSchema(namespace)
+	if err != nil {
+		if err == repository.ErrSchemaNotFound {
+			w.WriteHeader(http.StatusNotFound) // 404
+		} else {
+			w.WriteHeader(http.StatusInternalServerError) // 500
+		}
+		yaml.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent) // 204
+}
+
+// validateExtensions checks the extensions of payload against the registered schemas.
+// It writes 400 when they don't match, or 500 when the schemas can't be read, and returns false.
+func (mh *MetadataHandler) validateExtensions(w http.ResponseWriter, payload *metadata.ApplicationMetadata) bool {
+	err := mh.checkExtensions(payload.Extensions)
+	if err == nil {
+		return true
+	}
+	if isExtensionError(err) {
+		w.WriteHeader(http.StatusBadRequest) // 400
+		yaml.NewEncoder(w).Encode(metadata.ValidationMessage{Description: err.Error()})
+	} else {
+		w.WriteHeader(http.StatusInternalServerError) // 500
+		yaml.NewEncoder(w).Encode(err.Error())
+	}
+	return false
+}
+
+// checkExtensions validates ext against the registered schemas, an extension without schema being invalid
+func (mh *MetadataHandler) checkExtensions(ext metadata.Extensions) error {
+	if len(ext) == 0 {
+		return nil
+	}
+	return extensions.Validate(ext, func(namespace string) (*extensions.Schema, error) {
+		if mh.Schemas == nil {
+			return nil, nil
+		}
+		s, err := mh.Schemas.GetSchema(namespace)
+		if err != nil || s == nil {
+			return nil, err
+		}
+		return extensions.Compile(s.Schema)
+	})
+}
+
+// isExtensionError returns true when err is the fault of the extensions rather than of the schema store
+func isExtensionError(err error) bool {
+	var (
+		ve *extensions.ValidationError
+		ne *extensions.NoSchemaError
+	)
+	return errors.As(err, &ve) || errors.As(err, &ne)
+}
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/elumbantoruan/app-metadata/metadata"
+	"github.com/elumbantoruan/app-metadata/repository"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+const oncallSchema = `{
+  "type": "object",
+  "required": ["rotation"],
+  "properties": {
+    "rotation": {"enum": ["daily", "weekly"]},
+    "team": {"type": "string"}
+  }
+}`
+
+func putSchema(mh *MetadataHandler, namespace, schema, token string) *httptest.ResponseRecorder {
+	request, _ := http.NewRequest("PUT", "extensions/"+namespace, strings.NewReader(schema))
+	request = mux.SetURLVars(request, map[string]string{"namespace": namespace})
+	if token != "" {
+		request.Header.Set(AdminTokenHeader, token)
+	}
+	responseRecorder := httptest.NewRecorder()
+	mh.HandlePutExtensionSchema(responseRecorder, request)
+	return responseRecorder
+}
+
+func TestMetadataHandler_HandlePutExtensionSchema_ResultedRegistered(t *testing.T) {
+
+	mh := NewMetadataHandler(repository.NewInMemoryMetadataRepository())
+	mh.AdminToken = "secret"
+
+	assert.Equal(t, http.StatusForbidden, putSchema(mh, "oncall", oncallSchema, "").Code)
+	assert.Equal(t, http.StatusBadRequest, putSchema(mh, "On.Call", oncallSchema, "secret").Code)
+	assert.Equal(t, http.StatusBadRequest, putSchema(mh, "oncall", `{"type": "text"}`, "secret").Code)
+	assert.Equal(t, http.StatusCreated, putSchema(mh, "oncall", oncallSchema, "secret").Code)
+	assert.Equal(t, http.StatusOK, putSchema(mh, "oncall", oncallSchema, "secret").Code)
+
+	request, _ := http.NewRequest("GET", "extensions", strings.NewReader(""))
+	responseRecorder := httptest.NewRecorder()
+	mh.HandleGetExtensionSchemas(responseRecorder, request)
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	var schemas []repository.ExtensionSchema
+	yaml.NewDecoder(responseRecorder.Body).Decode(&schemas)
+	assert.Equal(t, 1, len(schemas))
+	assert.Equal(t, "oncall", schemas[0].Namespace)
+
+	request, _ = http.NewRequest("DELETE", "extensions/oncall", strings.NewReader(""))
+	request = mux.SetURLVars(request, map[string]string{"namespace": "oncall"})
+	request.Header.Set(AdminTokenHeader, "secret")
+	responseRecorder = httptest.NewRecorder()
+	mh.HandleDeleteExtensionSchema(responseRecorder, request)
+	assert.Equal(t, http.StatusNoContent, responseRecorder.Code)
+
+	request, _ = http.NewRequest("GET", "extensions/oncall", strings.NewReader(""))
+	request = mux.SetURLVars(request, map[string]string{"namespace": "oncall"})
+	responseRecorder = httptest.NewRecorder()
+	mh.HandleGetExtensionSchema(responseRecorder, request)
+	assert.Equal(t, http.StatusNotFound, responseRecorder.Code)
+}
+
+func TestMetadataHandler_HandlePostMetadataExtensions_ResultedValidated(t *testing.T) {
+
+	mh := NewMetadataHandler(repository.NewInMemoryMetadataRepository())
+	mh.AdminToken = "secret"
+	mh.DuplicatePolicy = DuplicatesOff
+
+	post := func(payload string) *httptest.ResponseRecorder {
+		request, _ := http.NewRequest("POST", "app-metadata", strings.NewReader(payload))
+		responseRecorder := httptest.NewRecorder()
+		mh.HandlePostMetadata(responseRecorder, request)
+		return responseRecorder
+	}
+
+	// no schema is registered yet
+	payload := createValidPayload() + "extensions:\n  oncall:\n    rotation: weekly\n    team: payments\n"
+	responseRecorder := post(payload)
+	assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+	assert.Contains(t, responseRecorder.Body.String(), "no schema is registered for extension namespace oncall")
+
+	assert.Equal(t, http.StatusCreated, putSchema(mh, "oncall", oncallSchema, "secret").Code)
+	responseRecorder = post(payload)
+	assert.Equal(t, http.StatusCreated, responseRecorder.Code)
+	var created metadata.ApplicationMetadata
+	yaml.NewDecoder(responseRecorder.Body).Decode(&created)
+	assert.Equal(t, map[string]interface{}{"rotation": "weekly", "team": "payments"}, created.Extensions["oncall"])
+
+	responseRecorder = post(strings.Replace(payload, "weekly", "monthly", 1))
+	assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+	assert.Contains(t, responseRecorder.Body.String(), "extensions.oncall.rotation: must be one of daily, weekly")
+
+	// extensions are queryable with a field selector
+	assert.Equal(t, http.StatusCreated, post(createValidPayload2()+"extensions:\n  oncall:\n    rotation: daily\n").Code)
+	request, _ := http.NewRequest("GET", "app-metadata?state=all&fieldSelector=extensions.oncall.rotation=weekly", strings.NewReader(""))
+	responseRecorder = httptest.NewRecorder()
+	mh.HandleGetAllMetadata(responseRecorder, request)
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	var res []metadata.ApplicationMetadata
+	yaml.NewDecoder(responseRecorder.Body).Decode(&res)
+	assert.Equal(t, 1, len(res))
+	assert.Equal(t, created.ApplicationID, res[0].ApplicationID)
+
+	request, _ = http.NewRequest("GET", "app-metadata?fieldSelector=maintainers=x", strings.NewReader(""))
+	responseRecorder = httptest.NewRecorder()
+	mh.HandleGetAllMetadata(responseRecorder, request)
+	assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+}
+
+func TestMetadataHandler_HandleImportMetadataExtensions_ResultedFailed(t *testing.T) {
+
+	mh := NewMetadataHandler(repository.NewInMemoryMetadataRepository())
+	mh.AdminToken = "secret"
+	assert.Equal(t, http.StatusCreated, putSchema(mh, "oncall", oncallSchema, "secret").Code)
+
+	payload := `[
+  {"title": "App 1", "version": "1.0.0", "extensions": {"oncall": {"rotation": "daily"}}},
+  {"title": "App 2", "version": "1.0.0", "extensions": {"oncall": {"team": "payments"}}}
+]`
+	request, _ := http.NewRequest("POST", "app-metadata:import", strings.NewReader(payload))
+	request.Header.Set("Content-Type", "application/json")
+	responseRecorder := httptest.NewRecorder()
+	mh.HandleImportMetadata(responseRecorder, request)
+
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	var results []ImportResult
+	yaml.NewDecoder(responseRecorder.Body).Decode(&results)
+	assert.Equal(t, importCreated, results[0].Status)
+	assert.Equal(t, importFailed, results[1].Status)
+	assert.Equal(t, "extensions.oncall: rotation is required", results[1].Error)
+}
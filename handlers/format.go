@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// payload formats supported by the bulk endpoints
+const (
+	formatYAML   = "yaml"
+	formatJSON   = "json"
+	formatNDJSON = "ndjson"
+)
+
+var mediaTypes = map[string]string{
+	formatYAML:   "application/x-yaml",
+	formatJSON:   "application/json",
+	formatNDJSON: "application/x-ndjson",
+}
+
+// formatFromMediaType maps a media type to one of the supported formats, yaml being the default
+func formatFromMediaType(mediaType string) string {
+	switch mediaType {
+	case "application/json":
+		return formatJSON
+	case "application/x-ndjson", "application/ndjson", "application/jsonl":
+		return formatNDJSON
+	}
+	return formatYAML
+}
+
+// requestFormat returns the format of the request body based on Content-Type header
+func requestFormat(r *http.Request) string {
+	mt, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return formatYAML
+	}
+	return formatFromMediaType(mt)
+}
+
+// responseFormat returns the format requested by the client, either through format query parameter or Accept header
+func responseFormat(r *http.Request) string {
+	if f := r.URL.Query().Get("format"); f != "" {
+		if _, ok := mediaTypes[f]; ok {
+			return f
+		}
+		return formatYAML
+	}
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		mt, _, err := mime.ParseMediaType(strings.TrimSpace(accept))
+		if err != nil {
+			continue
+		}
+		if f := formatFromMediaType(mt); f != formatYAML {
+			return f
+		}
+	}
+	return formatYAML
+}
+
+// encode writes v to w in the given format
+func encode(w io.Writer, format string, v interface{}) error {
+	if format == formatYAML {
+		return yaml.NewEncoder(w).Encode(v)
+	}
+	return json.NewEncoder(w).Encode(v)
+}
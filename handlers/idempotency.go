@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// IdempotencyKeyHeader is the request header carrying a client chosen key to safely retry a POST operation
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// replayedHeaders are the response headers stored with an idempotent response and replayed with it
+var replayedHeaders = []string{"Content-Type", "Location", "ETag", "Last-Modified", "Warning"}
+
+// handleIdempotent processes body with fn once per idempotency key.
+// The response of the first request is stored, and a repeat with the same key and body replays it.
+// A repeat with a different body returns 422, and a repeat while the first request is in progress returns 409.
+// Server errors aren't stored, and the key is released when fn panics, so that the request can be retried.
+func (mh *MetadataHandler) handleIdempotent(w http.ResponseWriter, r *http.Request, key string, body []byte, fn func(w http.ResponseWriter, b []byte)) {
+	// keys are scoped per client, identified like the rate limiter does, so that clients can't replay each other's
+	// responses by sending the same X-API-Key
+	scoped := identifyClient(r, mh.APIKeys, mh.TrustForwardedFor) + "\x00" + key
+	sum := sha256.Sum256(body)
+	hash := hex.EncodeToString(sum[:])
+
+	rec, err := mh.Idempotency.Reserve(scoped, hash, mh.IdempotencyTTL)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError) // 500
+		yaml.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	if rec != nil {
+		switch {
+		case rec.RequestHash != hash:
+			w.WriteHeader(http.StatusUnprocessableEntity) // 422
+			yaml.NewEncoder(w).Encode("Idempotency-Key was already used with a different request body")
+		case rec.Pending():
+			w.WriteHeader(http.StatusConflict) // 409
+			yaml.NewEncoder(w).Encode("a request with the same Idempotency-Key is in progress")
+		default:
+			for k, v := range rec.Header {
+				w.Header()[k] = append([]string(nil), v...)
+			}
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(rec.StatusCode)
+			w.Write(rec.Body)
+		}
+		return
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			// otherwise the key stays reserved and every retry returns 409 until it expires
+			mh.Idempotency.Release(scoped)
+			panic(p)
+		}
+	}()
+
+	rc := &responseCapture{ResponseWriter: w, status: http.StatusOK}
+	fn(rc, body)
+
+	if rc.status >= http.StatusInternalServerError {
+		mh.Idempotency.Release(scoped)
+		return
+	}
+	if rc.header == nil {
+		rc.captureHeader()
+	}
+	mh.Idempotency.Complete(scoped, rc.status, rc.header, rc.body.Bytes())
+}
+
+// responseCapture records the status, replayed headers and body written to a ResponseWriter
+type responseCapture struct {
+	http.ResponseWriter
+	status int
+	header http.Header
+	body   bytes.Buffer
+}
+
+// captureHeader records the replayed headers, as they're sent with the status
+func (rc *responseCapture) captureHeader() {
+	rc.header = http.Header{}
+	for _, k := range replayedHeaders {
+		if v := rc.Header().Values(k); len(v) > 0 {
+			rc.header[http.CanonicalHeaderKey(k)] = append([]string(nil), v...)
+		}
+	}
+}
+
+func (rc *responseCapture) WriteHeader(status int) {
+	if rc.header == nil {
+		rc.captureHeader()
+	}
+	rc.status = status
+	rc.ResponseWriter.WriteHeader(status)
+}
+
+func (rc *responseCapture) Write(b []byte) (int, error) {
+	if rc.header == nil {
+		rc.captureHeader()
+	}
+	rc.body.Write(b)
+	return rc.ResponseWriter.Write(b)
+}
@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/elumbantoruan/app-metadata/metadata"
+	"github.com/elumbantoruan/app-metadata/repository"
+	"github.com/stretchr/testify/assert"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestMetadataHandler_HandlePostMetadataIdempotencyKey_ResultedReplayed(t *testing.T) {
+
+	im := repository.NewInMemoryMetadataRepository()
+	mh := NewMetadataHandler(im)
+
+	post := func(payload string) *httptest.ResponseRecorder {
+		request, _ := http.NewRequest("POST", "app-metadata", strings.NewReader(payload))
+		request.Header.Set(IdempotencyKeyHeader, "key1")
+		responseRecorder := httptest.NewRecorder()
+		mh.HandlePostMetadata(responseRecorder, request)
+		return responseRecorder
+	}
+
+	first := post(createValidPayload())
+	assert.Equal(t, http.StatusCreated, first.Code)
+	second := post(createValidPayload())
+	assert.Equal(t, http.StatusCreated, second.Code)
+	assert.Equal(t, "true", second.Header().Get("Idempotent-Replayed"))
+
+	var mtd1, mtd2 metadata.ApplicationMetadata
+	yaml.NewDecoder(first.Body).Decode(&mtd1)
+	yaml.NewDecoder(second.Body).Decode(&mtd2)
+	assert.Equal(t, mtd1.ApplicationID, mtd2.ApplicationID)
+
+	all, _ := im.GetAll()
+	assert.Equal(t, 1, len(all))
+}
+
+func TestMetadataHandler_HandlePostMetadataIdempotencyKeyDifferentBody_ResultedUnprocessableEntity(t *testing.T) {
+
+	mh := NewMetadataHandler(repository.NewInMemoryMetadataRepository())
+
+	request, _ := http.NewRequest("POST", "app-metadata", strings.NewReader(createValidPayload()))
+	request.Header.Set(IdempotencyKeyHeader, "key1")
+	mh.HandlePostMetadata(httptest.NewRecorder(), request)
+
+	request, _ = http.NewRequest("POST", "app-metadata", strings.NewReader(createValidPayload2()))
+	request.Header.Set(IdempotencyKeyHeader, "key1")
+	responseRecorder := httptest.NewRecorder()
+	mh.HandlePostMetadata(responseRecorder, request)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, responseRecorder.Code) // 422
+}
+
+func TestMetadataHandler_HandlePostMetadataIdempotencyKeyServerError_ResultedRetried(t *testing.T) {
+
+	mh := NewMetadataHandler(NewFakeMetadataRepository())
+
+	request, _ := http.NewRequest("POST", "app-metadata", strings.NewReader(createValidPayload()))
+	request.Header.Set(IdempotencyKeyHeader, "key1")
+	mh.HandlePostMetadata(httptest.NewRecorder(), request)
+
+	// the failed response isn't stored, so the retry reaches the repository again
+	mh.Repository = repository.NewInMemoryMetadataRepository()
+	request, _ = http.NewRequest("POST", "app-metadata", strings.NewReader(createValidPayload()))
+	request.Header.Set(IdempotencyKeyHeader, "key1")
+	responseRecorder := httptest.NewRecorder()
+	mh.HandlePostMetadata(responseRecorder, request)
+
+	assert.Equal(t, http.StatusCreated, responseRecorder.Code)
+	assert.Equal(t, "", responseRecorder.Header().Get("Idempotent-Replayed"))
+}
+
+func TestMetadataHandler_HandlePostMetadataIdempotencyKeyInProgress_ResultedConflict(t *testing.T) {
+
+	mh := NewMetadataHandler(repository.NewInMemoryMetadataRepository())
+	sum := sha256.Sum256([]byte(createValidPayload()))
+	mh.Idempotency.Reserve("ip:\x00key1", hex.EncodeToString(sum[:]), mh.IdempotencyTTL)
+
+	request, _ := http.NewRequest("POST", "app-metadata", strings.NewReader(createValidPayload()))
+	request.Header.Set(IdempotencyKeyHeader, "key1")
+	responseRecorder := httptest.NewRecorder()
+	mh.HandlePostMetadata(responseRecorder, request)
+
+	assert.Equal(t, http.StatusConflict, responseRecorder.Code) // 409
+}
+
+func TestMetadataHandler_HandleIdempotentHeaders_ResultedReplayed(t *testing.T) {
+
+	mh := NewMetadataHandler(repository.NewInMemoryMetadataRepository())
+
+	calls := 0
+	handle := func() *httptest.ResponseRecorder {
+		request, _ := http.NewRequest("POST", "app-metadata", strings.NewReader(createValidPayload()))
+		responseRecorder := httptest.NewRecorder()
+		mh.handleIdempotent(responseRecorder, request, "key1", []byte(createValidPayload()), func(w http.ResponseWriter, b []byte) {
+			calls++
+			w.Header().Set("Location", "/app-metadata/app-id1")
+			w.Header().Set("ETag", `"1"`)
+			w.Header().Add("Warning", `199 - "possible duplicate"`)
+			w.Header().Set("X-Request-Id", "not replayed")
+			w.WriteHeader(http.StatusCreated)
+		})
+		return responseRecorder
+	}
+
+	handle()
+	replayed := handle()
+
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, http.StatusCreated, replayed.Code)
+	assert.Equal(t, "true", replayed.Header().Get("Idempotent-Replayed"))
+	assert.Equal(t, "/app-metadata/app-id1", replayed.Header().Get("Location"))
+	assert.Equal(t, `"1"`, replayed.Header().Get("ETag"))
+	assert.Equal(t, []string{`199 - "possible duplicate"`}, replayed.Header().Values("Warning"))
+	assert.Equal(t, "", replayed.Header().Get("X-Request-Id"))
+}
+
+func TestMetadataHandler_HandleIdempotentPanic_ResultedReleased(t *testing.T) {
+
+	mh := NewMetadataHandler(repository.NewInMemoryMetadataRepository())
+
+	request, _ := http.NewRequest("POST", "app-metadata", strings.NewReader(createValidPayload()))
+	assert.Panics(t, func() {
+		mh.handleIdempotent(httptest.NewRecorder(), request, "key1", []byte(createValidPayload()), func(w http.ResponseWriter, b []byte) {
+			panic("handler failed")
+		})
+	})
+
+	// the key was released, so the retry is processed instead of returning 409
+	request, _ = http.NewRequest("POST", "app-metadata", strings.NewReader(createValidPayload()))
+	request.Header.Set(IdempotencyKeyHeader, "key1")
+	responseRecorder := httptest.NewRecorder()
+	mh.HandlePostMetadata(responseRecorder, request)
+
+	assert.Equal(t, http.StatusCreated, responseRecorder.Code)
+	assert.Equal(t, "", responseRecorder.Header().Get("Idempotent-Replayed"))
+}
+
+func TestMetadataHandler_HandlePostMetadataIdempotencyKeyAPIKey_ResultedScopedPerClient(t *testing.T) {
+
+	im := repository.NewInMemoryMetadataRepository()
+	mh := NewMetadataHandler(im)
+	mh.APIKeys = map[string]bool{"client1": true, "client2": true}
+	mh.DuplicatePolicy = DuplicatesOff
+
+	post := func(apiKey, remoteAddr string) *httptest.ResponseRecorder {
+		request, _ := http.NewRequest("POST", "app-metadata", strings.NewReader(createValidPayload()))
+		request.RemoteAddr = remoteAddr
+		request.Header.Set(IdempotencyKeyHeader, "key1")
+		request.Header.Set(APIKeyHeader, apiKey)
+		responseRecorder := httptest.NewRecorder()
+		mh.HandlePostMetadata(responseRecorder, request)
+		return responseRecorder
+	}
+
+	assert.Equal(t, "", post("client1", "10.0.0.1:1234").Header().Get("Idempotent-Replayed"))
+	// a known key is the same client from any address
+	assert.Equal(t, "true", post("client1", "10.0.0.2:1234").Header().Get("Idempotent-Replayed"))
+	assert.Equal(t, "", post("client2", "10.0.0.1:1234").Header().Get("Idempotent-Replayed"))
+	// an unknown key doesn't set the client apart from the others at the same address
+	assert.Equal(t, "", post("made-up", "10.0.0.3:1234").Header().Get("Idempotent-Replayed"))
+	assert.Equal(t, "true", post("other", "10.0.0.3:1234").Header().Get("Idempotent-Replayed"))
+
+	all, _ := im.GetAll()
+	assert.Equal(t, 3, len(all))
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/elumbantoruan/app-metadata/labels"
+	"github.com/elumbantoruan/app-metadata/metadata"
+	"github.com/elumbantoruan/app-metadata/repository"
+	"github.com/stretchr/testify/assert"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestMetadataHandler_HandleGetAllMetadataLabelSelector_ResultedOK(t *testing.T) {
+
+	im := repository.NewInMemoryMetadataRepository()
+	for id, lbls := range map[string]map[string]string{
+		"appID1": {"team": "payments", "tier": "1"},
+		"appID2": {"team": "payments", "tier": "3"},
+		"appID3": {"team": "payments", "tier": "2", "deprecated": "true"},
+		"appID4": {"team": "search", "tier": "1"},
+	} {
+		var mtd metadata.ApplicationMetadata
+		yaml.Unmarshal([]byte(createValidPayload()), &mtd)
+		mtd.ApplicationID = id
+		mtd.Labels = lbls
+		im.Create(id, &mtd)
+	}
+
+	selector := url.QueryEscape("team=payments,tier in (1,2),!deprecated")
+	request, _ := http.NewRequest("GET", "app-metadata?labelSelector="+selector, strings.NewReader(""))
+	responseRecorder := httptest.NewRecorder()
+
+	mh := NewMetadataHandler(im)
+	mh.HandleGetAllMetadata(responseRecorder, request)
+
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	var mtds []metadata.ApplicationMetadata
+	yaml.NewDecoder(responseRecorder.Body).Decode(&mtds)
+	assert.Equal(t, 1, len(mtds))
+	assert.Equal(t, "appID1", mtds[0].ApplicationID)
+}
+
+func TestMetadataHandler_HandleGetAllMetadataLabelSelectorAfterUpdate_ResultedOK(t *testing.T) {
+
+	im := repository.NewInMemoryMetadataRepository()
+	var mtd metadata.ApplicationMetadata
+	yaml.Unmarshal([]byte(createValidPayload()), &mtd)
+	mtd.Labels = map[string]string{"team": "payments"}
+	im.Create("appID1", &mtd)
+
+	updated := mtd
+	updated.Labels = map[string]string{"team": "search"}
+	im.Update("appID1", &updated)
+
+	res, _ := im.List(repository.Query{Selector: mustParseSelector("team=payments")})
+	assert.Equal(t, 0, len(res))
+	res, _ = im.List(repository.Query{Selector: mustParseSelector("team=search")})
+	assert.Equal(t, 1, len(res))
+}
+
+func TestMetadataHandler_HandleGetAllMetadataInvalidLabelSelector_ResultedBadRequest(t *testing.T) {
+
+	request, _ := http.NewRequest("GET", "app-metadata?labelSelector="+url.QueryEscape("tier in (1"), strings.NewReader(""))
+	responseRecorder := httptest.NewRecorder()
+
+	mh := NewMetadataHandler(repository.NewInMemoryMetadataRepository())
+	mh.HandleGetAllMetadata(responseRecorder, request)
+
+	assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+}
+
+func TestMetadataHandler_HandlePostMetadataInvalidLabel_ResultedBadRequest(t *testing.T) {
+
+	payload := createValidPayload() + "labels:\n  team: pay ments\n"
+	request, _ := http.NewRequest("POST", "app-metadata", strings.NewReader(payload))
+	responseRecorder := httptest.NewRecorder()
+
+	mh := NewMetadataHandler(repository.NewInMemoryMetadataRepository())
+	mh.HandlePostMetadata(responseRecorder, request)
+
+	assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+}
+
+func mustParseSelector(s string) labels.Selector {
+	selector, err := labels.Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return selector
+}
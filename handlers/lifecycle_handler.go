@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/elumbantoruan/app-metadata/audit"
+	"github.com/elumbantoruan/app-metadata/lifecycle"
+	"github.com/elumbantoruan/app-metadata/metadata"
+	"github.com/elumbantoruan/app-metadata/repository"
+	"github.com/gorilla/mux"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// PrincipalHeader is the request header carrying the email of the caller, set by the authenticating proxy
+const PrincipalHeader = "X-User-Email"
+
+// TransitionRequest is the payload moving an application to another lifecycle state
+type TransitionRequest struct {
+	State string `yaml:"state" json:"state"`
+}
+
+// HandleTransitionMetadata handles POST operation moving an application to another lifecycle state.
+// Only a maintainer of the application can move it, and publishing an application in review requires a maintainer
+// other than the one who submitted it.
+func (mh *MetadataHandler) HandleTransitionMetadata(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	appID, ok := mux.Vars(r)["appID"]
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest) // 400
+		return
+	}
+	principal := r.Header.Get(PrincipalHeader)
+	if principal == "" {
+		mh.auditDenied(r, audit.Transition, appID, PrincipalHeader+" header is required")
+		w.WriteHeader(http.StatusUnauthorized) // 401
+		yaml.NewEncoder(w).Encode(PrincipalHeader + " header is required")
+		return
+	}
+	b, ok := mh.readBody(w, r)
+	if !ok {
+		return
+	}
+	var req TransitionRequest
+	if err := mh.unmarshal(b, &req); err != nil {
+		w.WriteHeader(http.StatusBadRequest) // 400
+		yaml.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	// the transition is applied to the live application under the repository lock, so that the writes in between
+	// aren't lost
+	mod, err := mh.Repository.Modify(appID, func(app *metadata.ApplicationMetadata) error {
+		return lifecycle.Transition(app, req.State, principal, time.Now())
+	})
+	if !mh.checkLockError(w, r, audit.Transition, err) {
+		return
+	}
+	if err != nil {
+		switch {
+		case err == repository.ErrIDNotFound:
+			w.WriteHeader(http.StatusNotFound) // 404
+			return
+		case err == lifecycle.ErrInvalidState:
+			w.WriteHeader(http.StatusBadRequest) // 400
+		case err == lifecycle.ErrInvalidTransition || isConflict(err):
+			w.WriteHeader(http.StatusConflict) // 409
+		case err == lifecycle.ErrNotMaintainer || err == lifecycle.ErrSelfApproval:
+			mh.auditDenied(r, audit.Transition, appID, err.Error())
+			w.WriteHeader(http.StatusForbidden) // 403
+		default:
+			w.WriteHeader(http.StatusInternalServerError) // 500
+		}
+		yaml.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	mh.auditAllowed(r, audit.Transition, appID, mod.Previous, mod.Current)
+
+	w.WriteHeader(http.StatusOK) // 200
+	yaml.NewEncoder(w).Encode(mod.Current)
+}
@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/elumbantoruan/app-metadata/lifecycle"
+	"github.com/elumbantoruan/app-metadata/metadata"
+	"github.com/elumbantoruan/app-metadata/repository"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+
+	"gopkg.in/yaml.v2"
+)
+
+func transition(mh *MetadataHandler, appID, state, principal string) *httptest.ResponseRecorder {
+	request, _ := http.NewRequest("POST", "app-metadata/"+appID+":transition", strings.NewReader("state: "+state))
+	request = mux.SetURLVars(request, map[string]string{"appID": appID})
+	if principal != "" {
+		request.Header.Set(PrincipalHeader, principal)
+	}
+	responseRecorder := httptest.NewRecorder()
+	mh.HandleTransitionMetadata(responseRecorder, request)
+	return responseRecorder
+}
+
+func createDraft(t *testing.T, mh *MetadataHandler) string {
+	request, _ := http.NewRequest("POST", "app-metadata", strings.NewReader(createValidPayload()))
+	responseRecorder := httptest.NewRecorder()
+	mh.HandlePostMetadata(responseRecorder, request)
+	assert.Equal(t, http.StatusCreated, responseRecorder.Code)
+
+	var mtd metadata.ApplicationMetadata
+	yaml.NewDecoder(responseRecorder.Body).Decode(&mtd)
+	assert.Equal(t, lifecycle.Draft, mtd.State)
+	return mtd.ApplicationID
+}
+
+func TestMetadataHandler_HandleTransitionMetadata_ResultedPublished(t *testing.T) {
+
+	im := repository.NewInMemoryMetadataRepository()
+	mh := NewMetadataHandler(im)
+	appID := createDraft(t, mh)
+
+	responseRecorder := transition(mh, appID, lifecycle.InReview, "firstmaintainer@hotmail.com")
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+
+	// the submitter can't approve their own application
+	responseRecorder = transition(mh, appID, lifecycle.Published, "firstmaintainer@hotmail.com")
+	assert.Equal(t, http.StatusForbidden, responseRecorder.Code) // 403
+
+	// neither can someone who isn't a maintainer
+	responseRecorder = transition(mh, appID, lifecycle.Published, "someone@example.com")
+	assert.Equal(t, http.StatusForbidden, responseRecorder.Code) // 403
+
+	responseRecorder = transition(mh, appID, lifecycle.Published, "secondmaitainer@gmail.com")
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+
+	res, _ := im.Get(appID)
+	assert.Equal(t, lifecycle.Published, res.State)
+	assert.Equal(t, "firstmaintainer@hotmail.com", res.Review.SubmittedBy)
+	assert.Equal(t, "secondmaitainer@gmail.com", res.Review.ApprovedBy)
+}
+
+func TestMetadataHandler_HandleTransitionMetadata_ResultedConflict(t *testing.T) {
+
+	mh := NewMetadataHandler(repository.NewInMemoryMetadataRepository())
+	appID := createDraft(t, mh)
+
+	responseRecorder := transition(mh, appID, lifecycle.Published, "secondmaitainer@gmail.com")
+	assert.Equal(t, http.StatusConflict, responseRecorder.Code) // 409
+
+	responseRecorder = transition(mh, appID, "archived", "secondmaitainer@gmail.com")
+	assert.Equal(t, http.StatusBadRequest, responseRecorder.Code) // 400
+
+	responseRecorder = transition(mh, appID, lifecycle.InReview, "")
+	assert.Equal(t, http.StatusUnauthorized, responseRecorder.Code) // 401
+}
+
+func TestMetadataHandler_HandlePutMetadata_InReviewResultedDraft(t *testing.T) {
+
+	im := repository.NewInMemoryMetadataRepository()
+	mh := NewMetadataHandler(im)
+	appID := createDraft(t, mh)
+	transition(mh, appID, lifecycle.InReview, "firstmaintainer@hotmail.com")
+
+	request, _ := http.NewRequest("PUT", "app-metadata/"+appID, strings.NewReader(createValidPayload2()))
+	request = mux.SetURLVars(request, map[string]string{"appID": appID})
+	responseRecorder := httptest.NewRecorder()
+	mh.HandlePutMetadata(responseRecorder, request)
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+
+	res, _ := im.Get(appID)
+	assert.Equal(t, lifecycle.Draft, res.State)
+	assert.Nil(t, res.Review)
+}
+
+func TestMetadataHandler_HandleGetAllMetadata_StateResultedFiltered(t *testing.T) {
+
+	im := repository.NewInMemoryMetadataRepository()
+	im.Create("appID1", &metadata.ApplicationMetadata{ApplicationID: "appID1", State: lifecycle.Draft})
+	im.Create("appID2", &metadata.ApplicationMetadata{ApplicationID: "appID2", State: lifecycle.Published})
+	im.Create("appID3", &metadata.ApplicationMetadata{ApplicationID: "appID3", State: lifecycle.Retired})
+	mh := NewMetadataHandler(im)
+
+	tests := []struct {
+		query    string
+		code     int
+		expected int
+	}{
+		{query: "", code: http.StatusOK, expected: 1},
+		{query: "?state=draft,retired", code: http.StatusOK, expected: 2},
+		{query: "?state=all", code: http.StatusOK, expected: 3},
+		{query: "?state=archived", code: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		request, _ := http.NewRequest("GET", "app-metadata"+tt.query, strings.NewReader(""))
+		responseRecorder := httptest.NewRecorder()
+		mh.HandleGetAllMetadata(responseRecorder, request)
+
+		assert.Equal(t, tt.code, responseRecorder.Code, tt.query)
+		if tt.code != http.StatusOK {
+			continue
+		}
+		var mtds []metadata.ApplicationMetadata
+		yaml.NewDecoder(responseRecorder.Body).Decode(&mtds)
+		assert.Equal(t, tt.expected, len(mtds), tt.query)
+	}
+}
+
+func TestMetadataHandler_HandleTransitionMetadataNotMaintainer_ResultedForbidden(t *testing.T) {
+
+	im := repository.NewInMemoryMetadataRepository()
+	mh := NewMetadataHandler(im)
+	appID := createDraft(t, mh)
+
+	responseRecorder := transition(mh, appID, lifecycle.InReview, "someone@example.com")
+	assert.Equal(t, http.StatusForbidden, responseRecorder.Code) // 403
+
+	res, _ := im.Get(appID)
+	assert.Equal(t, lifecycle.Draft, res.State)
+	assert.Equal(t, 1, res.Revision)
+}
+
+func TestMetadataHandler_HandlePutMetadata_PublishedResultedReviewedAgain(t *testing.T) {
+
+	im := repository.NewInMemoryMetadataRepository()
+	mh := NewMetadataHandler(im)
+	appID := createDraft(t, mh)
+	transition(mh, appID, lifecycle.InReview, "firstmaintainer@hotmail.com")
+	transition(mh, appID, lifecycle.Published, "secondmaitainer@gmail.com")
+
+	put := func(payload string) {
+		request, _ := http.NewRequest("PUT", "app-metadata/"+appID, strings.NewReader(payload))
+		request = mux.SetURLVars(request, map[string]string{"appID": appID})
+		responseRecorder := httptest.NewRecorder()
+		mh.HandlePutMetadata(responseRecorder, request)
+		assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	}
+
+	// the same content doesn't need another review
+	put(createValidPayload())
+	res, _ := im.Get(appID)
+	assert.Equal(t, lifecycle.Published, res.State)
+	assert.Equal(t, "secondmaitainer@gmail.com", res.Review.ApprovedBy)
+
+	put(createValidPayload2())
+	res, _ = im.Get(appID)
+	assert.Equal(t, lifecycle.Draft, res.State)
+	assert.Nil(t, res.Review)
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/elumbantoruan/app-metadata/metadata"
+	"github.com/elumbantoruan/app-metadata/repository"
+	"github.com/gorilla/mux"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// values of the links query parameter
+const (
+	LinksBroken = "broken"
+	LinksOK     = "ok"
+)
+
+// errLinksDisabled is returned when the links of the applications aren't checked
+var errLinksDisabled = errors.New("link checking is not enabled")
+
+// LinkReport is the status of a URL of an application
+type LinkReport struct {
+	// Field is website or source
+	Field string `yaml:"field" json:"field"`
+	URL   string `yaml:"url" json:"url"`
+	// Status is nil until the URL is checked
+	Status *repository.LinkStatus `yaml:"status,omitempty" json:"status,omitempty"`
+}
+
+// linksMatch returns the Query.Match keeping the applications with a broken URL, or with every URL checked and
+// none broken
+func (mh *MetadataHandler) linksMatch(links string) (func(data *metadata.ApplicationMetadata) bool, error) {
+	if links != LinksBroken && links != LinksOK {
+		return nil, errors.New("links must be broken or ok")
+	}
+	if mh.Links == nil {
+		return nil, errLinksDisabled
+	}
+	checked, err := mh.Links.ListLinks()
+	if err != nil {
+		return nil, err
+	}
+	broken := make(map[string]bool, len(checked))
+	for _, s := range checked {
+		broken[s.URL] = s.Broken
+	}
+	return func(data *metadata.ApplicationMetadata) bool {
+		anyBroken, allChecked := false, true
+		for _, u := range []string{data.Website, data.Source} {
+			if u == "" {
+				continue
+			}
+			b, ok := broken[u]
+			anyBroken = anyBroken || b
+			allChecked = allChecked && ok
+		}
+		if links == LinksBroken {
+			return anyBroken
+		}
+		return allChecked && !anyBroken
+	}, nil
+}
+
+// HandleGetLinks handles GET operation returning the latest status of the website and source URLs of an application
+func (mh *MetadataHandler) HandleGetLinks(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	if mh.Links == nil {
+		w.WriteHeader(http.StatusNotImplemented) // 501
+		yaml.NewEncoder(w).Encode(errLinksDisabled.Error())
+		return
+	}
+	appID, ok := mux.Vars(r)["appID"]
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest) // 400
+		return
+	}
+	app, err := mh.Repository.Get(appID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError) // 500
+		yaml.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	if app == nil {
+		w.WriteHeader(http.StatusNotFound) // 404
+		return
+	}
+
+	res := []LinkReport{}
+	for _, f := range []struct{ field, url string }{{"website", app.Website}, {"source", app.Source}} {
+		if f.url == "" {
+			continue
+		}
+		status, err := mh.Links.GetLink(f.url)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError) // 500
+			yaml.NewEncoder(w).Encode(err.Error())
+			return
+		}
+		res = append(res, LinkReport{Field: f.field, URL: f.url, Status: status})
+	}
+	w.WriteHeader(http.StatusOK) // 200
+	encode(w, responseFormat(r), res)
+}
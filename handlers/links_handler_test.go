@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/elumbantoruan/app-metadata/metadata"
+	"github.com/elumbantoruan/app-metadata/repository"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestMetadataHandler_HandleGetAllMetadataLinks_ResultedFiltered(t *testing.T) {
+
+	im := repository.NewInMemoryMetadataRepository()
+	im.Create("broken", &metadata.ApplicationMetadata{ApplicationID: "broken", Website: "https://ok.example.com", Source: "https://gone.example.com"})
+	im.Create("ok", &metadata.ApplicationMetadata{ApplicationID: "ok", Website: "https://ok.example.com"})
+	im.Create("unchecked", &metadata.ApplicationMetadata{ApplicationID: "unchecked", Website: "https://new.example.com"})
+	mh := NewMetadataHandler(im)
+
+	list := func(links string) *httptest.ResponseRecorder {
+		request, _ := http.NewRequest("GET", "/app-metadata?links="+links, strings.NewReader(""))
+		responseRecorder := httptest.NewRecorder()
+		mh.HandleGetAllMetadata(responseRecorder, request)
+		return responseRecorder
+	}
+	assert.Equal(t, http.StatusNotImplemented, list("broken").Code)
+
+	mh.Links = repository.NewInMemoryLinkStore()
+	mh.Links.PutLink(&repository.LinkStatus{URL: "https://ok.example.com", StatusCode: 200})
+	mh.Links.PutLink(&repository.LinkStatus{URL: "https://gone.example.com", StatusCode: 404, Broken: true})
+
+	assert.Equal(t, http.StatusBadRequest, list("dead").Code)
+
+	for links, expected := range map[string]string{"broken": "broken", "ok": "ok"} {
+		responseRecorder := list(links)
+		assert.Equal(t, http.StatusOK, responseRecorder.Code)
+		var res []metadata.ApplicationMetadata
+		yaml.Unmarshal(responseRecorder.Body.Bytes(), &res)
+		assert.Len(t, res, 1, links)
+		assert.Equal(t, expected, res[0].ApplicationID)
+	}
+}
+
+func TestMetadataHandler_HandleGetLinks_ResultedReport(t *testing.T) {
+
+	im := repository.NewInMemoryMetadataRepository()
+	im.Create("app", &metadata.ApplicationMetadata{ApplicationID: "app", Website: "https://ok.example.com", Source: "https://gone.example.com"})
+	mh := NewMetadataHandler(im)
+	mh.Links = repository.NewInMemoryLinkStore()
+	mh.Links.PutLink(&repository.LinkStatus{URL: "https://gone.example.com", StatusCode: 404, Broken: true})
+
+	get := func(appID string) *httptest.ResponseRecorder {
+		request, _ := http.NewRequest("GET", "/app-metadata/"+appID+"/links", strings.NewReader(""))
+		request = mux.SetURLVars(request, map[string]string{"appID": appID})
+		responseRecorder := httptest.NewRecorder()
+		mh.HandleGetLinks(responseRecorder, request)
+		return responseRecorder
+	}
+
+	assert.Equal(t, http.StatusNotFound, get("missing").Code)
+
+	responseRecorder := get("app")
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	var res []LinkReport
+	yaml.Unmarshal(responseRecorder.Body.Bytes(), &res)
+	assert.Len(t, res, 2)
+	assert.Equal(t, "website", res[0].Field)
+	// not checked yet
+	assert.Nil(t, res[0].Status)
+	assert.Equal(t, "source", res[1].Field)
+	assert.Equal(t, 404, res[1].Status.StatusCode)
+	assert.True(t, res[1].Status.Broken)
+}
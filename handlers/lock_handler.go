@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/elumbantoruan/app-metadata/audit"
+	"github.com/elumbantoruan/app-metadata/metadata"
+	"github.com/elumbantoruan/app-metadata/repository"
+	"github.com/gorilla/mux"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// LockRequest is the payload of a lock, with the reason shown to those whose edits are refused
+type LockRequest struct {
+	Reason string `yaml:"reason,omitempty" json:"reason,omitempty"`
+}
+
+// HandleLockMetadata handles the admin POST operation freezing an application against edits until it's unlocked
+func (mh *MetadataHandler) HandleLockMetadata(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	appID, ok := mux.Vars(r)["appID"]
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest) // 400
+		return
+	}
+	if !mh.requireAdmin(w, r) {
+		mh.auditDenied(r, audit.Lock, appID, "admin token is required")
+		return
+	}
+	b, ok := mh.readBody(w, r)
+	if !ok {
+		return
+	}
+	var req LockRequest
+	if err := mh.unmarshal(b, &req); err != nil {
+		w.WriteHeader(http.StatusBadRequest) // 400
+		yaml.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	mh.setLock(w, r, audit.Lock, appID, func(existing *metadata.ApplicationMetadata) (*metadata.Lock, string) {
+		if existing.Lock != nil {
+			return nil, "application is already locked"
+		}
+		return &metadata.Lock{
+			LockedBy: r.Header.Get(PrincipalHeader),
+			LockedAt: time.Now().UTC().Truncate(time.Second),
+			Reason:   req.Reason,
+		}, ""
+	})
+}
+
+// HandleUnlockMetadata handles the admin POST operation unlocking an application
+func (mh *MetadataHandler) HandleUnlockMetadata(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	appID, ok := mux.Vars(r)["appID"]
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest) // 400
+		return
+	}
+	if !mh.requireAdmin(w, r) {
+		mh.auditDenied(r, audit.Unlock, appID, "admin token is required")
+		return
+	}
+
+	mh.setLock(w, r, audit.Unlock, appID, func(existing *metadata.ApplicationMetadata) (*metadata.Lock, string) {
+		if existing.Lock == nil {
+			return nil, "application isn't locked"
+		}
+		return nil, ""
+	})
+}
+
+// setLock replaces the lock of appID with the one returned by fn, or writes 409 with the conflict fn returns
+func (mh *MetadataHandler) setLock(w http.ResponseWriter, r *http.Request, action, appID string, fn func(existing *metadata.ApplicationMetadata) (*metadata.Lock, string)) {
+	existing, err := mh.Repository.Get(appID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError) // 500
+		yaml.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	if existing == nil {
+		w.WriteHeader(http.StatusNotFound) // 404
+		return
+	}
+	lock, conflict := fn(existing)
+	if conflict != "" {
+		w.WriteHeader(http.StatusConflict) // 409
+		yaml.NewEncoder(w).Encode(conflict)
+		return
+	}
+
+	app, err := mh.Repository.SetLock(appID, lock)
+	if err != nil {
+		switch {
+		case err == repository.ErrIDNotFound:
+			w.WriteHeader(http.StatusConflict) // 409
+		case lockError(err) != nil:
+			// locked by another admin in between
+			w.WriteHeader(http.StatusConflict) // 409
+			err = errors.New("application is already locked")
+		default:
+			w.WriteHeader(http.StatusInternalServerError) // 500
+		}
+		yaml.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	mh.auditAllowed(r, action, appID, existing, app)
+
+	w.WriteHeader(http.StatusOK) // 200
+	yaml.NewEncoder(w).Encode(app)
+}
+
+// checkUnlocked writes 423 and returns false when the application is locked, recording the denied action
+func (mh *MetadataHandler) checkUnlocked(w http.ResponseWriter, r *http.Request, action, appID string, app *metadata.ApplicationMetadata) bool {
+	if app == nil || app.Lock == nil {
+		return true
+	}
+	mh.refuseLocked(w, r, action, appID, app.Lock)
+	return false
+}
+
+// checkLockError writes 423 and returns false when err is the LockedError of a write the repository refused,
+// such as when the application was locked after the handler read it
+func (mh *MetadataHandler) checkLockError(w http.ResponseWriter, r *http.Request, action string, err error) bool {
+	le := lockError(err)
+	if le == nil {
+		return true
+	}
+	mh.refuseLocked(w, r, action, le.ApplicationID, &le.Lock)
+	return false
+}
+
+// refuseLocked writes 423 with the reason of the lock, recording the denied action
+func (mh *MetadataHandler) refuseLocked(w http.ResponseWriter, r *http.Request, action, appID string, lock *metadata.Lock) {
+	msg := lockedMessage(lock)
+	mh.auditDenied(r, action, appID, msg)
+	w.WriteHeader(http.StatusLocked) // 423
+	yaml.NewEncoder(w).Encode(msg)
+}
+
+// lockError returns the LockedError in err, or nil
+func lockError(err error) *repository.LockedError {
+	var le *repository.LockedError
+	if errors.As(err, &le) {
+		return le
+	}
+	return nil
+}
+
+// lockedMessage describes why the edits of a locked application are refused
+func lockedMessage(lock *metadata.Lock) string {
+	msg := "application is locked until an admin unlocks it"
+	if lock.Reason != "" {
+		msg += ": " + lock.Reason
+	}
+	return msg
+}
@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/elumbantoruan/app-metadata/audit"
+	"github.com/elumbantoruan/app-metadata/metadata"
+	"github.com/elumbantoruan/app-metadata/repository"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+func lockRequest(mh *MetadataHandler, handler http.HandlerFunc, appID, body, token string) *httptest.ResponseRecorder {
+	request, _ := http.NewRequest("POST", "/app-metadata/"+appID+":lock", strings.NewReader(body))
+	request = mux.SetURLVars(request, map[string]string{"appID": appID})
+	request.Header.Set(PrincipalHeader, "admin@example.com")
+	if token != "" {
+		request.Header.Set(AdminTokenHeader, token)
+	}
+	responseRecorder := httptest.NewRecorder()
+	handler(responseRecorder, request)
+	return responseRecorder
+}
+
+func TestMetadataHandler_HandleLockMetadata_ResultedLocked(t *testing.T) {
+
+	im := repository.NewInMemoryMetadataRepository()
+	var mtd metadata.ApplicationMetadata
+	yaml.Unmarshal([]byte(createValidPayload()), &mtd)
+	mtd.ApplicationID = "app"
+	im.Create("app", &mtd)
+	mh := NewMetadataHandler(im)
+	mh.AdminToken = "secret"
+	mh.Audit = audit.NewLog(nil, nil)
+
+	assert.Equal(t, http.StatusForbidden, lockRequest(mh, mh.HandleLockMetadata, "app", "", "").Code)
+	assert.Equal(t, http.StatusNotFound, lockRequest(mh, mh.HandleLockMetadata, "missing", "", "secret").Code)
+	assert.Equal(t, http.StatusConflict, lockRequest(mh, mh.HandleUnlockMetadata, "app", "", "secret").Code)
+
+	responseRecorder := lockRequest(mh, mh.HandleLockMetadata, "app", "reason: migrating to the new schema", "secret")
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	app, _ := im.Get("app")
+	assert.Equal(t, "admin@example.com", app.Lock.LockedBy)
+	assert.Equal(t, "migrating to the new schema", app.Lock.Reason)
+	assert.False(t, app.Lock.LockedAt.IsZero())
+
+	// every edit of the application is refused
+	refused := map[string]*httptest.ResponseRecorder{}
+	request, _ := http.NewRequest("PUT", "/app-metadata/app", strings.NewReader(createValidPayload()))
+	request = mux.SetURLVars(request, map[string]string{"appID": "app"})
+	refused["update"] = httptest.NewRecorder()
+	mh.HandlePutMetadata(refused["update"], request)
+
+	request, _ = http.NewRequest("DELETE", "/app-metadata/app", strings.NewReader(""))
+	request = mux.SetURLVars(request, map[string]string{"appID": "app"})
+	refused["delete"] = httptest.NewRecorder()
+	mh.HandleDeleteMetadata(refused["delete"], request)
+
+	request, _ = http.NewRequest("POST", "/app-metadata/app:transition", strings.NewReader("state: in-review"))
+	request = mux.SetURLVars(request, map[string]string{"appID": "app"})
+	request.Header.Set(PrincipalHeader, "firstmaintainer@hotmail.com")
+	refused["transition"] = httptest.NewRecorder()
+	mh.HandleTransitionMetadata(refused["transition"], request)
+
+	request, _ = http.NewRequest("POST", "/app-metadata/app:rename", strings.NewReader("applicationID: renamed"))
+	request = mux.SetURLVars(request, map[string]string{"appID": "app"})
+	refused["rename"] = httptest.NewRecorder()
+	mh.HandleRenameMetadata(refused["rename"], request)
+
+	refused["release"] = postRelease(mh, "app", "version: 2.0.0")
+
+	request, _ = http.NewRequest("POST", "/app-metadata/app:purge", strings.NewReader(""))
+	request = mux.SetURLVars(request, map[string]string{"appID": "app"})
+	request.Header.Set(AdminTokenHeader, "secret")
+	refused["purge"] = httptest.NewRecorder()
+	mh.HandlePurgeMetadata(refused["purge"], request)
+
+	for action, responseRecorder := range refused {
+		assert.Equal(t, http.StatusLocked, responseRecorder.Code, action)
+		assert.Contains(t, responseRecorder.Body.String(), "application is locked until an admin unlocks it: migrating to the new schema", action)
+	}
+	// the lock without admin token is denied as well
+	denied := 0
+	for _, e := range mh.Audit.Query(audit.Filter{ApplicationID: "app"}) {
+		if e.Outcome == audit.Denied {
+			denied++
+		}
+	}
+	assert.Equal(t, len(refused)+1, denied)
+
+	updated := mtd
+	updated.Company = "updated company"
+	b, _ := yaml.Marshal(updated)
+	request, _ = http.NewRequest("POST", "app-metadata:import?upsert=true", strings.NewReader(string(b)))
+	responseRecorder = httptest.NewRecorder()
+	mh.HandleImportMetadata(responseRecorder, request)
+	var results []ImportResult
+	yaml.Unmarshal(responseRecorder.Body.Bytes(), &results)
+	assert.Equal(t, importFailed, results[0].Status)
+	assert.Equal(t, "application is locked until an admin unlocks it: migrating to the new schema", results[0].Error)
+
+	app, _ = im.Get("app")
+	assert.Equal(t, "pellucid Computing", app.Company)
+	assert.Equal(t, 2, app.Revision)
+
+	assert.Equal(t, http.StatusOK, lockRequest(mh, mh.HandleUnlockMetadata, "app", "", "secret").Code)
+	app, _ = im.Get("app")
+	assert.Nil(t, app.Lock)
+	assert.Equal(t, http.StatusCreated, postRelease(mh, "app", "version: 2.0.0").Code)
+}
+
+func TestMetadataHandler_HandleLockMetadataStaleWriters_ResultedLockKept(t *testing.T) {
+
+	im := repository.NewInMemoryMetadataRepository()
+	var mtd metadata.ApplicationMetadata
+	yaml.Unmarshal([]byte(createValidPayload()), &mtd)
+	mtd.ApplicationID = "app"
+	im.Create("app", &mtd)
+	mh := NewMetadataHandler(im)
+	mh.AdminToken = "secret"
+
+	im.AddAlias("app", "old")
+	// a writer reading the application before it's locked can't remove the lock
+	stale, _ := im.Get("app")
+	assert.Equal(t, http.StatusOK, lockRequest(mh, mh.HandleLockMetadata, "app", "reason: audit", "secret").Code)
+	updated := *stale
+	updated.Title = "stale title"
+	err := im.Update("app", &updated)
+	assert.IsType(t, &repository.LockedError{}, err)
+	app, _ := im.Get("app")
+	assert.NotNil(t, app.Lock)
+	assert.Equal(t, "Valid App 1", app.Title)
+
+	refused := map[string]*httptest.ResponseRecorder{}
+	request, _ := http.NewRequest("PUT", "/app-metadata/app/aliases/alias", strings.NewReader(""))
+	request = mux.SetURLVars(request, map[string]string{"appID": "app", "alias": "alias"})
+	refused["add alias"] = httptest.NewRecorder()
+	mh.HandlePutAlias(refused["add alias"], request)
+
+	request, _ = http.NewRequest("DELETE", "/app-metadata/app/aliases/old", strings.NewReader(""))
+	request = mux.SetURLVars(request, map[string]string{"appID": "app", "alias": "old"})
+	refused["remove alias"] = httptest.NewRecorder()
+	mh.HandleDeleteAlias(refused["remove alias"], request)
+
+	request, _ = http.NewRequest("PUT", "/maintainers/firstmaintainer@hotmail.com", strings.NewReader("name: Renamed"))
+	request = mux.SetURLVars(request, map[string]string{"email": "firstmaintainer@hotmail.com"})
+	request.Header.Set(PrincipalHeader, "firstmaintainer@hotmail.com")
+	refused["maintainer"] = httptest.NewRecorder()
+	mh.HandlePutMaintainer(refused["maintainer"], request)
+
+	for action, responseRecorder := range refused {
+		assert.Equal(t, http.StatusLocked, responseRecorder.Code, action)
+		assert.Contains(t, responseRecorder.Body.String(), "application is locked until an admin unlocks it: audit", action)
+	}
+	app, _ = im.Get("app")
+	assert.Equal(t, mtd.Maintainers[0].Name, app.Maintainers[0].Name)
+	aliases, _ := im.Aliases("app")
+	assert.Equal(t, []string{"old"}, aliases)
+}
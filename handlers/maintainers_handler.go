@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/elumbantoruan/app-metadata/audit"
+	"github.com/elumbantoruan/app-metadata/lifecycle"
+	"github.com/elumbantoruan/app-metadata/metadata"
+	"github.com/elumbantoruan/app-metadata/repository"
+	"github.com/gorilla/mux"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// MaintainerUpdate is the body of a PUT operation on a maintainer
+type MaintainerUpdate struct {
+	Name string `yaml:"name" json:"name"`
+}
+
+// HandleGetMaintainers handles GET operation returning every maintainer of the live applications
+func (mh *MetadataHandler) HandleGetMaintainers(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	res, err := mh.Repository.Maintainers()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError) // 500
+		yaml.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusOK) // 200
+	encode(w, responseFormat(r), res)
+}
+
+// HandleGetMaintainer handles GET operation returning the maintainer with a given email
+func (mh *MetadataHandler) HandleGetMaintainer(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	email, ok := mux.Vars(r)["email"]
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest) // 400
+		return
+	}
+	res, err := mh.Repository.GetMaintainer(email)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError) // 500
+		yaml.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	if res == nil {
+		w.WriteHeader(http.StatusNotFound) // 404
+		return
+	}
+	w.WriteHeader(http.StatusOK) // 200
+	encode(w, responseFormat(r), res)
+}
+
+// HandleGetMaintainerApplications handles GET operation returning the applications listing a given maintainer
+func (mh *MetadataHandler) HandleGetMaintainerApplications(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	email, ok := mux.Vars(r)["email"]
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest) // 400
+		return
+	}
+	res, err := mh.Repository.MaintainerApplications(email)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError) // 500
+		yaml.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	if len(res) == 0 {
+		w.WriteHeader(http.StatusNotFound) // 404
+		return
+	}
+	w.WriteHeader(http.StatusOK) // 200
+	encode(w, responseFormat(r), res)
+}
+
+// HandlePutMaintainer handles PUT operation changing the name of a maintainer in every application listing them.
+// Only the maintainer (X-User-Email header) or an admin can change it, see updateMaintainer.
+func (mh *MetadataHandler) HandlePutMaintainer(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	email, ok := mux.Vars(r)["email"]
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest) // 400
+		return
+	}
+	email = metadata.NormalizeEmail(email)
+	principal := r.Header.Get(PrincipalHeader)
+	if !mh.isAdmin(r) {
+		if principal == "" {
+			w.WriteHeader(http.StatusUnauthorized) // 401
+			yaml.NewEncoder(w).Encode(PrincipalHeader + " header is required")
+			return
+		}
+		if metadata.NormalizeEmail(principal) != email {
+			w.WriteHeader(http.StatusForbidden) // 403
+			yaml.NewEncoder(w).Encode("only the maintainer or an admin can change their name")
+			return
+		}
+	}
+
+	b, ok := mh.readBody(w, r)
+	if !ok {
+		return
+	}
+	var req MaintainerUpdate
+	if err := mh.unmarshal(b, &req); err != nil {
+		w.WriteHeader(http.StatusBadRequest) // 400
+		yaml.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	req.Name = metadata.NormalizeText(req.Name)
+	if req.Name == "" {
+		w.WriteHeader(http.StatusBadRequest) // 400
+		yaml.NewEncoder(w).Encode(metadata.ValidationMessage{Description: "maintainer's name is empty"})
+		return
+	}
+
+	found, err := mh.updateMaintainer(r, email, true, func(m *metadata.Maintainer) bool {
+		if m.Name == req.Name {
+			return false
+		}
+		m.Name = req.Name
+		return true
+	})
+	if !mh.checkLockError(w, r, audit.Update, err) {
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError) // 500
+		yaml.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	if !found {
+		w.WriteHeader(http.StatusNotFound) // 404
+		return
+	}
+
+	res, err := mh.Repository.GetMaintainer(email)
+	if err != nil || res == nil {
+		w.WriteHeader(http.StatusInternalServerError) // 500
+		return
+	}
+	w.WriteHeader(http.StatusOK) // 200
+	encode(w, responseFormat(r), res)
+}
+
+// updateMaintainer applies fn to the maintainer with a normalized email in every live application listing them, fn
+// returning true when it changed the maintainer.  Each application changed gets a new revision and an audit entry.
+// An edit of the content, as opposed to a field managed by the service, follows the lifecycle like PUT does: retired
+// applications are left as they are, and the others go back to draft to be reviewed again.  The applications are changed atomically
+// by the repository, and it returns false when none lists the maintainer.
+func (mh *MetadataHandler) updateMaintainer(r *http.Request, email string, edit bool, fn func(m *metadata.Maintainer) bool) (bool, error) {
+	mods, err := mh.Repository.UpdateMaintainer(email, func(app *metadata.ApplicationMetadata) bool {
+		if edit && app.State == lifecycle.Retired {
+			return false
+		}
+		previous := app.DeepCopy()
+		changed := false
+		for i := range app.Maintainers {
+			if metadata.NormalizeEmail(app.Maintainers[i].Email) == email && fn(&app.Maintainers[i]) {
+				changed = true
+			}
+		}
+		if changed && edit {
+			lifecycle.Edit(app, previous)
+		}
+		return changed
+	})
+	if err == repository.ErrIDNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return true, err
+	}
+	for _, m := range mods {
+		mh.auditAllowed(r, audit.Update, m.Current.ApplicationID, m.Previous, m.Current)
+	}
+	return true, nil
+}
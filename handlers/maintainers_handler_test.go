@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/elumbantoruan/app-metadata/lifecycle"
+	"github.com/elumbantoruan/app-metadata/metadata"
+	"github.com/elumbantoruan/app-metadata/repository"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestMetadataHandler_HandleGetMaintainers_ResultedDerived(t *testing.T) {
+
+	im := repository.NewInMemoryMetadataRepository()
+	im.Create("app1", &metadata.ApplicationMetadata{ApplicationID: "app1", Maintainers: []metadata.Maintainer{
+		{Name: "First Maintainer", Email: "first@example.com"},
+		{Name: "Second Maintainer", Email: "second@example.com"},
+	}})
+	im.Create("app2", &metadata.ApplicationMetadata{ApplicationID: "app2", Maintainers: []metadata.Maintainer{
+		{Name: "First Maintainer", Email: "First@Example.com"},
+	}})
+	mh := NewMetadataHandler(im)
+
+	request, _ := http.NewRequest("GET", "maintainers", strings.NewReader(""))
+	responseRecorder := httptest.NewRecorder()
+	mh.HandleGetMaintainers(responseRecorder, request)
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	var maintainers []repository.Maintainer
+	yaml.NewDecoder(responseRecorder.Body).Decode(&maintainers)
+	assert.Equal(t, []repository.Maintainer{
+		{Email: "first@example.com", Name: "First Maintainer", Applications: []string{"app1", "app2"}},
+		{Email: "second@example.com", Name: "Second Maintainer", Applications: []string{"app1"}},
+	}, maintainers)
+
+	getApps := func(email string) *httptest.ResponseRecorder {
+		request, _ := http.NewRequest("GET", "maintainers/"+email+"/apps", strings.NewReader(""))
+		request = mux.SetURLVars(request, map[string]string{"email": email})
+		responseRecorder := httptest.NewRecorder()
+		mh.HandleGetMaintainerApplications(responseRecorder, request)
+		return responseRecorder
+	}
+
+	responseRecorder = getApps("FIRST@example.com")
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	var apps []metadata.ApplicationMetadata
+	yaml.NewDecoder(responseRecorder.Body).Decode(&apps)
+	assert.Equal(t, 2, len(apps))
+
+	// the index follows the deletions
+	im.Delete("app1")
+	assert.Equal(t, http.StatusNotFound, getApps("second@example.com").Code)
+
+	request, _ = http.NewRequest("GET", "maintainers/second@example.com", strings.NewReader(""))
+	request = mux.SetURLVars(request, map[string]string{"email": "second@example.com"})
+	responseRecorder = httptest.NewRecorder()
+	mh.HandleGetMaintainer(responseRecorder, request)
+	assert.Equal(t, http.StatusNotFound, responseRecorder.Code)
+}
+
+func TestMetadataHandler_HandlePutMaintainer_ResultedPropagated(t *testing.T) {
+
+	im := repository.NewInMemoryMetadataRepository()
+	im.Create("app1", &metadata.ApplicationMetadata{ApplicationID: "app1", Maintainers: []metadata.Maintainer{
+		{Name: "First Maintainer", Email: "first@example.com"},
+		{Name: "Second Maintainer", Email: "second@example.com"},
+	}})
+	im.Create("app2", &metadata.ApplicationMetadata{ApplicationID: "app2", Maintainers: []metadata.Maintainer{
+		{Name: "F. Maintainer", Email: "First@Example.com"},
+	}})
+	mh := NewMetadataHandler(im)
+
+	put := func(principal string) *httptest.ResponseRecorder {
+		request, _ := http.NewRequest("PUT", "maintainers/first@example.com", strings.NewReader("name: First M. Maintainer"))
+		request = mux.SetURLVars(request, map[string]string{"email": "first@example.com"})
+		if principal != "" {
+			request.Header.Set(PrincipalHeader, principal)
+		}
+		responseRecorder := httptest.NewRecorder()
+		mh.HandlePutMaintainer(responseRecorder, request)
+		return responseRecorder
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, put("").Code)
+	assert.Equal(t, http.StatusForbidden, put("second@example.com").Code)
+
+	responseRecorder := put("FIRST@example.com")
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	var maintainer repository.Maintainer
+	yaml.NewDecoder(responseRecorder.Body).Decode(&maintainer)
+	assert.Equal(t, "First M. Maintainer", maintainer.Name)
+
+	app1, _ := im.Get("app1")
+	assert.Equal(t, "First M. Maintainer", app1.Maintainers[0].Name)
+	assert.Equal(t, "Second Maintainer", app1.Maintainers[1].Name)
+	assert.Equal(t, 2, app1.Revision)
+	app2, _ := im.Get("app2")
+	assert.Equal(t, "First M. Maintainer", app2.Maintainers[0].Name)
+	assert.Equal(t, "First@Example.com", app2.Maintainers[0].Email)
+
+	// the previous revision is untouched
+	previous, _ := im.GetRevision("app2", 1)
+	assert.Equal(t, "F. Maintainer", previous.Maintainers[0].Name)
+}
+
+func TestMetadataHandler_HandlePutMaintainerLifecycle_ResultedAtomic(t *testing.T) {
+
+	im := repository.NewInMemoryMetadataRepository()
+	for id, state := range map[string]string{"review": lifecycle.InReview, "retired": lifecycle.Retired, "published": lifecycle.Published} {
+		im.Create(id, &metadata.ApplicationMetadata{ApplicationID: id, State: state, Review: &metadata.Review{SubmittedBy: "second@example.com"},
+			Maintainers: []metadata.Maintainer{{Name: "First Maintainer", Email: "first@example.com"}}})
+	}
+	im.SetLock("published", &metadata.Lock{LockedBy: "admin@example.com"})
+	mh := NewMetadataHandler(im)
+
+	put := func() *httptest.ResponseRecorder {
+		request, _ := http.NewRequest("PUT", "maintainers/first@example.com", strings.NewReader("name: First M. Maintainer"))
+		request = mux.SetURLVars(request, map[string]string{"email": "first@example.com"})
+		request.Header.Set(PrincipalHeader, "first@example.com")
+		responseRecorder := httptest.NewRecorder()
+		mh.HandlePutMaintainer(responseRecorder, request)
+		return responseRecorder
+	}
+
+	// one locked application leaves every application unchanged
+	assert.Equal(t, http.StatusLocked, put().Code)
+	app, _ := im.Get("review")
+	assert.Equal(t, "First Maintainer", app.Maintainers[0].Name)
+	assert.Equal(t, 1, app.Revision)
+
+	im.SetLock("published", nil)
+	assert.Equal(t, http.StatusOK, put().Code)
+	// the reviewed content changed, so it has to be submitted again
+	app, _ = im.Get("review")
+	assert.Equal(t, "First M. Maintainer", app.Maintainers[0].Name)
+	assert.Equal(t, lifecycle.Draft, app.State)
+	assert.Nil(t, app.Review)
+	app, _ = im.Get("retired")
+	assert.Equal(t, "First Maintainer", app.Maintainers[0].Name)
+	// and so does the published one before it's listed again
+	app, _ = im.Get("published")
+	assert.Equal(t, "First M. Maintainer", app.Maintainers[0].Name)
+	assert.Equal(t, lifecycle.Draft, app.State)
+}
+
+func TestMetadataHandler_HandlePostMetadataInternationalEmail_ResultedNormalized(t *testing.T) {
+
+	mh := NewMetadataHandler(repository.NewInMemoryMetadataRepository())
+	mh.DuplicatePolicy = DuplicatesOff
+
+	post := func(payload string) *httptest.ResponseRecorder {
+		request, _ := http.NewRequest("POST", "app-metadata", strings.NewReader(payload))
+		responseRecorder := httptest.NewRecorder()
+		mh.HandlePostMetadata(responseRecorder, request)
+		return responseRecorder
+	}
+	// the name and the title are decomposed, e followed by a combining acute accent
+	payload := strings.Replace(createValidPayload(), "firstmaintainer@hotmail.com", "Jose\u0301@Bu\u0308cher.de", 1)
+	payload = strings.Replace(payload, "First Maintainer App1", "Jose\u0301 Mu\u0308ller", 1)
+	payload = strings.Replace(payload, "title: Valid App 1", "title: Cafe\u0301", 1)
+
+	responseRecorder := post(payload)
+	assert.Equal(t, http.StatusCreated, responseRecorder.Code)
+	var created metadata.ApplicationMetadata
+	yaml.NewDecoder(responseRecorder.Body).Decode(&created)
+	assert.Equal(t, "Café", created.Title)
+	assert.Equal(t, "José Müller", created.Maintainers[0].Name)
+	assert.Equal(t, "José@xn--bcher-kva.de", created.Maintainers[0].Email)
+
+	// lookups accept either form of the domain, in any case
+	for _, email := range []string{"josé@bücher.de", "JOSÉ@XN--BCHER-KVA.DE"} {
+		request, _ := http.NewRequest("GET", "maintainers/"+email, strings.NewReader(""))
+		request = mux.SetURLVars(request, map[string]string{"email": email})
+		responseRecorder = httptest.NewRecorder()
+		mh.HandleGetMaintainer(responseRecorder, request)
+		assert.Equal(t, http.StatusOK, responseRecorder.Code)
+		var maintainer repository.Maintainer
+		yaml.NewDecoder(responseRecorder.Body).Decode(&maintainer)
+		assert.Equal(t, "josé@xn--bcher-kva.de", maintainer.Email)
+	}
+
+	// the same maintainer can't be listed twice
+	responseRecorder = post(strings.Replace(payload, "secondmaitainer@gmail.com", "JOSÉ@xn--bcher-kva.de", 1))
+	assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+	assert.Contains(t, responseRecorder.Body.String(), "maintainer JOSÉ@xn--bcher-kva.de is listed more than once")
+
+	for _, email := range []string{"jo sé@bücher.de", "josé@bü_cher.de", "@bücher.de", "josé@"} {
+		responseRecorder = post(strings.Replace(payload, "Jose\u0301@Bu\u0308cher.de", email, 1))
+		assert.Equal(t, http.StatusBadRequest, responseRecorder.Code, email)
+	}
+}
@@ -1,117 +1,334 @@
 package handlers
 
 import (
+	"encoding/base64"
+	"errors"
 	"io/ioutil"
 	"net/http"
+	"strconv"
+	"time"
 
+	"github.com/elumbantoruan/app-metadata/audit"
+	"github.com/elumbantoruan/app-metadata/emailpolicy"
+	"github.com/elumbantoruan/app-metadata/events"
+	"github.com/elumbantoruan/app-metadata/labels"
+	"github.com/elumbantoruan/app-metadata/lifecycle"
 	"github.com/elumbantoruan/app-metadata/metadata"
 	"github.com/elumbantoruan/app-metadata/repository"
+	"github.com/elumbantoruan/app-metadata/strictyaml"
+	"github.com/elumbantoruan/app-metadata/verification"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 
 	yaml "gopkg.in/yaml.v2"
 )
 
+// default request body limits
+const (
+	DefaultMaxBodySize   = 1 << 20  // 1 MiB
+	DefaultMaxImportSize = 32 << 20 // 32 MiB
+)
+
+// DefaultIdempotencyTTL is how long a response is replayed for the same Idempotency-Key by default
+const DefaultIdempotencyTTL = 24 * time.Hour
+
 // MetadataHandler handles app-metadata resource
 type MetadataHandler struct {
 	Repository repository.MetadataRepository
+	// MaxBodySize is the maximum size in bytes of a single document request body
+	MaxBodySize int64
+	// MaxImportSize is the maximum size in bytes of an import request body
+	MaxImportSize int64
+	// Idempotency stores the responses of POST operations carrying an Idempotency-Key header
+	Idempotency repository.IdempotencyStore
+	// IdempotencyTTL is how long a response is replayed for the same Idempotency-Key
+	IdempotencyTTL time.Duration
+	// Events is the log of changes streamed to watchers, watch is disabled when it's nil
+	Events *events.Log
+	// HeartbeatInterval is how often an idle event stream sends a comment to keep the connection open
+	HeartbeatInterval time.Duration
+	// AdminToken authorizes admin operations through the X-Admin-Token header, they're disabled when it's empty
+	AdminToken string
+	// Audit records every mutation and denied attempt, auditing is disabled when it's nil
+	Audit *audit.Log
+	// TrustForwardedFor uses the first address of X-Forwarded-For as the source IP of audit entries
+	// and idempotency keys
+	TrustForwardedFor bool
+	// APIKeys are the keys identifying clients through X-API-Key, the others are identified by IP, see RateLimiter
+	APIKeys map[string]bool
+	// DuplicatePolicy tells whether near-duplicates of a new application are ignored, reported, or rejected
+	DuplicatePolicy string
+	// StrictDecoding rejects write bodies with unknown fields, duplicate keys, or more than one document
+	StrictDecoding bool
+	// DecodeLimits bound the depth and the alias expansion of a write body in strict decoding
+	DecodeLimits strictyaml.Options
+	// Schemas are the JSON Schemas the extensions are validated against, per namespace
+	Schemas repository.SchemaStore
+	// EmailPolicy restricts the email domains of the maintainers, every domain is allowed when it's nil
+	EmailPolicy *emailpolicy.Policy
+	// Verifier sends a token to confirm their email to new maintainers, verification is disabled when it's nil
+	Verifier *verification.Verifier
+	// Links holds the latest status of the website and source URLs, link checking is disabled when it's nil
+	Links repository.LinkStore
+	// ReadOnly refuses every write while it's enabled
+	ReadOnly *ReadOnlyMode
+	// ReadOnlyRetryAfter is the Retry-After of the writes refused in read-only mode, unless the mode sets one
+	ReadOnlyRetryAfter time.Duration
 }
 
 // NewMetadataHandler returns an instance of MetadataHandler
 func NewMetadataHandler(repo repository.MetadataRepository) *MetadataHandler {
 	return &MetadataHandler{
-		Repository: repo,
+		Repository:         repo,
+		MaxBodySize:        DefaultMaxBodySize,
+		MaxImportSize:      DefaultMaxImportSize,
+		Idempotency:        repository.NewInMemoryIdempotencyStore(),
+		Schemas:            repository.NewInMemorySchemaStore(),
+		IdempotencyTTL:     DefaultIdempotencyTTL,
+		HeartbeatInterval:  DefaultHeartbeatInterval,
+		DuplicatePolicy:    DuplicatesWarn,
+		StrictDecoding:     true,
+		ReadOnly:           &ReadOnlyMode{},
+		ReadOnlyRetryAfter: DefaultRetryAfter,
 	}
 }
 
-// HandlePostMetadata handles POST operation
-func (mh *MetadataHandler) HandlePostMetadata(w http.ResponseWriter, r *http.Request) {
-	defer r.Body.Close()
+// unmarshal decodes the yaml body of a write operation into v, strictly unless StrictDecoding is off
+func (mh *MetadataHandler) unmarshal(b []byte, v interface{}) error {
+	if mh.StrictDecoding {
+		return strictyaml.Unmarshal(b, v, mh.DecodeLimits)
+	}
+	return yaml.Unmarshal(b, v)
+}
 
-	var payload metadata.ApplicationMetadata
+// readBody reads the request body up to MaxBodySize.
+// It writes 413 when the body is too large, or 400 when it can't be read, and returns false.
+func (mh *MetadataHandler) readBody(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	if mh.MaxBodySize > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, mh.MaxBodySize)
+	}
 	b, err := ioutil.ReadAll(r.Body)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest) // 400
+		if isBodyTooLarge(err) {
+			w.WriteHeader(http.StatusRequestEntityTooLarge) // 413
+			yaml.NewEncoder(w).Encode(err.Error())
+		} else {
+			w.WriteHeader(http.StatusBadRequest) // 400
+		}
+		return nil, false
+	}
+	return b, true
+}
+
+func isBodyTooLarge(err error) bool {
+	var maxErr *http.MaxBytesError
+	return errors.As(err, &maxErr)
+}
+
+// HandlePostMetadata handles POST operation.
+// A request carrying an Idempotency-Key header is only processed once, see handleIdempotent.
+func (mh *MetadataHandler) HandlePostMetadata(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	b, ok := mh.readBody(w, r)
+	if !ok {
 		return
 	}
-	err = yaml.Unmarshal(b, &payload)
+	if key := r.Header.Get(IdempotencyKeyHeader); key != "" && mh.Idempotency != nil {
+		mh.handleIdempotent(w, r, key, b, func(w http.ResponseWriter, b []byte) {
+			mh.createMetadata(w, r, b)
+		})
+		return
+	}
+	mh.createMetadata(w, r, b)
+}
+
+// createMetadata creates the application metadata from the body of a POST operation
+func (mh *MetadataHandler) createMetadata(w http.ResponseWriter, r *http.Request, b []byte) {
+	var payload metadata.ApplicationMetadata
+	err := mh.unmarshal(b, &payload)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest) // 400
 		yaml.NewEncoder(w).Encode(err.Error())
 		return
 	}
 
-	// validate the payload first
+	// validate the payload first, with its text in canonical form
+	payload.Normalize()
 	valid, desc := payload.IsValid()
 	if !valid {
 		w.WriteHeader(http.StatusBadRequest) // 400
 		yaml.NewEncoder(w).Encode(desc)
 		return
 	}
+	if !mh.validateExtensions(w, &payload) {
+		return
+	}
+	if !mh.validateEmailPolicy(w, &payload) {
+		return
+	}
 
-	id := uuid.New()
-	payload.ApplicationID = id.String()
+	// the client may choose a slug as applicationID, a UUID is generated otherwise
+	id := payload.ApplicationID
+	if id == "" {
+		id = uuid.New().String()
+	} else if err := metadata.ValidateSlug(id); err != nil {
+		w.WriteHeader(http.StatusBadRequest) // 400
+		yaml.NewEncoder(w).Encode(metadata.ValidationMessage{Description: err.Error()})
+		return
+	}
+	payload.ApplicationID = id
+	payload.ClearServerFields()
+	// new applications go through review before they're listed
+	payload.State = lifecycle.Draft
 
-	err = mh.Repository.Create(id.String(), &payload)
+	if !mh.validateDependencies(w, &payload, mh.lookup) {
+		return
+	}
+	if !mh.checkDuplicates(w, r, &payload) {
+		return
+	}
+	pending, err := mh.markVerification(&payload, nil)
+	if err == nil {
+		err = mh.resolveDependencies(&payload)
+	}
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError) // 500
 		yaml.NewEncoder(w).Encode(err.Error())
 		return
 	}
 
+	err = mh.Repository.Create(id, &payload)
+	if !checkDependencyError(w, err) {
+		return
+	}
+	if err != nil {
+		if isConflict(err) {
+			w.WriteHeader(http.StatusConflict) // 409
+		} else {
+			w.WriteHeader(http.StatusInternalServerError) // 500
+		}
+		yaml.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	mh.auditAllowed(r, audit.Create, payload.ApplicationID, nil, &payload)
+	mh.sendVerifications(pending)
+
 	w.WriteHeader(http.StatusCreated)
 	yaml.NewEncoder(w).Encode(payload)
 }
 
 // HandlePutMetadata handles PUT operation
 func (mh *MetadataHandler) HandlePutMetadata(w http.ResponseWriter, r *http.Request) {
+	mh.putMetadata(w, r, false)
+}
+
+// putMetadata replaces the application with the payload, keeping the extensions of the live application
+// when keepExtensions is set
+func (mh *MetadataHandler) putMetadata(w http.ResponseWriter, r *http.Request, keepExtensions bool) {
 	defer r.Body.Close()
 
 	var payload metadata.ApplicationMetadata
-	b, err := ioutil.ReadAll(r.Body)
-	if err != nil {
-		w.WriteHeader(http.StatusBadRequest) // 400
+	b, ok := mh.readBody(w, r)
+	if !ok {
 		return
 	}
-	err = yaml.Unmarshal(b, &payload)
+	err := mh.unmarshal(b, &payload)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest) // 400
+		yaml.NewEncoder(w).Encode(err.Error())
 		return
 	}
 
-	// validate the payload first
+	// validate the payload first, with its text in canonical form
+	payload.Normalize()
 	valid, desc := payload.IsValid()
 	if !valid {
 		w.WriteHeader(http.StatusBadRequest) // 400
 		yaml.NewEncoder(w).Encode(desc)
 		return
 	}
+	if !mh.validateExtensions(w, &payload) {
+		return
+	}
+	if !mh.validateEmailPolicy(w, &payload) {
+		return
+	}
 
 	vars := mux.Vars(r)
-	var (
-		appID string
-		ok    bool
-	)
+	var appID string
 	if appID, ok = vars["appID"]; !ok {
 		w.WriteHeader(http.StatusBadRequest) // 400
 		return
 	}
 
 	payload.ApplicationID = appID
+	payload.ClearServerFields()
 
-	err = mh.Repository.Update(appID, &payload)
+	existing, err := mh.Repository.Get(appID)
 	if err != nil {
-		if err == repository.ErrIDNotFound {
+		w.WriteHeader(http.StatusInternalServerError) // 500
+		yaml.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	if !mh.checkUnlocked(w, r, audit.Update, appID, existing) {
+		return
+	}
+
+	if !mh.validateDependencies(w, &payload, mh.lookup) {
+		return
+	}
+	// an update is only warned of its near-duplicates, the policy may block creations
+	duplicates, err := mh.findDuplicates(&payload)
+	var pending []string
+	if err == nil {
+		pending, err = mh.markVerification(&payload, existing)
+	}
+	if err == nil {
+		err = mh.resolveDependencies(&payload)
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError) // 500
+		yaml.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	// the lifecycle and the releases are carried over from the live application under the repository lock,
+	// so that a transition or a release in between isn't lost
+	mod, err := mh.Repository.Modify(appID, func(app *metadata.ApplicationMetadata) error {
+		if err := lifecycle.Edit(&payload, app); err != nil {
+			return err
+		}
+		// releases are only changed through the releases subresource, and the version follows them
+		payload.SetReleases(app.Releases)
+		if keepExtensions {
+			payload.Extensions = app.Extensions
+		}
+		*app = payload
+		return nil
+	})
+	if !mh.checkLockError(w, r, audit.Update, err) || !checkDependencyError(w, err) {
+		return
+	}
+	if err != nil {
+		switch {
+		case err == lifecycle.ErrRetired:
+			mh.auditDenied(r, audit.Update, appID, err.Error())
 			w.WriteHeader(http.StatusConflict) // 409
-		} else {
+		case err == repository.ErrIDNotFound || isConflict(err):
+			w.WriteHeader(http.StatusConflict) // 409
+		default:
 			w.WriteHeader(http.StatusInternalServerError) // 500
 		}
 		yaml.NewEncoder(w).Encode(err.Error())
 		return
 	}
+	mh.auditAllowed(r, audit.Update, appID, mod.Previous, mod.Current)
+	mh.sendVerifications(pending)
 
+	warnDuplicates(w, duplicates)
 	w.WriteHeader(http.StatusOK) // 200
-	yaml.NewEncoder(w).Encode(payload)
+	yaml.NewEncoder(w).Encode(mod.Current)
 }
 
 // HandleGetMetadata handles GET operation for specified applicationID
@@ -143,11 +360,48 @@ func (mh *MetadataHandler) HandleGetMetadata(w http.ResponseWriter, r *http.Requ
 	yaml.NewEncoder(w).Encode(res)
 }
 
-// HandleGetAllMetadata handles all GET operation
+// ContinueHeader is the response header carrying the token of the next page of a paginated list
+const ContinueHeader = "X-Continue"
+
+// HandleGetAllMetadata handles all GET operation.
+// The labelSelector query parameter such as "team=payments,tier in (1,2),!deprecated" filters by labels,
+// and the fieldSelector query parameter such as "company=acme,extensions.oncall.rotation=weekly" by fields.
+// Only published resources are listed unless the state query parameter lists other states (or all).
+// The releasedSince query parameter such as "30d" or "2020-01-01T00:00:00Z" lists the resources released since then.
+// The deleted=true query parameter lists the resources in the trash instead.
+// The limit query parameter returns a page of resources, with the token of the next page in the X-Continue
+// header to pass as continue query parameter.
 func (mh *MetadataHandler) HandleGetAllMetadata(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
 
-	res, err := mh.Repository.GetAll()
+	query, err := parseListQuery(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest) // 400
+		yaml.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	if links := r.URL.Query().Get("links"); links != "" {
+		if query.Match, err = mh.linksMatch(links); err != nil {
+			if err == errLinksDisabled {
+				w.WriteHeader(http.StatusNotImplemented) // 501
+			} else {
+				w.WriteHeader(http.StatusBadRequest) // 400
+			}
+			yaml.NewEncoder(w).Encode(err.Error())
+			return
+		}
+	}
+
+	limit := query.Limit
+	if limit > 0 {
+		// ask one more to know whether there is a next page
+		query.Limit++
+	}
+	res, err := mh.Repository.List(query)
+	if limit > 0 && len(res) > limit {
+		res = res[:limit]
+		w.Header().Set(ContinueHeader, base64.RawURLEncoding.EncodeToString([]byte(res[limit-1].ApplicationID)))
+	}
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError) // 500
 		return
@@ -162,6 +416,54 @@ func (mh *MetadataHandler) HandleGetAllMetadata(w http.ResponseWriter, r *http.R
 	yaml.NewEncoder(w).Encode(res)
 }
 
+// parseListQuery returns the repository query of a list request
+func parseListQuery(r *http.Request) (repository.Query, error) {
+	var (
+		query repository.Query
+		err   error
+	)
+	q := r.URL.Query()
+	if ls := q.Get("labelSelector"); ls != "" {
+		if query.Selector, err = labels.Parse(ls); err != nil {
+			return query, err
+		}
+	}
+	if query.Fields, err = metadata.ParseFieldSelector(q.Get("fieldSelector")); err != nil {
+		return query, err
+	}
+	if l := q.Get("limit"); l != "" {
+		if query.Limit, err = strconv.Atoi(l); err != nil || query.Limit < 1 {
+			return query, errors.New("limit must be a positive number")
+		}
+	}
+	if d := q.Get("deleted"); d != "" {
+		if query.Deleted, err = strconv.ParseBool(d); err != nil {
+			return query, errors.New("deleted must be true or false")
+		}
+	}
+	// lists only show published applications unless asked otherwise, the trash shows every state
+	if st := q.Get("state"); st != "" {
+		if query.States, err = lifecycle.ParseStates(st); err != nil {
+			return query, err
+		}
+	} else if !query.Deleted {
+		query.States = []string{lifecycle.Published}
+	}
+	if s := q.Get("releasedSince"); s != "" {
+		if query.ReleasedSince, err = parseSince(s, time.Now()); err != nil {
+			return query, err
+		}
+	}
+	if c := q.Get("continue"); c != "" {
+		after, err := base64.RawURLEncoding.DecodeString(c)
+		if err != nil {
+			return query, errors.New("invalid continue token")
+		}
+		query.After = string(after)
+	}
+	return query, nil
+}
+
 // HandleDeleteMetadata handles DELETE operation
 func (mh *MetadataHandler) HandleDeleteMetadata(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
@@ -176,9 +478,22 @@ func (mh *MetadataHandler) HandleDeleteMetadata(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	err := mh.Repository.Delete(appID)
+	existing, err := mh.Repository.Get(appID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError) // 500
+		yaml.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	if !mh.checkUnlocked(w, r, audit.Delete, appID, existing) {
+		return
+	}
+
+	err = mh.Repository.Delete(appID)
+	if !mh.checkLockError(w, r, audit.Delete, err) {
+		return
+	}
 	if err != nil {
-		if err == repository.ErrIDNotFound {
+		if err == repository.ErrIDNotFound || isConflict(err) {
 			w.WriteHeader(http.StatusConflict) // 409
 		} else {
 			w.WriteHeader(http.StatusInternalServerError) // 500
@@ -186,6 +501,7 @@ func (mh *MetadataHandler) HandleDeleteMetadata(w http.ResponseWriter, r *http.R
 		yaml.NewEncoder(w).Encode(err.Error())
 		return
 	}
+	mh.auditAllowed(r, audit.Delete, appID, existing, nil)
 
 	w.WriteHeader(http.StatusNoContent) // 204
 }
@@ -6,6 +6,7 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/gorilla/mux"
 
@@ -42,6 +43,43 @@ func TestMetadataHandler_HandlePostMetadataBadYamlFormat_ResultedBadRequest(t *t
 	assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
 }
 
+func TestMetadataHandler_HandlePostMetadataUnknownField_ResultedBadRequest(t *testing.T) {
+
+	payload := strings.Replace(createValidPayload(), "license:", "licence:", 1)
+
+	mr := repository.NewInMemoryMetadataRepository()
+	mh := NewMetadataHandler(mr)
+	request, _ := http.NewRequest("POST", "app-metadata", strings.NewReader(payload))
+	responseRecorder := httptest.NewRecorder()
+	mh.HandlePostMetadata(responseRecorder, request)
+
+	assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+	assert.Contains(t, responseRecorder.Body.String(), `line 12, column 1: unknown field "licence"`)
+
+	// the typo is ignored once strict decoding is off
+	mh.StrictDecoding = false
+	request, _ = http.NewRequest("POST", "app-metadata", strings.NewReader(payload))
+	responseRecorder = httptest.NewRecorder()
+	mh.HandlePostMetadata(responseRecorder, request)
+
+	assert.Equal(t, http.StatusCreated, responseRecorder.Code)
+}
+
+func TestMetadataHandler_HandlePutMetadataDuplicateKey_ResultedBadRequest(t *testing.T) {
+
+	payload := createValidPayload() + "version: 2.0.0\n"
+	request, _ := http.NewRequest("PUT", "app-metadata/appID1", strings.NewReader(payload))
+	request = mux.SetURLVars(request, map[string]string{"appID": "appID1"})
+	responseRecorder := httptest.NewRecorder()
+
+	mr := repository.NewInMemoryMetadataRepository()
+	mh := NewMetadataHandler(mr)
+	mh.HandlePutMetadata(responseRecorder, request)
+
+	assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+	assert.Contains(t, responseRecorder.Body.String(), `line 16, column 1: duplicate key "version"`)
+}
+
 func TestMetadataHandler_HandlePostMetadataRepositoryError_ResultedInternalServerError(t *testing.T) {
 
 	payload := createValidPayload()
@@ -270,6 +308,45 @@ func TestMetadataHandler_HandleDeleteMetadata_ResultedConflict(t *testing.T) {
 
 }
 
+func TestMetadataHandler_HandlePostMetadataURLs_ResultedNormalized(t *testing.T) {
+
+	mh := NewMetadataHandler(repository.NewInMemoryMetadataRepository())
+	mh.DuplicatePolicy = DuplicatesOff
+
+	post := func(payload string) *httptest.ResponseRecorder {
+		request, _ := http.NewRequest("POST", "app-metadata", strings.NewReader(payload))
+		responseRecorder := httptest.NewRecorder()
+		mh.HandlePostMetadata(responseRecorder, request)
+		return responseRecorder
+	}
+
+	// the vcs is derived by the service whatever the client sends
+	payload := strings.Replace(createValidPayload(), "https://github.com/elumbantoruan/app-metadata",
+		"HTTPS://GitHub.com/Acme/Payments.git/?utm_source=newsletter\nvcs:\n  host: example.com", 1)
+	payload = strings.Replace(payload, "http://pellucidcomputing.com", "http://PellucidComputing.com:80/", 1)
+	responseRecorder := post(payload)
+	assert.Equal(t, http.StatusCreated, responseRecorder.Code)
+	var created metadata.ApplicationMetadata
+	yaml.NewDecoder(responseRecorder.Body).Decode(&created)
+	assert.Equal(t, "http://pellucidcomputing.com", created.Website)
+	assert.Equal(t, "https://github.com/Acme/Payments.git", created.Source)
+	assert.Equal(t, &metadata.VCS{Host: "github.com", Owner: "acme", Repo: "payments"}, created.VCS)
+	assert.Equal(t, http.StatusCreated, post(createValidPayload2()).Code)
+
+	request, _ := http.NewRequest("GET", "app-metadata?state=all&fieldSelector=source.owner=acme", strings.NewReader(""))
+	responseRecorder = httptest.NewRecorder()
+	mh.HandleGetAllMetadata(responseRecorder, request)
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	var res []metadata.ApplicationMetadata
+	yaml.NewDecoder(responseRecorder.Body).Decode(&res)
+	assert.Equal(t, 1, len(res))
+	assert.Equal(t, created.ApplicationID, res[0].ApplicationID)
+
+	responseRecorder = post(strings.Replace(createValidPayload(), "http://pellucidcomputing.com", "pellucidcomputing.com", 1))
+	assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+	assert.Contains(t, responseRecorder.Body.String(), "website: pellucidcomputing.com is not an absolute http or https URL")
+}
+
 func createValidPayload() string {
 	return `
 title: Valid App 1
@@ -370,6 +447,12 @@ var (
 	errInGet    = errors.New("error in get")
 	errInGetAll = errors.New("error in get all")
 	errInDelete = errors.New("error in delete")
+	errInBatch  = errors.New("error in batch")
+	errInEach   = errors.New("error in for each")
+	errInList   = errors.New("error in list")
+	errInPurge  = errors.New("error in purge")
+	errInAlias  = errors.New("error in alias")
+	errInMaint  = errors.New("error in maintainers")
 )
 
 // FakeMetadataRepository is a concrete implementation of MetadataRepository interface in memory
@@ -396,6 +479,11 @@ func (fm *FakeMetadataRepository) Get(appID string) (*metadata.ApplicationMetada
 	return nil, errInGet
 }
 
+// GetRevision returns a revision of the application metadata for a given appID
+func (fm *FakeMetadataRepository) GetRevision(appID string, revision int) (*metadata.ApplicationMetadata, error) {
+	return nil, errInGet
+}
+
 // GetAll returns all application metadata
 func (fm *FakeMetadataRepository) GetAll() ([]metadata.ApplicationMetadata, error) {
 	return nil, errInGetAll
@@ -405,3 +493,101 @@ func (fm *FakeMetadataRepository) GetAll() ([]metadata.ApplicationMetadata, erro
 func (fm *FakeMetadataRepository) Delete(appID string) error {
 	return errInDelete
 }
+
+// PutBatch stores a set of application metadata
+func (fm *FakeMetadataRepository) PutBatch(data []*metadata.ApplicationMetadata, opts repository.BatchOptions) ([]error, error) {
+	return nil, errInBatch
+}
+
+// ForEach calls fn for every application metadata
+func (fm *FakeMetadataRepository) ForEach(fn func(data *metadata.ApplicationMetadata) error) error {
+	return errInEach
+}
+
+// List returns the application metadata matching the query
+func (fm *FakeMetadataRepository) List(query repository.Query) ([]metadata.ApplicationMetadata, error) {
+	return nil, errInList
+}
+
+// Undelete restores the application metadata for a given appID from the trash
+func (fm *FakeMetadataRepository) Undelete(appID string) error {
+	return errInDelete
+}
+
+// Purge permanently removes the application metadata for a given appID
+func (fm *FakeMetadataRepository) Purge(appID string) error {
+	return errInPurge
+}
+
+// PurgeDeleted permanently removes the application metadata moved to the trash before a given time
+func (fm *FakeMetadataRepository) PurgeDeleted(before time.Time) (int, error) {
+	return 0, errInPurge
+}
+
+// Rename moves the application metadata for a given appID to newID
+func (fm *FakeMetadataRepository) Rename(appID, newID string) error {
+	return errInAlias
+}
+
+// AddAlias adds an alias resolving to a given appID
+func (fm *FakeMetadataRepository) AddAlias(appID, alias string) error {
+	return errInAlias
+}
+
+// RemoveAlias removes an alias of a given appID
+func (fm *FakeMetadataRepository) RemoveAlias(appID, alias string) error {
+	return errInAlias
+}
+
+// Aliases returns the aliases of a given appID
+func (fm *FakeMetadataRepository) Aliases(appID string) ([]string, error) {
+	return nil, errInAlias
+}
+
+// ResolveAlias returns the appID an alias resolves to
+func (fm *FakeMetadataRepository) ResolveAlias(alias string) (string, error) {
+	return "", nil
+}
+
+// Maintainers returns every maintainer
+func (fm *FakeMetadataRepository) Maintainers() ([]repository.Maintainer, error) {
+	return nil, errInMaint
+}
+
+// GetMaintainer returns the maintainer with a given email
+func (fm *FakeMetadataRepository) GetMaintainer(email string) (*repository.Maintainer, error) {
+	return nil, errInMaint
+}
+
+// MaintainerApplications returns the applications listing a given maintainer email
+func (fm *FakeMetadataRepository) MaintainerApplications(email string) ([]metadata.ApplicationMetadata, error) {
+	return nil, errInMaint
+}
+
+// Scrub rewrites every application metadata in place
+func (fm *FakeMetadataRepository) Scrub(fn func(data *metadata.ApplicationMetadata) bool) ([]string, error) {
+	return nil, errInMaint
+}
+
+// OnChange sets the function called with every change
+func (fm *FakeMetadataRepository) OnChange(fn repository.ChangeFunc) {
+}
+
+// OnWrite sets the function checking every write
+func (fm *FakeMetadataRepository) OnWrite(fn repository.CheckFunc) {
+}
+
+// SetLock replaces the lock of an application
+func (fm *FakeMetadataRepository) SetLock(appID string, lock *metadata.Lock) (*metadata.ApplicationMetadata, error) {
+	return nil, errInUpdate
+}
+
+// UpdateMaintainer changes a maintainer in every application listing them
+func (fm *FakeMetadataRepository) UpdateMaintainer(email string, fn func(data *metadata.ApplicationMetadata) bool) ([]repository.Modification, error) {
+	return nil, errInMaint
+}
+
+// Modify changes an application
+func (fm *FakeMetadataRepository) Modify(appID string, fn func(data *metadata.ApplicationMetadata) error) (*repository.Modification, error) {
+	return nil, errInUpdate
+}
@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// APIKeyHeader is the request header identifying a client, the client IP is used when it's missing or unknown
+const APIKeyHeader = "X-API-Key"
+
+// RateLimit defines a token bucket budget, Rate tokens are added per second up to Burst tokens.
+// A zero Rate disables the limit.
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// RateLimiter throttles requests per client with separate budgets for reads and writes
+type RateLimiter struct {
+	// TrustForwardedFor uses the first address of X-Forwarded-For as the client IP,
+	// it should only be enabled behind a proxy that sets the header
+	TrustForwardedFor bool
+	// APIKeys are the keys identifying clients.  A request is only limited by its X-API-Key when the key is one of
+	// them, so that sending random keys doesn't escape the budget of the client IP.
+	APIKeys map[string]bool
+
+	read  *tokenBuckets
+	write *tokenBuckets
+	now   func() time.Time
+}
+
+// NewRateLimiter returns an instance of RateLimiter
+func NewRateLimiter(read, write RateLimit) *RateLimiter {
+	return &RateLimiter{
+		read:  newTokenBuckets(read),
+		write: newTokenBuckets(write),
+		now:   time.Now,
+	}
+}
+
+// Middleware rejects requests exceeding the client budget with 429
+func (rl *RateLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		buckets := rl.write
+		if isReadMethod(r.Method) {
+			buckets = rl.read
+		}
+		if buckets.limit.Rate <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		allowed, remaining, retryAfter, reset := buckets.take(rl.clientKey(r), rl.now())
+		w.Header().Set("RateLimit-Limit", strconv.Itoa(buckets.limit.Burst))
+		w.Header().Set("RateLimit-Remaining", strconv.Itoa(remaining))
+		w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(reset)))
+		if !allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(retryAfter)))
+			w.WriteHeader(http.StatusTooManyRequests) // 429
+			yaml.NewEncoder(w).Encode("rate limit exceeded")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// clientKey identifies the client by API key when it's known, or by IP address otherwise
+func (rl *RateLimiter) clientKey(r *http.Request) string {
+	return identifyClient(r, rl.APIKeys, rl.TrustForwardedFor)
+}
+
+// identifyClient returns "key:" and the X-API-Key of r when it's one of apiKeys, or "ip:" and the client IP otherwise,
+// so that a client can't pass for another one by sending its key
+func identifyClient(r *http.Request, apiKeys map[string]bool, trustForwardedFor bool) string {
+	if key := r.Header.Get(APIKeyHeader); key != "" && apiKeys[key] {
+		return "key:" + key
+	}
+	return "ip:" + clientIP(r, trustForwardedFor)
+}
+
+// clientIP returns the address of the client, or the first address of X-Forwarded-For when trustForwardedFor is set
+func clientIP(r *http.Request, trustForwardedFor bool) string {
+	if trustForwardedFor {
+		if fwd := r.Header.Get("X-Forwarded-For"); fwd != "" {
+			return strings.TrimSpace(strings.Split(fwd, ",")[0])
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return host
+}
+
+func isReadMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// pruneInterval is how often buckets that refilled completely are dropped
+const pruneInterval = time.Minute
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// tokenBuckets holds a token bucket for each client
+type tokenBuckets struct {
+	limit     RateLimit
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastPrune time.Time
+}
+
+func newTokenBuckets(limit RateLimit) *tokenBuckets {
+	if limit.Burst < 1 {
+		limit.Burst = 1
+	}
+	return &tokenBuckets{
+		limit:   limit,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// take consumes a token for key.  It returns whether the request is allowed, the tokens left,
+// how long until a token is available, and how long until the bucket is full again.
+func (tb *tokenBuckets) take(key string, now time.Time) (allowed bool, remaining int, retryAfter, reset time.Duration) {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+
+	burst := float64(tb.limit.Burst)
+	if now.Sub(tb.lastPrune) > pruneInterval {
+		tb.prune(now)
+	}
+
+	b, ok := tb.buckets[key]
+	if !ok {
+		b = &bucket{tokens: burst, last: now}
+		tb.buckets[key] = b
+	}
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*tb.limit.Rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		allowed = true
+	} else {
+		retryAfter = tb.duration(1 - b.tokens)
+	}
+	return allowed, int(b.tokens), retryAfter, tb.duration(burst - b.tokens)
+}
+
+// prune drops the buckets that would have refilled completely by now
+func (tb *tokenBuckets) prune(now time.Time) {
+	burst := float64(tb.limit.Burst)
+	for k, b := range tb.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*tb.limit.Rate >= burst {
+			delete(tb.buckets, k)
+		}
+	}
+	tb.lastPrune = now
+}
+
+// duration returns how long it takes to add the given amount of tokens
+func (tb *tokenBuckets) duration(tokens float64) time.Duration {
+	return time.Duration(tokens / tb.limit.Rate * float64(time.Second))
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/elumbantoruan/app-metadata/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiter_Middleware_ResultedTooManyRequests(t *testing.T) {
+
+	now := time.Now()
+	rl := NewRateLimiter(RateLimit{Rate: 10, Burst: 2}, RateLimit{Rate: 1, Burst: 1})
+	rl.now = func() time.Time { return now }
+	rl.APIKeys = map[string]bool{"key1": true}
+	h := rl.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	serve := func(method, apiKey string) *httptest.ResponseRecorder {
+		request, _ := http.NewRequest(method, "app-metadata", strings.NewReader(""))
+		request.RemoteAddr = "10.0.0.1:1234"
+		if apiKey != "" {
+			request.Header.Set(APIKeyHeader, apiKey)
+		}
+		responseRecorder := httptest.NewRecorder()
+		h.ServeHTTP(responseRecorder, request)
+		return responseRecorder
+	}
+
+	assert.Equal(t, http.StatusOK, serve("POST", "").Code)
+	res := serve("POST", "")
+	assert.Equal(t, http.StatusTooManyRequests, res.Code) // 429
+	assert.Equal(t, "1", res.Header().Get("Retry-After"))
+	assert.Equal(t, "1", res.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "0", res.Header().Get("RateLimit-Remaining"))
+
+	// reads have their own budget
+	res = serve("GET", "")
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "1", res.Header().Get("RateLimit-Remaining"))
+
+	// another client has its own budget
+	assert.Equal(t, http.StatusOK, serve("POST", "key1").Code)
+
+	// but not one sending an unknown key, which shares the budget of its IP
+	assert.Equal(t, http.StatusTooManyRequests, serve("POST", "random").Code)
+
+	// the bucket refills over time
+	now = now.Add(time.Second)
+	assert.Equal(t, http.StatusOK, serve("POST", "").Code)
+}
+
+func TestMetadataHandler_HandlePostMetadataBodyTooLarge_ResultedRequestEntityTooLarge(t *testing.T) {
+
+	payload := createValidPayload()
+	request, _ := http.NewRequest("POST", "app-metadata", strings.NewReader(payload))
+	responseRecorder := httptest.NewRecorder()
+
+	mh := NewMetadataHandler(repository.NewInMemoryMetadataRepository())
+	mh.MaxBodySize = 16
+	mh.HandlePostMetadata(responseRecorder, request)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, responseRecorder.Code) // 413
+}
+
+func TestMetadataHandler_HandleImportMetadataBodyTooLarge_ResultedRequestEntityTooLarge(t *testing.T) {
+
+	payload := createValidPayload() + "---\n" + createValidPayload2()
+	request, _ := http.NewRequest("POST", "app-metadata:import", strings.NewReader(payload))
+	responseRecorder := httptest.NewRecorder()
+
+	mh := NewMetadataHandler(repository.NewInMemoryMetadataRepository())
+	mh.MaxImportSize = int64(len(createValidPayload()))
+	mh.HandleImportMetadata(responseRecorder, request)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, responseRecorder.Code) // 413
+}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// ReadOnlyPath is the path of the read-only mode, which stays writable in read-only mode to turn it off
+const ReadOnlyPath = "/read-only"
+
+// DefaultRetryAfter is how long clients are told to wait before retrying a write in read-only mode by default
+const DefaultRetryAfter = 5 * time.Minute
+
+// ReadOnlyStatus is the state of the read-only mode
+type ReadOnlyStatus struct {
+	Enabled bool   `yaml:"enabled" json:"enabled"`
+	Reason  string `yaml:"reason,omitempty" json:"reason,omitempty"`
+	// RetryAfter is the number of seconds in the Retry-After header of the writes refused, see ReadOnlyRetryAfter
+	RetryAfter int `yaml:"retryAfter,omitempty" json:"retryAfter,omitempty"`
+	// Since is set by the service when the mode is enabled
+	Since *time.Time `yaml:"since,omitempty" json:"since,omitempty"`
+}
+
+// ReadOnlyMode freezes every write of the service, such as during a migration, while reads keep working.
+// It's safe for concurrent use.
+type ReadOnlyMode struct {
+	mu     sync.RWMutex
+	status ReadOnlyStatus
+}
+
+// Status returns the state of the read-only mode
+func (m *ReadOnlyMode) Status() ReadOnlyStatus {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.status
+}
+
+// Enabled returns true when writes are refused
+func (m *ReadOnlyMode) Enabled() bool {
+	return m.Status().Enabled
+}
+
+// Set changes the state of the read-only mode, keeping the time it was enabled at while it stays enabled
+func (m *ReadOnlyMode) Set(status ReadOnlyStatus) ReadOnlyStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	status.Since = nil
+	if status.Enabled {
+		status.Since = m.status.Since
+		if status.Since == nil {
+			now := time.Now().UTC().Truncate(time.Second)
+			status.Since = &now
+		}
+	} else {
+		status.Reason = ""
+		status.RetryAfter = 0
+	}
+	m.status = status
+	return status
+}
+
+// EnforceReadOnly is a middleware rejecting every request but reads with 503 and Retry-After in read-only mode.
+// Requests to ReadOnlyPath are let through so that an admin can turn it off.
+func (mh *MetadataHandler) EnforceReadOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isReadMethod(r.Method) || r.URL.Path == ReadOnlyPath || !mh.rejectReadOnly(w) {
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// rejectReadOnly writes 503 and returns true in read-only mode
+func (mh *MetadataHandler) rejectReadOnly(w http.ResponseWriter) bool {
+	status := mh.ReadOnly.Status()
+	if !status.Enabled {
+		return false
+	}
+	retryAfter := status.RetryAfter
+	if retryAfter <= 0 {
+		retryAfter = ceilSeconds(mh.ReadOnlyRetryAfter)
+	}
+	msg := "the service is read-only"
+	if status.Reason != "" {
+		msg += ": " + status.Reason
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	w.WriteHeader(http.StatusServiceUnavailable) // 503
+	yaml.NewEncoder(w).Encode(msg)
+	return true
+}
+
+// HandleGetReadOnly handles GET operation returning the state of the read-only mode
+func (mh *MetadataHandler) HandleGetReadOnly(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	w.WriteHeader(http.StatusOK) // 200
+	encode(w, responseFormat(r), mh.ReadOnly.Status())
+}
+
+// HandlePutReadOnly handles PUT operation turning the read-only mode on or off, which requires the admin token
+func (mh *MetadataHandler) HandlePutReadOnly(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	if !mh.requireAdmin(w, r) {
+		return
+	}
+	b, ok := mh.readBody(w, r)
+	if !ok {
+		return
+	}
+	var req ReadOnlyStatus
+	if err := mh.unmarshal(b, &req); err != nil {
+		w.WriteHeader(http.StatusBadRequest) // 400
+		yaml.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	if req.RetryAfter < 0 {
+		w.WriteHeader(http.StatusBadRequest) // 400
+		yaml.NewEncoder(w).Encode("retryAfter must be a positive number of seconds")
+		return
+	}
+
+	status := mh.ReadOnly.Set(req)
+	log.Printf("read-only mode enabled: %t, reason: %q", status.Enabled, status.Reason)
+
+	w.WriteHeader(http.StatusOK) // 200
+	yaml.NewEncoder(w).Encode(status)
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/elumbantoruan/app-metadata/repository"
+	"github.com/stretchr/testify/assert"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestMetadataHandler_EnforceReadOnly_ResultedWritesRefused(t *testing.T) {
+
+	mh := NewMetadataHandler(repository.NewInMemoryMetadataRepository())
+	mh.AdminToken = "secret"
+	next := mh.EnforceReadOnly(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == ReadOnlyPath {
+			mh.HandlePutReadOnly(w, r)
+			return
+		}
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	serve := func(method, path, body, token string) *httptest.ResponseRecorder {
+		request, _ := http.NewRequest(method, path, strings.NewReader(body))
+		if token != "" {
+			request.Header.Set(AdminTokenHeader, token)
+		}
+		responseRecorder := httptest.NewRecorder()
+		next.ServeHTTP(responseRecorder, request)
+		return responseRecorder
+	}
+	assert.Equal(t, http.StatusTeapot, serve("POST", "/app-metadata", "", "").Code)
+
+	assert.Equal(t, http.StatusForbidden, serve("PUT", ReadOnlyPath, "enabled: true", "").Code)
+	assert.Equal(t, http.StatusBadRequest, serve("PUT", ReadOnlyPath, "enabled: true\nretryAfter: -1", "secret").Code)
+	responseRecorder := serve("PUT", ReadOnlyPath, "enabled: true\nreason: migrating the storage", "secret")
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	var status ReadOnlyStatus
+	yaml.Unmarshal(responseRecorder.Body.Bytes(), &status)
+	assert.True(t, status.Enabled)
+	assert.NotNil(t, status.Since)
+
+	for _, method := range []string{"POST", "PUT", "PATCH", "DELETE"} {
+		responseRecorder = serve(method, "/app-metadata/app", "", "")
+		assert.Equal(t, http.StatusServiceUnavailable, responseRecorder.Code, method)
+		assert.Equal(t, "300", responseRecorder.Header().Get("Retry-After"))
+		assert.Contains(t, responseRecorder.Body.String(), "the service is read-only: migrating the storage")
+	}
+	for _, method := range []string{"GET", "HEAD"} {
+		assert.Equal(t, http.StatusTeapot, serve(method, "/app-metadata/app", "", "").Code, method)
+	}
+
+	// the mode itself stays writable, and the time it was enabled at is kept while it stays enabled
+	assert.Equal(t, http.StatusOK, serve("PUT", ReadOnlyPath, "enabled: true\nretryAfter: 60", "secret").Code)
+	assert.Equal(t, status.Since, mh.ReadOnly.Status().Since)
+	assert.Equal(t, "60", serve("POST", "/app-metadata", "", "").Header().Get("Retry-After"))
+
+	responseRecorder = serve("PUT", ReadOnlyPath, "enabled: false\nreason: ignored", "secret")
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	assert.Equal(t, ReadOnlyStatus{}, mh.ReadOnly.Status())
+	assert.Equal(t, http.StatusTeapot, serve("POST", "/app-metadata", "", "").Code)
+}
@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/elumbantoruan/app-metadata/audit"
+	"github.com/elumbantoruan/app-metadata/lifecycle"
+	"github.com/elumbantoruan/app-metadata/metadata"
+	"github.com/elumbantoruan/app-metadata/repository"
+	"github.com/gorilla/mux"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// HandleGetReleases handles GET operation returning the releases of an application, from the highest version
+func (mh *MetadataHandler) HandleGetReleases(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	app, ok := mh.getApplication(w, r)
+	if !ok {
+		return
+	}
+	res := app.Releases
+	if res == nil {
+		res = []metadata.Release{}
+	}
+	w.WriteHeader(http.StatusOK) // 200
+	encode(w, responseFormat(r), res)
+}
+
+// HandleGetRelease handles GET operation returning a release of an application
+func (mh *MetadataHandler) HandleGetRelease(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	app, ok := mh.getApplication(w, r)
+	if !ok {
+		return
+	}
+	i := app.FindRelease(mux.Vars(r)["version"])
+	if i < 0 {
+		w.WriteHeader(http.StatusNotFound) // 404
+		return
+	}
+	w.WriteHeader(http.StatusOK) // 200
+	encode(w, responseFormat(r), app.Releases[i])
+}
+
+// HandlePostRelease handles POST operation adding a release to an application, released now unless releasedAt is set.
+// The version of the application becomes the highest release that isn't a prerelease.
+func (mh *MetadataHandler) HandlePostRelease(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	appID, ok := mux.Vars(r)["appID"]
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest) // 400
+		return
+	}
+	b, ok := mh.readBody(w, r)
+	if !ok {
+		return
+	}
+	var release metadata.Release
+	if err := mh.unmarshal(b, &release); err != nil {
+		w.WriteHeader(http.StatusBadRequest) // 400
+		yaml.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	if release.ReleasedAt.IsZero() {
+		release.ReleasedAt = time.Now().Truncate(time.Second)
+	}
+	release.Normalize()
+	if err := release.Validate(); err != nil {
+		w.WriteHeader(http.StatusBadRequest) // 400
+		yaml.NewEncoder(w).Encode(metadata.ValidationMessage{Field: "releases", Description: err.Error()})
+		return
+	}
+
+	ok = mh.modifyReleases(w, r, appID, func(app *metadata.ApplicationMetadata) error {
+		if app.FindRelease(release.Version) >= 0 {
+			return fmt.Errorf("release %s %w", release.Version, errReleaseExists)
+		}
+		app.SetReleases(append(app.Releases, release))
+		return nil
+	})
+	if !ok {
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated) // 201
+	yaml.NewEncoder(w).Encode(release)
+}
+
+// HandleDeleteRelease handles DELETE operation removing a release published by mistake.
+// The version of the application goes back to the highest remaining release that isn't a prerelease, or the highest
+// prerelease when only prereleases remain.  The last release can't be removed (409), see RemoveRelease.
+func (mh *MetadataHandler) HandleDeleteRelease(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	vars := mux.Vars(r)
+	appID, ok := vars["appID"]
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest) // 400
+		return
+	}
+	ok = mh.modifyReleases(w, r, appID, func(app *metadata.ApplicationMetadata) error {
+		i := app.FindRelease(vars["version"])
+		if i < 0 {
+			return errReleaseNotFound
+		}
+		return app.RemoveRelease(i)
+	})
+	if !ok {
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent) // 204
+}
+
+// getApplication returns the application of the appID route variable.
+// It writes 400, 404, or 500 and returns false when it can't.
+func (mh *MetadataHandler) getApplication(w http.ResponseWriter, r *http.Request) (*metadata.ApplicationMetadata, bool) {
+	appID, ok := mux.Vars(r)["appID"]
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest) // 400
+		return nil, false
+	}
+	app, err := mh.Repository.Get(appID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError) // 500
+		yaml.NewEncoder(w).Encode(err.Error())
+		return nil, false
+	}
+	if app == nil {
+		w.WriteHeader(http.StatusNotFound) // 404
+		return nil, false
+	}
+	return app, true
+}
+
+var (
+	errReleaseExists   = errors.New("already exists")
+	errReleaseNotFound = errors.New("release not found")
+)
+
+// modifyReleases changes the releases of appID with fn atomically, writing 404, 409, 423, or 500 and returning false
+// when it can't.  The releases of a retired or locked application can't change.
+func (mh *MetadataHandler) modifyReleases(w http.ResponseWriter, r *http.Request, appID string, fn func(app *metadata.ApplicationMetadata) error) bool {
+	mod, err := mh.Repository.Modify(appID, func(app *metadata.ApplicationMetadata) error {
+		if app.State == lifecycle.Retired {
+			return lifecycle.ErrRetired
+		}
+		return fn(app)
+	})
+	if !mh.checkLockError(w, r, audit.Release, err) {
+		return false
+	}
+	if err != nil {
+		switch {
+		case err == repository.ErrIDNotFound || err == errReleaseNotFound:
+			w.WriteHeader(http.StatusNotFound) // 404
+			return false
+		case err == lifecycle.ErrRetired:
+			mh.auditDenied(r, audit.Release, appID, err.Error())
+			w.WriteHeader(http.StatusConflict) // 409
+		case errors.Is(err, errReleaseExists) || err == metadata.ErrLastRelease || isConflict(err):
+			w.WriteHeader(http.StatusConflict) // 409
+		default:
+			w.WriteHeader(http.StatusInternalServerError) // 500
+		}
+		yaml.NewEncoder(w).Encode(err.Error())
+		return false
+	}
+	mh.auditAllowed(r, audit.Release, appID, mod.Previous, mod.Current)
+	return true
+}
+
+// parseSince parses the releasedSince query parameter, either a time in RFC 3339 or a duration before now
+// such as 30d or 12h
+func parseSince(s string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	var (
+		d   time.Duration
+		err error
+	)
+	if days := strings.TrimSuffix(s, "d"); days != s {
+		var n int
+		n, err = strconv.Atoi(days)
+		d = time.Duration(n) * 24 * time.Hour
+	} else {
+		d, err = time.ParseDuration(s)
+	}
+	if err != nil || d < 0 {
+		return time.Time{}, errors.New("releasedSince must be a time such as 2020-01-01T00:00:00Z or a duration such as 30d")
+	}
+	return now.Add(-d), nil
+}
@@ -0,0 +1,267 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/elumbantoruan/app-metadata/lifecycle"
+	"github.com/elumbantoruan/app-metadata/metadata"
+	"github.com/elumbantoruan/app-metadata/repository"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+
+	"gopkg.in/yaml.v2"
+)
+
+func postRelease(mh *MetadataHandler, appID, body string) *httptest.ResponseRecorder {
+	request, _ := http.NewRequest("POST", "/app-metadata/"+appID+"/releases", strings.NewReader(body))
+	request = mux.SetURLVars(request, map[string]string{"appID": appID})
+	responseRecorder := httptest.NewRecorder()
+	mh.HandlePostRelease(responseRecorder, request)
+	return responseRecorder
+}
+
+func TestMetadataHandler_HandlePostRelease_ResultedVersion(t *testing.T) {
+
+	im := repository.NewInMemoryMetadataRepository()
+	im.Create("app", &metadata.ApplicationMetadata{ApplicationID: "app", Version: "0.9.0"})
+	mh := NewMetadataHandler(im)
+
+	responseRecorder := postRelease(mh, "app", `
+version: v1.0.0
+releasedAt: 2020-01-01T00:00:00Z
+notes: First release
+artifacts:
+- name: app-linux-amd64.tar.gz
+  checksum: SHA256:9F86D081884C7D659A2FEAA0C55AD015A3BF4F1B2B0B822CD15D6C15B0F00A08
+`)
+	assert.Equal(t, http.StatusCreated, responseRecorder.Code)
+	var release metadata.Release
+	yaml.Unmarshal(responseRecorder.Body.Bytes(), &release)
+	assert.Equal(t, "1.0.0", release.Version)
+	assert.Equal(t, "sha256:9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08", release.Artifacts[0].Checksum)
+	app, _ := im.Get("app")
+	assert.Equal(t, "1.0.0", app.Version)
+	assert.Equal(t, 2, app.Revision)
+
+	// a prerelease doesn't change the version, while a lower release is only history
+	assert.Equal(t, http.StatusCreated, postRelease(mh, "app", "version: 1.1.0-rc.1").Code)
+	assert.Equal(t, http.StatusCreated, postRelease(mh, "app", "version: 0.9.0\nreleasedAt: 2019-06-01T00:00:00Z").Code)
+	app, _ = im.Get("app")
+	assert.Equal(t, "1.0.0", app.Version)
+	versions := []string{}
+	for _, r := range app.Releases {
+		versions = append(versions, r.Version)
+	}
+	assert.Equal(t, []string{"1.1.0-rc.1", "1.0.0", "0.9.0"}, versions)
+	assert.WithinDuration(t, time.Now(), app.Releases[0].ReleasedAt, time.Minute)
+
+	responseRecorder = postRelease(mh, "app", "version: 1.0")
+	assert.Equal(t, http.StatusConflict, responseRecorder.Code)
+	assert.Contains(t, responseRecorder.Body.String(), "release 1.0.0 already exists")
+
+	for body, expected := range map[string]string{
+		"version: latest": "release version: latest is not a valid semantic version",
+		"version: 2.0.0\nartifacts:\n- name: app.zip\n  checksum: md5:d41d8cd98f00b204e9800998ecf8427e": "artifact app.zip of release 2.0.0: md5:d41d8cd98f00b204e9800998ecf8427e is not a sha256: or sha512: checksum",
+		"version: 2.0.0\nartifacts:\n- name: app.zip\n  checksum: sha256:abc":                           "artifact app.zip of release 2.0.0: sha256:abc is not a valid sha256 checksum",
+		"version: 2.0.0\nnotes: <script>alert(1)</script>":                                              "release 2.0.0 notes: raw HTML <script> is not allowed in description",
+	} {
+		responseRecorder = postRelease(mh, "app", body)
+		assert.Equal(t, http.StatusBadRequest, responseRecorder.Code, body)
+		var msg metadata.ValidationMessage
+		yaml.Unmarshal(responseRecorder.Body.Bytes(), &msg)
+		assert.Equal(t, metadata.ValidationMessage{Field: "releases", Description: expected}, msg)
+	}
+	assert.Equal(t, http.StatusNotFound, postRelease(mh, "missing", "version: 1.0.0").Code)
+
+	im.Create("retired", &metadata.ApplicationMetadata{ApplicationID: "retired", Version: "1.0.0", State: lifecycle.Retired})
+	assert.Equal(t, http.StatusConflict, postRelease(mh, "retired", "version: 1.1.0").Code)
+}
+
+func TestMetadataHandler_HandleGetReleases_ResultedReleases(t *testing.T) {
+
+	im := repository.NewInMemoryMetadataRepository()
+	im.Create("app", &metadata.ApplicationMetadata{ApplicationID: "app", Version: "1.0.0"})
+	mh := NewMetadataHandler(im)
+
+	get := func(path string, vars map[string]string, handler http.HandlerFunc) *httptest.ResponseRecorder {
+		request, _ := http.NewRequest("GET", path, strings.NewReader(""))
+		request = mux.SetURLVars(request, vars)
+		responseRecorder := httptest.NewRecorder()
+		handler(responseRecorder, request)
+		return responseRecorder
+	}
+	responseRecorder := get("/app-metadata/app/releases", map[string]string{"appID": "app"}, mh.HandleGetReleases)
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	assert.Equal(t, "[]\n", responseRecorder.Body.String())
+
+	postRelease(mh, "app", "version: 1.0.0\nnotes: First release")
+	postRelease(mh, "app", "version: 1.1.0\nnotes: Second release")
+
+	responseRecorder = get("/app-metadata/app/releases", map[string]string{"appID": "app"}, mh.HandleGetReleases)
+	var releases []metadata.Release
+	yaml.Unmarshal(responseRecorder.Body.Bytes(), &releases)
+	assert.Len(t, releases, 2)
+	assert.Equal(t, "1.1.0", releases[0].Version)
+
+	responseRecorder = get("/app-metadata/app/releases/v1.0.0", map[string]string{"appID": "app", "version": "v1.0.0"}, mh.HandleGetRelease)
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	var release metadata.Release
+	yaml.Unmarshal(responseRecorder.Body.Bytes(), &release)
+	assert.Equal(t, "First release", release.Notes)
+
+	assert.Equal(t, http.StatusNotFound, get("/app-metadata/app/releases/2.0.0", map[string]string{"appID": "app", "version": "2.0.0"}, mh.HandleGetRelease).Code)
+	assert.Equal(t, http.StatusNotFound, get("/app-metadata/missing/releases", map[string]string{"appID": "missing"}, mh.HandleGetReleases).Code)
+}
+
+func TestMetadataHandler_HandleDeleteRelease_ResultedVersion(t *testing.T) {
+
+	im := repository.NewInMemoryMetadataRepository()
+	im.Create("app", &metadata.ApplicationMetadata{ApplicationID: "app", Version: "1.0.0"})
+	mh := NewMetadataHandler(im)
+	postRelease(mh, "app", "version: 1.0.0")
+	postRelease(mh, "app", "version: 1.1.0")
+
+	del := func(version string) *httptest.ResponseRecorder {
+		request, _ := http.NewRequest("DELETE", "/app-metadata/app/releases/"+version, strings.NewReader(""))
+		request = mux.SetURLVars(request, map[string]string{"appID": "app", "version": version})
+		responseRecorder := httptest.NewRecorder()
+		mh.HandleDeleteRelease(responseRecorder, request)
+		return responseRecorder
+	}
+	assert.Equal(t, http.StatusNoContent, del("1.1.0").Code)
+	assert.Equal(t, http.StatusNotFound, del("1.1.0").Code)
+	app, _ := im.Get("app")
+	assert.Equal(t, "1.0.0", app.Version)
+	assert.Len(t, app.Releases, 1)
+
+	// without a stable release left, the version doesn't name a deleted release
+	postRelease(mh, "app", "version: 2.1.0-rc.1")
+	assert.Equal(t, http.StatusNoContent, del("1.0.0").Code)
+	app, _ = im.Get("app")
+	assert.Equal(t, "2.1.0-rc.1", app.Version)
+	// the last release is kept, so the application keeps a valid version
+	assert.Equal(t, http.StatusConflict, del("2.1.0-rc.1").Code) // 409
+	app, _ = im.Get("app")
+	assert.Equal(t, "2.1.0-rc.1", app.Version)
+	assert.Len(t, app.Releases, 1)
+	valid, _ := app.IsValid()
+	assert.True(t, valid)
+}
+
+func TestMetadataHandler_HandlePostReleaseConcurrent_ResultedEveryRelease(t *testing.T) {
+
+	im := repository.NewInMemoryMetadataRepository()
+	im.Create("app", &metadata.ApplicationMetadata{ApplicationID: "app", Version: "1.0.0"})
+	mh := NewMetadataHandler(im)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			postRelease(mh, "app", fmt.Sprintf("version: 1.%d.0", i))
+		}(i)
+	}
+	wg.Wait()
+
+	app, _ := im.Get("app")
+	assert.Len(t, app.Releases, 20)
+	assert.Equal(t, "1.19.0", app.Version)
+	assert.Equal(t, 21, app.Revision)
+}
+
+func TestMetadataHandler_HandlePutMetadataReleases_ResultedKept(t *testing.T) {
+
+	im := repository.NewInMemoryMetadataRepository()
+	mh := NewMetadataHandler(im)
+	im.Create("app", &metadata.ApplicationMetadata{ApplicationID: "app", Version: "1.0.0"})
+	postRelease(mh, "app", "version: 1.2.0")
+
+	// the releases of the payload are ignored and the version follows the releases
+	payload := createValidPayload() + "releases:\n- version: 9.0.0\n  releasedAt: 2020-01-01T00:00:00Z\n"
+	request, _ := http.NewRequest("PUT", "/app-metadata/app", strings.NewReader(payload))
+	request = mux.SetURLVars(request, map[string]string{"appID": "app"})
+	responseRecorder := httptest.NewRecorder()
+	mh.HandlePutMetadata(responseRecorder, request)
+
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	app, _ := im.Get("app")
+	assert.Equal(t, "Valid App 1", app.Title)
+	assert.Equal(t, "1.2.0", app.Version)
+	assert.Len(t, app.Releases, 1)
+	assert.Equal(t, "1.2.0", app.Releases[0].Version)
+}
+
+func TestMetadataHandler_HandleGetAllMetadataReleasedSince_ResultedFiltered(t *testing.T) {
+
+	im := repository.NewInMemoryMetadataRepository()
+	mh := NewMetadataHandler(im)
+	im.Create("recent", &metadata.ApplicationMetadata{ApplicationID: "recent", Version: "1.0.0", State: lifecycle.Published})
+	im.Create("old", &metadata.ApplicationMetadata{ApplicationID: "old", Version: "1.0.0", State: lifecycle.Published})
+	im.Create("never", &metadata.ApplicationMetadata{ApplicationID: "never", Version: "1.0.0", State: lifecycle.Published})
+	postRelease(mh, "recent", "version: 1.0.0\nreleasedAt: 2020-01-01T00:00:00Z")
+	postRelease(mh, "recent", "version: 1.1.0")
+	postRelease(mh, "old", "version: 1.0.0\nreleasedAt: 2020-01-01T00:00:00Z")
+
+	list := func(since string) *httptest.ResponseRecorder {
+		request, _ := http.NewRequest("GET", "/app-metadata?releasedSince="+since, strings.NewReader(""))
+		responseRecorder := httptest.NewRecorder()
+		mh.HandleGetAllMetadata(responseRecorder, request)
+		return responseRecorder
+	}
+	for since, expected := range map[string][]string{
+		"30d":                  {"recent"},
+		"12h":                  {"recent"},
+		"2020-01-01T00:00:00Z": {"old", "recent"},
+	} {
+		responseRecorder := list(since)
+		assert.Equal(t, http.StatusOK, responseRecorder.Code, since)
+		var res []metadata.ApplicationMetadata
+		yaml.Unmarshal(responseRecorder.Body.Bytes(), &res)
+		ids := []string{}
+		for _, app := range res {
+			ids = append(ids, app.ApplicationID)
+		}
+		assert.Equal(t, expected, ids, since)
+	}
+	for _, since := range []string{"last-month", "-3d", "2020-01-01"} {
+		responseRecorder := list(since)
+		assert.Equal(t, http.StatusBadRequest, responseRecorder.Code, since)
+		assert.Contains(t, responseRecorder.Body.String(), "releasedSince must be a time such as 2020-01-01T00:00:00Z or a duration such as 30d")
+	}
+}
+
+func TestMetadataHandler_HandlePutMetadataConcurrentRelease_ResultedEveryRelease(t *testing.T) {
+
+	im := repository.NewInMemoryMetadataRepository()
+	im.Create("app", &metadata.ApplicationMetadata{ApplicationID: "app", Version: "1.0.0"})
+	mh := NewMetadataHandler(im)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			postRelease(mh, "app", fmt.Sprintf("version: 1.%d.0", i))
+		}(i)
+		go func() {
+			defer wg.Done()
+			request, _ := http.NewRequest("PUT", "app-metadata/app", strings.NewReader(createValidPayload()))
+			request = mux.SetURLVars(request, map[string]string{"appID": "app"})
+			mh.HandlePutMetadata(httptest.NewRecorder(), request)
+		}()
+	}
+	wg.Wait()
+
+	// the PUTs carry over the releases added in between
+	app, _ := im.Get("app")
+	assert.Len(t, app.Releases, 20)
+	assert.Equal(t, "1.19.0", app.Version)
+	assert.Equal(t, "Valid App 1", app.Title)
+}
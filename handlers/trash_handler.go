@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/elumbantoruan/app-metadata/audit"
+	"github.com/elumbantoruan/app-metadata/repository"
+	"github.com/gorilla/mux"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// HandleUndeleteMetadata handles POST operation restoring a deleted application from the trash
+func (mh *MetadataHandler) HandleUndeleteMetadata(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	appID, ok := mux.Vars(r)["appID"]
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest) // 400
+		return
+	}
+
+	err := mh.Repository.Undelete(appID)
+	if err != nil {
+		switch {
+		case err == repository.ErrIDNotFound:
+			w.WriteHeader(http.StatusNotFound) // 404
+		case isConflict(err):
+			w.WriteHeader(http.StatusConflict) // 409
+		default:
+			w.WriteHeader(http.StatusInternalServerError) // 500
+		}
+		yaml.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	res, err := mh.Repository.Get(appID)
+	if err != nil || res == nil {
+		w.WriteHeader(http.StatusInternalServerError) // 500
+		return
+	}
+	mh.auditAllowed(r, audit.Undelete, appID, nil, res)
+	w.WriteHeader(http.StatusOK) // 200
+	yaml.NewEncoder(w).Encode(res)
+}
+
+// HandlePurgeMetadata handles the admin POST operation permanently removing an application, whether it's in the trash or not
+func (mh *MetadataHandler) HandlePurgeMetadata(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	appID, ok := mux.Vars(r)["appID"]
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest) // 400
+		return
+	}
+	if !mh.requireAdmin(w, r) {
+		mh.auditDenied(r, audit.Purge, appID, "admin token is required")
+		return
+	}
+	existing, err := mh.Repository.Get(appID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError) // 500
+		yaml.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	if !mh.checkUnlocked(w, r, audit.Purge, appID, existing) {
+		return
+	}
+
+	err = mh.Repository.Purge(appID)
+	if !mh.checkLockError(w, r, audit.Purge, err) {
+		return
+	}
+	if err != nil {
+		if err == repository.ErrIDNotFound {
+			w.WriteHeader(http.StatusNotFound) // 404
+		} else {
+			w.WriteHeader(http.StatusInternalServerError) // 500
+		}
+		yaml.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	mh.auditAllowed(r, audit.Purge, appID, nil, nil)
+
+	w.WriteHeader(http.StatusNoContent) // 204
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/elumbantoruan/app-metadata/metadata"
+	"github.com/elumbantoruan/app-metadata/repository"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestMetadataHandler_HandleUndeleteMetadata_ResultedOK(t *testing.T) {
+
+	im := repository.NewInMemoryMetadataRepository()
+	var mtd metadata.ApplicationMetadata
+	yaml.Unmarshal([]byte(createValidPayload()), &mtd)
+	mtd.ApplicationID = "appID1"
+	im.Create("appID1", &mtd)
+	mh := NewMetadataHandler(im)
+
+	request, _ := http.NewRequest("DELETE", "app-metadata/appID1", strings.NewReader(""))
+	request = mux.SetURLVars(request, map[string]string{"appID": "appID1"})
+	responseRecorder := httptest.NewRecorder()
+	mh.HandleDeleteMetadata(responseRecorder, request)
+	assert.Equal(t, http.StatusNoContent, responseRecorder.Code)
+
+	// deleted applications are excluded from GET
+	request, _ = http.NewRequest("GET", "app-metadata/appID1", strings.NewReader(""))
+	request = mux.SetURLVars(request, map[string]string{"appID": "appID1"})
+	responseRecorder = httptest.NewRecorder()
+	mh.HandleGetMetadata(responseRecorder, request)
+	assert.Equal(t, http.StatusNotFound, responseRecorder.Code)
+
+	// and listed in the trash
+	request, _ = http.NewRequest("GET", "app-metadata?deleted=true", strings.NewReader(""))
+	responseRecorder = httptest.NewRecorder()
+	mh.HandleGetAllMetadata(responseRecorder, request)
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	var mtds []metadata.ApplicationMetadata
+	yaml.NewDecoder(responseRecorder.Body).Decode(&mtds)
+	assert.Equal(t, 1, len(mtds))
+	assert.NotNil(t, mtds[0].DeletedAt)
+
+	request, _ = http.NewRequest("POST", "app-metadata/appID1:undelete", strings.NewReader(""))
+	request = mux.SetURLVars(request, map[string]string{"appID": "appID1"})
+	responseRecorder = httptest.NewRecorder()
+	mh.HandleUndeleteMetadata(responseRecorder, request)
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+
+	res, _ := im.Get("appID1")
+	assert.NotNil(t, res)
+	assert.Nil(t, res.DeletedAt)
+}
+
+func TestMetadataHandler_HandleUndeleteMetadata_ResultedNotFound(t *testing.T) {
+
+	request, _ := http.NewRequest("POST", "app-metadata/appID1:undelete", strings.NewReader(""))
+	request = mux.SetURLVars(request, map[string]string{"appID": "appID1"})
+	responseRecorder := httptest.NewRecorder()
+
+	mh := NewMetadataHandler(repository.NewInMemoryMetadataRepository())
+	mh.HandleUndeleteMetadata(responseRecorder, request)
+
+	assert.Equal(t, http.StatusNotFound, responseRecorder.Code)
+}
+
+func TestMetadataHandler_HandlePurgeMetadata_ResultedForbidden(t *testing.T) {
+
+	im := repository.NewInMemoryMetadataRepository()
+	im.Create("appID1", &metadata.ApplicationMetadata{ApplicationID: "appID1"})
+	mh := NewMetadataHandler(im)
+	mh.AdminToken = "secret"
+
+	request, _ := http.NewRequest("POST", "app-metadata/appID1:purge", strings.NewReader(""))
+	request = mux.SetURLVars(request, map[string]string{"appID": "appID1"})
+	request.Header.Set(AdminTokenHeader, "wrong")
+	responseRecorder := httptest.NewRecorder()
+	mh.HandlePurgeMetadata(responseRecorder, request)
+
+	assert.Equal(t, http.StatusForbidden, responseRecorder.Code) // 403
+}
+
+func TestMetadataHandler_HandlePurgeMetadata_ResultedNoContent(t *testing.T) {
+
+	im := repository.NewInMemoryMetadataRepository()
+	im.Create("appID1", &metadata.ApplicationMetadata{ApplicationID: "appID1"})
+	im.Delete("appID1")
+	mh := NewMetadataHandler(im)
+	mh.AdminToken = "secret"
+
+	request, _ := http.NewRequest("POST", "app-metadata/appID1:purge", strings.NewReader(""))
+	request = mux.SetURLVars(request, map[string]string{"appID": "appID1"})
+	request.Header.Set(AdminTokenHeader, "secret")
+	responseRecorder := httptest.NewRecorder()
+	mh.HandlePurgeMetadata(responseRecorder, request)
+
+	assert.Equal(t, http.StatusNoContent, responseRecorder.Code)
+	assert.Equal(t, repository.ErrIDNotFound, im.Undelete("appID1"))
+}
+
+func TestInMemoryMetadataRepository_PurgeDeleted_ResultedPurged(t *testing.T) {
+
+	im := repository.NewInMemoryMetadataRepository()
+	im.Create("appID1", &metadata.ApplicationMetadata{ApplicationID: "appID1"})
+	im.Create("appID2", &metadata.ApplicationMetadata{ApplicationID: "appID2"})
+	im.Delete("appID1")
+
+	n, _ := im.PurgeDeleted(time.Now().Add(-time.Hour))
+	assert.Equal(t, 0, n)
+	n, _ = im.PurgeDeleted(time.Now().Add(time.Second))
+	assert.Equal(t, 1, n)
+
+	res, _ := im.Get("appID2")
+	assert.NotNil(t, res)
+}
@@ -0,0 +1,513 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/elumbantoruan/app-metadata/dedupe"
+	"github.com/elumbantoruan/app-metadata/labels"
+	"github.com/elumbantoruan/app-metadata/lifecycle"
+	"github.com/elumbantoruan/app-metadata/markdown"
+	"github.com/elumbantoruan/app-metadata/metadata"
+	"github.com/elumbantoruan/app-metadata/repository"
+	"github.com/elumbantoruan/app-metadata/ui"
+	"github.com/gorilla/mux"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// UIPageSize is the number of applications listed per page of the catalog browser
+const UIPageSize = 50
+
+// CSRFCookie holds the token the forms of the catalog browser must post back, so that other sites can't submit them
+const CSRFCookie = "app_metadata_csrf"
+
+// uiForm holds the fields of the create and edit forms as typed
+type uiForm struct {
+	ApplicationID string
+	Title         string
+	Version       string
+	Maintainers   string
+	Company       string
+	Website       string
+	Source        string
+	License       string
+	Labels        string
+	Dependencies  string
+	Description   string
+}
+
+// HandleUIList handles GET operation listing the applications in the catalog browser, with the q search and the company,
+// state, and labelSelector filters
+func (mh *MetadataHandler) HandleUIList(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	q := r.URL.Query()
+	data := struct {
+		Q, Company, State, LabelSelector string
+		Companies, States                []string
+		Apps                             []metadata.ApplicationMetadata
+		Error, Next                      string
+	}{
+		Q:             strings.TrimSpace(q.Get("q")),
+		Company:       q.Get("company"),
+		State:         q.Get("state"),
+		LabelSelector: q.Get("labelSelector"),
+		States:        append([]string{"all"}, lifecycle.States...),
+	}
+	if data.State == "" {
+		data.State = lifecycle.Published
+	}
+
+	query := repository.Query{Limit: UIPageSize + 1}
+	var err error
+	if query.States, err = lifecycle.ParseStates(data.State); err != nil {
+		data.Error = err.Error()
+	}
+	if query.Selector, err = labels.Parse(data.LabelSelector); err != nil {
+		data.Error = err.Error()
+	}
+	if c := q.Get("continue"); c != "" {
+		after, _ := base64.RawURLEncoding.DecodeString(c)
+		query.After = string(after)
+	}
+	search := strings.ToLower(metadata.NormalizeText(data.Q))
+	query.Match = func(app *metadata.ApplicationMetadata) bool {
+		if data.Company != "" && !strings.EqualFold(app.Company, data.Company) {
+			return false
+		}
+		return search == "" || strings.Contains(searchText(app), search)
+	}
+
+	if data.Error == "" {
+		if data.Apps, err = mh.Repository.List(query); err != nil {
+			mh.renderUIError(w, http.StatusInternalServerError, err.Error()) // 500
+			return
+		}
+		if len(data.Apps) > UIPageSize {
+			data.Apps = data.Apps[:UIPageSize]
+			next := url.Values{}
+			for k, v := range q {
+				next[k] = v
+			}
+			next.Set("continue", base64.RawURLEncoding.EncodeToString([]byte(data.Apps[UIPageSize-1].ApplicationID)))
+			data.Next = ui.Prefix + "/?" + next.Encode()
+		}
+	}
+	if data.Companies, err = mh.companies(); err != nil {
+		mh.renderUIError(w, http.StatusInternalServerError, err.Error()) // 500
+		return
+	}
+	status := http.StatusOK // 200
+	if data.Error != "" {
+		status = http.StatusBadRequest // 400
+	}
+	ui.Render(w, status, "list.html", data)
+}
+
+// searchText returns the text the q search of the catalog browser matches, in lowercase
+func searchText(app *metadata.ApplicationMetadata) string {
+	parts := []string{app.ApplicationID, app.Title, app.Company, app.Description}
+	for _, m := range app.Maintainers {
+		parts = append(parts, m.Name, m.Email)
+	}
+	return strings.ToLower(strings.Join(parts, "\n"))
+}
+
+// companies returns the companies of the live applications, sorted
+func (mh *MetadataHandler) companies() ([]string, error) {
+	seen := make(map[string]bool)
+	err := mh.Repository.ForEach(func(app *metadata.ApplicationMetadata) error {
+		if app.Company != "" {
+			seen[app.Company] = true
+		}
+		return nil
+	})
+	res := make([]string, 0, len(seen))
+	for c := range seen {
+		res = append(res, c)
+	}
+	sort.Strings(res)
+	return res, err
+}
+
+// HandleUIApplication handles GET operation showing an application in the catalog browser, with its description
+// rendered and its maintainers
+func (mh *MetadataHandler) HandleUIApplication(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	app, ok := mh.uiApplication(w, r)
+	if !ok {
+		return
+	}
+	broken := make(map[string]bool)
+	if mh.Links != nil {
+		for _, u := range []string{app.Website, app.Source} {
+			if status, err := mh.Links.GetLink(u); err == nil && status != nil && status.Broken {
+				broken[u] = true
+			}
+		}
+	}
+	// the Warning headers of the write aren't shown by browsers, so the near-duplicates are listed on the page
+	duplicates, err := mh.findDuplicates(app)
+	if err != nil {
+		mh.renderUIError(w, http.StatusInternalServerError, err.Error()) // 500
+		return
+	}
+	ui.Render(w, http.StatusOK, "detail.html", struct {
+		App         *metadata.ApplicationMetadata
+		Description template.HTML
+		Broken      map[string]bool
+		Duplicates  []dedupe.Match
+	}{
+		App: app,
+		// the renderer escapes raw HTML and drops unsafe links
+		Description: template.HTML(markdown.Render(app.Description).HTML),
+		Broken:      broken,
+		Duplicates:  duplicates,
+	})
+}
+
+// uiApplication returns the application of the id path variable.
+// It redirects an alias to its application, or writes 404 when it isn't found, and returns false.
+func (mh *MetadataHandler) uiApplication(w http.ResponseWriter, r *http.Request) (*metadata.ApplicationMetadata, bool) {
+	id := mux.Vars(r)["id"]
+	app, err := mh.Repository.Get(id)
+	if err != nil {
+		mh.renderUIError(w, http.StatusInternalServerError, err.Error()) // 500
+		return nil, false
+	}
+	if app != nil {
+		return app, true
+	}
+	if canonical, err := mh.Repository.ResolveAlias(id); err == nil && canonical != "" {
+		u := *r.URL
+		u.Path = strings.Replace(u.Path, "/apps/"+id, "/apps/"+canonical, 1)
+		u.RawPath = ""
+		http.Redirect(w, r, u.String(), http.StatusMovedPermanently) // 301
+		return nil, false
+	}
+	mh.renderUIError(w, http.StatusNotFound, fmt.Sprintf("application %s is not found", id)) // 404
+	return nil, false
+}
+
+// HandleUINewForm handles GET operation showing the form creating an application
+func (mh *MetadataHandler) HandleUINewForm(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	mh.renderUIForm(w, r, http.StatusOK, "", uiForm{}, nil)
+}
+
+// HandleUIEditForm handles GET operation showing the form editing an application
+func (mh *MetadataHandler) HandleUIEditForm(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	app, ok := mh.uiApplication(w, r)
+	if !ok {
+		return
+	}
+	mh.renderUIForm(w, r, http.StatusOK, app.ApplicationID, formFromApplication(app), nil)
+}
+
+// HandleUICreate handles POST operation of the form creating an application.
+// The application goes through the same validation and checks as POST /app-metadata, and the form is shown again
+// with every problem next to its field until it's accepted.
+func (mh *MetadataHandler) HandleUICreate(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	form, ok := mh.parseUIForm(w, r)
+	if !ok {
+		return
+	}
+	app, problems := form.application()
+	if len(problems) > 0 {
+		mh.renderUIForm(w, r, http.StatusBadRequest, "", form, problems) // 400
+		return
+	}
+	status, header, body := dispatch(r, mh.HandlePostMetadata, nil, app)
+	if status != http.StatusCreated {
+		mh.renderUIForm(w, r, status, "", form, map[string][]string{"": {apiError(body)}})
+		return
+	}
+	var created metadata.ApplicationMetadata
+	yaml.Unmarshal(body, &created)
+	copyWarnings(w, header)
+	http.Redirect(w, r, ui.Prefix+"/apps/"+url.PathEscape(created.ApplicationID), http.StatusSeeOther) // 303
+}
+
+// HandleUIUpdate handles POST operation of the form editing an application, going through PUT /app-metadata/{appID}.
+// The extensions of the application are kept since the form doesn't show them, taken from the live application under
+// the repository lock so that an extension change in between isn't lost.
+func (mh *MetadataHandler) HandleUIUpdate(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	existing, ok := mh.uiApplication(w, r)
+	if !ok {
+		return
+	}
+	form, ok := mh.parseUIForm(w, r)
+	if !ok {
+		return
+	}
+	form.ApplicationID = existing.ApplicationID
+	app, problems := form.application()
+	if len(problems) > 0 {
+		mh.renderUIForm(w, r, http.StatusBadRequest, existing.ApplicationID, form, problems) // 400
+		return
+	}
+	put := func(w http.ResponseWriter, r *http.Request) { mh.putMetadata(w, r, true) }
+	status, header, body := dispatch(r, put, map[string]string{"appID": existing.ApplicationID}, app)
+	if status != http.StatusOK {
+		mh.renderUIForm(w, r, status, existing.ApplicationID, form, map[string][]string{"": {apiError(body)}})
+		return
+	}
+	copyWarnings(w, header)
+	http.Redirect(w, r, ui.Prefix+"/apps/"+url.PathEscape(existing.ApplicationID), http.StatusSeeOther) // 303
+}
+
+// parseUIForm parses a posted form once its CSRF token is checked.
+// It writes 403 when the token doesn't match the cookie, or 400 when the form can't be read, and returns false.
+func (mh *MetadataHandler) parseUIForm(w http.ResponseWriter, r *http.Request) (uiForm, bool) {
+	if mh.MaxBodySize > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, mh.MaxBodySize)
+	}
+	if err := r.ParseForm(); err != nil {
+		status := http.StatusBadRequest // 400
+		if isBodyTooLarge(err) {
+			status = http.StatusRequestEntityTooLarge // 413
+		}
+		mh.renderUIError(w, status, err.Error())
+		return uiForm{}, false
+	}
+	cookie, err := r.Cookie(CSRFCookie)
+	if err != nil || cookie.Value == "" || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(r.PostForm.Get("csrf"))) != 1 {
+		mh.renderUIError(w, http.StatusForbidden, "the form expired, reload the page and submit it again") // 403
+		return uiForm{}, false
+	}
+	f := r.PostForm
+	return uiForm{
+		ApplicationID: strings.TrimSpace(f.Get("applicationID")),
+		Title:         strings.TrimSpace(f.Get("title")),
+		Version:       strings.TrimSpace(f.Get("version")),
+		Maintainers:   f.Get("maintainers"),
+		Company:       strings.TrimSpace(f.Get("company")),
+		Website:       strings.TrimSpace(f.Get("website")),
+		Source:        strings.TrimSpace(f.Get("source")),
+		License:       strings.TrimSpace(f.Get("license")),
+		Labels:        f.Get("labels"),
+		Dependencies:  f.Get("dependencies"),
+		// browsers post line breaks as CRLF
+		Description: strings.ReplaceAll(f.Get("description"), "\r\n", "\n"),
+	}, true
+}
+
+// application returns the application of the form in canonical form, with every problem of the form and of
+// ApplicationMetadata.Validate keyed by field
+func (f uiForm) application() (*metadata.ApplicationMetadata, map[string][]string) {
+	problems := make(map[string][]string)
+	app := &metadata.ApplicationMetadata{
+		ApplicationID: f.ApplicationID,
+		Title:         f.Title,
+		Version:       f.Version,
+		Company:       f.Company,
+		Website:       f.Website,
+		Source:        f.Source,
+		License:       f.License,
+		Description:   f.Description,
+	}
+	for _, line := range formLines(f.Maintainers) {
+		m := metadata.Maintainer{Email: line}
+		if i := strings.LastIndex(line, "<"); i >= 0 && strings.HasSuffix(line, ">") {
+			m.Name, m.Email = strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:len(line)-1])
+		}
+		app.Maintainers = append(app.Maintainers, m)
+	}
+	for _, line := range formLines(f.Labels) {
+		k, v, ok := strings.Cut(line, "=")
+		if !ok {
+			problems["labels"] = append(problems["labels"], fmt.Sprintf("%s isn't a key=value label", line))
+			continue
+		}
+		if app.Labels == nil {
+			app.Labels = make(map[string]string)
+		}
+		app.Labels[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	for _, line := range formLines(f.Dependencies) {
+		id, version, _ := strings.Cut(line, " ")
+		app.Dependencies = append(app.Dependencies, metadata.Dependency{ApplicationID: id, Version: strings.TrimSpace(version)})
+	}
+	if app.ApplicationID != "" {
+		if err := metadata.ValidateSlug(app.ApplicationID); err != nil {
+			problems["applicationID"] = append(problems["applicationID"], err.Error())
+		}
+	}
+
+	app.Normalize()
+	for _, p := range app.Validate() {
+		problems[p.Field] = append(problems[p.Field], p.Description)
+	}
+	if len(problems) == 0 {
+		return app, nil
+	}
+	return app, problems
+}
+
+// formFromApplication returns the form editing app
+func formFromApplication(app *metadata.ApplicationMetadata) uiForm {
+	var maintainers, labelLines, dependencies []string
+	for _, m := range app.Maintainers {
+		maintainers = append(maintainers, strings.TrimSpace(m.Name+" <"+m.Email+">"))
+	}
+	for k, v := range app.Labels {
+		labelLines = append(labelLines, k+"="+v)
+	}
+	sort.Strings(labelLines)
+	for _, d := range app.Dependencies {
+		dependencies = append(dependencies, strings.TrimSpace(d.ApplicationID+" "+d.Version))
+	}
+	return uiForm{
+		ApplicationID: app.ApplicationID,
+		Title:         app.Title,
+		Version:       app.Version,
+		Maintainers:   strings.Join(maintainers, "\n"),
+		Company:       app.Company,
+		Website:       app.Website,
+		Source:        app.Source,
+		License:       app.License,
+		Labels:        strings.Join(labelLines, "\n"),
+		Dependencies:  strings.Join(dependencies, "\n"),
+		Description:   app.Description,
+	}
+}
+
+// formLines returns the non blank lines of a textarea, trimmed
+func formLines(s string) []string {
+	var res []string
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			res = append(res, line)
+		}
+	}
+	return res
+}
+
+// renderUIForm writes the create form, or the edit form of appID, with the problems keyed by field, the empty field
+// holding those about the whole application
+func (mh *MetadataHandler) renderUIForm(w http.ResponseWriter, r *http.Request, status int, appID string, form uiForm, problems map[string][]string) {
+	data := struct {
+		New            bool
+		Form           uiForm
+		Errors         map[string][]string
+		CSRF           string
+		Action, Cancel string
+	}{
+		New:    appID == "",
+		Form:   form,
+		Errors: problems,
+		CSRF:   csrfToken(w, r),
+		Action: ui.Prefix + "/new",
+		Cancel: ui.Prefix + "/",
+	}
+	if appID != "" {
+		data.Action = ui.Prefix + "/apps/" + url.PathEscape(appID) + "/edit"
+		data.Cancel = ui.Prefix + "/apps/" + url.PathEscape(appID)
+	}
+	ui.Render(w, status, "form.html", data)
+}
+
+func (mh *MetadataHandler) renderUIError(w http.ResponseWriter, status int, message string) {
+	ui.Render(w, status, "error.html", struct{ Title, Message string }{http.StatusText(status), message})
+}
+
+// csrfToken returns the token of the CSRF cookie, setting a new one when the request doesn't carry it
+func csrfToken(w http.ResponseWriter, r *http.Request) string {
+	if cookie, err := r.Cookie(CSRFCookie); err == nil && cookie.Value != "" {
+		return cookie.Value
+	}
+	b := make([]byte, 32)
+	rand.Read(b)
+	token := base64.RawURLEncoding.EncodeToString(b)
+	http.SetCookie(w, &http.Cookie{
+		Name:     CSRFCookie,
+		Value:    token,
+		Path:     ui.Prefix,
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	})
+	return token
+}
+
+// dispatch applies app through an API handler as the yaml body of a copy of r, so that a write of the catalog browser
+// goes through the same checks and audit as the API.  It returns the status, the header, and the body of the response.
+func dispatch(r *http.Request, h http.HandlerFunc, vars map[string]string, app *metadata.ApplicationMetadata) (int, http.Header, []byte) {
+	b, err := yaml.Marshal(app)
+	if err != nil {
+		return http.StatusInternalServerError, nil, []byte(err.Error())
+	}
+	req := r.Clone(r.Context())
+	req.Body = ioutil.NopCloser(bytes.NewReader(b))
+	req.ContentLength = int64(len(b))
+	req.Header.Set("Content-Type", mediaTypes[formatYAML])
+	req.Header.Del(IdempotencyKeyHeader)
+	if vars != nil {
+		req = mux.SetURLVars(req, vars)
+	}
+	res := &capturedResponse{header: make(http.Header)}
+	h(res, req)
+	if res.status == 0 {
+		res.status = http.StatusOK
+	}
+	return res.status, res.header, res.body.Bytes()
+}
+
+// copyWarnings adds the Warning headers of a dispatched response, such as near-duplicates, to w
+func copyWarnings(w http.ResponseWriter, header http.Header) {
+	for _, v := range header.Values("Warning") {
+		w.Header().Add("Warning", v)
+	}
+}
+
+// capturedResponse records the response of a dispatched request
+type capturedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (c *capturedResponse) Header() http.Header {
+	return c.header
+}
+
+func (c *capturedResponse) WriteHeader(status int) {
+	if c.status == 0 {
+		c.status = status
+	}
+}
+
+func (c *capturedResponse) Write(b []byte) (int, error) {
+	c.WriteHeader(http.StatusOK)
+	return c.body.Write(b)
+}
+
+// apiError returns the message of an API error body, a ValidationMessage or a string
+func apiError(body []byte) string {
+	var vm metadata.ValidationMessage
+	if err := yaml.Unmarshal(body, &vm); err == nil && vm.Description != "" {
+		return vm.Description
+	}
+	var s string
+	if err := yaml.Unmarshal(body, &s); err == nil && s != "" {
+		return s
+	}
+	return strings.TrimSpace(string(body))
+}
@@ -0,0 +1,262 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/elumbantoruan/app-metadata/lifecycle"
+	"github.com/elumbantoruan/app-metadata/metadata"
+	"github.com/elumbantoruan/app-metadata/repository"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func newUIRouter(mh *MetadataHandler) *mux.Router {
+	m := mux.NewRouter()
+	m.HandleFunc("/ui/", mh.HandleUIList).Methods("GET")
+	m.HandleFunc("/ui/new", mh.HandleUINewForm).Methods("GET")
+	m.HandleFunc("/ui/new", mh.HandleUICreate).Methods("POST")
+	m.HandleFunc("/ui/apps/{id}", mh.HandleUIApplication).Methods("GET")
+	m.HandleFunc("/ui/apps/{id}/edit", mh.HandleUIEditForm).Methods("GET")
+	m.HandleFunc("/ui/apps/{id}/edit", mh.HandleUIUpdate).Methods("POST")
+	return m
+}
+
+// postUIForm posts form with the CSRF token of csrf, both as cookie and field, unless it's empty
+func postUIForm(m http.Handler, path, csrf string, form url.Values) *httptest.ResponseRecorder {
+	if csrf != "" {
+		form.Set("csrf", csrf)
+	}
+	request, _ := http.NewRequest("POST", path, strings.NewReader(form.Encode()))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if csrf != "" {
+		request.AddCookie(&http.Cookie{Name: CSRFCookie, Value: csrf})
+	}
+	responseRecorder := httptest.NewRecorder()
+	m.ServeHTTP(responseRecorder, request)
+	return responseRecorder
+}
+
+func getUI(m http.Handler, path string) *httptest.ResponseRecorder {
+	request, _ := http.NewRequest("GET", path, strings.NewReader(""))
+	responseRecorder := httptest.NewRecorder()
+	m.ServeHTTP(responseRecorder, request)
+	return responseRecorder
+}
+
+func TestMetadataHandler_HandleUIList_ResultedFiltered(t *testing.T) {
+
+	im := repository.NewInMemoryMetadataRepository()
+	im.Create("payments", &metadata.ApplicationMetadata{ApplicationID: "payments", Title: "Payments", Company: "Acme",
+		State: lifecycle.Published, Description: "Handles card payments",
+		Maintainers: []metadata.Maintainer{{Name: "Jane Doe", Email: "jane@acme.com"}}})
+	im.Create("ledger", &metadata.ApplicationMetadata{ApplicationID: "ledger", Title: "Ledger", Company: "Globex", State: lifecycle.Published})
+	im.Create("draft", &metadata.ApplicationMetadata{ApplicationID: "draft", Title: "Draft App", Company: "Acme", State: lifecycle.Draft})
+	m := newUIRouter(NewMetadataHandler(im))
+
+	responseRecorder := getUI(m, "/ui/")
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	assert.Equal(t, "text/html; charset=utf-8", responseRecorder.Header().Get("Content-Type"))
+	body := responseRecorder.Body.String()
+	assert.Contains(t, body, `href="/ui/apps/payments"`)
+	assert.Contains(t, body, `href="/ui/apps/ledger"`)
+	assert.NotContains(t, body, `href="/ui/apps/draft"`)
+	assert.Contains(t, body, "<option>Globex</option>")
+
+	body = getUI(m, "/ui/?q=JANE").Body.String()
+	assert.Contains(t, body, `href="/ui/apps/payments"`)
+	assert.NotContains(t, body, `href="/ui/apps/ledger"`)
+
+	body = getUI(m, "/ui/?company=acme&state=all").Body.String()
+	assert.Contains(t, body, `href="/ui/apps/payments"`)
+	assert.Contains(t, body, `href="/ui/apps/draft"`)
+	assert.NotContains(t, body, `href="/ui/apps/ledger"`)
+
+	responseRecorder = getUI(m, "/ui/?labelSelector=tier+in+(")
+	assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+	assert.Contains(t, responseRecorder.Body.String(), `class="error"`)
+}
+
+func TestMetadataHandler_HandleUIApplication_ResultedRendered(t *testing.T) {
+
+	im := repository.NewInMemoryMetadataRepository()
+	im.Create("payments", &metadata.ApplicationMetadata{ApplicationID: "payments", Title: "Payments <b>",
+		Maintainers: []metadata.Maintainer{{Name: "Jane Doe", Email: "jane@acme.com"}},
+		Description: "### Overview\n<script>alert(1)</script> [x](javascript:alert(1))"})
+	im.AddAlias("payments", "pay")
+	m := newUIRouter(NewMetadataHandler(im))
+
+	responseRecorder := getUI(m, "/ui/apps/payments")
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	body := responseRecorder.Body.String()
+	assert.Contains(t, body, "Payments &lt;b&gt;")
+	assert.Contains(t, body, `<h3 id="overview">Overview</h3>`)
+	assert.Contains(t, body, "&lt;script&gt;alert(1)&lt;/script&gt; x")
+	assert.NotContains(t, body, "<script>")
+	assert.NotContains(t, body, "javascript:")
+	assert.Contains(t, body, `<a href="mailto:jane@acme.com">jane@acme.com</a>`)
+
+	responseRecorder = getUI(m, "/ui/apps/pay")
+	assert.Equal(t, http.StatusMovedPermanently, responseRecorder.Code)
+	assert.Equal(t, "/ui/apps/payments", responseRecorder.Header().Get("Location"))
+
+	assert.Equal(t, http.StatusNotFound, getUI(m, "/ui/apps/missing").Code)
+}
+
+func TestMetadataHandler_HandleUICreate_ResultedValidated(t *testing.T) {
+
+	mh := NewMetadataHandler(repository.NewInMemoryMetadataRepository())
+	m := newUIRouter(mh)
+
+	responseRecorder := getUI(m, "/ui/new")
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	cookies := responseRecorder.Result().Cookies()
+	assert.Len(t, cookies, 1)
+	csrf := cookies[0].Value
+	assert.Contains(t, responseRecorder.Body.String(), `name="csrf" value="`+csrf+`"`)
+
+	form := url.Values{
+		"applicationID": {"Payments API"},
+		"title":         {"Payments"},
+		"maintainers":   {"Jane Doe <jane@acme.com>\nnot an email"},
+		"website":       {"ftp://acme.com"},
+		"labels":        {"tier"},
+		"description":   {"<iframe src=x>"},
+	}
+	assert.Equal(t, http.StatusForbidden, postUIForm(m, "/ui/new", "", form).Code)
+	assert.Equal(t, http.StatusForbidden, postUIForm(m, "/ui/new", "", url.Values{"csrf": {"forged"}}).Code)
+
+	// every problem is shown at once, with the values typed
+	responseRecorder = postUIForm(m, "/ui/new", csrf, form)
+	assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+	body := responseRecorder.Body.String()
+	for _, problem := range []string{
+		"version is empty",
+		"not an email is not a valid email address",
+		"website: ftp://acme.com is not an absolute http or https URL",
+		"tier isn&#39;t a key=value label",
+		"raw HTML &lt;iframe src=x&gt; is not allowed in description",
+		"Payments API",
+	} {
+		assert.Contains(t, body, problem)
+	}
+	assert.Contains(t, body, `value="Payments"`)
+	apps, _ := mh.Repository.GetAll()
+	assert.Len(t, apps, 0)
+
+	form = url.Values{
+		"applicationID": {"payments-api"},
+		"title":         {"Payments"},
+		"version":       {"1.0.0"},
+		"maintainers":   {"Jane Doe <jane@acme.com>"},
+		"labels":        {"tier=1"},
+		"description":   {"### Overview\r\nHandles payments"},
+	}
+	responseRecorder = postUIForm(m, "/ui/new", csrf, form)
+	assert.Equal(t, http.StatusSeeOther, responseRecorder.Code)
+	assert.Equal(t, "/ui/apps/payments-api", responseRecorder.Header().Get("Location"))
+	app, _ := mh.Repository.Get("payments-api")
+	assert.Equal(t, lifecycle.Draft, app.State)
+	assert.Equal(t, "Jane Doe", app.Maintainers[0].Name)
+	assert.Equal(t, "### Overview\nHandles payments", app.Description)
+	assert.Equal(t, map[string]string{"tier": "1"}, app.Labels)
+
+	// problems found by the API are shown above the form
+	responseRecorder = postUIForm(m, "/ui/new", csrf, form)
+	assert.Equal(t, http.StatusConflict, responseRecorder.Code)
+	assert.Contains(t, responseRecorder.Body.String(), `<p class="error">`)
+}
+
+func TestMetadataHandler_HandleUIUpdate_ResultedUpdated(t *testing.T) {
+
+	im := repository.NewInMemoryMetadataRepository()
+	im.Create("payments", &metadata.ApplicationMetadata{ApplicationID: "payments", Title: "Payments", Version: "1.0.0",
+		Maintainers: []metadata.Maintainer{{Name: "Jane Doe", Email: "jane@acme.com"}},
+		Labels:      map[string]string{"tier": "1", "team": "payments"},
+		Extensions:  metadata.Extensions{"oncall": map[string]interface{}{"rotation": "weekly"}}})
+	mh := NewMetadataHandler(im)
+	mh.AdminToken = "secret"
+	putSchema(mh, "oncall", oncallSchema, "secret")
+	m := newUIRouter(mh)
+
+	responseRecorder := getUI(m, "/ui/apps/payments/edit")
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	body := responseRecorder.Body.String()
+	assert.Contains(t, body, "Jane Doe &lt;jane@acme.com&gt;")
+	assert.Contains(t, body, "team=payments\ntier=1")
+	csrf := responseRecorder.Result().Cookies()[0].Value
+
+	form := url.Values{"title": {"Payments"}, "version": {""}, "maintainers": {"Jane Doe <jane@acme.com>"}}
+	responseRecorder = postUIForm(m, "/ui/apps/payments/edit", csrf, form)
+	assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+	assert.Contains(t, responseRecorder.Body.String(), "version is empty")
+
+	form.Set("version", "1.1.0")
+	responseRecorder = postUIForm(m, "/ui/apps/payments/edit", csrf, form)
+	assert.Equal(t, http.StatusSeeOther, responseRecorder.Code)
+	app, _ := im.Get("payments")
+	assert.Equal(t, "1.1.0", app.Version)
+	assert.Equal(t, 2, app.Revision)
+	assert.Nil(t, app.Labels)
+	assert.Equal(t, "weekly", app.Extensions["oncall"].(map[string]interface{})["rotation"])
+}
+
+// interleavedRepository runs before ahead of the first Modify, as a write landing after a handler read the application
+type interleavedRepository struct {
+	repository.MetadataRepository
+	before func()
+}
+
+func (ir *interleavedRepository) Modify(appID string, fn func(data *metadata.ApplicationMetadata) error) (*repository.Modification, error) {
+	if ir.before != nil {
+		ir.before()
+		ir.before = nil
+	}
+	return ir.MetadataRepository.Modify(appID, fn)
+}
+
+func TestMetadataHandler_HandleUIUpdateInterleavedExtensions_ResultedExtensionsKept(t *testing.T) {
+
+	im := repository.NewInMemoryMetadataRepository()
+	im.Create("payments", &metadata.ApplicationMetadata{ApplicationID: "payments", Title: "Payments", Version: "1.0.0",
+		Maintainers: []metadata.Maintainer{{Name: "Jane Doe", Email: "jane@acme.com"}},
+		Extensions:  metadata.Extensions{"oncall": map[string]interface{}{"rotation": "weekly"}}})
+	ir := &interleavedRepository{MetadataRepository: im}
+	m := newUIRouter(NewMetadataHandler(ir))
+	csrf := getUI(m, "/ui/apps/payments/edit").Result().Cookies()[0].Value
+
+	ir.before = func() {
+		im.Modify("payments", func(app *metadata.ApplicationMetadata) error {
+			app.Extensions = metadata.Extensions{"oncall": map[string]interface{}{"rotation": "daily"}}
+			return nil
+		})
+	}
+	form := url.Values{"title": {"Payments API"}, "version": {"1.0.0"}, "maintainers": {"Jane Doe <jane@acme.com>"}}
+	responseRecorder := postUIForm(m, "/ui/apps/payments/edit", csrf, form)
+	assert.Equal(t, http.StatusSeeOther, responseRecorder.Code)
+
+	// the edit carries over the extensions changed in between
+	app, _ := im.Get("payments")
+	assert.Equal(t, "Payments API", app.Title)
+	assert.Equal(t, "daily", app.Extensions["oncall"].(map[string]interface{})["rotation"])
+}
+
+func TestMetadataHandler_HandleUICreateDuplicate_ResultedWarning(t *testing.T) {
+
+	im := repository.NewInMemoryMetadataRepository()
+	im.Create("payments", &metadata.ApplicationMetadata{ApplicationID: "payments", Title: "Payments API"})
+	m := newUIRouter(NewMetadataHandler(im))
+
+	responseRecorder := getUI(m, "/ui/new")
+	csrf := responseRecorder.Result().Cookies()[0].Value
+	form := url.Values{"applicationID": {"payment-api"}, "title": {"Payment API"}, "version": {"1.0.0"}, "maintainers": {"Jane Doe <jane@acme.com>"}}
+	responseRecorder = postUIForm(m, "/ui/new", csrf, form)
+	assert.Equal(t, http.StatusSeeOther, responseRecorder.Code)
+	assert.Contains(t, responseRecorder.Header().Get("Warning"), "possible duplicate of payments")
+
+	body := getUI(m, "/ui/apps/payment-api").Body.String()
+	assert.Contains(t, body, `Possible duplicate of <a href="/ui/apps/payments">Payments API</a> (similar title)`)
+}
@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/elumbantoruan/app-metadata/audit"
+	"github.com/elumbantoruan/app-metadata/metadata"
+	"github.com/elumbantoruan/app-metadata/verification"
+	"github.com/gorilla/mux"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// validateEmailPolicy checks the maintainers of payload against EmailPolicy.
+// It writes 400 when an email breaks it and returns false.
+func (mh *MetadataHandler) validateEmailPolicy(w http.ResponseWriter, payload *metadata.ApplicationMetadata) bool {
+	if err := mh.checkEmailPolicy(payload); err != nil {
+		w.WriteHeader(http.StatusBadRequest) // 400
+		yaml.NewEncoder(w).Encode(metadata.ValidationMessage{Description: err.Error()})
+		return false
+	}
+	return true
+}
+
+// checkEmailPolicy checks the maintainers of payload against EmailPolicy, when there's one
+func (mh *MetadataHandler) checkEmailPolicy(payload *metadata.ApplicationMetadata) error {
+	if mh.EmailPolicy == nil {
+		return nil
+	}
+	return mh.EmailPolicy.Check(payload)
+}
+
+// markVerification sets the verification status of the maintainers of payload when verification is enabled.
+// A maintainer keeps their status in existing, the previous content of the application, and is verified when
+// another live application lists them as verified.  It returns the emails of the other maintainers, now pending,
+// which are sent a token once the payload is written.
+func (mh *MetadataHandler) markVerification(payload, existing *metadata.ApplicationMetadata) ([]string, error) {
+	if mh.Verifier == nil || len(payload.Maintainers) == 0 {
+		return nil, nil
+	}
+	previous := make(map[string]string)
+	if existing != nil {
+		for _, m := range existing.Maintainers {
+			previous[metadata.NormalizeEmail(m.Email)] = m.Verification
+		}
+	}
+	maintainers := make([]metadata.Maintainer, len(payload.Maintainers))
+	seen := make(map[string]bool)
+	var pending []string
+	for i, m := range payload.Maintainers {
+		email := metadata.NormalizeEmail(m.Email)
+		status := previous[email]
+		if status == "" {
+			verified, err := mh.isVerified(email)
+			if err != nil {
+				return nil, err
+			}
+			status = metadata.EmailPending
+			if verified {
+				status = metadata.EmailVerified
+			} else if !seen[email] {
+				pending = append(pending, m.Email)
+			}
+		}
+		seen[email] = true
+		m.Verification = status
+		maintainers[i] = m
+	}
+	payload.Maintainers = maintainers
+	return pending, nil
+}
+
+// isVerified returns true when a live application lists the normalized email as verified
+func (mh *MetadataHandler) isVerified(email string) (bool, error) {
+	apps, err := mh.Repository.MaintainerApplications(email)
+	if err != nil {
+		return false, err
+	}
+	for _, app := range apps {
+		for _, m := range app.Maintainers {
+			if metadata.NormalizeEmail(m.Email) == email && m.Verification == metadata.EmailVerified {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// sendVerifications sends a token to each email
+func (mh *MetadataHandler) sendVerifications(emails []string) {
+	for _, email := range emails {
+		// the application is already written, the maintainer can ask for another token
+		if err := mh.Verifier.Send(email); err != nil {
+			log.Printf("verification: sending a token to %s: %v", email, err)
+		}
+	}
+}
+
+// HandleVerifyMaintainer handles GET operation confirming the email carried by the token query parameter, which
+// marks the maintainer verified in every live application listing them
+func (mh *MetadataHandler) HandleVerifyMaintainer(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	if mh.Verifier == nil {
+		w.WriteHeader(http.StatusNotImplemented) // 501
+		yaml.NewEncoder(w).Encode("email verification is not enabled")
+		return
+	}
+	// it's a GET from the emailed link, but it writes
+	if mh.rejectReadOnly(w) {
+		return
+	}
+	email, err := mh.Verifier.Signer.Parse(r.URL.Query().Get("token"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest) // 400
+		yaml.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	found, err := mh.updateMaintainer(r, email, false, func(m *metadata.Maintainer) bool {
+		if m.Verification == metadata.EmailVerified {
+			return false
+		}
+		m.Verification = metadata.EmailVerified
+		return true
+	})
+	if !mh.checkLockError(w, r, audit.Update, err) {
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError) // 500
+		yaml.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	if !found {
+		w.WriteHeader(http.StatusNotFound) // 404
+		return
+	}
+
+	res, err := mh.Repository.GetMaintainer(email)
+	if err != nil || res == nil {
+		w.WriteHeader(http.StatusInternalServerError) // 500
+		return
+	}
+	w.WriteHeader(http.StatusOK) // 200
+	encode(w, responseFormat(r), res)
+}
+
+// HandleSendVerification handles POST operation sending another token to a pending maintainer.
+// Only the maintainer (X-User-Email header) or an admin can ask for it.
+func (mh *MetadataHandler) HandleSendVerification(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	if mh.Verifier == nil {
+		w.WriteHeader(http.StatusNotImplemented) // 501
+		yaml.NewEncoder(w).Encode("email verification is not enabled")
+		return
+	}
+	email, ok := mux.Vars(r)["email"]
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest) // 400
+		return
+	}
+	email = metadata.NormalizeEmail(email)
+	if !mh.isAdmin(r) {
+		principal := r.Header.Get(PrincipalHeader)
+		if principal == "" {
+			w.WriteHeader(http.StatusUnauthorized) // 401
+			yaml.NewEncoder(w).Encode(PrincipalHeader + " header is required")
+			return
+		}
+		if metadata.NormalizeEmail(principal) != email {
+			w.WriteHeader(http.StatusForbidden) // 403
+			yaml.NewEncoder(w).Encode("only the maintainer or an admin can ask for a verification token")
+			return
+		}
+	}
+
+	m, err := mh.Repository.GetMaintainer(email)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError) // 500
+		yaml.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	if m == nil {
+		w.WriteHeader(http.StatusNotFound) // 404
+		return
+	}
+	verified, err := mh.isVerified(email)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError) // 500
+		yaml.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	if verified {
+		w.WriteHeader(http.StatusConflict) // 409
+		yaml.NewEncoder(w).Encode(email + " is already verified")
+		return
+	}
+	if err := mh.Verifier.Send(email); err != nil {
+		if errors.Is(err, verification.ErrQueueFull) {
+			w.WriteHeader(http.StatusServiceUnavailable) // 503
+		} else {
+			w.WriteHeader(http.StatusBadGateway) // 502
+		}
+		yaml.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusAccepted) // 202
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/elumbantoruan/app-metadata/emailpolicy"
+	"github.com/elumbantoruan/app-metadata/metadata"
+	"github.com/elumbantoruan/app-metadata/repository"
+	"github.com/elumbantoruan/app-metadata/verification"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// fakeSender records the messages instead of sending them
+type fakeSender struct {
+	to     []string
+	bodies []string
+}
+
+func (fs *fakeSender) Send(to, subject, body string) error {
+	fs.to = append(fs.to, to)
+	fs.bodies = append(fs.bodies, body)
+	return nil
+}
+
+// token returns the token of the last message sent
+func (fs *fakeSender) token() string {
+	body := fs.bodies[len(fs.bodies)-1]
+	token := body[strings.Index(body, "?token=")+len("?token="):]
+	return strings.TrimSpace(token)
+}
+
+func TestMetadataHandler_HandlePostMetadataEmailPolicy_ResultedRejected(t *testing.T) {
+
+	mh := NewMetadataHandler(repository.NewInMemoryMetadataRepository())
+	mh.EmailPolicy = emailpolicy.New(map[string][]string{"pellucid Computing": {"pellucidcomputing.com"}}, emailpolicy.DefaultDisposableDomains)
+
+	request, _ := http.NewRequest("POST", "app-metadata", strings.NewReader(createValidPayload()))
+	responseRecorder := httptest.NewRecorder()
+	mh.HandlePostMetadata(responseRecorder, request)
+	assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+	assert.Contains(t, responseRecorder.Body.String(), "firstmaintainer@hotmail.com isn't allowed for company pellucid Computing")
+
+	payload := strings.Replace(createValidPayload(), "company: pellucid Computing", "company: Other", 1)
+	payload = strings.Replace(payload, "gmail.com", "yopmail.com", 1)
+	request, _ = http.NewRequest("POST", "app-metadata", strings.NewReader(payload))
+	responseRecorder = httptest.NewRecorder()
+	mh.HandlePostMetadata(responseRecorder, request)
+	assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+	assert.Contains(t, responseRecorder.Body.String(), "secondmaitainer@yopmail.com uses the disposable domain yopmail.com")
+}
+
+func TestMetadataHandler_HandleVerifyMaintainer_ResultedVerified(t *testing.T) {
+
+	im := repository.NewInMemoryMetadataRepository()
+	mh := NewMetadataHandler(im)
+	mh.DuplicatePolicy = DuplicatesOff
+	sender := &fakeSender{}
+	mh.Verifier = &verification.Verifier{
+		Signer: verification.NewSigner([]byte("secret"), 0),
+		Sender: sender,
+		URL:    "http://localhost:5000/maintainers:verify",
+	}
+
+	post := func(payload string) metadata.ApplicationMetadata {
+		request, _ := http.NewRequest("POST", "app-metadata", strings.NewReader(payload))
+		responseRecorder := httptest.NewRecorder()
+		mh.HandlePostMetadata(responseRecorder, request)
+		assert.Equal(t, http.StatusCreated, responseRecorder.Code)
+		var created metadata.ApplicationMetadata
+		yaml.NewDecoder(responseRecorder.Body).Decode(&created)
+		return created
+	}
+	verify := func(token string) *httptest.ResponseRecorder {
+		request, _ := http.NewRequest("GET", "maintainers:verify?token="+token, strings.NewReader(""))
+		responseRecorder := httptest.NewRecorder()
+		mh.HandleVerifyMaintainer(responseRecorder, request)
+		return responseRecorder
+	}
+
+	// a client can't mark itself verified
+	created := post(strings.Replace(createValidPayload(), "  email: firstmaintainer@hotmail.com", "  email: firstmaintainer@hotmail.com\n  verification: verified", 1))
+	assert.Equal(t, metadata.EmailPending, created.Maintainers[0].Verification)
+	assert.Equal(t, metadata.EmailPending, created.Maintainers[1].Verification)
+	assert.Equal(t, []string{"firstmaintainer@hotmail.com", "secondmaitainer@gmail.com"}, sender.to)
+
+	assert.Equal(t, http.StatusBadRequest, verify("bogus").Code)
+	responseRecorder := verify(sender.token())
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	app, _ := im.Get(created.ApplicationID)
+	assert.Equal(t, metadata.EmailPending, app.Maintainers[0].Verification)
+	assert.Equal(t, metadata.EmailVerified, app.Maintainers[1].Verification)
+
+	// a verified maintainer isn't sent another token when listed by another application
+	created = post(createValidPayload2())
+	assert.Equal(t, 3, len(sender.to))
+	for _, m := range created.Maintainers {
+		if m.Email == "secondmaitainer@gmail.com" {
+			assert.Equal(t, metadata.EmailVerified, m.Verification)
+		}
+	}
+
+	// nor when the application is updated
+	request, _ := http.NewRequest("PUT", "app-metadata/"+created.ApplicationID, strings.NewReader(createValidPayload2()))
+	request = mux.SetURLVars(request, map[string]string{"appID": created.ApplicationID})
+	responseRecorder = httptest.NewRecorder()
+	mh.HandlePutMetadata(responseRecorder, request)
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	assert.Equal(t, 3, len(sender.to))
+
+	send := func(email, principal string) *httptest.ResponseRecorder {
+		request, _ := http.NewRequest("POST", "maintainers/"+email+":send-verification", strings.NewReader(""))
+		request = mux.SetURLVars(request, map[string]string{"email": email})
+		request.Header.Set(PrincipalHeader, principal)
+		responseRecorder := httptest.NewRecorder()
+		mh.HandleSendVerification(responseRecorder, request)
+		return responseRecorder
+	}
+	assert.Equal(t, http.StatusForbidden, send("firstmaintainer@hotmail.com", "secondmaitainer@gmail.com").Code)
+	assert.Equal(t, http.StatusConflict, send("secondmaitainer@gmail.com", "secondmaitainer@gmail.com").Code)
+	assert.Equal(t, http.StatusAccepted, send("firstmaintainer@hotmail.com", "firstmaintainer@hotmail.com").Code)
+	assert.Equal(t, 4, len(sender.to))
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/elumbantoruan/app-metadata/events"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// DefaultHeartbeatInterval is how often a comment is sent on an idle event stream to keep the connection open
+const DefaultHeartbeatInterval = 15 * time.Second
+
+// HandleWatchMetadata handles GET operation streaming the changes of application metadata as Server-Sent Events.
+// Each event carries its resource version as id, so a client resumes with the Last-Event-ID header
+// (or resourceVersion query parameter).  labelSelector and fieldSelector query parameters filter the events.
+func (mh *MetadataHandler) HandleWatchMetadata(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	if mh.Events == nil {
+		w.WriteHeader(http.StatusNotImplemented) // 501
+		yaml.NewEncoder(w).Encode("watch is not enabled")
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError) // 500
+		yaml.NewEncoder(w).Encode("streaming is not supported")
+		return
+	}
+
+	q := r.URL.Query()
+	filter, err := events.NewFilter(q.Get("labelSelector"), q.Get("fieldSelector"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest) // 400
+		yaml.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	version := mh.Events.Version()
+	resume := r.Header.Get("Last-Event-ID")
+	if resume == "" {
+		resume = q.Get("resourceVersion")
+	}
+	if resume != "" {
+		version, err = strconv.ParseUint(resume, 10, 64)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest) // 400
+			yaml.NewEncoder(w).Encode("invalid resource version")
+			return
+		}
+	}
+	pending, changed, err := mh.Events.Since(version)
+	if err == events.ErrGone {
+		w.WriteHeader(http.StatusGone) // 410
+		yaml.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK) // 200
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(mh.HeartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		for _, e := range pending {
+			version = e.ResourceVersion
+			if !filter.Matches(e) {
+				continue
+			}
+			if err := writeEvent(w, e); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+			pending = nil
+			continue
+		case <-changed:
+		}
+
+		pending, changed, err = mh.Events.Since(version)
+		if err != nil {
+			// the watcher fell behind the log, let it reconnect and get 410
+			return
+		}
+	}
+}
+
+// writeEvent writes e in Server-Sent Events format
+func writeEvent(w http.ResponseWriter, e events.Event) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ResourceVersion, e.Type, b)
+	return err
+}
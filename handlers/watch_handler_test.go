@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/elumbantoruan/app-metadata/events"
+	"github.com/elumbantoruan/app-metadata/metadata"
+	"github.com/elumbantoruan/app-metadata/repository"
+	"github.com/stretchr/testify/assert"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestMetadataHandler_HandleWatchMetadata_ResultedEvents(t *testing.T) {
+
+	log := events.NewLog(10)
+	repo := events.NewRecordingRepository(repository.NewInMemoryMetadataRepository(), log)
+	mh := NewMetadataHandler(repo)
+	mh.Events = log
+	server := httptest.NewServer(http.HandlerFunc(mh.HandleWatchMetadata))
+	defer server.Close()
+
+	response, err := http.Get(server.URL + "?fieldSelector=type!=deleted")
+	assert.Nil(t, err)
+	defer response.Body.Close()
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, "text/event-stream", response.Header.Get("Content-Type"))
+
+	var mtd metadata.ApplicationMetadata
+	yaml.Unmarshal([]byte(createValidPayload()), &mtd)
+	repo.Create("appID1", &mtd)
+	repo.Delete("appID1")
+	repo.Create("appID2", &mtd)
+
+	reader := bufio.NewReader(response.Body)
+	first := readEvent(t, reader)
+	assert.Equal(t, uint64(1), first.ResourceVersion)
+	assert.Equal(t, events.Created, first.Type)
+	assert.Equal(t, "appID1", first.ApplicationID)
+
+	// the delete event is filtered out
+	second := readEvent(t, reader)
+	assert.Equal(t, uint64(3), second.ResourceVersion)
+	assert.Equal(t, "appID2", second.ApplicationID)
+}
+
+func TestRecordingRepository_ConcurrentUpdates_ResultedEventsInOrder(t *testing.T) {
+
+	log := events.NewLog(100)
+	repo := events.NewRecordingRepository(repository.NewInMemoryMetadataRepository(), log)
+	repo.Create("appID1", &metadata.ApplicationMetadata{ApplicationID: "appID1", Title: "title"})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			repo.Update("appID1", &metadata.ApplicationMetadata{ApplicationID: "appID1", Title: fmt.Sprint("title ", i)})
+		}(i)
+	}
+	wg.Wait()
+
+	// the events are recorded in the order the updates are applied, so the latest one is the stored state
+	recorded, _, _ := log.Since(0)
+	assert.Len(t, recorded, 51)
+	for i, e := range recorded {
+		assert.Equal(t, i+1, e.Object.Revision)
+	}
+	stored, _ := repo.Get("appID1")
+	assert.Equal(t, stored.Title, recorded[50].Object.Title)
+}
+
+func TestRecordingRepository_Scrub_ResultedEventsReplaced(t *testing.T) {
+
+	log := events.NewLog(10)
+	repo := events.NewRecordingRepository(repository.NewInMemoryMetadataRepository(), log)
+	repo.Create("appID1", &metadata.ApplicationMetadata{ApplicationID: "appID1", Title: "title",
+		Maintainers: []metadata.Maintainer{{Name: "First Maintainer", Email: "first@example.com"}}})
+	// a watcher may still be writing the events it read
+	watched, _, _ := log.Since(0)
+
+	repo.Scrub(func(data *metadata.ApplicationMetadata) bool {
+		data.Maintainers = []metadata.Maintainer{{Name: "Erased Maintainer", Email: "erased@erased.invalid"}}
+		return true
+	})
+
+	assert.Equal(t, "First Maintainer", watched[0].Object.Maintainers[0].Name)
+	recorded, _, _ := log.Since(0)
+	assert.Equal(t, "Erased Maintainer", recorded[0].Object.Maintainers[0].Name)
+}
+
+func TestMetadataHandler_HandleWatchMetadataLastEventID_ResultedResumed(t *testing.T) {
+
+	log := events.NewLog(10)
+	for _, id := range []string{"appID1", "appID2", "appID3"} {
+		log.Append(events.Created, id, &metadata.ApplicationMetadata{ApplicationID: id})
+	}
+	mh := NewMetadataHandler(repository.NewInMemoryMetadataRepository())
+	mh.Events = log
+	server := httptest.NewServer(http.HandlerFunc(mh.HandleWatchMetadata))
+	defer server.Close()
+
+	request, _ := http.NewRequest("GET", server.URL, nil)
+	request.Header.Set("Last-Event-ID", "1")
+	response, err := http.DefaultClient.Do(request)
+	assert.Nil(t, err)
+	defer response.Body.Close()
+
+	reader := bufio.NewReader(response.Body)
+	assert.Equal(t, "appID2", readEvent(t, reader).ApplicationID)
+	assert.Equal(t, "appID3", readEvent(t, reader).ApplicationID)
+}
+
+func TestMetadataHandler_HandleWatchMetadataDroppedVersion_ResultedGone(t *testing.T) {
+
+	log := events.NewLog(1)
+	for _, id := range []string{"appID1", "appID2", "appID3"} {
+		log.Append(events.Created, id, &metadata.ApplicationMetadata{ApplicationID: id})
+	}
+	mh := NewMetadataHandler(repository.NewInMemoryMetadataRepository())
+	mh.Events = log
+
+	request, _ := http.NewRequest("GET", "app-metadata/events?resourceVersion=1", strings.NewReader(""))
+	responseRecorder := httptest.NewRecorder()
+	mh.HandleWatchMetadata(responseRecorder, request)
+
+	assert.Equal(t, http.StatusGone, responseRecorder.Code) // 410
+}
+
+// readEvent reads the next Server-Sent Event and decodes its data
+func readEvent(t *testing.T, reader *bufio.Reader) events.Event {
+	var e events.Event
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.HasPrefix(line, "data: ") {
+			json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &e)
+		}
+		if line == "\n" && e.ResourceVersion != 0 {
+			return e
+		}
+	}
+}
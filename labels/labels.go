@@ -0,0 +1,67 @@
+package labels
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	maxNameLength   = 63
+	maxPrefixLength = 253
+	maxValueLength  = 63
+)
+
+var (
+	nameRe   = regexp.MustCompile(`^[A-Za-z0-9]([-A-Za-z0-9_.]*[A-Za-z0-9])?$`)
+	prefixRe = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`)
+)
+
+// ValidateKey checks a label key, an optional DNS subdomain prefix followed by "/" and a name
+// of at most 63 alphanumeric characters, '-', '_' or '.' starting and ending with an alphanumeric
+func ValidateKey(key string) error {
+	name := key
+	if i := strings.LastIndex(key, "/"); i >= 0 {
+		prefix := key[:i]
+		name = key[i+1:]
+		if len(prefix) == 0 || len(prefix) > maxPrefixLength || !prefixRe.MatchString(prefix) {
+			return fmt.Errorf("label key %s has an invalid prefix", key)
+		}
+	}
+	if len(name) == 0 || len(name) > maxNameLength || !nameRe.MatchString(name) {
+		return fmt.Errorf("label key %s is not valid", key)
+	}
+	return nil
+}
+
+// ValidateValue checks a label value, empty or at most 63 alphanumeric characters, '-', '_' or '.'
+// starting and ending with an alphanumeric
+func ValidateValue(value string) error {
+	if len(value) == 0 {
+		return nil
+	}
+	if len(value) > maxValueLength || !nameRe.MatchString(value) {
+		return fmt.Errorf("label value %s is not valid", value)
+	}
+	return nil
+}
+
+// Validate checks every key and value of a label set
+func Validate(labels map[string]string) error {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	// report the first error in a stable order
+	sort.Strings(keys)
+	for _, k := range keys {
+		if err := ValidateKey(k); err != nil {
+			return err
+		}
+		if err := ValidateValue(labels[k]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
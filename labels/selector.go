@@ -0,0 +1,230 @@
+package labels
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Operator is the comparison of a selector requirement
+type Operator string
+
+// supported selector operators
+const (
+	Equals       Operator = "="
+	NotEquals    Operator = "!="
+	In           Operator = "in"
+	NotIn        Operator = "notin"
+	Exists       Operator = "exists"
+	DoesNotExist Operator = "!"
+)
+
+// Requirement is a single condition of a selector such as tier in (1,2)
+type Requirement struct {
+	Key      string
+	Operator Operator
+	Values   []string
+}
+
+// Matches returns true when the label set satisfies the requirement
+func (r Requirement) Matches(labels map[string]string) bool {
+	v, ok := labels[r.Key]
+	switch r.Operator {
+	case Equals, In:
+		return ok && contains(r.Values, v)
+	case NotEquals, NotIn:
+		return !ok || !contains(r.Values, v)
+	case Exists:
+		return ok
+	case DoesNotExist:
+		return !ok
+	}
+	return false
+}
+
+// String returns the requirement in selector syntax
+func (r Requirement) String() string {
+	switch r.Operator {
+	case Exists:
+		return r.Key
+	case DoesNotExist:
+		return "!" + r.Key
+	case In, NotIn:
+		return fmt.Sprintf("%s %s (%s)", r.Key, r.Operator, strings.Join(r.Values, ","))
+	}
+	return r.Key + string(r.Operator) + r.Values[0]
+}
+
+// Selector is a set of requirements a label set must all satisfy.
+// The zero Selector matches everything.
+type Selector struct {
+	Requirements []Requirement
+}
+
+// Empty returns true when the selector has no requirement
+func (s Selector) Empty() bool {
+	return len(s.Requirements) == 0
+}
+
+// Matches returns true when the label set satisfies every requirement
+func (s Selector) Matches(labels map[string]string) bool {
+	for _, r := range s.Requirements {
+		if !r.Matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns the selector in selector syntax
+func (s Selector) String() string {
+	parts := make([]string, len(s.Requirements))
+	for i, r := range s.Requirements {
+		parts[i] = r.String()
+	}
+	return strings.Join(parts, ",")
+}
+
+// Parse parses a Kubernetes style label selector such as "team=payments,tier in (1,2),!deprecated".
+// Supported requirements are key=value, key==value, key!=value, key in (v1,v2), key notin (v1,v2),
+// key (the label exists), and !key (the label doesn't exist).
+func Parse(selector string) (Selector, error) {
+	p := &parser{input: selector}
+	var s Selector
+	p.skipSpaces()
+	if p.done() {
+		return s, nil
+	}
+	for {
+		r, err := p.requirement()
+		if err != nil {
+			return Selector{}, fmt.Errorf("invalid label selector %q: %v", selector, err)
+		}
+		s.Requirements = append(s.Requirements, r)
+		p.skipSpaces()
+		if p.done() {
+			break
+		}
+		if !p.consume(",") {
+			return Selector{}, fmt.Errorf("invalid label selector %q: expected ',' at %d", selector, p.pos)
+		}
+	}
+	sort.SliceStable(s.Requirements, func(i, j int) bool {
+		return s.Requirements[i].Key < s.Requirements[j].Key
+	})
+	return s, nil
+}
+
+type parser struct {
+	input string
+	pos   int
+}
+
+func (p *parser) done() bool {
+	return p.pos >= len(p.input)
+}
+
+func (p *parser) skipSpaces() {
+	for !p.done() && p.input[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+func (p *parser) consume(s string) bool {
+	if strings.HasPrefix(p.input[p.pos:], s) {
+		p.pos += len(s)
+		return true
+	}
+	return false
+}
+
+// word reads up to a space or a selector delimiter
+func (p *parser) word() string {
+	start := p.pos
+	for !p.done() && !strings.ContainsRune(" =!,()", rune(p.input[p.pos])) {
+		p.pos++
+	}
+	return p.input[start:p.pos]
+}
+
+func (p *parser) requirement() (Requirement, error) {
+	p.skipSpaces()
+	if p.consume("!") {
+		p.skipSpaces()
+		key := p.word()
+		if err := ValidateKey(key); err != nil {
+			return Requirement{}, err
+		}
+		return Requirement{Key: key, Operator: DoesNotExist}, nil
+	}
+
+	key := p.word()
+	if err := ValidateKey(key); err != nil {
+		return Requirement{}, err
+	}
+	p.skipSpaces()
+
+	var op Operator
+	switch {
+	case p.done() || strings.HasPrefix(p.input[p.pos:], ","):
+		return Requirement{Key: key, Operator: Exists}, nil
+	case p.consume("=="), p.consume("="):
+		op = Equals
+	case p.consume("!="):
+		op = NotEquals
+	default:
+		switch w := p.word(); w {
+		case "in":
+			op = In
+		case "notin":
+			op = NotIn
+		default:
+			return Requirement{}, fmt.Errorf("unknown operator %q after %s", w, key)
+		}
+		values, err := p.set()
+		if err != nil {
+			return Requirement{}, err
+		}
+		return Requirement{Key: key, Operator: op, Values: values}, nil
+	}
+
+	p.skipSpaces()
+	value := p.word()
+	if err := ValidateValue(value); err != nil {
+		return Requirement{}, err
+	}
+	return Requirement{Key: key, Operator: op, Values: []string{value}}, nil
+}
+
+// set reads a parenthesized, comma separated list of values
+func (p *parser) set() ([]string, error) {
+	p.skipSpaces()
+	if !p.consume("(") {
+		return nil, fmt.Errorf("expected '(' at %d", p.pos)
+	}
+	var values []string
+	for {
+		p.skipSpaces()
+		v := p.word()
+		if err := ValidateValue(v); err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+		p.skipSpaces()
+		if p.consume(")") {
+			return values, nil
+		}
+		if !p.consume(",") {
+			return nil, fmt.Errorf("expected ',' or ')' at %d", p.pos)
+		}
+	}
+}
+
+func contains(values []string, v string) bool {
+	for _, s := range values {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+package labels
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse_Selector_ResultedRequirements(t *testing.T) {
+
+	s, err := Parse("team=payments, tier in (1, 2),!deprecated,runtime notin (jvm),env!=prod,owner,kind==service")
+
+	assert.Nil(t, err)
+	assert.Equal(t, []Requirement{
+		{Key: "deprecated", Operator: DoesNotExist},
+		{Key: "env", Operator: NotEquals, Values: []string{"prod"}},
+		{Key: "kind", Operator: Equals, Values: []string{"service"}},
+		{Key: "owner", Operator: Exists},
+		{Key: "runtime", Operator: NotIn, Values: []string{"jvm"}},
+		{Key: "team", Operator: Equals, Values: []string{"payments"}},
+		{Key: "tier", Operator: In, Values: []string{"1", "2"}},
+	}, s.Requirements)
+}
+
+func TestParse_InvalidSelector_ResultedError(t *testing.T) {
+
+	for _, selector := range []string{
+		"team=pay ments",
+		"tier in 1,2",
+		"tier in (1,2",
+		"team=payments,,tier=1",
+		"team like payments",
+		"-team=payments",
+	} {
+		_, err := Parse(selector)
+		assert.NotNil(t, err, selector)
+	}
+}
+
+func TestSelector_Matches_ResultedMatch(t *testing.T) {
+
+	s, _ := Parse("team=payments,tier in (1,2),!deprecated")
+
+	assert.True(t, s.Matches(map[string]string{"team": "payments", "tier": "2"}))
+	assert.False(t, s.Matches(map[string]string{"team": "payments", "tier": "3"}))
+	assert.False(t, s.Matches(map[string]string{"team": "payments", "tier": "1", "deprecated": ""}))
+	assert.False(t, s.Matches(nil))
+	assert.True(t, Selector{}.Matches(nil))
+}
+
+func TestValidate_Labels_ResultedError(t *testing.T) {
+
+	assert.Nil(t, Validate(map[string]string{"example.com/team": "payments", "tier": ""}))
+	assert.NotNil(t, Validate(map[string]string{"team": "pay ments"}))
+	assert.NotNil(t, Validate(map[string]string{"Example.com/team": "payments"}))
+	assert.NotNil(t, Validate(map[string]string{"/team": "payments"}))
+}
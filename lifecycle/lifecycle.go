@@ -0,0 +1,156 @@
+package lifecycle
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/elumbantoruan/app-metadata/diff"
+	"github.com/elumbantoruan/app-metadata/metadata"
+)
+
+// lifecycle states of an application
+const (
+	Draft      = "draft"
+	InReview   = "in-review"
+	Published  = "published"
+	Deprecated = "deprecated"
+	Retired    = "retired"
+)
+
+// States lists every state in lifecycle order
+var States = []string{Draft, InReview, Published, Deprecated, Retired}
+
+// transitions maps a state to the states it can move to
+var transitions = map[string][]string{
+	Draft:      {InReview},
+	InReview:   {Draft, Published},
+	Published:  {Deprecated},
+	Deprecated: {Published, Retired},
+	Retired:    {},
+}
+
+var (
+	ErrInvalidState      = errors.New("invalid lifecycle state")
+	ErrInvalidTransition = errors.New("lifecycle transition is not allowed")
+	ErrNotMaintainer     = errors.New("only a maintainer of the application can change its lifecycle state")
+	ErrSelfApproval      = errors.New("the application must be approved by a different maintainer than the submitter")
+	ErrRetired           = errors.New("retired applications can't be edited")
+)
+
+// Effective returns the state of an application, applications stored before lifecycle states existed are published
+func Effective(state string) string {
+	if state == "" {
+		return Published
+	}
+	return state
+}
+
+// Valid returns true when state is a known lifecycle state
+func Valid(state string) bool {
+	_, ok := transitions[state]
+	return ok
+}
+
+// ParseStates parses a comma separated list of states, "all" meaning every state
+func ParseStates(s string) ([]string, error) {
+	if s == "all" {
+		return States, nil
+	}
+	var states []string
+	for _, st := range strings.Split(s, ",") {
+		st = strings.TrimSpace(st)
+		if !Valid(st) {
+			return nil, ErrInvalidState
+		}
+		states = append(states, st)
+	}
+	return states, nil
+}
+
+// CanTransition returns true when an application in state from can move to state to
+func CanTransition(from, to string) bool {
+	for _, s := range transitions[Effective(from)] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Transition moves app to state to on behalf of principal, the email of the caller, who must be a maintainer of app.
+// Submitting for review records the submitter, and publishing an application in review
+// requires a maintainer of the application other than the submitter.
+func Transition(app *metadata.ApplicationMetadata, to, principal string, now time.Time) error {
+	if !Valid(to) {
+		return ErrInvalidState
+	}
+	from := Effective(app.State)
+	if !CanTransition(from, to) {
+		return ErrInvalidTransition
+	}
+	if !isMaintainer(app, principal) {
+		return ErrNotMaintainer
+	}
+
+	now = now.UTC()
+	switch {
+	case to == InReview:
+		app.Review = &metadata.Review{SubmittedBy: principal, SubmittedAt: &now}
+	case from == InReview && to == Published:
+		if app.Review != nil && metadata.NormalizeEmail(app.Review.SubmittedBy) == metadata.NormalizeEmail(principal) {
+			return ErrSelfApproval
+		}
+		review := metadata.Review{}
+		if app.Review != nil {
+			review = *app.Review
+		}
+		review.ApprovedBy = principal
+		review.ApprovedAt = &now
+		app.Review = &review
+	case from == InReview && to == Draft:
+		// rejected, it has to be submitted again
+		app.Review = nil
+	}
+	app.State = to
+	return nil
+}
+
+// Edit carries the lifecycle of existing over to app, the replacement of its content.
+// Editing an application in review, published, or deprecated sends it back to draft, so that the changed content
+// is reviewed again before it's published.  A replacement with the same content keeps the state.
+func Edit(app, existing *metadata.ApplicationMetadata) error {
+	app.State = existing.State
+	app.Review = existing.Review
+	switch Effective(existing.State) {
+	case Retired:
+		return ErrRetired
+	case InReview, Published, Deprecated:
+		if edited(existing, app) {
+			app.State = Draft
+			app.Review = nil
+		}
+	}
+	return nil
+}
+
+// edited returns true when app changes the content of existing, the version and the releases being managed
+// through the releases subresource
+func edited(existing, app *metadata.ApplicationMetadata) bool {
+	for _, c := range diff.Compare(existing, app) {
+		if c.Path != "version" && !strings.HasPrefix(c.Path, "releases[") {
+			return true
+		}
+	}
+	return false
+}
+
+func isMaintainer(app *metadata.ApplicationMetadata, email string) bool {
+	email = metadata.NormalizeEmail(email)
+	for _, m := range app.Maintainers {
+		if metadata.NormalizeEmail(m.Email) == email {
+			return true
+		}
+	}
+	return false
+}
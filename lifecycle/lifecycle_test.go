@@ -0,0 +1,143 @@
+package lifecycle
+
+import (
+	"testing"
+	"time"
+
+	"github.com/elumbantoruan/app-metadata/metadata"
+	"github.com/stretchr/testify/assert"
+)
+
+func createApp(state string) *metadata.ApplicationMetadata {
+	return &metadata.ApplicationMetadata{
+		ApplicationID: "app-id1",
+		Title:         "Valid App 1",
+		Version:       "1.0.1",
+		Maintainers: []metadata.Maintainer{
+			{Name: "First Maintainer", Email: "first@example.com"},
+			{Name: "Second Maintainer", Email: "second@example.com"},
+		},
+		State:  state,
+		Review: &metadata.Review{SubmittedBy: "first@example.com"},
+	}
+}
+
+func TestTransition_States_ResultedAllowedOrRefused(t *testing.T) {
+
+	tests := []struct {
+		from      string
+		to        string
+		principal string
+		expected  error
+	}{
+		{from: Draft, to: InReview, principal: "first@example.com"},
+		{from: Draft, to: Published, principal: "second@example.com", expected: ErrInvalidTransition},
+		{from: Draft, to: Deprecated, principal: "first@example.com", expected: ErrInvalidTransition},
+		{from: Draft, to: Retired, principal: "first@example.com", expected: ErrInvalidTransition},
+		{from: Draft, to: Draft, principal: "first@example.com", expected: ErrInvalidTransition},
+		{from: Draft, to: InReview, principal: "someone@example.com", expected: ErrNotMaintainer},
+		{from: InReview, to: Draft, principal: "second@example.com"},
+		{from: InReview, to: Published, principal: "second@example.com"},
+		{from: InReview, to: Published, principal: "Second@Example.com"},
+		{from: InReview, to: Published, principal: "first@example.com", expected: ErrSelfApproval},
+		{from: InReview, to: Published, principal: "someone@example.com", expected: ErrNotMaintainer},
+		{from: InReview, to: Deprecated, principal: "second@example.com", expected: ErrInvalidTransition},
+		{from: InReview, to: Retired, principal: "second@example.com", expected: ErrInvalidTransition},
+		{from: Published, to: Deprecated, principal: "first@example.com"},
+		{from: Published, to: Deprecated, principal: "someone@example.com", expected: ErrNotMaintainer},
+		{from: Published, to: Draft, principal: "first@example.com", expected: ErrInvalidTransition},
+		{from: Published, to: InReview, principal: "first@example.com", expected: ErrInvalidTransition},
+		{from: Published, to: Retired, principal: "first@example.com", expected: ErrInvalidTransition},
+		// applications stored before lifecycle states existed are published
+		{from: "", to: Deprecated, principal: "first@example.com"},
+		{from: "", to: InReview, principal: "first@example.com", expected: ErrInvalidTransition},
+		{from: Deprecated, to: Published, principal: "first@example.com"},
+		{from: Deprecated, to: Retired, principal: "first@example.com"},
+		{from: Deprecated, to: Retired, principal: "someone@example.com", expected: ErrNotMaintainer},
+		{from: Deprecated, to: Draft, principal: "first@example.com", expected: ErrInvalidTransition},
+		{from: Retired, to: Published, principal: "first@example.com", expected: ErrInvalidTransition},
+		{from: Retired, to: Draft, principal: "first@example.com", expected: ErrInvalidTransition},
+		{from: Draft, to: "archived", principal: "first@example.com", expected: ErrInvalidState},
+	}
+	for _, tt := range tests {
+		app := createApp(tt.from)
+		err := Transition(app, tt.to, tt.principal, time.Now())
+
+		name := tt.from + " -> " + tt.to + " by " + tt.principal
+		assert.Equal(t, tt.expected, err, name)
+		if tt.expected != nil {
+			assert.Equal(t, tt.from, app.State, name)
+		} else {
+			assert.Equal(t, tt.to, app.State, name)
+		}
+	}
+}
+
+func TestTransition_Review_ResultedRecorded(t *testing.T) {
+
+	now := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	app := createApp(Draft)
+	app.Review = nil
+
+	assert.Nil(t, Transition(app, InReview, "first@example.com", now))
+	assert.Equal(t, "first@example.com", app.Review.SubmittedBy)
+	assert.Equal(t, now, *app.Review.SubmittedAt)
+
+	assert.Nil(t, Transition(app, Published, "second@example.com", now))
+	assert.Equal(t, "first@example.com", app.Review.SubmittedBy)
+	assert.Equal(t, "second@example.com", app.Review.ApprovedBy)
+	assert.Equal(t, now, *app.Review.ApprovedAt)
+
+	// a rejected application has to be submitted again
+	app = createApp(InReview)
+	assert.Nil(t, Transition(app, Draft, "second@example.com", now))
+	assert.Nil(t, app.Review)
+}
+
+func TestEdit_States_ResultedReviewedAgain(t *testing.T) {
+
+	tests := []struct {
+		state    string
+		expected string
+		err      error
+	}{
+		{state: Draft, expected: Draft},
+		{state: InReview, expected: Draft},
+		{state: Published, expected: Draft},
+		{state: "", expected: Draft},
+		{state: Deprecated, expected: Draft},
+		{state: Retired, err: ErrRetired},
+	}
+	for _, tt := range tests {
+		existing := createApp(tt.state)
+		app := createApp("")
+		app.Review = nil
+		app.Title = "Valid App 2"
+
+		err := Edit(app, existing)
+		assert.Equal(t, tt.err, err, tt.state)
+		if err != nil {
+			continue
+		}
+		assert.Equal(t, tt.expected, app.State, tt.state)
+		if tt.expected == Draft && tt.state != Draft {
+			assert.Nil(t, app.Review, tt.state)
+		}
+	}
+}
+
+func TestEdit_SameContent_ResultedStateKept(t *testing.T) {
+
+	for _, state := range []string{Draft, InReview, Published, Deprecated} {
+		existing := createApp(state)
+		existing.Releases = []metadata.Release{{Version: "1.0.1"}}
+		app := createApp("")
+		app.Review = nil
+		// the version and the releases are managed through the releases subresource
+		app.Version = "0.1.0"
+
+		assert.Nil(t, Edit(app, existing), state)
+		assert.Equal(t, state, app.State, state)
+		assert.Equal(t, existing.Review, app.Review, state)
+	}
+}
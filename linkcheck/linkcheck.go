@@ -0,0 +1,299 @@
+// Package linkcheck periodically checks that the website and source URLs of the applications are reachable
+package linkcheck
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/elumbantoruan/app-metadata/metadata"
+	"github.com/elumbantoruan/app-metadata/repository"
+)
+
+// default settings of a Checker
+const (
+	DefaultConcurrency  = 8
+	DefaultTimeout      = 10 * time.Second
+	DefaultHostInterval = time.Second
+	DefaultMaxRedirects = 10
+)
+
+// Checker requests URLs with HEAD, or GET when the server doesn't support HEAD, following redirects.
+// A URL is broken when it can't be reached, or it ends with a 4xx or 5xx status other than 429 Too Many Requests,
+// which only tells the server throttled the checker.
+//
+// The URLs come from the clients, so the Client of New only connects to the addresses AllowAddress accepts, public
+// ones by default.  The address is checked once the host is resolved, on every connection, redirects included, so
+// that a URL can't make the service reach itself or its private network.
+type Checker struct {
+	Client *http.Client
+	// AllowAddress tells whether the Client of New may connect to ip, IsPublic when nil
+	AllowAddress func(ip net.IP) bool
+	// Concurrency is the maximum number of URLs checked at the same time
+	Concurrency int
+	// Timeout bounds each request of a check, redirects included, from the moment its host allows it
+	Timeout time.Duration
+	// HostInterval is the minimum time between two requests to the same host
+	HostInterval time.Duration
+	MaxRedirects int
+	UserAgent    string
+
+	now  func() time.Time
+	mu   sync.Mutex
+	next map[string]time.Time
+}
+
+// New returns a Checker with the default settings
+func New() *Checker {
+	c := &Checker{
+		AllowAddress: IsPublic,
+		Concurrency:  DefaultConcurrency,
+		Timeout:      DefaultTimeout,
+		HostInterval: DefaultHostInterval,
+		MaxRedirects: DefaultMaxRedirects,
+		UserAgent:    "app-metadata-linkcheck",
+		now:          time.Now,
+		next:         make(map[string]time.Time),
+	}
+	dialer := &net.Dialer{Timeout: DefaultTimeout, Control: c.control}
+	// no proxy, it would be the only address checked
+	c.Client = &http.Client{Transport: &http.Transport{
+		DialContext:         dialer.DialContext,
+		ForceAttemptHTTP2:   true,
+		MaxIdleConns:        100,
+		IdleConnTimeout:     90 * time.Second,
+		TLSHandshakeTimeout: DefaultTimeout,
+	}}
+	return c
+}
+
+// IsPublic reports whether ip is a public address, that is not a loopback, private, link-local, multicast, or
+// unspecified one
+func IsPublic(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() && !ip.IsMulticast() && !ip.IsUnspecified()
+}
+
+// control refuses to connect to an address AllowAddress doesn't accept, it runs after the host is resolved
+func (c *Checker) control(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	allow := c.AllowAddress
+	if allow == nil {
+		allow = IsPublic
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !allow(ip) {
+		return fmt.Errorf("address %s is not allowed", host)
+	}
+	return nil
+}
+
+// Check returns the status of a single URL
+func (c *Checker) Check(ctx context.Context, u string) repository.LinkStatus {
+	status := repository.LinkStatus{URL: u}
+	resp, redirects, err := c.request(ctx, http.MethodHead, u)
+	if err == nil && (resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented) {
+		resp.Body.Close()
+		resp, redirects, err = c.request(ctx, http.MethodGet, u)
+	}
+	status.CheckedAt = c.now().UTC()
+	if err != nil {
+		status.Error = err.Error()
+		status.Broken = true
+		return status
+	}
+	defer resp.Body.Close()
+	// a bit of the body is read so that the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	status.StatusCode = resp.StatusCode
+	status.Redirects = redirects
+	if final := resp.Request.URL.String(); final != u {
+		status.FinalURL = final
+	}
+	status.Broken = resp.StatusCode >= 400 && resp.StatusCode != http.StatusTooManyRequests
+	return status
+}
+
+// request sends a request to u once its host allows it, and returns the response with the number of redirects followed.
+// The Timeout starts once the host allows the request, so that the time spent waiting for it isn't counted.
+func (c *Checker) request(ctx context.Context, method, u string) (*http.Response, int, error) {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := c.wait(ctx, parsed.Host); err != nil {
+		return nil, 0, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	req, err := http.NewRequestWithContext(ctx, method, u, nil)
+	if err != nil {
+		cancel()
+		return nil, 0, err
+	}
+	if c.UserAgent != "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
+	redirects := 0
+	client := *c.Client
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) > c.MaxRedirects {
+			return fmt.Errorf("stopped after %d redirects", c.MaxRedirects)
+		}
+		redirects = len(via)
+		return c.wait(req.Context(), req.URL.Host)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		cancel()
+		// the url.Error repeats the method and the URL
+		if ue, ok := err.(*url.Error); ok {
+			err = ue.Err
+		}
+		return nil, redirects, err
+	}
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, redirects, nil
+}
+
+// cancelBody releases the timeout of a request once its body is closed
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// wait blocks until a request to host is allowed by HostInterval, or ctx is done
+func (c *Checker) wait(ctx context.Context, host string) error {
+	if c.HostInterval <= 0 {
+		return nil
+	}
+	c.mu.Lock()
+	now := c.now()
+	next := c.next[host]
+	if next.Before(now) {
+		next = now
+	}
+	c.next[host] = next.Add(c.HostInterval)
+	c.mu.Unlock()
+
+	d := next.Sub(now)
+	if d <= 0 {
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// CheckAll checks urls with at most Concurrency of them at the same time, and returns their status in the same order
+func (c *Checker) CheckAll(ctx context.Context, urls []string) []repository.LinkStatus {
+	res := make([]repository.LinkStatus, len(urls))
+	concurrency := c.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				res[i] = c.Check(ctx, urls[i])
+			}
+		}()
+	}
+	for i := range urls {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	return res
+}
+
+// URLs returns the website and source URLs of the live applications, ordered and without duplicates
+func URLs(repo repository.MetadataRepository) ([]string, error) {
+	seen := make(map[string]bool)
+	err := repo.ForEach(func(app *metadata.ApplicationMetadata) error {
+		for _, u := range []string{app.Website, app.Source} {
+			if u != "" && metadata.ValidateURL(u) == nil {
+				seen[u] = true
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	urls := make([]string, 0, len(seen))
+	for u := range seen {
+		urls = append(urls, u)
+	}
+	sort.Strings(urls)
+	return urls, nil
+}
+
+// Refresh checks the URLs of the live applications and stores their status, dropping the status of the URLs no
+// application uses anymore
+func (c *Checker) Refresh(ctx context.Context, repo repository.MetadataRepository, store repository.LinkStore) error {
+	urls, err := URLs(repo)
+	if err != nil {
+		return err
+	}
+	retained := make(map[string]bool, len(urls))
+	for _, u := range urls {
+		retained[u] = true
+	}
+	if err := store.RetainLinks(retained); err != nil {
+		return err
+	}
+	for _, status := range c.CheckAll(ctx, urls) {
+		if ctx.Err() != nil {
+			// the checks were interrupted, their status tells nothing about the URLs
+			return ctx.Err()
+		}
+		if err := store.PutLink(&status); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Run refreshes the status of the URLs right away, then every interval until ctx is done
+func (c *Checker) Run(ctx context.Context, repo repository.MetadataRepository, store repository.LinkStore, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := c.Refresh(ctx, repo, store); err != nil && ctx.Err() == nil {
+			log.Printf("checking links: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package linkcheck
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/elumbantoruan/app-metadata/metadata"
+	"github.com/elumbantoruan/app-metadata/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestChecker() *Checker {
+	c := New()
+	// the test servers listen on the loopback
+	c.AllowAddress = func(ip net.IP) bool { return true }
+	c.HostInterval = 0
+	c.Timeout = time.Second
+	return c
+}
+
+func TestChecker_Check_ResultedStatus(t *testing.T) {
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("/missing", http.NotFound)
+	mux.HandleFunc("/throttled", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+	mux.HandleFunc("/old", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/older", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/older", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/ok", http.StatusFound)
+	})
+	mux.HandleFunc("/loop", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop", http.StatusFound)
+	})
+	mux.HandleFunc("/get-only", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(2 * time.Second):
+		}
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	c := newTestChecker()
+	c.Timeout = 200 * time.Millisecond
+	c.MaxRedirects = 3
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+
+	s := c.Check(context.Background(), srv.URL+"/ok")
+	assert.Equal(t, repository.LinkStatus{URL: srv.URL + "/ok", StatusCode: 200, CheckedAt: now}, s)
+
+	s = c.Check(context.Background(), srv.URL+"/missing")
+	assert.Equal(t, 404, s.StatusCode)
+	assert.True(t, s.Broken)
+
+	s = c.Check(context.Background(), srv.URL+"/throttled")
+	assert.Equal(t, 429, s.StatusCode)
+	assert.False(t, s.Broken)
+
+	s = c.Check(context.Background(), srv.URL+"/old")
+	assert.Equal(t, 200, s.StatusCode)
+	assert.Equal(t, 2, s.Redirects)
+	assert.Equal(t, srv.URL+"/ok", s.FinalURL)
+	assert.False(t, s.Broken)
+
+	s = c.Check(context.Background(), srv.URL+"/loop")
+	assert.True(t, s.Broken)
+	assert.Equal(t, "stopped after 3 redirects", s.Error)
+
+	s = c.Check(context.Background(), srv.URL+"/get-only")
+	assert.Equal(t, 200, s.StatusCode)
+	assert.False(t, s.Broken)
+
+	s = c.Check(context.Background(), srv.URL+"/slow")
+	assert.True(t, s.Broken)
+	assert.NotEmpty(t, s.Error)
+	assert.Equal(t, 0, s.StatusCode)
+}
+
+func TestChecker_CheckAll_ResultedBounded(t *testing.T) {
+
+	var mu sync.Mutex
+	current, peak := 0, 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		current++
+		if current > peak {
+			peak = current
+		}
+		mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		current--
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	c := newTestChecker()
+	c.Concurrency = 2
+	urls := []string{}
+	for _, p := range []string{"/a", "/b", "/c", "/d", "/e", "/f"} {
+		urls = append(urls, srv.URL+p)
+	}
+	res := c.CheckAll(context.Background(), urls)
+	assert.Len(t, res, len(urls))
+	for i, s := range res {
+		assert.Equal(t, urls[i], s.URL)
+		assert.Equal(t, 200, s.StatusCode)
+	}
+	assert.Equal(t, 2, peak)
+}
+
+func TestChecker_CheckPrivateAddress_ResultedBroken(t *testing.T) {
+
+	var hits int32
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+	}))
+	defer target.Close()
+	// 127.0.0.2 is a loopback address as well, but one only the redirect uses
+	_, port, _ := net.SplitHostPort(target.Listener.Addr().String())
+	redirect := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://127.0.0.2:"+port+"/", http.StatusFound)
+	}))
+	defer redirect.Close()
+
+	c := New()
+	c.HostInterval = 0
+	s := c.Check(context.Background(), target.URL)
+	assert.True(t, s.Broken)
+	assert.Contains(t, s.Error, "address 127.0.0.1 is not allowed")
+
+	c.AllowAddress = func(ip net.IP) bool { return ip.Equal(net.IPv4(127, 0, 0, 1)) }
+	s = c.Check(context.Background(), redirect.URL)
+	assert.True(t, s.Broken)
+	assert.Contains(t, s.Error, "address 127.0.0.2 is not allowed")
+	assert.Equal(t, int32(0), atomic.LoadInt32(&hits))
+}
+
+func TestIsPublic_ResultedPublicOnly(t *testing.T) {
+
+	for _, addr := range []string{"127.0.0.1", "::1", "10.1.2.3", "172.16.0.1", "192.168.1.1", "169.254.169.254", "fe80::1", "fd00::1", "0.0.0.0", "::", "::ffff:127.0.0.1"} {
+		assert.False(t, IsPublic(net.ParseIP(addr)), addr)
+	}
+	for _, addr := range []string{"93.184.216.34", "2606:2800:220:1:248:1893:25c8:1946"} {
+		assert.True(t, IsPublic(net.ParseIP(addr)), addr)
+	}
+}
+
+func TestChecker_CheckAll_ResultedRateLimitedPerHost(t *testing.T) {
+
+	var mu sync.Mutex
+	var times []time.Time
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		times = append(times, time.Now())
+		mu.Unlock()
+	}))
+	defer srv.Close()
+	var other int32
+	otherSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&other, 1)
+	}))
+	defer otherSrv.Close()
+
+	c := newTestChecker()
+	c.HostInterval = 50 * time.Millisecond
+	start := time.Now()
+	c.CheckAll(context.Background(), []string{srv.URL + "/a", srv.URL + "/b", srv.URL + "/c", otherSrv.URL})
+
+	assert.Len(t, times, 3)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&other))
+	for i := 1; i < len(times); i++ {
+		assert.True(t, times[i].Sub(times[i-1]) >= 40*time.Millisecond)
+	}
+	assert.True(t, time.Since(start) >= 100*time.Millisecond)
+}
+
+func TestChecker_CheckAllQueuedHost_ResultedTimeoutAfterWait(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+	defer srv.Close()
+
+	// every URL needs two requests, each waiting longer than the timeout for its turn
+	c := newTestChecker()
+	c.HostInterval = 150 * time.Millisecond
+	c.Timeout = 100 * time.Millisecond
+	urls := []string{srv.URL + "/a", srv.URL + "/b"}
+	res := c.CheckAll(context.Background(), urls)
+
+	for _, s := range res {
+		assert.False(t, s.Broken, s.Error)
+		assert.Equal(t, 200, s.StatusCode)
+	}
+}
+
+func TestChecker_Refresh_ResultedStored(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/broken" {
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	repo := repository.NewInMemoryMetadataRepository()
+	repo.Create("1", &metadata.ApplicationMetadata{ApplicationID: "1", Title: "App 1", Website: srv.URL + "/site", Source: srv.URL + "/broken"})
+	repo.Create("2", &metadata.ApplicationMetadata{ApplicationID: "2", Title: "App 2", Website: srv.URL + "/site"})
+	repo.Create("3", &metadata.ApplicationMetadata{ApplicationID: "3", Title: "App 3", Website: "not a url"})
+	store := repository.NewInMemoryLinkStore()
+	store.PutLink(&repository.LinkStatus{URL: "https://gone.example.com"})
+
+	c := newTestChecker()
+	assert.Nil(t, c.Refresh(context.Background(), repo, store))
+
+	links, err := store.ListLinks()
+	assert.Nil(t, err)
+	assert.Len(t, links, 2)
+	assert.Equal(t, srv.URL+"/broken", links[0].URL)
+	assert.True(t, links[0].Broken)
+	assert.Equal(t, srv.URL+"/site", links[1].URL)
+	assert.False(t, links[1].Broken)
+
+	gone, err := store.GetLink("https://gone.example.com")
+	assert.Nil(t, err)
+	assert.Nil(t, gone)
+}
@@ -1,43 +1,92 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"log"
 	"net/http"
+	"os"
+	"strings"
 
+	"github.com/elumbantoruan/app-metadata/emailpolicy"
 	"github.com/elumbantoruan/app-metadata/handlers"
 	"github.com/elumbantoruan/app-metadata/repository"
-
-	"github.com/gorilla/mux"
+	"github.com/elumbantoruan/app-metadata/server"
 )
 
 func main() {
+	cfg := server.DefaultConfig()
+	addr := flag.String("addr", ":5000", "address to listen on")
+	flag.Int64Var(&cfg.MaxBodySize, "max-body-size", cfg.MaxBodySize, "maximum size in bytes of a request body")
+	flag.Int64Var(&cfg.MaxImportSize, "max-import-size", cfg.MaxImportSize, "maximum size in bytes of an import request body")
+	flag.Float64Var(&cfg.ReadLimit.Rate, "read-rate", cfg.ReadLimit.Rate, "read requests per second allowed per client, 0 disables the limit")
+	flag.IntVar(&cfg.ReadLimit.Burst, "read-burst", cfg.ReadLimit.Burst, "read requests a client can burst")
+	flag.Float64Var(&cfg.WriteLimit.Rate, "write-rate", cfg.WriteLimit.Rate, "write requests per second allowed per client, 0 disables the limit")
+	flag.IntVar(&cfg.WriteLimit.Burst, "write-burst", cfg.WriteLimit.Burst, "write requests a client can burst")
+	flag.BoolVar(&cfg.TrustForwardedFor, "trust-forwarded-for", cfg.TrustForwardedFor, "identify clients by X-Forwarded-For when running behind a proxy")
+	apiKeys := flag.String("api-keys", os.Getenv("APP_METADATA_API_KEYS"), "comma separated keys identifying clients in X-API-Key for rate limiting, defaults to $APP_METADATA_API_KEYS")
+	flag.DurationVar(&cfg.IdempotencyTTL, "idempotency-ttl", cfg.IdempotencyTTL, "how long a response is replayed for the same Idempotency-Key")
+	flag.IntVar(&cfg.EventLogSize, "event-log-size", cfg.EventLogSize, "number of changes kept in memory for watchers to resume from")
+	flag.DurationVar(&cfg.TrashRetention, "trash-retention", cfg.TrashRetention, "how long a deleted application stays in the trash, 0 keeps it forever")
+	flag.DurationVar(&cfg.PurgeInterval, "purge-interval", cfg.PurgeInterval, "how often the trash is checked for applications to purge")
+	flag.StringVar(&cfg.AdminToken, "admin-token", os.Getenv("APP_METADATA_ADMIN_TOKEN"), "token authorizing admin operations in X-Admin-Token header, defaults to $APP_METADATA_ADMIN_TOKEN")
+	flag.StringVar(&cfg.AuditLogPath, "audit-log", cfg.AuditLogPath, "file the audit log is appended to, verified on startup")
+	unique := flag.String("unique", "", "unique constraints separated by ';', each a comma separated list of title, version, company, website, source, or license, such as title,company;source")
+	flag.StringVar(&cfg.DuplicatePolicy, "duplicates", cfg.DuplicatePolicy, "what to do with near-duplicates of a new application: off, warn, or block")
+	flag.BoolVar(&cfg.LenientDecoding, "lenient-yaml", cfg.LenientDecoding, "accept write bodies with unknown fields and duplicate keys, as older versions did")
+	emailDomains := flag.String("email-domains", "", "email domains the maintainers of a company's applications must use, such as Acme=acme.com,acme.io;Globex=globex.com")
+	disposable := flag.String("disposable-domains", strings.Join(cfg.DisposableDomains, ","), "comma separated email domains no maintainer may use")
+	flag.StringVar(&cfg.SMTPAddr, "smtp-addr", cfg.SMTPAddr, "host:port of the SMTP server verification tokens are sent through, verification is disabled when empty")
+	flag.StringVar(&cfg.SMTPFrom, "smtp-from", cfg.SMTPFrom, "sender address of the verification emails")
+	flag.StringVar(&cfg.SMTPUsername, "smtp-user", cfg.SMTPUsername, "username authenticating to the SMTP server, with the password in $APP_METADATA_SMTP_PASSWORD")
+	flag.DurationVar(&cfg.SMTPTimeout, "smtp-timeout", cfg.SMTPTimeout, "timeout of the delivery of a verification email to the SMTP server")
+	flag.IntVar(&cfg.SMTPQueueSize, "smtp-queue-size", cfg.SMTPQueueSize, "number of verification emails waiting to be sent, more are refused")
+	flag.StringVar(&cfg.VerificationKey, "verification-key", os.Getenv("APP_METADATA_VERIFICATION_KEY"), "key signing the verification tokens, defaults to $APP_METADATA_VERIFICATION_KEY")
+	flag.StringVar(&cfg.VerificationURL, "verification-url", cfg.VerificationURL, "public URL of GET /maintainers:verify the emailed link points to")
+	flag.DurationVar(&cfg.VerificationTTL, "verification-ttl", cfg.VerificationTTL, "how long a verification token is valid")
+	flag.DurationVar(&cfg.LinkCheckInterval, "link-check-interval", cfg.LinkCheckInterval, "how often the website and source URLs are checked, 0 (the default) disables link checking")
+	flag.IntVar(&cfg.LinkCheckConcurrency, "link-check-concurrency", cfg.LinkCheckConcurrency, "maximum number of URLs checked at the same time")
+	flag.DurationVar(&cfg.LinkCheckTimeout, "link-check-timeout", cfg.LinkCheckTimeout, "timeout of each request checking a URL, redirects included, once its host allows it")
+	flag.DurationVar(&cfg.LinkCheckHostInterval, "link-check-host-interval", cfg.LinkCheckHostInterval, "minimum time between two requests to the same host")
+	flag.BoolVar(&cfg.LinkCheckAllowPrivate, "link-check-allow-private", cfg.LinkCheckAllowPrivate, "check the URLs on loopback, private, and link-local addresses too")
+	flag.BoolVar(&cfg.UI, "ui", cfg.UI, "serve the HTML catalog browser under /ui")
+	flag.BoolVar(&cfg.ReadOnly, "read-only", cfg.ReadOnly, "start in read-only mode, refusing writes with 503 until an admin turns it off")
+	flag.DurationVar(&cfg.ReadOnlyRetryAfter, "read-only-retry-after", cfg.ReadOnlyRetryAfter, "Retry-After of the writes refused in read-only mode")
+	flag.Parse()
+	cfg.SMTPPassword = os.Getenv("APP_METADATA_SMTP_PASSWORD")
+
+	var err error
+	if cfg.UniqueConstraints, err = repository.ParseUniqueConstraints(*unique); err != nil {
+		log.Fatal(err)
+	}
+	if cfg.CompanyDomains, err = emailpolicy.ParseCompanyDomains(*emailDomains); err != nil {
+		log.Fatal(err)
+	}
+	for _, key := range strings.Split(*apiKeys, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			cfg.APIKeys = append(cfg.APIKeys, key)
+		}
+	}
+	cfg.DisposableDomains = nil
+	for _, d := range strings.Split(*disposable, ",") {
+		if d = strings.TrimSpace(d); d != "" {
+			cfg.DisposableDomains = append(cfg.DisposableDomains, d)
+		}
+	}
+	switch cfg.DuplicatePolicy {
+	case handlers.DuplicatesOff, handlers.DuplicatesWarn, handlers.DuplicatesBlock:
+	default:
+		log.Fatalf("invalid -duplicates %q, expected off, warn, or block", cfg.DuplicatePolicy)
+	}
 
-	m, err := registerHandlers()
+	m, err := server.RegisterHandlers(context.Background(), cfg)
 	if err != nil {
 		log.Fatal(err)
 	}
 	http.Handle("/", m)
 
-	err = http.ListenAndServe(":5000", nil)
+	err = http.ListenAndServe(*addr, nil)
 	if err != nil {
 		log.Fatal(err)
 	}
 }
-
-func registerHandlers() (*mux.Router, error) {
-	m := mux.NewRouter()
-
-	// initialize in-memory metadata repository
-	inMem := repository.NewInMemoryMetadataRepository()
-	// initialize metadata handler and inject the implementation of repository interface
-	appMd := handlers.NewMetadataHandler(inMem)
-
-	// Register app-metadata resource
-	m.HandleFunc("/app-metadata", appMd.HandlePostMetadata).Methods("POST")
-	m.HandleFunc("/app-metadata/{appID}", appMd.HandlePutMetadata).Methods("PUT")
-	m.HandleFunc("/app-metadata/{appID}", appMd.HandleGetMetadata).Methods("GET")
-	m.HandleFunc("/app-metadata", appMd.HandleGetAllMetadata).Methods("GET")
-	m.HandleFunc("/app-metadata/{appID}", appMd.HandleDeleteMetadata).Methods("DELETE")
-
-	return m, nil
-}
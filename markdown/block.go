@@ -0,0 +1,338 @@
+package markdown
+
+import (
+	"strconv"
+	"strings"
+)
+
+// kinds of block
+const (
+	blockParagraph = iota
+	blockHeading
+	blockCode
+	blockQuote
+	blockList
+	blockRule
+)
+
+type block struct {
+	kind int
+	// text is the inline source of a paragraph or a heading, or the content of a code block
+	text  string
+	level int
+	lang  string
+	// children are the blocks of a quote
+	children []*block
+	// items are the blocks of each item of a list
+	items   [][]*block
+	ordered bool
+	start   int
+	tight   bool
+}
+
+// parseBlocks splits lines into blocks, nesting quotes and lists up to maxDepth
+func parseBlocks(lines []string, depth int) []*block {
+	var blocks []*block
+	var para []string
+	flush := func() {
+		if len(para) > 0 {
+			blocks = append(blocks, &block{kind: blockParagraph, text: strings.Join(para, "\n")})
+			para = nil
+		}
+	}
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		trimmed := strings.TrimLeft(line, " ")
+		indent := len(line) - len(trimmed)
+
+		if strings.TrimSpace(line) == "" {
+			flush()
+			i++
+			continue
+		}
+		if indent >= 4 {
+			if len(para) > 0 {
+				// continuation of the paragraph
+				para = append(para, trimmed)
+				i++
+				continue
+			}
+			var code []string
+			j := i
+			for ; j < len(lines); j++ {
+				if strings.TrimSpace(lines[j]) == "" {
+					code = append(code, "")
+					continue
+				}
+				if leading(lines[j]) < 4 {
+					break
+				}
+				code = append(code, lines[j][4:])
+			}
+			for len(code) > 0 && code[len(code)-1] == "" {
+				code = code[:len(code)-1]
+			}
+			blocks = append(blocks, &block{kind: blockCode, text: strings.Join(code, "\n") + "\n"})
+			i = j
+			continue
+		}
+		if len(para) > 0 && isSetextUnderline(trimmed) {
+			level := 1
+			if trimmed[0] == '-' {
+				level = 2
+			}
+			blocks = append(blocks, &block{kind: blockHeading, level: level, text: strings.Join(para, "\n")})
+			para = nil
+			i++
+			continue
+		}
+		if fence, lang, ok := openingFence(trimmed); ok {
+			flush()
+			var code []string
+			j := i + 1
+			for ; j < len(lines); j++ {
+				t := strings.TrimSpace(lines[j])
+				if leading(lines[j]) < 4 && len(t) >= len(fence) && strings.Trim(t, fence[:1]) == "" {
+					break
+				}
+				// the content is dedented by the indentation of the fence
+				l := lines[j]
+				for k := 0; k < indent && strings.HasPrefix(l, " "); k++ {
+					l = l[1:]
+				}
+				code = append(code, l)
+			}
+			text := strings.Join(code, "\n")
+			if len(code) > 0 {
+				text += "\n"
+			}
+			blocks = append(blocks, &block{kind: blockCode, text: text, lang: lang})
+			i = j + 1
+			continue
+		}
+		if level := headingLevel(trimmed); level > 0 {
+			flush()
+			blocks = append(blocks, &block{kind: blockHeading, level: level, text: headingText(trimmed[level:])})
+			i++
+			continue
+		}
+		if isRule(trimmed) {
+			flush()
+			blocks = append(blocks, &block{kind: blockRule})
+			i++
+			continue
+		}
+		if strings.HasPrefix(trimmed, ">") {
+			flush()
+			var quoted []string
+			j := i
+			for ; j < len(lines); j++ {
+				t := strings.TrimLeft(lines[j], " ")
+				if leading(lines[j]) >= 4 || !strings.HasPrefix(t, ">") {
+					break
+				}
+				t = strings.TrimPrefix(t[1:], " ")
+				quoted = append(quoted, t)
+			}
+			if depth < maxDepth {
+				blocks = append(blocks, &block{kind: blockQuote, children: parseBlocks(quoted, depth+1)})
+			} else {
+				blocks = append(blocks, &block{kind: blockParagraph, text: strings.Join(quoted, "\n")})
+			}
+			i = j
+			continue
+		}
+		if m, ok := parseMarker(line); ok && (len(para) == 0 || !m.ordered || m.start == 1) {
+			flush()
+			list, j := parseList(lines, i, m, depth)
+			blocks = append(blocks, list)
+			i = j
+			continue
+		}
+		para = append(para, trimmed)
+		i++
+	}
+	flush()
+	return blocks
+}
+
+// marker is the marker of a list item
+type marker struct {
+	ordered bool
+	start   int
+	// delim is the bullet, or the '.' or ')' following the number
+	delim byte
+	// width is the indentation of the content of the item
+	width int
+}
+
+// parseMarker parses the marker of a list item starting line
+func parseMarker(line string) (marker, bool) {
+	trimmed := strings.TrimLeft(line, " ")
+	indent := len(line) - len(trimmed)
+	if indent >= 4 || trimmed == "" {
+		return marker{}, false
+	}
+	var m marker
+	n := 0
+	switch c := trimmed[0]; {
+	case c == '-' || c == '*' || c == '+':
+		m.delim, n = c, 1
+	case c >= '0' && c <= '9':
+		for n < len(trimmed) && n < 9 && trimmed[n] >= '0' && trimmed[n] <= '9' {
+			n++
+		}
+		if n == len(trimmed) || (trimmed[n] != '.' && trimmed[n] != ')') {
+			return marker{}, false
+		}
+		m.ordered = true
+		m.start, _ = strconv.Atoi(trimmed[:n])
+		m.delim = trimmed[n]
+		n++
+	default:
+		return marker{}, false
+	}
+	rest := trimmed[n:]
+	if rest != "" && rest[0] != ' ' {
+		return marker{}, false
+	}
+	spaces := leading(rest)
+	if spaces == 0 || spaces > 4 || spaces == len(rest) {
+		// an empty item, or an indented code block in the item
+		spaces = 1
+	}
+	m.width = indent + n + spaces
+	return m, true
+}
+
+// parseList parses the items of a list starting at lines[i], and returns the list with the index of the line after it
+func parseList(lines []string, i int, first marker, depth int) (*block, int) {
+	list := &block{kind: blockList, ordered: first.ordered, start: first.start, tight: true}
+	j := i
+	for j < len(lines) {
+		m, ok := parseMarker(lines[j])
+		if !ok || m.ordered != first.ordered || m.delim != first.delim || isRule(lines[j]) {
+			break
+		}
+		var item []string
+		if m.width < len(lines[j]) {
+			item = append(item, lines[j][m.width:])
+		} else {
+			item = append(item, "")
+		}
+		j++
+	collect:
+		for j < len(lines) {
+			l := lines[j]
+			switch {
+			case strings.TrimSpace(l) == "":
+				item = append(item, "")
+			case leading(l) >= m.width:
+				item = append(item, l[m.width:])
+			case item[len(item)-1] != "" && !startsBlock(l):
+				// lazy continuation of a paragraph
+				item = append(item, strings.TrimLeft(l, " "))
+			default:
+				break collect
+			}
+			j++
+		}
+		trailing := false
+		for len(item) > 1 && item[len(item)-1] == "" {
+			item = item[:len(item)-1]
+			trailing = true
+		}
+		for _, l := range item[1:] {
+			if l == "" {
+				list.tight = false
+			}
+		}
+		if trailing && j < len(lines) {
+			if next, ok := parseMarker(lines[j]); ok && next.ordered == first.ordered && next.delim == first.delim {
+				list.tight = false
+			}
+		}
+		if depth < maxDepth {
+			list.items = append(list.items, parseBlocks(item, depth+1))
+		} else {
+			list.items = append(list.items, []*block{{kind: blockParagraph, text: strings.Join(item, "\n")}})
+		}
+	}
+	return list, j
+}
+
+// startsBlock tells whether line starts a block other than a paragraph
+func startsBlock(line string) bool {
+	trimmed := strings.TrimLeft(line, " ")
+	if leading(line) >= 4 {
+		return false
+	}
+	_, _, fence := openingFence(trimmed)
+	_, item := parseMarker(line)
+	return fence || item || headingLevel(trimmed) > 0 || isRule(trimmed) || strings.HasPrefix(trimmed, ">")
+}
+
+// openingFence returns the fence of a fenced code block, and the language of its info string
+func openingFence(trimmed string) (fence, lang string, ok bool) {
+	if !strings.HasPrefix(trimmed, "```") && !strings.HasPrefix(trimmed, "~~~") {
+		return "", "", false
+	}
+	n := 3
+	for n < len(trimmed) && trimmed[n] == trimmed[0] {
+		n++
+	}
+	info := strings.TrimSpace(trimmed[n:])
+	if trimmed[0] == '`' && strings.Contains(info, "`") {
+		return "", "", false
+	}
+	if fields := strings.Fields(info); len(fields) > 0 {
+		lang = strings.Map(func(r rune) rune {
+			if r == '-' || r == '_' || r == '+' || r == '#' || r == '.' || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') || ('0' <= r && r <= '9') {
+				return r
+			}
+			return -1
+		}, fields[0])
+	}
+	return trimmed[:n], lang, true
+}
+
+// headingLevel returns the level of an ATX heading, or 0
+func headingLevel(trimmed string) int {
+	n := 0
+	for n < len(trimmed) && trimmed[n] == '#' {
+		n++
+	}
+	if n == 0 || n > 6 || (n < len(trimmed) && trimmed[n] != ' ') {
+		return 0
+	}
+	return n
+}
+
+// headingText returns the text of an ATX heading without its closing sequence of '#'
+func headingText(s string) string {
+	s = strings.TrimSpace(s)
+	if t := strings.TrimRight(s, "#"); t == "" || strings.HasSuffix(t, " ") {
+		s = strings.TrimSpace(t)
+	}
+	return s
+}
+
+// isRule tells whether trimmed is a thematic break, three or more '-', '*', or '_' optionally separated by spaces
+func isRule(trimmed string) bool {
+	s := strings.ReplaceAll(strings.TrimSpace(trimmed), " ", "")
+	if len(s) < 3 || (s[0] != '-' && s[0] != '*' && s[0] != '_') {
+		return false
+	}
+	return strings.Trim(s, s[:1]) == ""
+}
+
+// isSetextUnderline tells whether trimmed underlines the paragraph above it as a heading
+func isSetextUnderline(trimmed string) bool {
+	s := strings.TrimRight(trimmed, " ")
+	return s != "" && (s[0] == '=' || s[0] == '-') && strings.Trim(s, s[:1]) == ""
+}
+
+// leading returns the number of leading spaces of s
+func leading(s string) int {
+	return len(s) - len(strings.TrimLeft(s, " "))
+}
@@ -0,0 +1,376 @@
+package markdown
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+// kinds of inline element
+const (
+	inlineText = iota
+	inlineCode
+	inlineEmphasis
+	inlineStrong
+	inlineDelete
+	inlineBreak
+	inlineHTML
+	inlineLink
+	inlineImage
+)
+
+type inline struct {
+	kind int
+	// text is the text of a text or code, the raw HTML of a tag, or the URL of a link or an image
+	text  string
+	title string
+	// allowed tells whether a tag is among AllowedTags without attributes
+	allowed  bool
+	children []*inline
+}
+
+var (
+	autolink  = regexp.MustCompile(`^<([A-Za-z][A-Za-z0-9+.-]{1,31}:[^\s<>]*)>`)
+	autoemail = regexp.MustCompile(`^<([A-Za-z0-9.!#$%&'*+/=?^_{|}~-]+@[A-Za-z0-9](?:[A-Za-z0-9-]*[A-Za-z0-9])?(?:\.[A-Za-z0-9](?:[A-Za-z0-9-]*[A-Za-z0-9])?)*)>`)
+	htmlTag   = regexp.MustCompile(`^<(/?)([A-Za-z][A-Za-z0-9-]*)((?:\s[^<>]*)?/?)>`)
+	// comments, processing instructions, declarations, and CDATA
+	htmlOther = regexp.MustCompile(`^(?s:<!--.*?-->|<\?.*?\?>|<![A-Za-z\[].*?>)`)
+	entity    = regexp.MustCompile(`^&(?:#[0-9]{1,7}|#[xX][0-9a-fA-F]{1,6}|[A-Za-z][A-Za-z0-9]{1,31});`)
+)
+
+// maxDestination bounds the length of the destination and the title of a link
+const maxDestination = 4096
+
+type inlineParser struct {
+	s     string
+	depth int
+	// brackets maps the index of each '[' to the index of its matching ']'
+	brackets map[int]int
+	// noCloser maps a delimiter to the smallest index after which it has no closer
+	noCloser map[string]int
+}
+
+// parseInline parses the inline elements of s, nested depth elements deep
+func parseInline(s string, depth int) []*inline {
+	if depth >= maxDepth {
+		return []*inline{{kind: inlineText, text: s}}
+	}
+	p := &inlineParser{s: s, depth: depth, noCloser: make(map[string]int)}
+	return p.parse()
+}
+
+func (p *inlineParser) parse() []*inline {
+	s := p.s
+	var nodes []*inline
+	var text strings.Builder
+	add := func(n *inline) {
+		if text.Len() > 0 {
+			nodes = append(nodes, &inline{kind: inlineText, text: text.String()})
+			text.Reset()
+		}
+		nodes = append(nodes, n)
+	}
+	for i := 0; i < len(s); {
+		switch c := s[i]; c {
+		case '\\':
+			if i+1 < len(s) && s[i+1] == '\n' {
+				add(&inline{kind: inlineBreak})
+				i += 2
+				continue
+			}
+			if i+1 < len(s) && strings.IndexByte("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", s[i+1]) >= 0 {
+				text.WriteByte(s[i+1])
+				i += 2
+				continue
+			}
+		case '\n':
+			// the text is flushed on every line so that the trailing spaces are trimmed without copying it again
+			t := text.String()
+			text.Reset()
+			if strings.HasSuffix(t, "  ") {
+				nodes = append(nodes, &inline{kind: inlineText, text: strings.TrimRight(t, " ")}, &inline{kind: inlineBreak})
+			} else {
+				nodes = append(nodes, &inline{kind: inlineText, text: strings.TrimRight(t, " ") + "\n"})
+			}
+			for i++; i < len(s) && s[i] == ' '; i++ {
+			}
+			continue
+		case '`':
+			n := run(s, i)
+			if end := p.closer(s[i:i+n], i+n, false); end >= 0 {
+				add(&inline{kind: inlineCode, text: codeText(s[i+n : end])})
+				i = end + n
+				continue
+			}
+			text.WriteString(s[i : i+n])
+			i += n
+			continue
+		case '*', '_', '~':
+			if n, end := p.emphasis(i); end >= 0 {
+				kind := inlineEmphasis
+				if c == '~' {
+					kind = inlineDelete
+				} else if n == 2 {
+					kind = inlineStrong
+				}
+				add(&inline{kind: kind, children: parseInline(s[i+n:end], p.depth+1)})
+				i = end + n
+				continue
+			}
+			n := run(s, i)
+			text.WriteString(s[i : i+n])
+			i += n
+			continue
+		case '!', '[':
+			if c == '!' && (i+1 == len(s) || s[i+1] != '[') {
+				break
+			}
+			open := i
+			if c == '!' {
+				open++
+			}
+			if n, end, ok := p.link(open); ok {
+				if c == '!' {
+					n.kind = inlineImage
+				}
+				add(n)
+				i = end
+				continue
+			}
+			text.WriteString(s[i : open+1])
+			i = open + 1
+			continue
+		case '<':
+			if m := autolink.FindStringSubmatch(s[i:]); m != nil {
+				add(&inline{kind: inlineLink, text: m[1], children: []*inline{{kind: inlineText, text: m[1]}}})
+				i += len(m[0])
+				continue
+			}
+			if m := autoemail.FindStringSubmatch(s[i:]); m != nil {
+				add(&inline{kind: inlineLink, text: "mailto:" + m[1], children: []*inline{{kind: inlineText, text: m[1]}}})
+				i += len(m[0])
+				continue
+			}
+			if m := htmlTag.FindStringSubmatch(s[i:]); m != nil {
+				name := strings.ToLower(m[2])
+				allowed := AllowedTags[name] && strings.TrimSpace(strings.TrimSuffix(m[3], "/")) == "" && (m[1] == "" || m[3] == "")
+				tag := m[0]
+				if allowed {
+					tag = "<" + m[1] + name + ">"
+				}
+				add(&inline{kind: inlineHTML, text: tag, allowed: allowed})
+				i += len(m[0])
+				continue
+			}
+			if m := htmlOther.FindString(s[i:]); m != "" {
+				add(&inline{kind: inlineHTML, text: m})
+				i += len(m)
+				continue
+			}
+		case '&':
+			if m := entity.FindString(s[i:]); m != "" {
+				text.WriteString(html.UnescapeString(m))
+				i += len(m)
+				continue
+			}
+		}
+		text.WriteByte(s[i])
+		i++
+	}
+	if text.Len() > 0 {
+		nodes = append(nodes, &inline{kind: inlineText, text: text.String()})
+	}
+	return nodes
+}
+
+// emphasis returns the length of the delimiter of the emphasis opened at i, and the index of its closer, or -1
+func (p *inlineParser) emphasis(i int) (int, int) {
+	s := p.s
+	c := s[i]
+	n := run(s, i)
+	if i+n == len(s) || isSpace(s[i+n]) {
+		return 0, -1
+	}
+	if c == '_' && i > 0 && isAlnum(s[i-1]) {
+		return 0, -1
+	}
+	lengths := []int{2, 1}
+	if c == '~' {
+		lengths = []int{2}
+	}
+	for _, l := range lengths {
+		if n < l {
+			continue
+		}
+		if end := p.closer(s[i:i+l], i+l, true); end >= 0 {
+			return l, end
+		}
+	}
+	return 0, -1
+}
+
+// closer returns the index of the first closer of delimiter d after from, or -1.
+// The closer of an emphasis follows a non space, and for '_' doesn't precede an alphanumeric character, while the
+// closer of a code span is a run of backticks of the same length.
+func (p *inlineParser) closer(d string, from int, emphasis bool) int {
+	if v, ok := p.noCloser[d]; ok && from >= v {
+		return -1
+	}
+	s := p.s
+	for j := from; j < len(s); {
+		k := strings.Index(s[j:], d)
+		if k < 0 {
+			break
+		}
+		k += j
+		if emphasis {
+			if k > from && !isSpace(s[k-1]) && (d[0] != '_' || k+len(d) == len(s) || !isAlnum(s[k+len(d)])) {
+				return k
+			}
+			j = k + 1
+			continue
+		}
+		if n := run(s, k); n != len(d) {
+			j = k + n
+			continue
+		}
+		return k
+	}
+	p.noCloser[d] = from
+	return -1
+}
+
+// link parses a link whose text opens at i, and returns it with the index following it
+func (p *inlineParser) link(i int) (*inline, int, bool) {
+	s := p.s
+	end := p.bracket(i)
+	if end < 0 || end+1 >= len(s) || s[end+1] != '(' {
+		return nil, 0, false
+	}
+	dest, title, after, ok := destination(s, end+2)
+	if !ok {
+		return nil, 0, false
+	}
+	return &inline{kind: inlineLink, text: dest, title: title, children: parseInline(s[i+1:end], p.depth+1)}, after, true
+}
+
+// bracket returns the index of the ']' matching the '[' at i, or -1
+func (p *inlineParser) bracket(i int) int {
+	if p.brackets == nil {
+		p.brackets = make(map[int]int)
+		var open []int
+		for j := 0; j < len(p.s); j++ {
+			switch p.s[j] {
+			case '\\':
+				j++
+			case '[':
+				open = append(open, j)
+			case ']':
+				if len(open) > 0 {
+					p.brackets[open[len(open)-1]] = j
+					open = open[:len(open)-1]
+				}
+			}
+		}
+	}
+	if end, ok := p.brackets[i]; ok {
+		return end
+	}
+	return -1
+}
+
+// destination parses the destination and the optional title of a link starting at i, up to the closing ')'.
+// Both are at most maxDestination bytes, so that unclosed links can't make parsing quadratic.
+func destination(s string, i int) (dest, title string, after int, ok bool) {
+	if len(s) > i+maxDestination {
+		s = s[:i+maxDestination]
+	}
+	i = skipSpaces(s, i)
+	var b strings.Builder
+	if i < len(s) && s[i] == '<' {
+		j := i + 1
+		for ; j < len(s) && s[j] != '>' && s[j] != '\n' && s[j] != '<'; j++ {
+		}
+		if j == len(s) || s[j] != '>' {
+			return "", "", 0, false
+		}
+		b.WriteString(s[i+1 : j])
+		i = j + 1
+	} else {
+		depth := 0
+	dest:
+		for ; i < len(s); i++ {
+			switch c := s[i]; {
+			case c == '\\' && i+1 < len(s) && s[i+1] != '\n' && !isSpace(s[i+1]):
+				i++
+				b.WriteByte(s[i])
+				continue
+			case isSpace(c):
+				break dest
+			case c == '(':
+				depth++
+			case c == ')':
+				if depth == 0 {
+					break dest
+				}
+				depth--
+			}
+			b.WriteByte(s[i])
+		}
+	}
+	i = skipSpaces(s, i)
+	if i < len(s) && (s[i] == '"' || s[i] == '\'' || s[i] == '(') {
+		closing := s[i]
+		if closing == '(' {
+			closing = ')'
+		}
+		j := i + 1
+		for ; j < len(s) && s[j] != closing; j++ {
+			if s[j] == '\\' {
+				j++
+			}
+		}
+		if j >= len(s) {
+			return "", "", 0, false
+		}
+		title = html.UnescapeString(s[i+1 : j])
+		i = skipSpaces(s, j+1)
+	}
+	if i >= len(s) || s[i] != ')' {
+		return "", "", 0, false
+	}
+	return html.UnescapeString(b.String()), title, i + 1, true
+}
+
+// codeText returns the content of a code span, with line endings as spaces and one space stripped from both ends
+func codeText(s string) string {
+	s = strings.ReplaceAll(s, "\n", " ")
+	if len(s) > 1 && s[0] == ' ' && s[len(s)-1] == ' ' && strings.TrimSpace(s) != "" {
+		s = s[1 : len(s)-1]
+	}
+	return s
+}
+
+// run returns the length of the run of the character at i
+func run(s string, i int) int {
+	n := 1
+	for i+n < len(s) && s[i+n] == s[i] {
+		n++
+	}
+	return n
+}
+
+func skipSpaces(s string, i int) int {
+	for i < len(s) && isSpace(s[i]) {
+		i++
+	}
+	return i
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\t'
+}
+
+func isAlnum(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c >= 0x80
+}
@@ -0,0 +1,309 @@
+// Package markdown renders the Markdown descriptions of applications to sanitized HTML, and extracts their headings,
+// links, and a plain text excerpt.
+//
+// It supports the common subset of CommonMark: ATX and setext headings, paragraphs, block quotes, bullet and ordered
+// lists, fenced and indented code, thematic breaks, emphasis, strikethrough, code spans, links, images, and autolinks.
+// Raw HTML is escaped, except for AllowedTags without attributes, and only http, https, mailto, and relative links
+// are rendered as links.
+package markdown
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"strings"
+	"unicode"
+)
+
+// ExcerptLength is the maximum number of characters of an excerpt, before its ellipsis
+const ExcerptLength = 200
+
+// maxDepth bounds the nesting of block quotes, lists, and inline elements, deeper ones are rendered as text
+const maxDepth = 16
+
+// AllowedTags are the raw HTML tags a description may contain, without attributes
+var AllowedTags = map[string]bool{
+	"b":      true,
+	"br":     true,
+	"code":   true,
+	"del":    true,
+	"em":     true,
+	"i":      true,
+	"kbd":    true,
+	"mark":   true,
+	"s":      true,
+	"small":  true,
+	"strong": true,
+	"sub":    true,
+	"sup":    true,
+	"u":      true,
+}
+
+// Heading is a heading of a description, with the id of its anchor in the HTML
+type Heading struct {
+	Level int    `yaml:"level" json:"level"`
+	Text  string `yaml:"text" json:"text"`
+	ID    string `yaml:"id" json:"id"`
+}
+
+// Link is a link of a description, images aside
+type Link struct {
+	Text string `yaml:"text" json:"text"`
+	URL  string `yaml:"url" json:"url"`
+}
+
+// Document is a rendered description
+type Document struct {
+	HTML string `yaml:"html" json:"html"`
+	// Excerpt is the beginning of the text of the paragraphs, lists, and quotes, without markup
+	Excerpt  string    `yaml:"excerpt" json:"excerpt"`
+	Headings []Heading `yaml:"headings" json:"headings"`
+	Links    []Link    `yaml:"links" json:"links"`
+}
+
+// Render renders src to sanitized HTML
+func Render(src string) *Document {
+	return render(src).doc
+}
+
+// Excerpt returns the excerpt of src, see Document.Excerpt
+func Excerpt(src string) string {
+	return Render(src).Excerpt
+}
+
+// Validate checks that src is at most maxSize bytes and doesn't contain raw HTML besides AllowedTags without attributes
+func Validate(src string, maxSize int) error {
+	if len(src) > maxSize {
+		return fmt.Errorf("description is longer than %d bytes", maxSize)
+	}
+	if r := render(src); len(r.disallowed) > 0 {
+		tag := r.disallowed[0]
+		if len(tag) > 40 {
+			tag = tag[:40] + "..."
+		}
+		return fmt.Errorf("raw HTML %s is not allowed in description", tag)
+	}
+	return nil
+}
+
+type renderer struct {
+	doc        *Document
+	html       strings.Builder
+	ids        map[string]int
+	text       []string
+	disallowed []string
+}
+
+func render(src string) *renderer {
+	src = strings.ReplaceAll(strings.ReplaceAll(src, "\r\n", "\n"), "\t", "    ")
+	r := &renderer{
+		doc: &Document{Headings: []Heading{}, Links: []Link{}},
+		ids: make(map[string]int),
+	}
+	r.blocks(parseBlocks(strings.Split(src, "\n"), 0), false, true)
+	r.doc.HTML = r.html.String()
+	r.doc.Excerpt = excerpt(strings.Join(r.text, " "), ExcerptLength)
+	return r
+}
+
+// blocks writes the HTML of blocks, the paragraphs of a tight list without <p>.  The text of the paragraphs goes to the
+// excerpt unless they're in a heading.
+func (r *renderer) blocks(blocks []*block, tight, excerpted bool) {
+	for i, b := range blocks {
+		switch b.kind {
+		case blockParagraph:
+			nodes := parseInline(b.text, 0)
+			if tight {
+				r.inlines(&r.html, nodes, false)
+				if i < len(blocks)-1 {
+					r.html.WriteString("\n")
+				}
+			} else {
+				r.html.WriteString("<p>")
+				r.inlines(&r.html, nodes, false)
+				r.html.WriteString("</p>\n")
+			}
+			if excerpted {
+				r.text = append(r.text, plain(nodes))
+			}
+		case blockHeading:
+			nodes := parseInline(b.text, 0)
+			text := strings.Join(strings.Fields(plain(nodes)), " ")
+			id := r.id(text)
+			r.doc.Headings = append(r.doc.Headings, Heading{Level: b.level, Text: text, ID: id})
+			fmt.Fprintf(&r.html, "<h%d id=\"%s\">", b.level, html.EscapeString(id))
+			r.inlines(&r.html, nodes, false)
+			fmt.Fprintf(&r.html, "</h%d>\n", b.level)
+		case blockCode:
+			r.html.WriteString("<pre><code")
+			if b.lang != "" {
+				fmt.Fprintf(&r.html, " class=\"language-%s\"", html.EscapeString(b.lang))
+			}
+			r.html.WriteString(">")
+			r.html.WriteString(html.EscapeString(b.text))
+			r.html.WriteString("</code></pre>\n")
+		case blockQuote:
+			r.html.WriteString("<blockquote>\n")
+			r.blocks(b.children, false, excerpted)
+			r.html.WriteString("</blockquote>\n")
+		case blockList:
+			tag := "ul"
+			if b.ordered {
+				tag = "ol"
+			}
+			if b.ordered && b.start != 1 {
+				fmt.Fprintf(&r.html, "<ol start=\"%d\">\n", b.start)
+			} else {
+				fmt.Fprintf(&r.html, "<%s>\n", tag)
+			}
+			for _, item := range b.items {
+				r.html.WriteString("<li>")
+				if !b.tight && len(item) > 0 {
+					r.html.WriteString("\n")
+				}
+				r.blocks(item, b.tight, excerpted)
+				r.html.WriteString("</li>\n")
+			}
+			fmt.Fprintf(&r.html, "</%s>\n", tag)
+		case blockRule:
+			r.html.WriteString("<hr>\n")
+		}
+	}
+}
+
+// inlines writes the HTML of nodes, rendering the links of a link's text as text
+func (r *renderer) inlines(w *strings.Builder, nodes []*inline, inLink bool) {
+	for _, n := range nodes {
+		switch n.kind {
+		case inlineText:
+			w.WriteString(html.EscapeString(n.text))
+		case inlineCode:
+			w.WriteString("<code>" + html.EscapeString(n.text) + "</code>")
+		case inlineEmphasis, inlineStrong, inlineDelete:
+			tag := map[int]string{inlineEmphasis: "em", inlineStrong: "strong", inlineDelete: "del"}[n.kind]
+			w.WriteString("<" + tag + ">")
+			r.inlines(w, n.children, inLink)
+			w.WriteString("</" + tag + ">")
+		case inlineBreak:
+			w.WriteString("<br>\n")
+		case inlineHTML:
+			if n.allowed {
+				w.WriteString(n.text)
+			} else {
+				r.disallowed = append(r.disallowed, n.text)
+				w.WriteString(html.EscapeString(n.text))
+			}
+		case inlineLink:
+			if inLink || !safeLink(n.text) {
+				r.inlines(w, n.children, inLink)
+				continue
+			}
+			r.doc.Links = append(r.doc.Links, Link{Text: strings.Join(strings.Fields(plain(n.children)), " "), URL: n.text})
+			fmt.Fprintf(w, "<a href=\"%s\"", html.EscapeString(n.text))
+			if n.title != "" {
+				fmt.Fprintf(w, " title=\"%s\"", html.EscapeString(n.title))
+			}
+			w.WriteString(" rel=\"nofollow noopener noreferrer\">")
+			r.inlines(w, n.children, true)
+			w.WriteString("</a>")
+		case inlineImage:
+			alt := plain(n.children)
+			if !safeImage(n.text) {
+				w.WriteString(html.EscapeString(alt))
+				continue
+			}
+			fmt.Fprintf(w, "<img src=\"%s\" alt=\"%s\"", html.EscapeString(n.text), html.EscapeString(alt))
+			if n.title != "" {
+				fmt.Fprintf(w, " title=\"%s\"", html.EscapeString(n.title))
+			}
+			w.WriteString(">")
+		}
+	}
+}
+
+// plain returns the text of nodes without markup
+func plain(nodes []*inline) string {
+	var b strings.Builder
+	for _, n := range nodes {
+		switch n.kind {
+		case inlineText, inlineCode:
+			b.WriteString(n.text)
+		case inlineBreak:
+			b.WriteString("\n")
+		case inlineHTML:
+			if !n.allowed {
+				b.WriteString(n.text)
+			} else if n.text == "<br>" {
+				b.WriteString("\n")
+			}
+		default:
+			b.WriteString(plain(n.children))
+		}
+	}
+	return b.String()
+}
+
+// id returns the anchor of a heading, lowercase words joined by '-' and unique in the document
+func (r *renderer) id(text string) string {
+	var b strings.Builder
+	dash := false
+	for _, c := range strings.ToLower(text) {
+		switch {
+		case unicode.IsLetter(c) || unicode.IsDigit(c):
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			dash = false
+			b.WriteRune(c)
+		case c == ' ' || c == '-' || c == '_':
+			dash = true
+		}
+	}
+	id := b.String()
+	if id == "" {
+		id = "section"
+	}
+	n := r.ids[id]
+	r.ids[id]++
+	if n > 0 {
+		id = fmt.Sprintf("%s-%d", id, n)
+	}
+	return id
+}
+
+// safeLink tells whether u is an http, https, mailto, or relative URL
+func safeLink(u string) bool {
+	parsed, err := url.Parse(strings.TrimSpace(u))
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(parsed.Scheme) {
+	case "", "http", "https", "mailto":
+		return true
+	}
+	return false
+}
+
+// safeImage tells whether u is an absolute http or https URL
+func safeImage(u string) bool {
+	parsed, err := url.Parse(strings.TrimSpace(u))
+	if err != nil {
+		return false
+	}
+	scheme := strings.ToLower(parsed.Scheme)
+	return (scheme == "http" || scheme == "https") && parsed.Host != ""
+}
+
+// excerpt returns s with its whitespace collapsed, cut at a word boundary to at most n characters followed by an ellipsis
+func excerpt(s string, n int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	cut := string(runes[:n])
+	if i := strings.LastIndex(cut, " "); i > 0 {
+		cut = cut[:i]
+	}
+	return strings.TrimRight(cut, " ,.;:") + "…"
+}
@@ -0,0 +1,84 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRender_Blocks_ResultedHTML(t *testing.T) {
+
+	tests := []struct {
+		src  string
+		html string
+	}{
+		{src: "### Interesting title\nSome *application* content", html: "<h3 id=\"interesting-title\">Interesting title</h3>\n<p>Some <em>application</em> content</p>\n"},
+		{src: "Title\n=====\n\nSub ##\n---", html: "<h1 id=\"title\">Title</h1>\n<h2 id=\"sub\">Sub ##</h2>\n"},
+		{src: "- a\n- **b**\n  c\n\n---", html: "<ul>\n<li>a</li>\n<li><strong>b</strong>\nc</li>\n</ul>\n<hr>\n"},
+		{src: "3. a\n\n4. b", html: "<ol start=\"3\">\n<li>\n<p>a</p>\n</li>\n<li>\n<p>b</p>\n</li>\n</ol>\n"},
+		{src: "> quote\n> - item", html: "<blockquote>\n<p>quote</p>\n<ul>\n<li>item</li>\n</ul>\n</blockquote>\n"},
+		{src: "```go\nif a < b {}\n```", html: "<pre><code class=\"language-go\">if a &lt; b {}\n</code></pre>\n"},
+		{src: "    indented\n    code", html: "<pre><code>indented\ncode\n</code></pre>\n"},
+		{src: "`a <b>` ~~old~~ line  \nbreak", html: "<p><code>a &lt;b&gt;</code> <del>old</del> line<br>\nbreak</p>\n"},
+		{src: `\*not emphasis\* snake_case_name &copy; &lt;b&gt;`, html: "<p>*not emphasis* snake_case_name © &lt;b&gt;</p>\n"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.html, Render(tt.src).HTML, tt.src)
+	}
+}
+
+func TestRender_Sanitized_ResultedSafeHTML(t *testing.T) {
+
+	tests := []struct {
+		src  string
+		html string
+	}{
+		{src: "<script>alert(1)</script>", html: "<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>\n"},
+		{src: "<b>bold</b><br/> <B onclick=\"x()\">no</B>", html: "<p><b>bold</b><br> &lt;B onclick=&#34;x()&#34;&gt;no</b></p>\n"},
+		{src: "<!-- hidden -->", html: "<p>&lt;!-- hidden --&gt;</p>\n"},
+		{src: "[x](javascript:alert(1)) [y](JaVaScRiPt:alert(1)) [z](data:text/html,x)", html: "<p>x y z</p>\n"},
+		{src: `[x](https://example.com/?a=1&b="2" "t")`, html: "<p><a href=\"https://example.com/?a=1&amp;b=&#34;2&#34;\" title=\"t\" rel=\"nofollow noopener noreferrer\">x</a></p>\n"},
+		{src: "![logo](https://example.com/logo.png) ![x](javascript:alert(1))", html: "<p><img src=\"https://example.com/logo.png\" alt=\"logo\"> x</p>\n"},
+		{src: "<https://example.com> <dev@example.com>", html: "<p><a href=\"https://example.com\" rel=\"nofollow noopener noreferrer\">https://example.com</a> <a href=\"mailto:dev@example.com\" rel=\"nofollow noopener noreferrer\">dev@example.com</a></p>\n"},
+		{src: "[[nested](https://a.example)](https://b.example)", html: "<p><a href=\"https://b.example\" rel=\"nofollow noopener noreferrer\">nested</a></p>\n"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.html, Render(tt.src).HTML, tt.src)
+	}
+}
+
+func TestRender_Extracted_ResultedHeadingsLinksAndExcerpt(t *testing.T) {
+
+	doc := Render("# Payments\n\nHandles *card* payments, see [the docs](https://docs.example.com).\n\n" +
+		"## Setup\n\n```\nmake\n```\n\n- [Runbook](/runbook)\n- [bad](javascript:x)\n\n## Setup\n")
+
+	assert.Equal(t, []Heading{
+		{Level: 1, Text: "Payments", ID: "payments"},
+		{Level: 2, Text: "Setup", ID: "setup"},
+		{Level: 2, Text: "Setup", ID: "setup-1"},
+	}, doc.Headings)
+	assert.Equal(t, []Link{
+		{Text: "the docs", URL: "https://docs.example.com"},
+		{Text: "Runbook", URL: "/runbook"},
+	}, doc.Links)
+	assert.Equal(t, "Handles card payments, see the docs. Runbook bad", doc.Excerpt)
+
+	long := Excerpt(strings.Repeat("word ", 100))
+	assert.True(t, strings.HasSuffix(long, "word…"))
+	assert.True(t, len([]rune(long)) <= ExcerptLength+1)
+
+	empty := Render("")
+	assert.Equal(t, "", empty.HTML)
+	assert.Equal(t, []Heading{}, empty.Headings)
+}
+
+func TestValidate_Description_ResultedError(t *testing.T) {
+
+	assert.Nil(t, Validate("### Title\n<b>bold</b> <kbd>Ctrl</kbd>\n```html\n<script>shown as code</script>\n```", 1000))
+	assert.EqualError(t, Validate(strings.Repeat("a", 11), 10), "description is longer than 10 bytes")
+	assert.EqualError(t, Validate("hi <script>alert(1)</script>", 1000), "raw HTML <script> is not allowed in description")
+	assert.EqualError(t, Validate("<b class=\"x\">bold</b>", 1000), "raw HTML <b class=\"x\"> is not allowed in description")
+	assert.EqualError(t, Validate("<iframe src=\"https://evil.example/aaaaaaaaaaaaaaaa\">", 1000),
+		"raw HTML <iframe src=\"https://evil.example/aaaaaa... is not allowed in description")
+}
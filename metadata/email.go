@@ -0,0 +1,109 @@
+package metadata
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/elumbantoruan/app-metadata/markdown"
+
+	"golang.org/x/net/idna"
+	"golang.org/x/text/unicode/norm"
+)
+
+// hostname matches a domain in ASCII form, IDN labels being punycode
+var hostname = regexp.MustCompile(`^[a-z0-9](?:[a-z0-9-]{0,61}[a-z0-9])?(?:\.[a-z0-9](?:[a-z0-9-]{0,61}[a-z0-9])?)*$`)
+
+// atext are the ASCII characters allowed in the local part of an email address, besides letters and digits
+const atext = ".!#$%&'*+/=?^_`{|}~-"
+
+// ValidateEmail checks an email address, whose local part may hold non-ASCII characters (RFC 6531) and whose
+// domain may be internationalized
+func ValidateEmail(email string) error {
+	i := strings.LastIndex(email, "@")
+	if i <= 0 {
+		return errors.New("missing local part or domain")
+	}
+	local, domain := email[:i], email[i+1:]
+	if len(local) > 64 {
+		return errors.New("local part is longer than 64 bytes")
+	}
+	for _, r := range local {
+		switch {
+		case r <= unicode.MaxASCII:
+			if !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9' || strings.ContainsRune(atext, r)) {
+				return fmt.Errorf("invalid character %q in local part", r)
+			}
+		case !unicode.IsGraphic(r) || unicode.IsSpace(r):
+			return fmt.Errorf("invalid character %U in local part", r)
+		}
+	}
+	ascii, err := idna.Lookup.ToASCII(norm.NFC.String(domain))
+	if err != nil || !hostname.MatchString(ascii) || len(ascii) > 253 {
+		return fmt.Errorf("invalid domain %s", domain)
+	}
+	return nil
+}
+
+// CanonicalEmail returns the form an email address is stored in: trimmed, in Unicode normalization form C, with the
+// domain in lowercase punycode.  The case of the local part is kept.
+func CanonicalEmail(email string) string {
+	email = norm.NFC.String(strings.TrimSpace(email))
+	i := strings.LastIndex(email, "@")
+	if i < 0 {
+		return email
+	}
+	return email[:i+1] + NormalizeDomain(email[i+1:])
+}
+
+// NormalizeEmail returns the form of an email address maintainers are keyed by and compared in, the canonical form
+// in lowercase
+func NormalizeEmail(email string) string {
+	return strings.ToLower(CanonicalEmail(email))
+}
+
+// NormalizeDomain returns a domain in lowercase punycode without trailing dot, or only in lowercase when it isn't a
+// valid IDN
+func NormalizeDomain(domain string) string {
+	domain = strings.TrimSuffix(strings.TrimSpace(domain), ".")
+	if ascii, err := idna.Lookup.ToASCII(norm.NFC.String(domain)); err == nil {
+		return ascii
+	}
+	return strings.ToLower(domain)
+}
+
+// NormalizeText returns s in Unicode normalization form C, so that composed and decomposed accents compare equal
+func NormalizeText(s string) string {
+	return norm.NFC.String(s)
+}
+
+// Normalize puts the title and the names of the maintainers in normalization form C, their emails in canonical
+// form, see CanonicalEmail, and the website and source URLs in canonical form as well, see CanonicalURL.
+// It derives VCS from the source, and Excerpt from the description, and normalizes the releases, see Release.Normalize.
+func (am *ApplicationMetadata) Normalize() {
+	am.Title = NormalizeText(am.Title)
+	am.Website = CanonicalURL(am.Website)
+	am.Source = CanonicalURL(am.Source)
+	am.VCS = ParseVCS(am.Source)
+	am.Excerpt = markdown.Excerpt(am.Description)
+	if len(am.Maintainers) > 0 {
+		maintainers := make([]Maintainer, len(am.Maintainers))
+		for i, m := range am.Maintainers {
+			m.Name = NormalizeText(m.Name)
+			m.Email = CanonicalEmail(m.Email)
+			maintainers[i] = m
+		}
+		am.Maintainers = maintainers
+	}
+	if len(am.Releases) > 0 {
+		releases := make([]Release, len(am.Releases))
+		for i, r := range am.Releases {
+			r.Artifacts = append([]Artifact(nil), r.Artifacts...)
+			r.Normalize()
+			releases[i] = r
+		}
+		am.Releases = releases
+	}
+}
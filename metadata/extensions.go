@@ -0,0 +1,80 @@
+package metadata
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// Extensions holds structured data the core fields don't model, such as an on-call rotation or SLO targets,
+// keyed by namespace.  The document of each namespace is validated against the JSON Schema registered for it.
+type Extensions map[string]interface{}
+
+// UnmarshalYAML decodes the documents with string keys as json does, so that they can be validated and encoded in json
+func (e *Extensions) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var m map[string]interface{}
+	if err := unmarshal(&m); err != nil {
+		return err
+	}
+	for k, v := range m {
+		m[k] = Normalize(v)
+	}
+	*e = m
+	return nil
+}
+
+// Normalize converts the map[interface{}]interface{} decoded by yaml into map[string]interface{}, recursively
+func Normalize(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[fmt.Sprint(k)] = Normalize(e)
+		}
+		return m
+	case map[string]interface{}:
+		for k, e := range v {
+			v[k] = Normalize(e)
+		}
+		return v
+	case []interface{}:
+		for i, e := range v {
+			v[i] = Normalize(e)
+		}
+		return v
+	}
+	return v
+}
+
+// Flatten returns the scalar values of the extensions keyed by their dotted path, such as oncall.rotation.
+// Arrays aren't flattened.
+func (e Extensions) Flatten() map[string]string {
+	flat := make(map[string]string)
+	var walk func(path string, v interface{})
+	walk = func(path string, v interface{}) {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			keys := make([]string, 0, len(v))
+			for k := range v {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				walk(path+"."+k, v[k])
+			}
+		case []interface{}:
+		case nil:
+			flat[path] = ""
+		case string:
+			flat[path] = v
+		case float64:
+			flat[path] = strconv.FormatFloat(v, 'f', -1, 64)
+		default:
+			flat[path] = fmt.Sprint(v)
+		}
+	}
+	for ns, v := range e {
+		walk(ns, v)
+	}
+	return flat
+}
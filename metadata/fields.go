@@ -0,0 +1,111 @@
+package metadata
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ExtensionsField prefixes the dotted path of an extension value in a field selector, such as extensions.oncall.rotation
+const ExtensionsField = "extensions."
+
+// selectableFields returns the value of the core fields a field selector can match
+var selectableFields = map[string]func(am *ApplicationMetadata) string{
+	"applicationID": func(am *ApplicationMetadata) string { return am.ApplicationID },
+	"title":         func(am *ApplicationMetadata) string { return am.Title },
+	"version":       func(am *ApplicationMetadata) string { return am.Version },
+	"company":       func(am *ApplicationMetadata) string { return am.Company },
+	"license":       func(am *ApplicationMetadata) string { return am.License },
+	"website":       func(am *ApplicationMetadata) string { return am.Website },
+	"source":        func(am *ApplicationMetadata) string { return am.Source },
+	"source.host":   func(am *ApplicationMetadata) string { return am.vcs().Host },
+	"source.owner":  func(am *ApplicationMetadata) string { return am.vcs().Owner },
+	"source.repo":   func(am *ApplicationMetadata) string { return am.vcs().Repo },
+}
+
+// vcs returns the repository of the source, empty when it isn't on a known forge
+func (am *ApplicationMetadata) vcs() VCS {
+	if am.VCS != nil {
+		return *am.VCS
+	}
+	if v := ParseVCS(am.Source); v != nil {
+		return *v
+	}
+	return VCS{}
+}
+
+// FieldRequirement is a single condition of a field selector such as company=pellucid or type!=deleted
+type FieldRequirement struct {
+	Field  string
+	Negate bool
+	Value  string
+}
+
+// Matches returns true when the value of the field satisfies the requirement
+func (r FieldRequirement) Matches(value string) bool {
+	return (value == r.Value) != r.Negate
+}
+
+// ParseFieldSelector parses a comma separated list of field=value or field!=value.  A field is one of
+// applicationID, title, version, company, license, website, source, source.host, source.owner, source.repo, or
+// extensions. followed by the dotted path of an extension value, or one of the extra fields.
+func ParseFieldSelector(selector string, extra ...string) ([]FieldRequirement, error) {
+	if strings.TrimSpace(selector) == "" {
+		return nil, nil
+	}
+	var reqs []FieldRequirement
+	for _, part := range strings.Split(selector, ",") {
+		var req FieldRequirement
+		kv := strings.SplitN(part, "!=", 2)
+		if len(kv) == 2 {
+			req.Negate = true
+		} else {
+			kv = strings.SplitN(part, "=", 2)
+		}
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid field selector %q", selector)
+		}
+		req.Field = strings.TrimSpace(kv[0])
+		req.Value = strings.TrimSpace(strings.TrimPrefix(kv[1], "="))
+		if !selectable(req.Field, extra) {
+			return nil, fmt.Errorf("field selector doesn't support %s", req.Field)
+		}
+		reqs = append(reqs, req)
+	}
+	return reqs, nil
+}
+
+func selectable(field string, extra []string) bool {
+	if _, ok := selectableFields[field]; ok {
+		return true
+	}
+	if strings.HasPrefix(field, ExtensionsField) && len(field) > len(ExtensionsField) {
+		return true
+	}
+	for _, f := range extra {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// FieldValue returns the value of a field selectable by ParseFieldSelector, an empty string when it isn't set
+func (am *ApplicationMetadata) FieldValue(field string) string {
+	if get, ok := selectableFields[field]; ok {
+		return get(am)
+	}
+	if strings.HasPrefix(field, ExtensionsField) {
+		return am.Extensions.Flatten()[strings.TrimPrefix(field, ExtensionsField)]
+	}
+	return ""
+}
+
+// MatchesFields returns true when the application satisfies every field requirement
+func (am *ApplicationMetadata) MatchesFields(reqs []FieldRequirement) bool {
+	for _, r := range reqs {
+		if !r.Matches(am.FieldValue(r.Field)) {
+			return false
+		}
+	}
+	return true
+}
@@ -2,64 +2,283 @@ package metadata
 
 import (
 	"fmt"
-	"regexp"
+	"time"
+
+	"github.com/elumbantoruan/app-metadata/labels"
+	"github.com/elumbantoruan/app-metadata/markdown"
 )
 
 // ApplicationMetadata represents a metadata for an application
 type ApplicationMetadata struct {
-	ApplicationID string       `yaml:"applicationID"`
-	Title         string       `yaml:"title"`
-	Version       string       `yaml:"version"`
-	Maintainers   []Maintainer `yaml:"maintainers"`
-	Company       string       `yaml:"company"`
-	Website       string       `yaml:"website"`
-	Source        string       `yaml:"source"`
-	License       string       `yaml:"license"`
-	Description   string       `yaml:"description"`
+	ApplicationID string       `yaml:"applicationID" json:"applicationID"`
+	Title         string       `yaml:"title" json:"title"`
+	Version       string       `yaml:"version" json:"version"`
+	Maintainers   []Maintainer `yaml:"maintainers" json:"maintainers"`
+	Company       string       `yaml:"company" json:"company"`
+	Website       string       `yaml:"website" json:"website"`
+	Source        string       `yaml:"source" json:"source"`
+	// VCS is derived by the service from Source, see ParseVCS
+	VCS         *VCS   `yaml:"vcs,omitempty" json:"vcs,omitempty"`
+	License     string `yaml:"license" json:"license"`
+	Description string `yaml:"description" json:"description"`
+	// Excerpt is derived by the service from Description for list views, see markdown.Excerpt
+	Excerpt      string            `yaml:"excerpt,omitempty" json:"excerpt,omitempty"`
+	Dependencies []Dependency      `yaml:"dependencies,omitempty" json:"dependencies,omitempty"`
+	Labels       map[string]string `yaml:"labels,omitempty" json:"labels,omitempty"`
+	Extensions   Extensions        `yaml:"extensions,omitempty" json:"extensions,omitempty"`
+	// Releases are managed by the service through the releases subresource, ordered from the highest version.
+	// Version is the highest of them that isn't a prerelease, see SetReleases.
+	Releases []Release `yaml:"releases,omitempty" json:"releases,omitempty"`
+	// DeletedAt is set by the service when the application is moved to the trash
+	DeletedAt *time.Time `yaml:"deletedAt,omitempty" json:"deletedAt,omitempty"`
+	// State is the lifecycle state managed by the service, see package lifecycle
+	State  string  `yaml:"state,omitempty" json:"state,omitempty"`
+	Review *Review `yaml:"review,omitempty" json:"review,omitempty"`
+	// Lock is set by an admin to freeze the application against edits until it's unlocked
+	Lock *Lock `yaml:"lock,omitempty" json:"lock,omitempty"`
+	// Revision is set by the service, starting at 1 and incremented with every change of the application
+	Revision int `yaml:"revision,omitempty" json:"revision,omitempty"`
+}
+
+// MaxDescriptionSize is the maximum size in bytes of a description
+const MaxDescriptionSize = 64 << 10
+
+// Review records who submitted the application for review and who approved it
+type Review struct {
+	SubmittedBy string     `yaml:"submittedBy,omitempty" json:"submittedBy,omitempty"`
+	SubmittedAt *time.Time `yaml:"submittedAt,omitempty" json:"submittedAt,omitempty"`
+	ApprovedBy  string     `yaml:"approvedBy,omitempty" json:"approvedBy,omitempty"`
+	ApprovedAt  *time.Time `yaml:"approvedAt,omitempty" json:"approvedAt,omitempty"`
+}
+
+// Lock records who froze the application against edits and why
+type Lock struct {
+	LockedBy string    `yaml:"lockedBy,omitempty" json:"lockedBy,omitempty"`
+	LockedAt time.Time `yaml:"lockedAt" json:"lockedAt"`
+	Reason   string    `yaml:"reason,omitempty" json:"reason,omitempty"`
 }
 
 // Maintainer contains the information of application maintainer.
 type Maintainer struct {
-	Name  string `yaml:"name"`
-	Email string `yaml:"email"`
+	Name  string `yaml:"name" json:"name"`
+	Email string `yaml:"email" json:"email"`
+	// Verification is set by the service when email verification is enabled, to EmailVerified or EmailPending
+	Verification string `yaml:"verification,omitempty" json:"verification,omitempty"`
+}
+
+// verification status of a maintainer's email
+const (
+	EmailVerified = "verified"
+	EmailPending  = "pending"
+)
+
+// Dependency references another application by its ID, with an optional version constraint such as ">=1.2.0, <2.0.0"
+type Dependency struct {
+	ApplicationID string `yaml:"applicationID" json:"applicationID"`
+	Version       string `yaml:"version,omitempty" json:"version,omitempty"`
 }
 
 // ValidationMessage returns a validation message such as error description
 type ValidationMessage struct {
-	Description string `yaml:""`
+	// Field is the field the message is about, when it's about a single one
+	Field       string `yaml:"field,omitempty" json:"field,omitempty"`
+	Description string `yaml:"" json:"description"`
 }
 
-// IsValid validates the ApplicationMetadata
-func (am ApplicationMetadata) IsValid() (valid bool, desc *ValidationMessage) {
-	valid, desc = am.isValidVersion()
-	if !valid {
-		return valid, desc
+// ClearServerFields resets the fields managed by the service, so that a client can't set them through a payload
+func (am *ApplicationMetadata) ClearServerFields() {
+	am.DeletedAt = nil
+	am.State = ""
+	am.Review = nil
+	am.Lock = nil
+	am.Revision = 0
+	am.Releases = nil
+	if len(am.Maintainers) > 0 {
+		maintainers := make([]Maintainer, len(am.Maintainers))
+		for i, m := range am.Maintainers {
+			m.Verification = ""
+			maintainers[i] = m
+		}
+		am.Maintainers = maintainers
 	}
-	return am.isValidEmail()
 }
 
-func (am ApplicationMetadata) isValidVersion() (valid bool, desc *ValidationMessage) {
-	if len(am.Version) == 0 {
-		vm := ValidationMessage{
-			Description: "version is empty",
+// DeepCopy returns a copy of the application metadata sharing no slice, map, or pointer with it
+func (am *ApplicationMetadata) DeepCopy() *ApplicationMetadata {
+	d := *am
+	if am.VCS != nil {
+		vcs := *am.VCS
+		d.VCS = &vcs
+	}
+	if am.Maintainers != nil {
+		d.Maintainers = append([]Maintainer(nil), am.Maintainers...)
+	}
+	if am.Dependencies != nil {
+		d.Dependencies = append([]Dependency(nil), am.Dependencies...)
+	}
+	if am.Labels != nil {
+		d.Labels = make(map[string]string, len(am.Labels))
+		for k, v := range am.Labels {
+			d.Labels[k] = v
+		}
+	}
+	if am.Extensions != nil {
+		d.Extensions = make(Extensions, len(am.Extensions))
+		for k, v := range am.Extensions {
+			d.Extensions[k] = copyValue(v)
+		}
+	}
+	if am.Releases != nil {
+		d.Releases = make([]Release, len(am.Releases))
+		for i, r := range am.Releases {
+			if r.Artifacts != nil {
+				r.Artifacts = append([]Artifact(nil), r.Artifacts...)
+			}
+			d.Releases[i] = r
 		}
-		return false, &vm
+	}
+	d.DeletedAt = copyTime(am.DeletedAt)
+	if am.Review != nil {
+		review := *am.Review
+		review.SubmittedAt = copyTime(review.SubmittedAt)
+		review.ApprovedAt = copyTime(review.ApprovedAt)
+		d.Review = &review
+	}
+	if am.Lock != nil {
+		lock := *am.Lock
+		d.Lock = &lock
+	}
+	return &d
+}
+
+// copyValue copies the maps and arrays of a document decoded from yaml or json, recursively
+func copyValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[k] = copyValue(e)
+		}
+		return m
+	case map[interface{}]interface{}:
+		m := make(map[interface{}]interface{}, len(v))
+		for k, e := range v {
+			m[k] = copyValue(e)
+		}
+		return m
+	case []interface{}:
+		a := make([]interface{}, len(v))
+		for i, e := range v {
+			a[i] = copyValue(e)
+		}
+		return a
+	}
+	return v
+}
+
+func copyTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	c := *t
+	return &c
+}
+
+// IsValid validates the ApplicationMetadata, returning the first problem found by Validate
+func (am ApplicationMetadata) IsValid() (valid bool, desc *ValidationMessage) {
+	if problems := am.Validate(); len(problems) > 0 {
+		return false, &problems[0]
 	}
 	return true, nil
 }
 
-func (am ApplicationMetadata) isValidEmail() (valid bool, desc *ValidationMessage) {
-	re := regexp.MustCompile("^[a-zA-Z0-9.!#$%&'*+/=?^_`{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$")
-	var vm ValidationMessage
+// Validate returns every problem of the ApplicationMetadata, with the field it's about
+func (am ApplicationMetadata) Validate() []ValidationMessage {
+	var problems []ValidationMessage
+	problems = append(problems, am.validateVersion()...)
+	problems = append(problems, am.validateEmails()...)
+	problems = append(problems, am.validateURLs()...)
+	problems = append(problems, am.validateDependencies()...)
+	problems = append(problems, am.validateReleases()...)
+	if err := markdown.Validate(am.Description, MaxDescriptionSize); err != nil {
+		problems = append(problems, ValidationMessage{Field: "description", Description: err.Error()})
+	}
+	if err := labels.Validate(am.Labels); err != nil {
+		problems = append(problems, ValidationMessage{Field: "labels", Description: err.Error()})
+	}
+	return problems
+}
+
+func (am ApplicationMetadata) validateVersion() []ValidationMessage {
+	if len(am.Version) == 0 {
+		return []ValidationMessage{{Field: "version", Description: "version is empty"}}
+	}
+	return nil
+}
+
+func (am ApplicationMetadata) validateEmails() []ValidationMessage {
+	var problems []ValidationMessage
+	problem := func(format string, args ...interface{}) {
+		problems = append(problems, ValidationMessage{Field: "maintainers", Description: fmt.Sprintf(format, args...)})
+	}
+	seen := make(map[string]bool)
 	for _, m := range am.Maintainers {
 		if len(m.Email) == 0 {
-			vm.Description = "maintainer's email is empty"
-			return false, &vm
+			problem("maintainer's email is empty")
+			continue
+		}
+		if err := ValidateEmail(m.Email); err != nil {
+			problem("%s is not a valid email address", m.Email)
+			continue
 		}
-		if !re.MatchString(m.Email) {
-			vm.Description = fmt.Sprintf("%s is not a valid email address", m.Email)
-			return false, &vm
+		// emails are case insensitive, and an IDN domain is the same in Unicode and punycode
+		email := NormalizeEmail(m.Email)
+		if seen[email] {
+			problem("maintainer %s is listed more than once", m.Email)
 		}
+		seen[email] = true
 	}
-	return true, nil
+	return problems
+}
+
+func (am ApplicationMetadata) validateURLs() []ValidationMessage {
+	var problems []ValidationMessage
+	for _, f := range []struct{ name, value string }{{"website", am.Website}, {"source", am.Source}} {
+		if f.value == "" {
+			continue
+		}
+		if err := ValidateURL(f.value); err != nil {
+			problems = append(problems, ValidationMessage{Field: f.name, Description: fmt.Sprintf("%s: %v", f.name, err)})
+		}
+	}
+	return problems
+}
+
+func (am ApplicationMetadata) validateDependencies() []ValidationMessage {
+	var problems []ValidationMessage
+	problem := func(format string, args ...interface{}) {
+		problems = append(problems, ValidationMessage{Field: "dependencies", Description: fmt.Sprintf(format, args...)})
+	}
+	seen := make(map[string]bool)
+	for _, d := range am.Dependencies {
+		if len(d.ApplicationID) == 0 {
+			problem("dependency's applicationID is empty")
+			continue
+		}
+		if d.ApplicationID == am.ApplicationID {
+			problem("%s can't depend on itself", d.ApplicationID)
+			continue
+		}
+		if seen[d.ApplicationID] {
+			problem("dependency %s is listed more than once", d.ApplicationID)
+			continue
+		}
+		seen[d.ApplicationID] = true
+		if len(d.Version) > 0 {
+			if _, err := ParseVersionConstraint(d.Version); err != nil {
+				problem("%s", err.Error())
+			}
+		}
+	}
+	return problems
 }
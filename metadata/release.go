@@ -0,0 +1,169 @@
+package metadata
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/elumbantoruan/app-metadata/markdown"
+)
+
+// Release is a version of an application, with its changelog notes in Markdown and the checksums of its artifacts
+type Release struct {
+	Version    string     `yaml:"version" json:"version"`
+	ReleasedAt time.Time  `yaml:"releasedAt" json:"releasedAt"`
+	Notes      string     `yaml:"notes,omitempty" json:"notes,omitempty"`
+	Artifacts  []Artifact `yaml:"artifacts,omitempty" json:"artifacts,omitempty"`
+}
+
+// Artifact is a file published with a release, with its checksum such as "sha256:<hex digest>"
+type Artifact struct {
+	Name     string `yaml:"name" json:"name"`
+	Checksum string `yaml:"checksum" json:"checksum"`
+}
+
+// MaxReleaseNotesSize is the maximum size in bytes of the notes of a release
+const MaxReleaseNotesSize = 16 << 10
+
+// checksumAlgorithms are the supported checksum algorithms with the length of their hex digest
+var checksumAlgorithms = map[string]int{"sha256": 64, "sha512": 128}
+
+// ErrLastRelease is returned by RemoveRelease for the only release of an application, which its version depends on
+var ErrLastRelease = errors.New("the last release of an application can't be removed")
+
+// Normalize writes the version in canonical form, without a leading "v", and the release time in UTC
+func (r *Release) Normalize() {
+	if v, err := ParseSemVer(r.Version); err == nil {
+		r.Version = v.String()
+	}
+	r.ReleasedAt = r.ReleasedAt.UTC()
+	for i, a := range r.Artifacts {
+		r.Artifacts[i].Checksum = strings.ToLower(strings.TrimSpace(a.Checksum))
+	}
+}
+
+// Validate returns the first problem of the release
+func (r Release) Validate() error {
+	if _, err := ParseSemVer(r.Version); err != nil {
+		return fmt.Errorf("release version: %s", err.Error())
+	}
+	if r.ReleasedAt.IsZero() {
+		return fmt.Errorf("release %s has no releasedAt", r.Version)
+	}
+	if err := markdown.Validate(r.Notes, MaxReleaseNotesSize); err != nil {
+		return fmt.Errorf("release %s notes: %s", r.Version, err.Error())
+	}
+	seen := make(map[string]bool, len(r.Artifacts))
+	for _, a := range r.Artifacts {
+		if a.Name == "" {
+			return fmt.Errorf("release %s has an artifact without name", r.Version)
+		}
+		if seen[a.Name] {
+			return fmt.Errorf("artifact %s of release %s is listed more than once", a.Name, r.Version)
+		}
+		seen[a.Name] = true
+		if err := ValidateChecksum(a.Checksum); err != nil {
+			return fmt.Errorf("artifact %s of release %s: %s", a.Name, r.Version, err.Error())
+		}
+	}
+	return nil
+}
+
+// ValidateChecksum validates a checksum such as "sha256:<hex digest>", sha256 and sha512 being supported
+func ValidateChecksum(checksum string) error {
+	algorithm, digest, ok := strings.Cut(checksum, ":")
+	size, supported := checksumAlgorithms[algorithm]
+	if !ok || !supported {
+		return fmt.Errorf("%s is not a sha256: or sha512: checksum", checksum)
+	}
+	if len(digest) != size || strings.Trim(digest, "0123456789abcdef") != "" {
+		return fmt.Errorf("%s is not a valid %s checksum", checksum, algorithm)
+	}
+	return nil
+}
+
+// SetReleases replaces the releases of the application, ordered from the highest version, and sets its Version
+// to the highest release that isn't a prerelease.  Version is left unchanged when there is none.
+func (am *ApplicationMetadata) SetReleases(releases []Release) {
+	if len(releases) == 0 {
+		am.Releases = nil
+		return
+	}
+	am.Releases = append([]Release(nil), releases...)
+	sort.SliceStable(am.Releases, func(i, j int) bool {
+		return compareVersions(am.Releases[i].Version, am.Releases[j].Version) > 0
+	})
+	for _, r := range am.Releases {
+		if v, err := ParseSemVer(r.Version); err == nil && v.Prerelease == "" {
+			am.Version = r.Version
+			return
+		}
+	}
+}
+
+// RemoveRelease removes the release at index i.  When the version of the application was that release and no other
+// release but prereleases remains, the version becomes the highest prerelease.  It returns ErrLastRelease rather than
+// remove the only release, which would leave the application without a version.
+func (am *ApplicationMetadata) RemoveRelease(i int) error {
+	if len(am.Releases) == 1 {
+		return ErrLastRelease
+	}
+	removed, version := am.Releases[i].Version, am.Version
+	releases := append([]Release(nil), am.Releases[:i]...)
+	am.SetReleases(append(releases, am.Releases[i+1:]...))
+	if am.Version == version && compareVersions(version, removed) == 0 {
+		am.Version = am.Releases[0].Version
+	}
+	return nil
+}
+
+// FindRelease returns the index of the release with the same precedence as version, or -1 when there is none
+func (am ApplicationMetadata) FindRelease(version string) int {
+	for i, r := range am.Releases {
+		if compareVersions(r.Version, version) == 0 {
+			return i
+		}
+	}
+	return -1
+}
+
+// ReleasedSince returns true when the application has a release at or after t
+func (am ApplicationMetadata) ReleasedSince(t time.Time) bool {
+	for _, r := range am.Releases {
+		if !r.ReleasedAt.Before(t) {
+			return true
+		}
+	}
+	return false
+}
+
+func (am ApplicationMetadata) validateReleases() []ValidationMessage {
+	var problems []ValidationMessage
+	for i, r := range am.Releases {
+		if err := r.Validate(); err != nil {
+			problems = append(problems, ValidationMessage{Field: "releases", Description: err.Error()})
+			continue
+		}
+		if am.FindRelease(r.Version) < i {
+			problems = append(problems, ValidationMessage{Field: "releases", Description: fmt.Sprintf("release %s is listed more than once", r.Version)})
+		}
+	}
+	return problems
+}
+
+// compareVersions compares two semantic versions, an invalid version being lower than any valid one
+func compareVersions(a, b string) int {
+	va, errA := ParseSemVer(a)
+	vb, errB := ParseSemVer(b)
+	switch {
+	case errA != nil && errB != nil:
+		return strings.Compare(a, b)
+	case errA != nil:
+		return -1
+	case errB != nil:
+		return 1
+	}
+	return va.Compare(vb)
+}
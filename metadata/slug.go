@@ -0,0 +1,35 @@
+package metadata
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// MaxSlugLength is the maximum length of an applicationID chosen by a client
+const MaxSlugLength = 63
+
+var slugRegexp = regexp.MustCompile("^[a-z0-9]([a-z0-9-]*[a-z0-9])?$")
+
+// reservedSlugs can't be used as applicationID since they're routes of their own
+var reservedSlugs = map[string]bool{
+	"events": true,
+}
+
+// ValidateSlug validates an applicationID chosen by a client, or an alias: lowercase alphanumeric characters
+// and '-', starting and ending with an alphanumeric character, such as payments-api.
+// Generated IDs are UUIDs, which are valid slugs as well.
+func ValidateSlug(slug string) error {
+	if len(slug) == 0 {
+		return fmt.Errorf("applicationID is empty")
+	}
+	if len(slug) > MaxSlugLength {
+		return fmt.Errorf("applicationID %s must be no more than %d characters", slug, MaxSlugLength)
+	}
+	if !slugRegexp.MatchString(slug) {
+		return fmt.Errorf("applicationID %s must consist of lowercase alphanumeric characters or '-', and start and end with an alphanumeric character", slug)
+	}
+	if reservedSlugs[slug] {
+		return fmt.Errorf("applicationID %s is reserved", slug)
+	}
+	return nil
+}
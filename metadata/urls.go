@@ -0,0 +1,134 @@
+package metadata
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// VCS is the repository a source URL points to on a known forge, derived by the service
+type VCS struct {
+	Host  string `yaml:"host" json:"host"`
+	Owner string `yaml:"owner" json:"owner"`
+	Repo  string `yaml:"repo" json:"repo"`
+}
+
+// forges are the hosts whose repositories ParseVCS recognizes.  GitLab nests projects in groups, so the owner is
+// every segment before the repository there.
+var forges = map[string]bool{
+	"github.com":    false,
+	"bitbucket.org": false,
+	"codeberg.org":  false,
+	"git.sr.ht":     false,
+	"gitlab.com":    true,
+}
+
+// trackingParams are the query parameters removed from URLs, along with any utm_ parameter
+var trackingParams = map[string]bool{
+	"fbclid":  true,
+	"gclid":   true,
+	"mc_cid":  true,
+	"mc_eid":  true,
+	"msclkid": true,
+	"yclid":   true,
+	"_ga":     true,
+}
+
+// ValidateURL checks that u is an absolute http or https URL with a host
+func ValidateURL(u string) error {
+	parsed, err := url.Parse(strings.TrimSpace(u))
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" || parsed.Hostname() == "" {
+		return fmt.Errorf("%s is not an absolute http or https URL", u)
+	}
+	return nil
+}
+
+// CanonicalURL returns an http(s) URL with its scheme and host in lowercase, an IDN host in punycode, without the
+// default port, trailing slash, or tracking parameters such as utm_source.  An invalid URL is only trimmed.
+func CanonicalURL(u string) string {
+	u = strings.TrimSpace(u)
+	if ValidateURL(u) != nil {
+		return u
+	}
+	parsed, _ := url.Parse(u)
+	parsed.Scheme = strings.ToLower(parsed.Scheme)
+	host, port := NormalizeDomain(parsed.Hostname()), parsed.Port()
+	if (parsed.Scheme == "http" && port == "80") || (parsed.Scheme == "https" && port == "443") {
+		port = ""
+	}
+	if strings.Contains(host, ":") {
+		// IPv6
+		host = "[" + host + "]"
+	}
+	if port != "" {
+		host += ":" + port
+	}
+	parsed.Host = host
+	parsed.Path = strings.TrimRight(parsed.Path, "/")
+	parsed.RawPath = strings.TrimRight(parsed.RawPath, "/")
+	if parsed.RawQuery != "" {
+		q := parsed.Query()
+		stripped := false
+		for k := range q {
+			if trackingParams[strings.ToLower(k)] || strings.HasPrefix(strings.ToLower(k), "utm_") {
+				q.Del(k)
+				stripped = true
+			}
+		}
+		if stripped {
+			parsed.RawQuery = q.Encode()
+		}
+	}
+	return parsed.String()
+}
+
+// ParseVCS returns the repository a source URL points to on a known forge, or nil
+func ParseVCS(source string) *VCS {
+	if ValidateURL(source) != nil {
+		return nil
+	}
+	parsed, _ := url.Parse(CanonicalURL(source))
+	host := strings.TrimPrefix(parsed.Hostname(), "www.")
+	nested, ok := forges[host]
+	if !ok {
+		return nil
+	}
+	path := strings.Trim(parsed.Path, "/")
+	// GitLab separates the project from its pages, such as /-/tree/main
+	if i := strings.Index(path, "/-/"); i >= 0 {
+		path = path[:i]
+	}
+	segments := strings.Split(path, "/")
+	if len(segments) < 2 || segments[0] == "" || segments[1] == "" {
+		return nil
+	}
+	if !nested {
+		// deeper segments are a branch, a directory, or a page of the repository
+		segments = segments[:2]
+	}
+	repo := strings.TrimSuffix(segments[len(segments)-1], ".git")
+	if repo == "" {
+		return nil
+	}
+	// the forges ignore the case of owners and repositories
+	owner := strings.ToLower(strings.Join(segments[:len(segments)-1], "/"))
+	return &VCS{Host: host, Owner: owner, Repo: strings.ToLower(repo)}
+}
+
+// URLKey returns a URL in canonical form without its scheme, in lowercase, to compare URLs, see CanonicalURL
+func URLKey(u string) string {
+	u = strings.ToLower(CanonicalURL(u))
+	if i := strings.Index(u, "://"); i >= 0 {
+		u = u[i+3:]
+	}
+	return u
+}
+
+// SourceKey returns the repository of a source URL on a known forge, such as github.com/owner/name, so that the
+// different ways of writing its address compare equal, see ParseVCS.  Other URLs are compared as URLKey.
+func SourceKey(source string) string {
+	if vcs := ParseVCS(source); vcs != nil {
+		return vcs.Host + "/" + vcs.Owner + "/" + vcs.Repo
+	}
+	return URLKey(source)
+}
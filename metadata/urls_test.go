@@ -0,0 +1,70 @@
+package metadata
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanonicalURL_ResultedNormalized(t *testing.T) {
+
+	tests := []struct {
+		url      string
+		expected string
+	}{
+		{url: "HTTPS://Example.COM/", expected: "https://example.com"},
+		{url: "http://example.com:80/docs/", expected: "http://example.com/docs"},
+		{url: "https://example.com:8443/docs", expected: "https://example.com:8443/docs"},
+		{url: "https://bücher.de/", expected: "https://xn--bcher-kva.de"},
+		{url: "https://example.com/?utm_source=news&UTM_Medium=mail&gclid=1&page=2", expected: "https://example.com?page=2"},
+		{url: "https://example.com/Path?b=2&a=1#Intro", expected: "https://example.com/Path?b=2&a=1#Intro"},
+		{url: "http://[::1]:80/", expected: "http://[::1]"},
+		{url: " not a url ", expected: "not a url"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, CanonicalURL(tt.url), tt.url)
+	}
+
+	for _, u := range []string{"example.com", "ftp://example.com", "git@github.com:acme/payments.git", "https://", "/docs"} {
+		assert.NotNil(t, ValidateURL(u), u)
+	}
+}
+
+func TestParseVCS_ResultedRepository(t *testing.T) {
+
+	tests := []struct {
+		source   string
+		expected *VCS
+	}{
+		{source: "https://github.com/Acme/Payments", expected: &VCS{Host: "github.com", Owner: "acme", Repo: "payments"}},
+		{source: "https://www.github.com/acme/payments.git/", expected: &VCS{Host: "github.com", Owner: "acme", Repo: "payments"}},
+		{source: "https://github.com/acme/payments/tree/main/cmd", expected: &VCS{Host: "github.com", Owner: "acme", Repo: "payments"}},
+		{source: "https://gitlab.com/acme/platform/payments/-/tree/main", expected: &VCS{Host: "gitlab.com", Owner: "acme/platform", Repo: "payments"}},
+		{source: "https://bitbucket.org/acme/payments/src/main", expected: &VCS{Host: "bitbucket.org", Owner: "acme", Repo: "payments"}},
+		{source: "https://git.sr.ht/~acme/payments", expected: &VCS{Host: "git.sr.ht", Owner: "~acme", Repo: "payments"}},
+		{source: "https://github.com/acme", expected: nil},
+		{source: "https://git.example.com/acme/payments", expected: nil},
+		{source: "git@github.com:acme/payments.git", expected: nil},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, ParseVCS(tt.source), tt.source)
+	}
+}
+
+func TestSourceKey_ResultedSameRepository(t *testing.T) {
+
+	tests := []struct {
+		source   string
+		expected string
+	}{
+		{source: "https://github.com/Acme/Payments", expected: "github.com/acme/payments"},
+		{source: "http://www.github.com/acme/payments.git", expected: "github.com/acme/payments"},
+		{source: "https://github.com/acme/payments/tree/main", expected: "github.com/acme/payments"},
+		{source: "https://Git.Example.com/acme/Payments/", expected: "git.example.com/acme/payments"},
+		{source: "http://git.example.com/acme/payments/", expected: "git.example.com/acme/payments"},
+		{source: "", expected: ""},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, SourceKey(tt.source), tt.source)
+	}
+}
@@ -0,0 +1,176 @@
+package metadata
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SemVer is a parsed semantic version such as 1.2.3-rc.1+build.5
+type SemVer struct {
+	Major      int
+	Minor      int
+	Patch      int
+	Prerelease string
+	Build      string
+}
+
+// ParseSemVer parses a semantic version, a leading "v" is accepted and missing minor or patch are zero
+func ParseSemVer(s string) (SemVer, error) {
+	var v SemVer
+	str := strings.TrimPrefix(strings.TrimSpace(s), "v")
+	if i := strings.Index(str, "+"); i >= 0 {
+		v.Build = str[i+1:]
+		str = str[:i]
+	}
+	if i := strings.Index(str, "-"); i >= 0 {
+		v.Prerelease = str[i+1:]
+		str = str[:i]
+	}
+	parts := strings.Split(str, ".")
+	if len(parts) > 3 || str == "" {
+		return v, fmt.Errorf("%s is not a valid semantic version", s)
+	}
+	nums := []*int{&v.Major, &v.Minor, &v.Patch}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return v, fmt.Errorf("%s is not a valid semantic version", s)
+		}
+		*nums[i] = n
+	}
+	return v, nil
+}
+
+// String returns the version in MAJOR.MINOR.PATCH[-PRERELEASE][+BUILD] form
+func (v SemVer) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Prerelease != "" {
+		s += "-" + v.Prerelease
+	}
+	if v.Build != "" {
+		s += "+" + v.Build
+	}
+	return s
+}
+
+// Compare returns -1, 0, or 1 when v is lower, equal, or greater than o following semver precedence
+func (v SemVer) Compare(o SemVer) int {
+	for _, d := range []int{v.Major - o.Major, v.Minor - o.Minor, v.Patch - o.Patch} {
+		if d != 0 {
+			return sign(d)
+		}
+	}
+	return comparePrerelease(v.Prerelease, o.Prerelease)
+}
+
+func comparePrerelease(a, b string) int {
+	switch {
+	case a == b:
+		return 0
+	case a == "":
+		return 1
+	case b == "":
+		return -1
+	}
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		an, aErr := strconv.Atoi(as[i])
+		bn, bErr := strconv.Atoi(bs[i])
+		switch {
+		case aErr == nil && bErr == nil:
+			if an != bn {
+				return sign(an - bn)
+			}
+		case aErr == nil:
+			// numeric identifiers have lower precedence
+			return -1
+		case bErr == nil:
+			return 1
+		default:
+			if c := strings.Compare(as[i], bs[i]); c != 0 {
+				return c
+			}
+		}
+	}
+	return sign(len(as) - len(bs))
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
+
+// VersionConstraint is a set of comparisons a version must all satisfy, such as ">=1.2.0, <2.0.0".
+// Supported operators are =, !=, >, >=, <, <=, ~ (same minor), and ^ (same major).
+type VersionConstraint struct {
+	clauses []constraintClause
+}
+
+type constraintClause struct {
+	op      string
+	version SemVer
+}
+
+// operators ordered so that two character operators are matched first
+var constraintOperators = []string{">=", "<=", "!=", "=", ">", "<", "~", "^"}
+
+// ParseVersionConstraint parses a comma separated list of comparisons
+func ParseVersionConstraint(s string) (VersionConstraint, error) {
+	var c VersionConstraint
+	for _, clause := range strings.Split(s, ",") {
+		clause = strings.TrimSpace(clause)
+		if clause == "" {
+			return c, fmt.Errorf("%s is not a valid version constraint", s)
+		}
+		op := "="
+		for _, o := range constraintOperators {
+			if strings.HasPrefix(clause, o) {
+				op = o
+				clause = strings.TrimSpace(clause[len(o):])
+				break
+			}
+		}
+		v, err := ParseSemVer(clause)
+		if err != nil {
+			return c, fmt.Errorf("%s is not a valid version constraint", s)
+		}
+		c.clauses = append(c.clauses, constraintClause{op: op, version: v})
+	}
+	return c, nil
+}
+
+// Check returns true when v satisfies every comparison of the constraint
+func (c VersionConstraint) Check(v SemVer) bool {
+	for _, cl := range c.clauses {
+		cmp := v.Compare(cl.version)
+		var ok bool
+		switch cl.op {
+		case "=":
+			ok = cmp == 0
+		case "!=":
+			ok = cmp != 0
+		case ">":
+			ok = cmp > 0
+		case ">=":
+			ok = cmp >= 0
+		case "<":
+			ok = cmp < 0
+		case "<=":
+			ok = cmp <= 0
+		case "~":
+			ok = cmp >= 0 && v.Major == cl.version.Major && v.Minor == cl.version.Minor
+		case "^":
+			ok = cmp >= 0 && v.Major == cl.version.Major
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/elumbantoruan/app-metadata/metadata"
+)
+
+// UniqueConstraint requires the combination of Fields to be unique among the live applications,
+// such as title and company for a title unique per company.  Values are compared case insensitively, URLs in
+// canonical form without their scheme, and sources by repository when they're on a known forge.  Applications missing
+// any of the fields aren't constrained.
+type UniqueConstraint struct {
+	Fields []string
+}
+
+// uniqueFields are the fields a UniqueConstraint can be made of
+var uniqueFields = map[string]func(d *metadata.ApplicationMetadata) string{
+	"title":   func(d *metadata.ApplicationMetadata) string { return normalizeText(d.Title) },
+	"version": func(d *metadata.ApplicationMetadata) string { return normalizeText(d.Version) },
+	"company": func(d *metadata.ApplicationMetadata) string { return normalizeText(d.Company) },
+	"website": func(d *metadata.ApplicationMetadata) string { return metadata.URLKey(d.Website) },
+	"source":  func(d *metadata.ApplicationMetadata) string { return metadata.SourceKey(d.Source) },
+	"license": func(d *metadata.ApplicationMetadata) string { return normalizeText(d.License) },
+}
+
+// ParseUniqueConstraints parses constraints separated by ';', each being a comma separated list of fields,
+// such as "title,company;source"
+func ParseUniqueConstraints(s string) ([]UniqueConstraint, error) {
+	var constraints []UniqueConstraint
+	for _, c := range strings.Split(s, ";") {
+		if strings.TrimSpace(c) == "" {
+			continue
+		}
+		var uc UniqueConstraint
+		for _, f := range strings.Split(c, ",") {
+			f = strings.TrimSpace(f)
+			if _, ok := uniqueFields[f]; !ok {
+				return nil, fmt.Errorf("unknown field %q in unique constraint, expected one of title, version, company, website, source, license", f)
+			}
+			uc.Fields = append(uc.Fields, f)
+		}
+		sort.Strings(uc.Fields)
+		constraints = append(constraints, uc)
+	}
+	return constraints, nil
+}
+
+func (uc UniqueConstraint) String() string {
+	return strings.Join(uc.Fields, ",")
+}
+
+// key returns the normalized values of the fields of the constraint, or an empty string when any of them is empty
+func (uc UniqueConstraint) key(d *metadata.ApplicationMetadata) string {
+	values := make([]string, len(uc.Fields))
+	for i, f := range uc.Fields {
+		values[i] = uniqueFields[f](d)
+		if values[i] == "" {
+			return ""
+		}
+	}
+	return strings.Join(values, "\x00")
+}
+
+// ConstraintError is returned when a write would violate a UniqueConstraint
+type ConstraintError struct {
+	Constraint UniqueConstraint
+	// ApplicationID is the application already holding the values
+	ApplicationID string
+}
+
+func (e *ConstraintError) Error() string {
+	return fmt.Sprintf("%s must be unique, they're the same as %s", strings.Join(e.Constraint.Fields, " and "), e.ApplicationID)
+}
+
+func normalizeText(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// IdempotencyRecord is the response stored for an idempotency key
+type IdempotencyRecord struct {
+	// RequestHash identifies the request body the key was first used with
+	RequestHash string
+	// StatusCode is zero while the first request is still in progress
+	StatusCode int
+	// Header holds the response headers replayed along with Body, such as Location or Warning
+	Header    map[string][]string
+	Body      []byte
+	ExpiresAt time.Time
+}
+
+// Pending returns true when the first request using the key hasn't completed yet
+func (r *IdempotencyRecord) Pending() bool {
+	return r.StatusCode == 0
+}
+
+// IdempotencyStore defines an interface to store the response of requests carrying an idempotency key
+type IdempotencyStore interface {
+	// Reserve returns the record of key when it exists, otherwise it atomically stores a pending record and returns nil
+	Reserve(key, requestHash string, ttl time.Duration) (*IdempotencyRecord, error)
+	// Complete stores the response of a reserved key
+	Complete(key string, statusCode int, header map[string][]string, body []byte) error
+	// Release removes a reserved key so the request can be retried
+	Release(key string) error
+	// Purge removes the completed records fn returns true for, and returns how many it removed
+	Purge(fn func(rec *IdempotencyRecord) bool) (int, error)
+}
+
+var (
+	ErrKeyNotReserved = errors.New("idempotency key not reserved")
+)
+
+// InMemoryIdempotencyStore is a concrete implementation of IdempotencyStore interface in memory
+type InMemoryIdempotencyStore struct {
+	mu        sync.Mutex
+	records   map[string]*IdempotencyRecord
+	now       func() time.Time
+	lastSweep time.Time
+}
+
+// NewInMemoryIdempotencyStore creates a new instance of InMemoryIdempotencyStore
+func NewInMemoryIdempotencyStore() IdempotencyStore {
+	return &InMemoryIdempotencyStore{
+		records: make(map[string]*IdempotencyRecord),
+		now:     time.Now,
+	}
+}
+
+// Reserve returns the record of key when it exists, otherwise it stores a pending record and returns nil
+func (is *InMemoryIdempotencyStore) Reserve(key, requestHash string, ttl time.Duration) (*IdempotencyRecord, error) {
+	is.mu.Lock()
+	defer is.mu.Unlock()
+
+	now := is.now()
+	if now.Sub(is.lastSweep) > time.Minute {
+		is.sweep(now)
+	}
+	if rec, ok := is.records[key]; ok && now.Before(rec.ExpiresAt) {
+		ret := *rec
+		return &ret, nil
+	}
+	is.records[key] = &IdempotencyRecord{
+		RequestHash: requestHash,
+		ExpiresAt:   now.Add(ttl),
+	}
+	return nil, nil
+}
+
+// Complete stores the response of a reserved key
+func (is *InMemoryIdempotencyStore) Complete(key string, statusCode int, header map[string][]string, body []byte) error {
+	is.mu.Lock()
+	defer is.mu.Unlock()
+
+	rec, ok := is.records[key]
+	if !ok {
+		return ErrKeyNotReserved
+	}
+	rec.StatusCode = statusCode
+	rec.Header = header
+	rec.Body = body
+	return nil
+}
+
+// Release removes a reserved key
+func (is *InMemoryIdempotencyStore) Release(key string) error {
+	is.mu.Lock()
+	defer is.mu.Unlock()
+
+	delete(is.records, key)
+	return nil
+}
+
+// Purge removes the completed records fn returns true for, such as the responses referencing erased data
+func (is *InMemoryIdempotencyStore) Purge(fn func(rec *IdempotencyRecord) bool) (int, error) {
+	is.mu.Lock()
+	defer is.mu.Unlock()
+
+	n := 0
+	for k, rec := range is.records {
+		if !rec.Pending() && fn(rec) {
+			delete(is.records, k)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// sweep removes the expired records, the caller must hold the lock
+func (is *InMemoryIdempotencyStore) sweep(now time.Time) {
+	for k, rec := range is.records {
+		if !now.Before(rec.ExpiresAt) {
+			delete(is.records, k)
+		}
+	}
+	is.lastSweep = now
+}
@@ -1,39 +1,142 @@
 package repository
 
 import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/elumbantoruan/app-metadata/labels"
 	"github.com/elumbantoruan/app-metadata/metadata"
 )
 
 // InMemoryMetadataRepository is a concrete implementation of MetadataRepository interface in memory
 type InMemoryMetadataRepository struct {
 	Storage map[string]*metadata.ApplicationMetadata
+	mu      sync.RWMutex
+	// labelIndex maps label key -> label value -> set of appID
+	labelIndex map[string]map[string]map[string]bool
+	// indexed holds a copy of the labels indexed for each appID, so that the caller changing
+	// data after storing it doesn't corrupt the index
+	indexed map[string]map[string]string
+	// maintainerIndex maps a normalized maintainer email -> set of appID
+	maintainerIndex map[string]map[string]bool
+	// indexedEmails holds the normalized maintainer emails indexed for each appID
+	indexedEmails map[string][]string
+	// trash holds the deleted application metadata until they're restored or purged
+	trash map[string]*metadata.ApplicationMetadata
+	// revisions holds a copy of every revision of each appID, the first one at index 0
+	revisions map[string][]metadata.ApplicationMetadata
+	// aliases maps an alias to the appID it resolves to
+	aliases map[string]string
+	// constraints are checked under the write lock, so that concurrent writes can't both pass them
+	constraints []UniqueConstraint
+	// onChange is called with every change under the write lock
+	onChange ChangeFunc
+	// onWrite checks every write under the write lock
+	onWrite CheckFunc
+	now     func() time.Time
 }
 
-// NewInMemoryMetadataRepository creates a new instance of InMemoryMetadataRepository
-func NewInMemoryMetadataRepository() MetadataRepository {
+// NewInMemoryMetadataRepository creates a new instance of InMemoryMetadataRepository enforcing the unique constraints
+func NewInMemoryMetadataRepository(constraints ...UniqueConstraint) MetadataRepository {
 	data := make(map[string]*metadata.ApplicationMetadata)
 	return &InMemoryMetadataRepository{
-		Storage: data,
+		Storage:         data,
+		labelIndex:      make(map[string]map[string]map[string]bool),
+		indexed:         make(map[string]map[string]string),
+		maintainerIndex: make(map[string]map[string]bool),
+		indexedEmails:   make(map[string][]string),
+		trash:           make(map[string]*metadata.ApplicationMetadata),
+		revisions:       make(map[string][]metadata.ApplicationMetadata),
+		aliases:         make(map[string]string),
+		constraints:     constraints,
+		now:             time.Now,
 	}
 }
 
-// Create adds an application metadata into a repository
+// Create adds an application metadata into a repository.
+// It returns ErrIDConflict when appID is already taken by an application, in the trash or not, or by an alias.
 func (im *InMemoryMetadataRepository) Create(appID string, data *metadata.ApplicationMetadata) error {
-	im.Storage[appID] = data
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	if im.taken(appID) {
+		return ErrIDConflict
+	}
+	if err := im.checkConstraints(appID, data, nil); err != nil {
+		return err
+	}
+	if err := im.checkWrite(appID, data, nil, nil); err != nil {
+		return err
+	}
+	im.revise(appID, data)
+	im.put(appID, data)
+	im.notify(Created, appID, data)
 	return nil
 }
 
-// Update updates the application metadata for a given appID
+// Update updates the application metadata for a given appID.
+// It returns a LockedError when the application is locked, whose lock is only changed through SetLock.
 func (im *InMemoryMetadataRepository) Update(appID string, data *metadata.ApplicationMetadata) error {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
 	if _, ok := im.Storage[appID]; !ok {
 		return ErrIDNotFound
 	}
-	im.Storage[appID] = data
+	if err := im.checkUnlocked(appID); err != nil {
+		return err
+	}
+	data.Lock = nil
+	if err := im.checkConstraints(appID, data, nil); err != nil {
+		return err
+	}
+	if err := im.checkWrite(appID, data, im.Storage[appID], nil); err != nil {
+		return err
+	}
+	im.revise(appID, data)
+	im.put(appID, data)
+	im.notify(Updated, appID, data)
 	return nil
 }
 
+// Modify calls fn with a copy of the live application appID, and stores the copy changed by fn under the write lock,
+// so that the writes in between aren't lost.  Nothing is stored when fn returns an error, which is returned, or when
+// the application is locked (LockedError) or breaks a unique constraint.
+func (im *InMemoryMetadataRepository) Modify(appID string, fn func(data *metadata.ApplicationMetadata) error) (*Modification, error) {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	v, ok := im.Storage[appID]
+	if !ok {
+		return nil, ErrIDNotFound
+	}
+	if err := im.checkUnlocked(appID); err != nil {
+		return nil, err
+	}
+	d := v.DeepCopy()
+	d.ApplicationID = appID
+	if err := fn(d); err != nil {
+		return nil, err
+	}
+	d.Lock = nil
+	if err := im.checkConstraints(appID, d, nil); err != nil {
+		return nil, err
+	}
+	if err := im.checkWrite(appID, d, v, nil); err != nil {
+		return nil, err
+	}
+	im.revise(appID, d)
+	im.put(appID, d)
+	im.notify(Updated, appID, d)
+	return &Modification{Previous: v, Current: d}, nil
+}
+
 // Get returns application metadata for a given appID
 func (im *InMemoryMetadataRepository) Get(appID string) (*metadata.ApplicationMetadata, error) {
+	im.mu.RLock()
+	defer im.mu.RUnlock()
+
 	ret, ok := im.Storage[appID]
 	if ok {
 		return ret, nil
@@ -41,22 +144,587 @@ func (im *InMemoryMetadataRepository) Get(appID string) (*metadata.ApplicationMe
 	return nil, nil
 }
 
+// GetRevision returns a revision of the application metadata for a given appID, or nil when it doesn't exist.
+// The revisions of an application in the trash are kept until it's purged.
+func (im *InMemoryMetadataRepository) GetRevision(appID string, revision int) (*metadata.ApplicationMetadata, error) {
+	im.mu.RLock()
+	defer im.mu.RUnlock()
+
+	revisions := im.revisions[appID]
+	if revision < 1 || revision > len(revisions) {
+		return nil, nil
+	}
+	return revisions[revision-1].DeepCopy(), nil
+}
+
 // GetAll returns all application metadata
 func (im *InMemoryMetadataRepository) GetAll() ([]metadata.ApplicationMetadata, error) {
+	im.mu.RLock()
+	defer im.mu.RUnlock()
+
 	var results []metadata.ApplicationMetadata
 	for k, v := range im.Storage {
-		v.ApplicationID = k
-		results = append(results, *v)
+		d := *v
+		d.ApplicationID = k
+		results = append(results, d)
 	}
 
 	return results, nil
 }
 
-// Delete removes the application metadata for a given an appID
+// Delete moves the application metadata for a given an appID to the trash, unless it's locked
 func (im *InMemoryMetadataRepository) Delete(appID string) error {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	v, ok := im.Storage[appID]
+	if !ok {
+		return ErrIDNotFound
+	}
+	if err := im.checkUnlocked(appID); err != nil {
+		return err
+	}
+	d := *v
+	d.ApplicationID = appID
+	deletedAt := im.now().UTC()
+	d.DeletedAt = &deletedAt
+	im.remove(appID)
+	im.trash[appID] = &d
+	im.notify(Deleted, appID, v)
+	return nil
+}
+
+// Undelete restores the application metadata for a given appID from the trash
+func (im *InMemoryMetadataRepository) Undelete(appID string) error {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	v, ok := im.trash[appID]
+	if !ok {
+		return ErrIDNotFound
+	}
+	if _, ok := im.Storage[appID]; ok {
+		return ErrIDConflict
+	}
+	if err := im.checkConstraints(appID, v, nil); err != nil {
+		return err
+	}
+	delete(im.trash, appID)
+	v.DeletedAt = nil
+	im.put(appID, v)
+	im.notify(Created, appID, v)
+	return nil
+}
+
+// Purge permanently removes the application metadata for a given appID, whether it's in the trash or not
+func (im *InMemoryMetadataRepository) Purge(appID string) error {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	v, live := im.Storage[appID]
+	_, trashed := im.trash[appID]
+	if !live && !trashed {
+		return ErrIDNotFound
+	}
+	if err := im.checkUnlocked(appID); err != nil {
+		return err
+	}
+	im.remove(appID)
+	delete(im.trash, appID)
+	delete(im.revisions, appID)
+	im.removeAliases(appID)
+	// an application in the trash was already reported as deleted
+	if live {
+		im.notify(Deleted, appID, v)
+	}
+	return nil
+}
+
+// PurgeDeleted permanently removes the application metadata moved to the trash before a given time,
+// and returns how many were removed
+func (im *InMemoryMetadataRepository) PurgeDeleted(before time.Time) (int, error) {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	n := 0
+	for id, v := range im.trash {
+		if v.DeletedAt.Before(before) {
+			delete(im.trash, id)
+			delete(im.revisions, id)
+			im.removeAliases(id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// PutBatch stores a set of application metadata keyed by their ApplicationID.
+// The returned slice holds one error per item (nil on success).  In atomic mode
+// nothing is stored when any item fails, and ErrBatchAborted is returned.
+func (im *InMemoryMetadataRepository) PutBatch(data []*metadata.ApplicationMetadata, opts BatchOptions) ([]error, error) {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	results := make([]error, len(data))
+	// accepted items, checked against the unique constraints along with the stored ones
+	seen := make(map[string]*metadata.ApplicationMetadata)
+	failed := false
+	for i, d := range data {
+		_, exists := im.Storage[d.ApplicationID]
+		_, trashed := im.trash[d.ApplicationID]
+		_, aliased := im.aliases[d.ApplicationID]
+		if (exists && !opts.Upsert) || trashed || aliased || seen[d.ApplicationID] != nil {
+			results[i] = ErrIDConflict
+			failed = true
+			continue
+		}
+		if err := im.checkUnlocked(d.ApplicationID); err != nil {
+			results[i] = err
+			failed = true
+			continue
+		}
+		d.Lock = nil
+		if err := im.checkConstraints(d.ApplicationID, d, seen); err != nil {
+			results[i] = err
+			failed = true
+			continue
+		}
+		seen[d.ApplicationID] = d
+	}
+	// the items are checked along with every other accepted one, until refusing some doesn't refuse others
+	for refused := im.onWrite != nil; refused; {
+		refused = false
+		for i, d := range data {
+			if results[i] != nil {
+				continue
+			}
+			if err := im.checkWrite(d.ApplicationID, d, im.Storage[d.ApplicationID], seen); err != nil {
+				results[i] = err
+				failed = true
+				refused = true
+				delete(seen, d.ApplicationID)
+			}
+		}
+	}
+	if failed && opts.Atomic {
+		return results, ErrBatchAborted
+	}
+
+	for i, d := range data {
+		if results[i] != nil {
+			continue
+		}
+		changeType := Created
+		if _, exists := im.Storage[d.ApplicationID]; exists {
+			changeType = Updated
+		}
+		im.revise(d.ApplicationID, d)
+		im.put(d.ApplicationID, d)
+		im.notify(changeType, d.ApplicationID, d)
+	}
+	return results, nil
+}
+
+// Scrub rewrites every application metadata, in the trash or not, along with every revision, without creating a
+// revision.  fn returns true when it changed an application, and may change data in place: the live and deleted
+// applications are given as copies replacing them, since readers may still hold the stored ones, while the revisions
+// are only kept by the repository.  It returns the sorted appIDs changed.
+func (im *InMemoryMetadataRepository) Scrub(fn func(data *metadata.ApplicationMetadata) bool) ([]string, error) {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	changed := make(map[string]bool)
+	for id, v := range im.Storage {
+		d := v.DeepCopy()
+		if fn(d) {
+			im.put(id, d)
+			im.notify(Updated, id, d)
+			changed[id] = true
+		}
+	}
+	for id, v := range im.trash {
+		d := v.DeepCopy()
+		if fn(d) {
+			im.trash[id] = d
+			changed[id] = true
+		}
+	}
+	for id, revisions := range im.revisions {
+		for i := range revisions {
+			if fn(&revisions[i]) {
+				changed[id] = true
+			}
+		}
+	}
+
+	ids := make([]string, 0, len(changed))
+	for id := range changed {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// ForEach calls fn for every application metadata ordered by appID, stopping at the first error.
+// The lock is only held while looking up each item, so fn may be slow (e.g. writing to a client).
+func (im *InMemoryMetadataRepository) ForEach(fn func(data *metadata.ApplicationMetadata) error) error {
+	im.mu.RLock()
+	ids := make([]string, 0, len(im.Storage))
+	for k := range im.Storage {
+		ids = append(ids, k)
+	}
+	im.mu.RUnlock()
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		im.mu.RLock()
+		v, ok := im.Storage[id]
+		var d metadata.ApplicationMetadata
+		if ok {
+			d = *v
+			d.ApplicationID = id
+		}
+		im.mu.RUnlock()
+		if !ok {
+			// removed since the snapshot was taken
+			continue
+		}
+		if err := fn(&d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// List returns the application metadata matching the query ordered by appID, so that After pages through them.
+// Equality, set membership, and existence requirements of the selector are resolved through the label index,
+// so only the candidates are checked against the whole selector.
+func (im *InMemoryMetadataRepository) List(query Query) ([]metadata.ApplicationMetadata, error) {
+	im.mu.RLock()
+	defer im.mu.RUnlock()
+
+	source := im.Storage
+	var candidates map[string]bool
+	if query.Deleted {
+		// the trash isn't indexed
+		source = im.trash
+		candidates = make(map[string]bool, len(im.trash))
+		for id := range im.trash {
+			candidates[id] = true
+		}
+	} else {
+		candidates = im.selectorCandidates(query.Selector)
+	}
+	ids := make([]string, 0, len(candidates))
+	for id := range candidates {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var results []metadata.ApplicationMetadata
+	for _, id := range ids {
+		if query.Limit > 0 && len(results) == query.Limit {
+			break
+		}
+		v := source[id]
+		if id <= query.After || !query.Selector.Matches(v.Labels) || !matchesState(v, query.States) || !v.MatchesFields(query.Fields) {
+			continue
+		}
+		if !query.ReleasedSince.IsZero() && !v.ReleasedSince(query.ReleasedSince) {
+			continue
+		}
+		if query.Match != nil && !query.Match(v) {
+			continue
+		}
+		d := *v
+		d.ApplicationID = id
+		results = append(results, d)
+	}
+	return results, nil
+}
+
+// selectorCandidates returns the set of appID that may match the selector using the label index,
+// or every appID when the selector has no indexable requirement
+func (im *InMemoryMetadataRepository) selectorCandidates(selector labels.Selector) map[string]bool {
+	var candidates map[string]bool
+	for _, r := range selector.Requirements {
+		var ids map[string]bool
+		switch r.Operator {
+		case labels.Equals, labels.In:
+			ids = make(map[string]bool)
+			for _, v := range r.Values {
+				for id := range im.labelIndex[r.Key][v] {
+					ids[id] = true
+				}
+			}
+		case labels.Exists:
+			ids = make(map[string]bool)
+			for _, set := range im.labelIndex[r.Key] {
+				for id := range set {
+					ids[id] = true
+				}
+			}
+		default:
+			// negative requirements can't narrow down the candidates
+			continue
+		}
+		candidates = intersect(candidates, ids)
+	}
+	if candidates != nil {
+		return candidates
+	}
+
+	candidates = make(map[string]bool, len(im.Storage))
+	for id := range im.Storage {
+		candidates[id] = true
+	}
+	return candidates
+}
+
+// intersect returns the ids of b that are also in a, a nil a meaning every id
+func intersect(a, b map[string]bool) map[string]bool {
+	if a == nil {
+		return b
+	}
+	for id := range b {
+		if !a[id] {
+			delete(b, id)
+		}
+	}
+	return b
+}
+
+// put stores data and keeps the indexes up to date, the caller must hold the write lock
+func (im *InMemoryMetadataRepository) put(appID string, data *metadata.ApplicationMetadata) {
+	im.unindex(appID)
+	im.Storage[appID] = data
+	im.index(appID, data)
+}
+
+// Rename moves the application metadata for a given appID to newID, keeping appID as an alias of newID.
+// The aliases of appID resolve to newID as well.
+func (im *InMemoryMetadataRepository) Rename(appID, newID string) error {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	v, ok := im.Storage[appID]
+	if !ok {
+		return ErrIDNotFound
+	}
+	if err := im.checkUnlocked(appID); err != nil {
+		return err
+	}
+	// renaming back to one of its own aliases is allowed
+	if im.aliases[newID] == appID {
+		delete(im.aliases, newID)
+	} else if im.taken(newID) {
+		return ErrIDConflict
+	}
+
+	d := *v
+	d.ApplicationID = newID
+	im.remove(appID)
+	im.revisions[newID] = im.revisions[appID]
+	delete(im.revisions, appID)
+	im.revise(newID, &d)
+	im.put(newID, &d)
+	im.notify(Deleted, appID, &d)
+	im.notify(Created, newID, &d)
+
+	for alias, id := range im.aliases {
+		if id == appID {
+			im.aliases[alias] = newID
+		}
+	}
+	im.aliases[appID] = newID
+	return nil
+}
+
+// AddAlias adds an alias resolving to a given appID
+func (im *InMemoryMetadataRepository) AddAlias(appID, alias string) error {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
 	if _, ok := im.Storage[appID]; !ok {
 		return ErrIDNotFound
 	}
-	delete(im.Storage, appID)
+	if err := im.checkUnlocked(appID); err != nil {
+		return err
+	}
+	if im.taken(alias) {
+		return ErrIDConflict
+	}
+	im.aliases[alias] = appID
+	return nil
+}
+
+// RemoveAlias removes an alias of a given appID
+func (im *InMemoryMetadataRepository) RemoveAlias(appID, alias string) error {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	if im.aliases[alias] != appID {
+		return ErrIDNotFound
+	}
+	if err := im.checkUnlocked(appID); err != nil {
+		return err
+	}
+	delete(im.aliases, alias)
 	return nil
 }
+
+// Aliases returns the sorted aliases of a given appID
+func (im *InMemoryMetadataRepository) Aliases(appID string) ([]string, error) {
+	im.mu.RLock()
+	defer im.mu.RUnlock()
+
+	res := []string{}
+	for alias, id := range im.aliases {
+		if id == appID {
+			res = append(res, alias)
+		}
+	}
+	sort.Strings(res)
+	return res, nil
+}
+
+// ResolveAlias returns the appID an alias resolves to, or an empty string when it isn't an alias
+func (im *InMemoryMetadataRepository) ResolveAlias(alias string) (string, error) {
+	im.mu.RLock()
+	defer im.mu.RUnlock()
+
+	return im.aliases[alias], nil
+}
+
+// OnChange sets the function called with every change of an application metadata, under the write lock
+func (im *InMemoryMetadataRepository) OnChange(fn ChangeFunc) {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	im.onChange = fn
+}
+
+// OnWrite sets the function checking every application about to be written, under the write lock
+func (im *InMemoryMetadataRepository) OnWrite(fn CheckFunc) {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	im.onWrite = fn
+}
+
+// checkWrite calls the check function with data about to be stored as appID, looking up the live applications
+// as they are once data and pending are stored.  The caller must hold the write lock.
+func (im *InMemoryMetadataRepository) checkWrite(appID string, data, previous *metadata.ApplicationMetadata, pending map[string]*metadata.ApplicationMetadata) error {
+	if im.onWrite == nil {
+		return nil
+	}
+	lookup := func(id string) (*metadata.ApplicationMetadata, error) {
+		if canonical, ok := im.aliases[id]; ok {
+			id = canonical
+		}
+		if id == appID {
+			return data, nil
+		}
+		if d, ok := pending[id]; ok {
+			return d, nil
+		}
+		return im.Storage[id], nil
+	}
+	return im.onWrite(data, previous, lookup)
+}
+
+// notify calls the change function with a copy of data, the caller must hold the write lock
+func (im *InMemoryMetadataRepository) notify(changeType, appID string, data *metadata.ApplicationMetadata) {
+	if im.onChange == nil {
+		return
+	}
+	d := data.DeepCopy()
+	d.ApplicationID = appID
+	im.onChange(changeType, appID, d)
+}
+
+// checkConstraints returns a ConstraintError when data holds the same unique values as a live application
+// other than appID, or as one of pending.  The caller must hold the lock.
+func (im *InMemoryMetadataRepository) checkConstraints(appID string, data *metadata.ApplicationMetadata, pending map[string]*metadata.ApplicationMetadata) error {
+	for _, uc := range im.constraints {
+		key := uc.key(data)
+		if key == "" {
+			continue
+		}
+		for _, m := range []map[string]*metadata.ApplicationMetadata{im.Storage, pending} {
+			for id, v := range m {
+				if id != appID && uc.key(v) == key {
+					return &ConstraintError{Constraint: uc, ApplicationID: id}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// taken returns true when id is used by an application, in the trash or not, or by an alias.
+// The caller must hold the lock.
+func (im *InMemoryMetadataRepository) taken(id string) bool {
+	_, live := im.Storage[id]
+	_, trashed := im.trash[id]
+	_, aliased := im.aliases[id]
+	return live || trashed || aliased
+}
+
+// removeAliases removes every alias of appID, the caller must hold the write lock
+func (im *InMemoryMetadataRepository) removeAliases(appID string) {
+	for alias, id := range im.aliases {
+		if id == appID {
+			delete(im.aliases, alias)
+		}
+	}
+}
+
+// revise sets the revision of data to the next one of appID and keeps a deep copy of it, so that later changes
+// of data don't rewrite the history.  The caller must hold the write lock.
+func (im *InMemoryMetadataRepository) revise(appID string, data *metadata.ApplicationMetadata) {
+	data.Revision = len(im.revisions[appID]) + 1
+	d := data.DeepCopy()
+	d.ApplicationID = appID
+	im.revisions[appID] = append(im.revisions[appID], *d)
+}
+
+// remove deletes appID and its index entries, the caller must hold the write lock
+func (im *InMemoryMetadataRepository) remove(appID string) {
+	im.unindex(appID)
+	delete(im.Storage, appID)
+}
+
+func (im *InMemoryMetadataRepository) index(appID string, data *metadata.ApplicationMetadata) {
+	indexed := make(map[string]string, len(data.Labels))
+	for k, v := range data.Labels {
+		indexed[k] = v
+		values, ok := im.labelIndex[k]
+		if !ok {
+			values = make(map[string]map[string]bool)
+			im.labelIndex[k] = values
+		}
+		ids, ok := values[v]
+		if !ok {
+			ids = make(map[string]bool)
+			values[v] = ids
+		}
+		ids[appID] = true
+	}
+	im.indexed[appID] = indexed
+	im.indexMaintainers(appID, data)
+}
+
+func (im *InMemoryMetadataRepository) unindex(appID string) {
+	for k, v := range im.indexed[appID] {
+		delete(im.labelIndex[k][v], appID)
+		if len(im.labelIndex[k][v]) == 0 {
+			delete(im.labelIndex[k], v)
+		}
+		if len(im.labelIndex[k]) == 0 {
+			delete(im.labelIndex, k)
+		}
+	}
+	delete(im.indexed, appID)
+	im.unindexMaintainers(appID)
+}
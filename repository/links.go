@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// LinkStatus is the result of the latest check of a URL, see package linkcheck
+type LinkStatus struct {
+	URL string `yaml:"url" json:"url"`
+	// StatusCode is the status of the last response, zero when no response was received
+	StatusCode int `yaml:"statusCode,omitempty" json:"statusCode,omitempty"`
+	// FinalURL is the URL the redirects ended at, empty when there was none
+	FinalURL  string `yaml:"finalURL,omitempty" json:"finalURL,omitempty"`
+	Redirects int    `yaml:"redirects,omitempty" json:"redirects,omitempty"`
+	// Error tells why no response was received, such as a timeout
+	Error     string    `yaml:"error,omitempty" json:"error,omitempty"`
+	Broken    bool      `yaml:"broken" json:"broken"`
+	CheckedAt time.Time `yaml:"checkedAt" json:"checkedAt"`
+}
+
+// LinkStore defines an interface to store the latest status of the URLs of the applications
+type LinkStore interface {
+	// PutLink creates or replaces the status of a URL
+	PutLink(status *LinkStatus) error
+	// GetLink returns nil when the URL wasn't checked
+	GetLink(url string) (*LinkStatus, error)
+	// ListLinks returns the status of every URL checked, ordered by URL
+	ListLinks() ([]LinkStatus, error)
+	// RetainLinks removes the status of the URLs not in urls, which no application uses anymore
+	RetainLinks(urls map[string]bool) error
+}
+
+// InMemoryLinkStore is a concrete implementation of LinkStore interface in memory
+type InMemoryLinkStore struct {
+	mu    sync.RWMutex
+	links map[string]LinkStatus
+}
+
+// NewInMemoryLinkStore creates a new instance of InMemoryLinkStore
+func NewInMemoryLinkStore() LinkStore {
+	return &InMemoryLinkStore{
+		links: make(map[string]LinkStatus),
+	}
+}
+
+// PutLink creates or replaces the status of a URL
+func (ls *InMemoryLinkStore) PutLink(status *LinkStatus) error {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+
+	ls.links[status.URL] = *status
+	return nil
+}
+
+// GetLink returns nil when the URL wasn't checked
+func (ls *InMemoryLinkStore) GetLink(url string) (*LinkStatus, error) {
+	ls.mu.RLock()
+	defer ls.mu.RUnlock()
+
+	s, ok := ls.links[url]
+	if !ok {
+		return nil, nil
+	}
+	return &s, nil
+}
+
+// ListLinks returns the status of every URL checked, ordered by URL
+func (ls *InMemoryLinkStore) ListLinks() ([]LinkStatus, error) {
+	ls.mu.RLock()
+	defer ls.mu.RUnlock()
+
+	res := make([]LinkStatus, 0, len(ls.links))
+	for _, s := range ls.links {
+		res = append(res, s)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].URL < res[j].URL })
+	return res, nil
+}
+
+// RetainLinks removes the status of the URLs not in urls
+func (ls *InMemoryLinkStore) RetainLinks(urls map[string]bool) error {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+
+	for u := range ls.links {
+		if !urls[u] {
+			delete(ls.links, u)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"fmt"
+
+	"github.com/elumbantoruan/app-metadata/metadata"
+)
+
+// LockedError is returned when a write targets an application an admin locked
+type LockedError struct {
+	ApplicationID string
+	Lock          metadata.Lock
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("application %s is locked", e.ApplicationID)
+}
+
+// SetLock replaces the lock of a live application, nil unlocking it, and returns the application.  It's the only
+// write allowed while the application is locked, and returns a LockedError when locking an application already locked.
+func (im *InMemoryMetadataRepository) SetLock(appID string, lock *metadata.Lock) (*metadata.ApplicationMetadata, error) {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	v, ok := im.Storage[appID]
+	if !ok {
+		return nil, ErrIDNotFound
+	}
+	if lock != nil {
+		if err := im.checkUnlocked(appID); err != nil {
+			return nil, err
+		}
+		l := *lock
+		lock = &l
+	}
+	d := v.DeepCopy()
+	d.ApplicationID = appID
+	d.Lock = lock
+	im.revise(appID, d)
+	im.put(appID, d)
+	im.notify(Updated, appID, d)
+	return d.DeepCopy(), nil
+}
+
+// checkUnlocked returns a LockedError when the live application appID is locked, the caller must hold the lock
+func (im *InMemoryMetadataRepository) checkUnlocked(appID string) error {
+	if v, ok := im.Storage[appID]; ok && v.Lock != nil {
+		return &LockedError{ApplicationID: appID, Lock: *v.Lock}
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"sort"
+
+	"github.com/elumbantoruan/app-metadata/metadata"
+)
+
+// Maintainer is a maintainer derived from the live applications listing them, keyed by normalized email
+type Maintainer struct {
+	Email string `yaml:"email" json:"email"`
+	// Name is the name the applications give the maintainer the most
+	Name string `yaml:"name" json:"name"`
+	// Applications are the appIDs of the applications listing the maintainer, ordered
+	Applications []string `yaml:"applications" json:"applications"`
+}
+
+// newMaintainer returns the maintainer with a given normalized email derived from the applications listing them
+func newMaintainer(email string, apps []*metadata.ApplicationMetadata, ids []string) Maintainer {
+	counts := make(map[string]int)
+	for _, app := range apps {
+		for _, m := range app.Maintainers {
+			if metadata.NormalizeEmail(m.Email) == email {
+				counts[m.Name]++
+			}
+		}
+	}
+	name := ""
+	for n, c := range counts {
+		if c > counts[name] || (c == counts[name] && n < name) {
+			name = n
+		}
+	}
+	return Maintainer{Email: email, Name: name, Applications: ids}
+}
+
+// Maintainers returns every maintainer of the live applications ordered by email
+func (im *InMemoryMetadataRepository) Maintainers() ([]Maintainer, error) {
+	im.mu.RLock()
+	defer im.mu.RUnlock()
+
+	emails := make([]string, 0, len(im.maintainerIndex))
+	for email := range im.maintainerIndex {
+		emails = append(emails, email)
+	}
+	sort.Strings(emails)
+	res := make([]Maintainer, 0, len(emails))
+	for _, email := range emails {
+		res = append(res, im.maintainer(email))
+	}
+	return res, nil
+}
+
+// GetMaintainer returns the maintainer with a given email, or nil when no live application lists them
+func (im *InMemoryMetadataRepository) GetMaintainer(email string) (*Maintainer, error) {
+	im.mu.RLock()
+	defer im.mu.RUnlock()
+
+	email = metadata.NormalizeEmail(email)
+	if len(im.maintainerIndex[email]) == 0 {
+		return nil, nil
+	}
+	m := im.maintainer(email)
+	return &m, nil
+}
+
+// MaintainerApplications returns the live applications listing a given maintainer email, ordered by appID
+func (im *InMemoryMetadataRepository) MaintainerApplications(email string) ([]metadata.ApplicationMetadata, error) {
+	im.mu.RLock()
+	defer im.mu.RUnlock()
+
+	ids := im.maintainerIDs(metadata.NormalizeEmail(email))
+	res := make([]metadata.ApplicationMetadata, 0, len(ids))
+	for _, id := range ids {
+		d := *im.Storage[id]
+		d.ApplicationID = id
+		res = append(res, d)
+	}
+	return res, nil
+}
+
+// UpdateMaintainer calls fn with a copy of every live application listing a given maintainer email, ordered by appID,
+// fn returning true when it changed the copy.  The copies changed are stored under the write lock, so that writes in
+// between aren't lost, and atomically: nothing is stored when one of them is locked (LockedError) or breaks a unique
+// constraint.  It returns the applications changed, or ErrIDNotFound when no live application lists the email.
+func (im *InMemoryMetadataRepository) UpdateMaintainer(email string, fn func(data *metadata.ApplicationMetadata) bool) ([]Modification, error) {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	ids := im.maintainerIDs(metadata.NormalizeEmail(email))
+	if len(ids) == 0 {
+		return nil, ErrIDNotFound
+	}
+	var mods []Modification
+	pending := make(map[string]*metadata.ApplicationMetadata)
+	for _, id := range ids {
+		d := im.Storage[id].DeepCopy()
+		d.ApplicationID = id
+		if !fn(d) {
+			continue
+		}
+		if err := im.checkUnlocked(id); err != nil {
+			return nil, err
+		}
+		d.Lock = nil
+		if err := im.checkConstraints(id, d, pending); err != nil {
+			return nil, err
+		}
+		pending[id] = d
+		mods = append(mods, Modification{Previous: im.Storage[id], Current: d})
+	}
+	for _, m := range mods {
+		id := m.Current.ApplicationID
+		im.revise(id, m.Current)
+		im.put(id, m.Current)
+		im.notify(Updated, id, m.Current)
+	}
+	return mods, nil
+}
+
+// maintainer derives the maintainer with a given normalized email, the caller must hold the lock
+func (im *InMemoryMetadataRepository) maintainer(email string) Maintainer {
+	ids := im.maintainerIDs(email)
+	apps := make([]*metadata.ApplicationMetadata, len(ids))
+	for i, id := range ids {
+		apps[i] = im.Storage[id]
+	}
+	return newMaintainer(email, apps, ids)
+}
+
+// maintainerIDs returns the sorted appIDs listing a given normalized email, the caller must hold the lock
+func (im *InMemoryMetadataRepository) maintainerIDs(email string) []string {
+	ids := make([]string, 0, len(im.maintainerIndex[email]))
+	for id := range im.maintainerIndex[email] {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// indexMaintainers adds the maintainers of appID to the maintainer index, the caller must hold the write lock
+func (im *InMemoryMetadataRepository) indexMaintainers(appID string, data *metadata.ApplicationMetadata) {
+	emails := make([]string, 0, len(data.Maintainers))
+	for _, m := range data.Maintainers {
+		email := metadata.NormalizeEmail(m.Email)
+		ids, ok := im.maintainerIndex[email]
+		if !ok {
+			ids = make(map[string]bool)
+			im.maintainerIndex[email] = ids
+		}
+		ids[appID] = true
+		emails = append(emails, email)
+	}
+	im.indexedEmails[appID] = emails
+}
+
+// unindexMaintainers removes appID from the maintainer index, the caller must hold the write lock
+func (im *InMemoryMetadataRepository) unindexMaintainers(appID string) {
+	for _, email := range im.indexedEmails[appID] {
+		delete(im.maintainerIndex[email], appID)
+		if len(im.maintainerIndex[email]) == 0 {
+			delete(im.maintainerIndex, email)
+		}
+	}
+	delete(im.indexedEmails, appID)
+}
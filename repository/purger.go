@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// RunPurger permanently removes the application metadata that stayed in the trash longer than retention.
+// It checks every interval until ctx is done, skipping the checks while paused returns true, such as in read-only mode.
+func RunPurger(ctx context.Context, repo MetadataRepository, retention, interval time.Duration, paused func() bool) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if paused() {
+				continue
+			}
+			n, err := repo.PurgeDeleted(now.Add(-retention))
+			if err != nil {
+				log.Printf("purging the trash: %v", err)
+				continue
+			}
+			if n > 0 {
+				log.Printf("purged %d application(s) from the trash", n)
+			}
+		}
+	}
+}
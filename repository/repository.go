@@ -2,7 +2,10 @@ package repository
 
 import (
 	"errors"
+	"time"
 
+	"github.com/elumbantoruan/app-metadata/labels"
+	"github.com/elumbantoruan/app-metadata/lifecycle"
 	"github.com/elumbantoruan/app-metadata/metadata"
 )
 
@@ -10,11 +13,102 @@ import (
 type MetadataRepository interface {
 	Create(appID string, data *metadata.ApplicationMetadata) error
 	Update(appID string, data *metadata.ApplicationMetadata) error
+	Modify(appID string, fn func(data *metadata.ApplicationMetadata) error) (*Modification, error)
 	Get(appID string) (*metadata.ApplicationMetadata, error)
+	GetRevision(appID string, revision int) (*metadata.ApplicationMetadata, error)
 	GetAll() ([]metadata.ApplicationMetadata, error)
 	Delete(appID string) error
+	Undelete(appID string) error
+	Purge(appID string) error
+	PurgeDeleted(before time.Time) (int, error)
+	PutBatch(data []*metadata.ApplicationMetadata, opts BatchOptions) ([]error, error)
+	ForEach(fn func(data *metadata.ApplicationMetadata) error) error
+	List(query Query) ([]metadata.ApplicationMetadata, error)
+	Rename(appID, newID string) error
+	AddAlias(appID, alias string) error
+	RemoveAlias(appID, alias string) error
+	Aliases(appID string) ([]string, error)
+	ResolveAlias(alias string) (string, error)
+	Maintainers() ([]Maintainer, error)
+	GetMaintainer(email string) (*Maintainer, error)
+	MaintainerApplications(email string) ([]metadata.ApplicationMetadata, error)
+	UpdateMaintainer(email string, fn func(data *metadata.ApplicationMetadata) bool) ([]Modification, error)
+	Scrub(fn func(data *metadata.ApplicationMetadata) bool) ([]string, error)
+	SetLock(appID string, lock *metadata.Lock) (*metadata.ApplicationMetadata, error)
+	OnChange(fn ChangeFunc)
+	OnWrite(fn CheckFunc)
+}
+
+// change types reported to a ChangeFunc
+const (
+	Created = "created"
+	Updated = "updated"
+	Deleted = "deleted"
+)
+
+// ChangeFunc is called with every write of an application metadata while the repository holds its write lock,
+// so that the changes are observed in the order they're applied.  data is a copy of the application after the
+// change, or its last state when it's deleted.  It must not call the repository.
+type ChangeFunc func(changeType, appID string, data *metadata.ApplicationMetadata)
+
+// CheckFunc is called with every application about to be created, updated, or imported while the repository holds
+// its write lock, so that a check involving other applications, such as a dependency cycle, can't be passed by two
+// concurrent writes.  previous is the stored application, nil for a new one.  lookup returns the live application
+// with an appID or an alias as it is once the write is applied, along with the other applications of a batch.
+// A non-nil error refuses the write and is returned by it.  It must not call the repository.
+type CheckFunc func(data, previous *metadata.ApplicationMetadata, lookup func(appID string) (*metadata.ApplicationMetadata, error)) error
+
+// Modification is an application changed by a write, with its content before and after it
+type Modification struct {
+	Previous *metadata.ApplicationMetadata
+	Current  *metadata.ApplicationMetadata
+}
+
+// Query filters the application metadata returned by List
+type Query struct {
+	// Selector matches the labels of the applications, the empty selector matches everything
+	Selector labels.Selector
+	// After skips the applications whose appID isn't greater than After, to resume from a previous page
+	After string
+	// Limit is the maximum number of applications returned, zero means no limit
+	Limit int
+	// Deleted lists the applications in the trash instead of the live ones
+	Deleted bool
+	// States matches the lifecycle state of the applications, every state when empty
+	States []string
+	// Fields matches the core fields and the extensions of the applications, see metadata.ParseFieldSelector
+	Fields []metadata.FieldRequirement
+	// ReleasedSince matches the applications with a release at or after it, every application when it's zero
+	ReleasedSince time.Time
+	// Match is called with each application matching the other conditions, and keeps it when it returns true.
+	// It's called with the lock held, so it must not call the repository.
+	Match func(data *metadata.ApplicationMetadata) bool
+}
+
+// matchesState returns true when the lifecycle state of data is one of states, or states is empty
+func matchesState(data *metadata.ApplicationMetadata, states []string) bool {
+	if len(states) == 0 {
+		return true
+	}
+	state := lifecycle.Effective(data.State)
+	for _, s := range states {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
+
+// BatchOptions controls how PutBatch applies a set of application metadata
+type BatchOptions struct {
+	// Atomic applies either every item or none of them
+	Atomic bool
+	// Upsert replaces an existing application with the same ID instead of reporting a conflict
+	Upsert bool
 }
 
 var (
-	ErrIDNotFound = errors.New("id not found")
+	ErrIDNotFound   = errors.New("id not found")
+	ErrIDConflict   = errors.New("id already exists")
+	ErrBatchAborted = errors.New("batch aborted")
 )
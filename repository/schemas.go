@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ExtensionSchema is the JSON Schema the extensions of a namespace are validated against
+type ExtensionSchema struct {
+	Namespace string `yaml:"namespace" json:"namespace"`
+	// Schema is the schema document decoded into generic values, see metadata.Normalize
+	Schema    interface{} `yaml:"schema" json:"schema"`
+	UpdatedAt time.Time   `yaml:"updatedAt" json:"updatedAt"`
+}
+
+// SchemaStore defines an interface to store the schemas of the extension namespaces
+type SchemaStore interface {
+	// PutSchema creates or replaces the schema of a namespace, it returns true when it's created
+	PutSchema(schema *ExtensionSchema) (bool, error)
+	// GetSchema returns nil when the namespace has no schema
+	GetSchema(namespace string) (*ExtensionSchema, error)
+	// ListSchemas returns the schemas ordered by namespace
+	ListSchemas() ([]ExtensionSchema, error)
+	DeleteSchema(namespace string) error
+}
+
+var (
+	ErrSchemaNotFound = errors.New("schema not found")
+)
+
+// InMemorySchemaStore is a concrete implementation of SchemaStore interface in memory
+type InMemorySchemaStore struct {
+	mu      sync.RWMutex
+	schemas map[string]ExtensionSchema
+}
+
+// NewInMemorySchemaStore creates a new instance of InMemorySchemaStore
+func NewInMemorySchemaStore() SchemaStore {
+	return &InMemorySchemaStore{
+		schemas: make(map[string]ExtensionSchema),
+	}
+}
+
+// PutSchema creates or replaces the schema of a namespace, it returns true when it's created
+func (ss *InMemorySchemaStore) PutSchema(schema *ExtensionSchema) (bool, error) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	_, exists := ss.schemas[schema.Namespace]
+	ss.schemas[schema.Namespace] = *schema
+	return !exists, nil
+}
+
+// GetSchema returns nil when the namespace has no schema
+func (ss *InMemorySchemaStore) GetSchema(namespace string) (*ExtensionSchema, error) {
+	ss.mu.RLock()
+	defer ss.mu.RUnlock()
+
+	s, ok := ss.schemas[namespace]
+	if !ok {
+		return nil, nil
+	}
+	return &s, nil
+}
+
+// ListSchemas returns the schemas ordered by namespace
+func (ss *InMemorySchemaStore) ListSchemas() ([]ExtensionSchema, error) {
+	ss.mu.RLock()
+	defer ss.mu.RUnlock()
+
+	res := make([]ExtensionSchema, 0, len(ss.schemas))
+	for _, s := range ss.schemas {
+		res = append(res, s)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Namespace < res[j].Namespace })
+	return res, nil
+}
+
+// DeleteSchema removes the schema of a namespace, it returns ErrSchemaNotFound when there is none
+func (ss *InMemorySchemaStore) DeleteSchema(namespace string) error {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	if _, ok := ss.schemas[namespace]; !ok {
+		return ErrSchemaNotFound
+	}
+	delete(ss.schemas, namespace)
+	return nil
+}
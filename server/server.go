@@ -0,0 +1,293 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/smtp"
+	"os"
+	"time"
+
+	"github.com/elumbantoruan/app-metadata/audit"
+	"github.com/elumbantoruan/app-metadata/dependency"
+	"github.com/elumbantoruan/app-metadata/emailpolicy"
+	"github.com/elumbantoruan/app-metadata/events"
+	"github.com/elumbantoruan/app-metadata/handlers"
+	"github.com/elumbantoruan/app-metadata/linkcheck"
+	"github.com/elumbantoruan/app-metadata/repository"
+	"github.com/elumbantoruan/app-metadata/ui"
+	"github.com/elumbantoruan/app-metadata/verification"
+
+	"github.com/gorilla/mux"
+)
+
+// Config holds the settings of the service
+type Config struct {
+	MaxBodySize       int64
+	MaxImportSize     int64
+	ReadLimit         handlers.RateLimit
+	WriteLimit        handlers.RateLimit
+	TrustForwardedFor bool
+	// APIKeys identify the clients sending them in X-API-Key for rate limiting and idempotency keys, the others are
+	// identified by IP
+	APIKeys        []string
+	IdempotencyTTL time.Duration
+	EventLogSize   int
+	// TrashRetention is how long a deleted application stays in the trash before it's purged
+	TrashRetention time.Duration
+	// PurgeInterval is how often the trash is checked for applications to purge
+	PurgeInterval time.Duration
+	// AdminToken authorizes admin operations, they're disabled when it's empty
+	AdminToken string
+	// AuditLogPath is the file the audit log is appended to, it's only kept in memory when empty
+	AuditLogPath string
+	// UniqueConstraints are enforced by the repository on every write
+	UniqueConstraints []repository.UniqueConstraint
+	// DuplicatePolicy is off, warn, or block, see handlers.DuplicatesWarn
+	DuplicatePolicy string
+	// LenientDecoding ignores unknown fields and duplicate keys of write bodies instead of rejecting them
+	LenientDecoding bool
+	// CompanyDomains are the email domains the maintainers of a company's applications must use
+	CompanyDomains map[string][]string
+	// DisposableDomains are the email domains no maintainer may use
+	DisposableDomains []string
+	// SMTPAddr is the host:port of the server verification tokens are sent through, verification is disabled when empty
+	SMTPAddr     string
+	SMTPFrom     string
+	SMTPUsername string
+	SMTPPassword string
+	// SMTPTimeout bounds the delivery of a message to the SMTP server
+	SMTPTimeout time.Duration
+	// SMTPQueueSize is the number of messages waiting to be sent, more are refused
+	SMTPQueueSize int
+	// VerificationKey signs the verification tokens
+	VerificationKey string
+	// VerificationURL is the public URL of GET /maintainers:verify, which the emailed link points to
+	VerificationURL string
+	// VerificationTTL is how long a verification token is valid
+	VerificationTTL time.Duration
+	// LinkCheckInterval is how often the website and source URLs are checked, 0 disables link checking.
+	// Only public addresses are checked unless LinkCheckAllowPrivate is set.
+	LinkCheckInterval time.Duration
+	// LinkCheckConcurrency is the maximum number of URLs checked at the same time
+	LinkCheckConcurrency int
+	// LinkCheckTimeout bounds each request checking a URL, once its host allows it
+	LinkCheckTimeout time.Duration
+	// LinkCheckHostInterval is the minimum time between two requests to the same host
+	LinkCheckHostInterval time.Duration
+	// LinkCheckAllowPrivate lets the checker connect to loopback, private, and link-local addresses
+	LinkCheckAllowPrivate bool
+	// UI serves the HTML catalog browser under /ui
+	UI bool
+	// ReadOnly starts the service in read-only mode, which admins can turn off through the API
+	ReadOnly bool
+	// ReadOnlyRetryAfter is the Retry-After of the writes refused in read-only mode
+	ReadOnlyRetryAfter time.Duration
+}
+
+// DefaultConfig returns the default settings of the service
+func DefaultConfig() Config {
+	return Config{
+		MaxBodySize:     handlers.DefaultMaxBodySize,
+		MaxImportSize:   handlers.DefaultMaxImportSize,
+		ReadLimit:       handlers.RateLimit{Rate: 20, Burst: 40},
+		WriteLimit:      handlers.RateLimit{Rate: 2, Burst: 10},
+		IdempotencyTTL:  handlers.DefaultIdempotencyTTL,
+		EventLogSize:    1000,
+		TrashRetention:  30 * 24 * time.Hour,
+		PurgeInterval:   time.Hour,
+		DuplicatePolicy: handlers.DuplicatesWarn,
+		// a copy, so that changing the config doesn't change the defaults
+		DisposableDomains: append([]string(nil), emailpolicy.DefaultDisposableDomains...),
+		VerificationTTL:   verification.DefaultTTL,
+		SMTPTimeout:       verification.DefaultSMTPTimeout,
+		SMTPQueueSize:     verification.DefaultQueueSize,
+
+		LinkCheckConcurrency:  linkcheck.DefaultConcurrency,
+		LinkCheckTimeout:      linkcheck.DefaultTimeout,
+		LinkCheckHostInterval: linkcheck.DefaultHostInterval,
+		UI:                    true,
+		ReadOnlyRetryAfter:    handlers.DefaultRetryAfter,
+	}
+}
+
+// RegisterHandlers returns a router serving the app-metadata resource backed by an in-memory repository.
+// It also starts purging the trash, sending verification emails, and checking the links of the applications in the
+// background, until ctx is done.
+func RegisterHandlers(ctx context.Context, cfg Config) (*mux.Router, error) {
+	m := mux.NewRouter()
+
+	// initialize in-memory metadata repository
+	inMem := repository.NewInMemoryMetadataRepository(cfg.UniqueConstraints...)
+	// dependencies are checked under the write lock as well, so that concurrent writes can't create a cycle
+	inMem.OnWrite(dependency.Check)
+	// record every write into the event log streamed to watchers
+	eventLog := events.NewLog(cfg.EventLogSize)
+	repo := events.NewRecordingRepository(inMem, eventLog)
+	// initialize metadata handler and inject the implementation of repository interface
+	appMd := handlers.NewMetadataHandler(repo)
+	appMd.Events = eventLog
+	appMd.MaxBodySize = cfg.MaxBodySize
+	appMd.MaxImportSize = cfg.MaxImportSize
+	// responses of POST operations with an Idempotency-Key are kept in memory as well
+	appMd.Idempotency = repository.NewInMemoryIdempotencyStore()
+	appMd.IdempotencyTTL = cfg.IdempotencyTTL
+	// so are the schemas of the extensions
+	appMd.Schemas = repository.NewInMemorySchemaStore()
+	appMd.AdminToken = cfg.AdminToken
+	appMd.TrustForwardedFor = cfg.TrustForwardedFor
+	if len(cfg.APIKeys) > 0 {
+		appMd.APIKeys = make(map[string]bool, len(cfg.APIKeys))
+		for _, key := range cfg.APIKeys {
+			appMd.APIKeys[key] = true
+		}
+	}
+	appMd.DuplicatePolicy = cfg.DuplicatePolicy
+	appMd.StrictDecoding = !cfg.LenientDecoding
+	appMd.ReadOnly.Set(handlers.ReadOnlyStatus{Enabled: cfg.ReadOnly})
+	appMd.ReadOnlyRetryAfter = cfg.ReadOnlyRetryAfter
+	if cfg.TrashRetention > 0 && cfg.PurgeInterval > 0 {
+		go repository.RunPurger(ctx, inMem, cfg.TrashRetention, cfg.PurgeInterval, appMd.ReadOnly.Enabled)
+	}
+	if len(cfg.CompanyDomains) > 0 || len(cfg.DisposableDomains) > 0 {
+		appMd.EmailPolicy = emailpolicy.New(cfg.CompanyDomains, cfg.DisposableDomains)
+	}
+	verifier, err := newVerifier(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	appMd.Verifier = verifier
+	auditLog, err := openAuditLog(cfg.AuditLogPath)
+	if err != nil {
+		return nil, err
+	}
+	appMd.Audit = auditLog
+	if cfg.LinkCheckInterval > 0 {
+		appMd.Links = repository.NewInMemoryLinkStore()
+		checker := linkcheck.New()
+		checker.Concurrency = cfg.LinkCheckConcurrency
+		checker.Timeout = cfg.LinkCheckTimeout
+		checker.HostInterval = cfg.LinkCheckHostInterval
+		if cfg.LinkCheckAllowPrivate {
+			checker.AllowAddress = func(ip net.IP) bool { return true }
+		}
+		go checker.Run(ctx, inMem, appMd.Links, cfg.LinkCheckInterval)
+	}
+
+	// throttle every client with separate read and write budgets
+	rl := handlers.NewRateLimiter(cfg.ReadLimit, cfg.WriteLimit)
+	rl.TrustForwardedFor = cfg.TrustForwardedFor
+	rl.APIKeys = appMd.APIKeys
+	m.Use(handlers.RequestIDMiddleware)
+	m.Use(rl.Middleware)
+	m.Use(appMd.EnforceReadOnly)
+	m.Use(appMd.ResolveAliases)
+
+	// Register app-metadata resource
+	// the watch routes are registered first since they overlap with the routes below
+	m.HandleFunc("/app-metadata", appMd.HandleWatchMetadata).Methods("GET").Queries("watch", "true")
+	m.HandleFunc("/app-metadata/events", appMd.HandleWatchMetadata).Methods("GET")
+	m.HandleFunc("/app-metadata", appMd.HandlePostMetadata).Methods("POST")
+	m.HandleFunc("/app-metadata/{appID}", appMd.HandlePutMetadata).Methods("PUT")
+	m.HandleFunc("/app-metadata/{appID}", appMd.HandleGetMetadata).Methods("GET")
+	m.HandleFunc("/app-metadata", appMd.HandleGetAllMetadata).Methods("GET")
+	m.HandleFunc("/app-metadata/{appID}", appMd.HandleDeleteMetadata).Methods("DELETE")
+	m.HandleFunc("/app-metadata/{appID:[^/:]+}:undelete", appMd.HandleUndeleteMetadata).Methods("POST")
+	m.HandleFunc("/app-metadata/{appID:[^/:]+}:transition", appMd.HandleTransitionMetadata).Methods("POST")
+	m.HandleFunc("/app-metadata/{appID:[^/:]+}:rename", appMd.HandleRenameMetadata).Methods("POST")
+	m.HandleFunc("/app-metadata/{appID:[^/:]+}:purge", appMd.HandlePurgeMetadata).Methods("POST")
+	m.HandleFunc("/app-metadata/{appID:[^/:]+}:lock", appMd.HandleLockMetadata).Methods("POST")
+	m.HandleFunc("/app-metadata/{appID:[^/:]+}:unlock", appMd.HandleUnlockMetadata).Methods("POST")
+	m.HandleFunc("/app-metadata:import", appMd.HandleImportMetadata).Methods("POST")
+	m.HandleFunc("/app-metadata:export", appMd.HandleExportMetadata).Methods("GET")
+	m.HandleFunc("/app-metadata:diff", appMd.HandleDiffApplications).Methods("GET")
+	m.HandleFunc("/app-metadata/{appID}/diff", appMd.HandleDiffRevisions).Methods("GET")
+	m.HandleFunc("/app-metadata/{appID}/aliases", appMd.HandleGetAliases).Methods("GET")
+	m.HandleFunc("/app-metadata/{appID}/links", appMd.HandleGetLinks).Methods("GET")
+	m.HandleFunc("/app-metadata/{appID}/description", appMd.HandleGetDescription).Methods("GET")
+	m.HandleFunc("/app-metadata/{appID}/aliases/{alias}", appMd.HandlePutAlias).Methods("PUT")
+	m.HandleFunc("/app-metadata/{appID}/aliases/{alias}", appMd.HandleDeleteAlias).Methods("DELETE")
+	m.HandleFunc("/app-metadata/{appID}/releases", appMd.HandleGetReleases).Methods("GET")
+	m.HandleFunc("/app-metadata/{appID}/releases", appMd.HandlePostRelease).Methods("POST")
+	m.HandleFunc("/app-metadata/{appID}/releases/{version}", appMd.HandleGetRelease).Methods("GET")
+	m.HandleFunc("/app-metadata/{appID}/releases/{version}", appMd.HandleDeleteRelease).Methods("DELETE")
+	m.HandleFunc("/app-metadata/{appID}/dependencies", appMd.HandleGetDependencies).Methods("GET")
+	m.HandleFunc("/app-metadata/{appID}/dependents", appMd.HandleGetDependents).Methods("GET")
+	m.HandleFunc("/maintainers", appMd.HandleGetMaintainers).Methods("GET")
+	m.HandleFunc("/maintainers:verify", appMd.HandleVerifyMaintainer).Methods("GET")
+	m.HandleFunc("/maintainers/{email:[^/:]+}:send-verification", appMd.HandleSendVerification).Methods("POST")
+	m.HandleFunc("/maintainers/{email:[^/:]+}:erase", appMd.HandleEraseMaintainer).Methods("POST")
+	m.HandleFunc("/maintainers/{email:[^/:]+}:export", appMd.HandleExportMaintainer).Methods("GET")
+	m.HandleFunc("/maintainers/{email}", appMd.HandleGetMaintainer).Methods("GET")
+	m.HandleFunc("/maintainers/{email}", appMd.HandlePutMaintainer).Methods("PUT")
+	m.HandleFunc("/maintainers/{email}/apps", appMd.HandleGetMaintainerApplications).Methods("GET")
+	m.HandleFunc("/extensions", appMd.HandleGetExtensionSchemas).Methods("GET")
+	m.HandleFunc("/extensions/{namespace}", appMd.HandleGetExtensionSchema).Methods("GET")
+	m.HandleFunc("/extensions/{namespace}", appMd.HandlePutExtensionSchema).Methods("PUT")
+	m.HandleFunc("/extensions/{namespace}", appMd.HandleDeleteExtensionSchema).Methods("DELETE")
+	m.HandleFunc("/audit", appMd.HandleGetAudit).Methods("GET")
+	m.HandleFunc("/audit:verify", appMd.HandleVerifyAudit).Methods("GET")
+	m.HandleFunc(handlers.ReadOnlyPath, appMd.HandleGetReadOnly).Methods("GET")
+	m.HandleFunc(handlers.ReadOnlyPath, appMd.HandlePutReadOnly).Methods("PUT")
+
+	if cfg.UI {
+		// the catalog browser writes through the handlers above
+		m.Handle(ui.Prefix, http.RedirectHandler(ui.Prefix+"/", http.StatusMovedPermanently)).Methods("GET")
+		m.PathPrefix(ui.Prefix + "/static/").Handler(ui.Static()).Methods("GET")
+		m.HandleFunc(ui.Prefix+"/", appMd.HandleUIList).Methods("GET")
+		m.HandleFunc(ui.Prefix+"/new", appMd.HandleUINewForm).Methods("GET")
+		m.HandleFunc(ui.Prefix+"/new", appMd.HandleUICreate).Methods("POST")
+		m.HandleFunc(ui.Prefix+"/apps/{id}", appMd.HandleUIApplication).Methods("GET")
+		m.HandleFunc(ui.Prefix+"/apps/{id}/edit", appMd.HandleUIEditForm).Methods("GET")
+		m.HandleFunc(ui.Prefix+"/apps/{id}/edit", appMd.HandleUIUpdate).Methods("POST")
+	}
+
+	return m, nil
+}
+
+// newVerifier returns the verifier sending tokens through cfg.SMTPAddr, or nil when it's empty.
+// The tokens are queued and sent in the background until ctx is done.
+func newVerifier(ctx context.Context, cfg Config) (*verification.Verifier, error) {
+	if cfg.SMTPAddr == "" {
+		return nil, nil
+	}
+	if cfg.VerificationKey == "" || cfg.VerificationURL == "" || cfg.SMTPFrom == "" {
+		return nil, fmt.Errorf("email verification requires a sender, a verification key, and a verification URL")
+	}
+	sender := &verification.SMTPSender{Addr: cfg.SMTPAddr, From: cfg.SMTPFrom, Timeout: cfg.SMTPTimeout}
+	if cfg.SMTPUsername != "" {
+		host, _, err := net.SplitHostPort(cfg.SMTPAddr)
+		if err != nil {
+			return nil, err
+		}
+		sender.Auth = smtp.PlainAuth("", cfg.SMTPUsername, cfg.SMTPPassword, host)
+	}
+	queue := verification.NewQueue(sender, cfg.SMTPQueueSize)
+	go queue.Run(ctx)
+	return &verification.Verifier{
+		Signer: verification.NewSigner([]byte(cfg.VerificationKey), cfg.VerificationTTL),
+		Sender: queue,
+		URL:    cfg.VerificationURL,
+	}, nil
+}
+
+// openAuditLog returns the audit log continuing the chain stored at path, which is verified first
+func openAuditLog(path string) (*audit.Log, error) {
+	if path == "" {
+		return audit.NewLog(nil, nil), nil
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := audit.Read(f)
+	if err == nil {
+		err = audit.Verify(entries)
+	}
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return audit.NewLog(entries, f), nil
+}
@@ -0,0 +1,209 @@
+// Package strictyaml decodes a single YAML document into a struct, rejecting what yaml.Unmarshal
+// silently accepts: unknown fields, duplicate keys, and further documents.  It also bounds the depth
+// of the document and the expansion of its aliases, so that a small payload can't turn into a huge one.
+package strictyaml
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// default limits of a document
+const (
+	DefaultMaxDepth = 32
+	DefaultMaxNodes = 10000
+)
+
+// Options are the limits applied to a document, zero meaning the default
+type Options struct {
+	// MaxDepth is how deep mappings and sequences can be nested
+	MaxDepth int
+	// MaxNodes is how many nodes the document can have once its aliases are expanded
+	MaxNodes int
+}
+
+// Error is a problem found at a position of the document
+type Error struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Message)
+}
+
+// Unmarshal decodes the single YAML document in b into v, which must be a pointer to a struct.
+// It returns an *Error for an unknown field, a duplicate key, a second document, or a document over the limits.
+func Unmarshal(b []byte, v interface{}, opts Options) error {
+	opts = opts.withDefaults()
+
+	dec := yamlv3.NewDecoder(bytes.NewReader(b))
+	var doc yamlv3.Node
+	if err := dec.Decode(&doc); err != nil {
+		if err == io.EOF {
+			// an empty document decodes into the zero value as yaml.Unmarshal does
+			return nil
+		}
+		return err
+	}
+	var next yamlv3.Node
+	if err := dec.Decode(&next); err != io.EOF {
+		if err != nil {
+			return err
+		}
+		return &Error{Line: next.Line, Column: next.Column, Message: "expected a single document"}
+	}
+
+	c := checker{opts: opts}
+	if err := c.check(&doc, reflect.TypeOf(v), 0); err != nil {
+		return err
+	}
+	return yaml.Unmarshal(b, v)
+}
+
+// Decoder reads the documents of a YAML stream one at a time, applying the checks of Unmarshal to each of them
+type Decoder struct {
+	dec  *yamlv3.Decoder
+	opts Options
+}
+
+// NewDecoder returns a Decoder reading the stream from r
+func NewDecoder(r io.Reader, opts Options) *Decoder {
+	return &Decoder{dec: yamlv3.NewDecoder(r), opts: opts.withDefaults()}
+}
+
+// Decode decodes the next document of the stream into v.  It returns io.EOF at the end of the stream,
+// and an *Error for an unknown field, a duplicate key, or a document over the limits.
+func (d *Decoder) Decode(v interface{}) error {
+	var doc yamlv3.Node
+	if err := d.dec.Decode(&doc); err != nil {
+		return err
+	}
+	c := checker{opts: d.opts}
+	if err := c.check(&doc, reflect.TypeOf(v), 0); err != nil {
+		return err
+	}
+	// the document is decoded on its own, as Unmarshal would
+	b, err := yamlv3.Marshal(&doc)
+	if err != nil {
+		return err
+	}
+	return yaml.Unmarshal(b, v)
+}
+
+func (opts Options) withDefaults() Options {
+	if opts.MaxDepth <= 0 {
+		opts.MaxDepth = DefaultMaxDepth
+	}
+	if opts.MaxNodes <= 0 {
+		opts.MaxNodes = DefaultMaxNodes
+	}
+	return opts
+}
+
+type checker struct {
+	opts  Options
+	nodes int
+}
+
+// check walks node along with the Go type it's decoded into, expanding aliases
+func (c *checker) check(node *yamlv3.Node, t reflect.Type, depth int) error {
+	c.nodes++
+	if c.nodes > c.opts.MaxNodes {
+		return &Error{Line: node.Line, Column: node.Column, Message: fmt.Sprintf("document has more than %d nodes once its aliases are expanded", c.opts.MaxNodes)}
+	}
+	if depth > c.opts.MaxDepth {
+		return &Error{Line: node.Line, Column: node.Column, Message: fmt.Sprintf("document is nested deeper than %d levels", c.opts.MaxDepth)}
+	}
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch node.Kind {
+	case yamlv3.DocumentNode:
+		for _, n := range node.Content {
+			if err := c.check(n, t, depth); err != nil {
+				return err
+			}
+		}
+	case yamlv3.AliasNode:
+		return c.check(node.Alias, t, depth)
+	case yamlv3.SequenceNode:
+		var elem reflect.Type
+		if t != nil && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+			elem = t.Elem()
+		}
+		for _, n := range node.Content {
+			if err := c.check(n, elem, depth+1); err != nil {
+				return err
+			}
+		}
+	case yamlv3.MappingNode:
+		var fields map[string]reflect.Type
+		if t != nil && t.Kind() == reflect.Struct && t != reflect.TypeOf(time.Time{}) {
+			fields = structFields(t)
+		}
+		var elem reflect.Type
+		if t != nil && t.Kind() == reflect.Map {
+			elem = t.Elem()
+		}
+		seen := make(map[string]bool)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if seen[key.Value] {
+				return &Error{Line: key.Line, Column: key.Column, Message: fmt.Sprintf("duplicate key %q", key.Value)}
+			}
+			seen[key.Value] = true
+			if key.Tag == "!!merge" {
+				// << merges the fields of an anchored mapping into this one
+				if err := c.check(value, t, depth); err != nil {
+					return err
+				}
+				continue
+			}
+			if fields != nil {
+				ft, ok := fields[key.Value]
+				if !ok {
+					return &Error{Line: key.Line, Column: key.Column, Message: fmt.Sprintf("unknown field %q", key.Value)}
+				}
+				elem = ft
+			}
+			if err := c.check(key, nil, depth+1); err != nil {
+				return err
+			}
+			if err := c.check(value, elem, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// structFields maps the yaml names of the fields of t to their type
+func structFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		tag := f.Tag.Get("yaml")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
@@ -0,0 +1,103 @@
+package strictyaml
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/elumbantoruan/app-metadata/metadata"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnmarshal_ValidDocument_ResultedDecoded(t *testing.T) {
+
+	payload := `
+title: Valid App 1
+version: 0.0.1
+maintainers:
+- &first
+  name: firstmaintainer app1
+  email: firstmaintainer@hotmail.com
+- *first
+labels:
+  team: payments
+`
+	var app metadata.ApplicationMetadata
+	err := Unmarshal([]byte(payload), &app, Options{})
+	assert.Nil(t, err)
+	assert.Equal(t, "Valid App 1", app.Title)
+	assert.Equal(t, 2, len(app.Maintainers))
+	assert.Equal(t, "payments", app.Labels["team"])
+}
+
+func TestUnmarshal_InvalidDocument_ResultedError(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		payload  string
+		expected string
+	}{
+		{
+			name:     "unknown field",
+			payload:  "title: App\nlicence: Apache-2.0\n",
+			expected: `line 2, column 1: unknown field "licence"`,
+		},
+		{
+			name:     "unknown nested field",
+			payload:  "maintainers:\n- name: first\n  mail: first@hotmail.com\n",
+			expected: `line 3, column 3: unknown field "mail"`,
+		},
+		{
+			name:     "duplicate key",
+			payload:  "title: App\nversion: 1.0.0\ntitle: Other\n",
+			expected: `line 3, column 1: duplicate key "title"`,
+		},
+		{
+			name:     "duplicate label",
+			payload:  "labels:\n  team: a\n  team: b\n",
+			expected: `line 3, column 3: duplicate key "team"`,
+		},
+		{
+			name:     "second document",
+			payload:  "title: App\n---\ntitle: Other\n",
+			expected: "line 2, column 1: expected a single document",
+		},
+		{
+			name:     "too deep",
+			payload:  "labels:\n  team: " + strings.Repeat("[", 40) + strings.Repeat("]", 40) + "\n",
+			expected: "nested deeper than 32 levels",
+		},
+		{
+			name: "alias bomb",
+			payload: `
+labels:
+  a: &a ["x","x","x","x","x","x","x","x","x","x"]
+  b: &b [*a,*a,*a,*a,*a,*a,*a,*a,*a,*a]
+  c: &c [*b,*b,*b,*b,*b,*b,*b,*b,*b,*b]
+  d: &d [*c,*c,*c,*c,*c,*c,*c,*c,*c,*c]
+  e: [*d,*d,*d,*d,*d,*d,*d,*d,*d,*d]
+`,
+			expected: "more than 10000 nodes",
+		},
+	}
+	for _, tt := range tests {
+		var app metadata.ApplicationMetadata
+		err := Unmarshal([]byte(tt.payload), &app, Options{})
+		if assert.NotNil(t, err, tt.name) {
+			assert.Contains(t, err.Error(), tt.expected, tt.name)
+		}
+	}
+}
+
+func TestDecoder_Stream_ResultedErrorInSecondDocument(t *testing.T) {
+
+	payload := "---\ntitle: App 1\n---\ntitle: App 2\nlicence: Apache-2.0\n"
+	dec := NewDecoder(strings.NewReader(payload), Options{})
+
+	var first metadata.ApplicationMetadata
+	assert.Nil(t, dec.Decode(&first))
+	assert.Equal(t, "App 1", first.Title)
+
+	var second metadata.ApplicationMetadata
+	err := dec.Decode(&second)
+	assert.EqualError(t, err, `line 5, column 1: unknown field "licence"`)
+}
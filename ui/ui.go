@@ -0,0 +1,45 @@
+// Package ui holds the templates and the assets of the HTML catalog browser, embedded in the binary
+package ui
+
+import (
+	"embed"
+	"html/template"
+	"io/fs"
+	"net/http"
+)
+
+// Prefix is the path the catalog browser is served under
+const Prefix = "/ui"
+
+//go:embed templates/*.html
+var templates embed.FS
+
+//go:embed static
+var static embed.FS
+
+// pages are executed through the layout, each defining its title and content
+var pages = parse("list.html", "detail.html", "form.html", "error.html")
+
+func parse(names ...string) map[string]*template.Template {
+	layout := template.Must(template.ParseFS(templates, "templates/layout.html"))
+	res := make(map[string]*template.Template, len(names))
+	for _, name := range names {
+		res[name] = template.Must(template.Must(layout.Clone()).ParseFS(templates, "templates/"+name))
+	}
+	return res
+}
+
+// Render writes the page with the given status
+func Render(w http.ResponseWriter, status int, page string, data interface{}) error {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", "default-src 'self'; img-src https: http:; frame-ancestors 'none'")
+	w.WriteHeader(status)
+	return pages[page].ExecuteTemplate(w, "layout", data)
+}
+
+// Static serves the assets under Prefix/static/
+func Static() http.Handler {
+	sub, _ := fs.Sub(static, "static")
+	return http.StripPrefix(Prefix+"/static/", http.FileServer(http.FS(sub)))
+}
@@ -0,0 +1,260 @@
+// Package verification confirms that maintainers own their email address with signed tokens sent to it
+package verification
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/smtp"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/elumbantoruan/app-metadata/metadata"
+)
+
+// default settings
+const (
+	// DefaultTTL is how long a token is valid by default
+	DefaultTTL = 72 * time.Hour
+	// DefaultSMTPTimeout bounds the delivery of a message, from the connection to the server until it's accepted
+	DefaultSMTPTimeout = 30 * time.Second
+	// DefaultQueueSize is the number of messages a Queue holds
+	DefaultQueueSize = 100
+)
+
+// ErrQueueFull is returned by Queue.Send when the queue can't take another message
+var ErrQueueFull = errors.New("too many verification emails are waiting to be sent")
+
+// token errors
+var (
+	ErrInvalidToken = errors.New("invalid verification token")
+	ErrExpiredToken = errors.New("verification token expired")
+)
+
+// Signer issues tokens carrying an email and an expiry, signed with HMAC-SHA256
+type Signer struct {
+	Key []byte
+	TTL time.Duration
+	now func() time.Time
+}
+
+// NewSigner returns a Signer of tokens valid for ttl, DefaultTTL when it's zero
+func NewSigner(key []byte, ttl time.Duration) *Signer {
+	if ttl == 0 {
+		ttl = DefaultTTL
+	}
+	return &Signer{Key: key, TTL: ttl, now: time.Now}
+}
+
+// Token returns a token for the normalized email
+func (s *Signer) Token(email string) string {
+	payload := metadata.NormalizeEmail(email) + "|" + strconv.FormatInt(s.now().Add(s.TTL).Unix(), 10)
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString([]byte(payload)) + "." + enc.EncodeToString(s.sign(payload))
+}
+
+// Parse returns the email carried by a token after checking its signature and expiry
+func (s *Signer) Parse(token string) (string, error) {
+	enc := base64.RawURLEncoding
+	p, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return "", ErrInvalidToken
+	}
+	payload, err := enc.DecodeString(p)
+	if err != nil {
+		return "", ErrInvalidToken
+	}
+	mac, err := enc.DecodeString(sig)
+	if err != nil || !hmac.Equal(mac, s.sign(string(payload))) {
+		return "", ErrInvalidToken
+	}
+	// the email may contain '|' but the expiry doesn't
+	i := strings.LastIndex(string(payload), "|")
+	if i < 0 {
+		return "", ErrInvalidToken
+	}
+	email := string(payload[:i])
+	exp, err := strconv.ParseInt(string(payload[i+1:]), 10, 64)
+	if err != nil {
+		return "", ErrInvalidToken
+	}
+	if s.now().Unix() > exp {
+		return "", ErrExpiredToken
+	}
+	return email, nil
+}
+
+func (s *Signer) sign(payload string) []byte {
+	h := hmac.New(sha256.New, s.Key)
+	h.Write([]byte(payload))
+	return h.Sum(nil)
+}
+
+// Sender delivers a message to an email address
+type Sender interface {
+	Send(to, subject, body string) error
+}
+
+// SMTPSender sends messages through an SMTP server
+type SMTPSender struct {
+	// Addr is the host:port of the server
+	Addr string
+	From string
+	// Auth authenticates to the server, messages are sent without authentication when it's nil
+	Auth smtp.Auth
+	// Timeout bounds the delivery of a message, DefaultSMTPTimeout when it's zero
+	Timeout time.Duration
+}
+
+// Send sends a plain text message like smtp.SendMail, upgrading to TLS when the server supports it, within Timeout.
+// An address which isn't ASCII is only sent to a server supporting SMTPUTF8.
+func (s *SMTPSender) Send(to, subject, body string) error {
+	timeout := s.Timeout
+	if timeout <= 0 {
+		timeout = DefaultSMTPTimeout
+	}
+	host, _, err := net.SplitHostPort(s.Addr)
+	if err != nil {
+		return err
+	}
+	conn, err := (&net.Dialer{Timeout: timeout}).Dial("tcp", s.Addr)
+	if err != nil {
+		return err
+	}
+	// the deadline bounds the whole exchange, so that a stalled server can't hold the sender
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		conn.Close()
+		return err
+	}
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if s.Auth != nil {
+		if ok, _ := c.Extension("AUTH"); !ok {
+			return errors.New("smtp: server doesn't support AUTH")
+		}
+		if err := c.Auth(s.Auth); err != nil {
+			return err
+		}
+	}
+	if !isASCII(s.From) || !isASCII(to) {
+		// Mail asks for SMTPUTF8 when the server supports it, other servers would mangle the address
+		if ok, _ := c.Extension("SMTPUTF8"); !ok {
+			return fmt.Errorf("smtp: server doesn't support SMTPUTF8, required by %s", to)
+		}
+	}
+	if err := c.Mail(s.From); err != nil {
+		return err
+	}
+	if err := c.Rcpt(to); err != nil {
+		return err
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	msg := "From: " + s.From + "\r\n" +
+		"To: " + to + "\r\n" +
+		"Subject: " + subject + "\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: text/plain; charset=UTF-8\r\n" +
+		"\r\n" +
+		strings.ReplaceAll(body, "\n", "\r\n")
+	if _, err := w.Write([]byte(msg)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// message is a message waiting in a Queue
+type message struct {
+	to, subject, body string
+}
+
+// Queue is a Sender handing the messages to another Sender in the background, so that requests don't wait for the
+// mail server.  It holds a bounded number of messages, Send returns ErrQueueFull rather than waiting when it's full.
+type Queue struct {
+	Sender   Sender
+	messages chan message
+}
+
+// NewQueue returns a Queue holding up to size messages for sender, DefaultQueueSize when it's zero
+func NewQueue(sender Sender, size int) *Queue {
+	if size <= 0 {
+		size = DefaultQueueSize
+	}
+	return &Queue{Sender: sender, messages: make(chan message, size)}
+}
+
+// Send queues a message, which Run sends later
+func (q *Queue) Send(to, subject, body string) error {
+	select {
+	case q.messages <- message{to: to, subject: subject, body: body}:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Run sends the queued messages one at a time until ctx is done, logging those which can't be sent
+func (q *Queue) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case m := <-q.messages:
+			if err := q.Sender.Send(m.to, m.subject, m.body); err != nil {
+				log.Printf("verification: sending to %s: %v", m.to, err)
+			}
+		}
+	}
+}
+
+// Verifier sends a link to confirm their email to the maintainers
+type Verifier struct {
+	Signer *Signer
+	Sender Sender
+	// URL is the verify endpoint the link points to, the token is added as query parameter
+	URL string
+}
+
+// Send sends a verification link to email
+func (v *Verifier) Send(email string) error {
+	if strings.ContainsAny(email, "\r\n") {
+		return fmt.Errorf("invalid email address %q", email)
+	}
+	link := v.URL + "?token=" + url.QueryEscape(v.Signer.Token(email))
+	body := fmt.Sprintf("You were added as a maintainer of an application.\n\n"+
+		"Confirm your email address by opening the link below within %s:\n\n%s\n", v.Signer.TTL, link)
+	return v.Sender.Send(email, "Confirm your maintainer email address", body)
+}
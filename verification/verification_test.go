@@ -0,0 +1,173 @@
+package verification
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSigner_Token_ResultedParsed(t *testing.T) {
+
+	s := NewSigner([]byte("secret"), time.Hour)
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+
+	token := s.Token("First|M@Example.com")
+	email, err := s.Parse(token)
+	assert.Nil(t, err)
+	assert.Equal(t, "first|m@example.com", email)
+
+	// signed with another key
+	_, err = NewSigner([]byte("other"), time.Hour).Parse(token)
+	assert.Equal(t, ErrInvalidToken, err)
+	// tampered
+	_, err = s.Parse("x" + token)
+	assert.Equal(t, ErrInvalidToken, err)
+	_, err = s.Parse("")
+	assert.Equal(t, ErrInvalidToken, err)
+
+	now = now.Add(2 * time.Hour)
+	_, err = s.Parse(token)
+	assert.Equal(t, ErrExpiredToken, err)
+}
+
+// fakeSMTPServer accepts a single message and sends its recipients and data to the returned channel
+func fakeSMTPServer(t *testing.T) (string, <-chan []string) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	received := make(chan []string, 1)
+	go func() {
+		defer l.Close()
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		tp := textproto.NewConn(conn)
+		tp.PrintfLine("220 localhost fake")
+		var msg []string
+		for {
+			line, err := tp.ReadLine()
+			if err != nil {
+				return
+			}
+			switch cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0]); cmd {
+			case "EHLO", "HELO":
+				tp.PrintfLine("250 localhost")
+			case "MAIL":
+				tp.PrintfLine("250 OK")
+			case "RCPT":
+				msg = append(msg, line)
+				tp.PrintfLine("250 OK")
+			case "DATA":
+				tp.PrintfLine("354 go ahead")
+				data, _ := tp.ReadDotLines()
+				msg = append(msg, data...)
+				tp.PrintfLine("250 OK")
+			case "QUIT":
+				tp.PrintfLine("221 bye")
+				received <- msg
+				return
+			default:
+				tp.PrintfLine("502 not implemented")
+			}
+		}
+	}()
+	return l.Addr().String(), received
+}
+
+func TestVerifier_Send_ResultedDelivered(t *testing.T) {
+
+	addr, received := fakeSMTPServer(t)
+	v := &Verifier{
+		Signer: NewSigner([]byte("secret"), 0),
+		Sender: &SMTPSender{Addr: addr, From: "app-metadata@example.com"},
+		URL:    "https://app-metadata.example.com/maintainers:verify",
+	}
+	assert.Nil(t, v.Send("first@example.com"))
+
+	var msg []string
+	select {
+	case msg = <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message received")
+	}
+	assert.Equal(t, "RCPT TO:<first@example.com>", msg[0])
+	body := strings.Join(msg, "\n")
+	assert.Contains(t, body, "Subject: Confirm your maintainer email address")
+	i := strings.Index(body, "?token=")
+	assert.True(t, i > 0)
+	token := bufio.NewScanner(strings.NewReader(body[i+len("?token="):]))
+	token.Scan()
+	email, err := v.Signer.Parse(token.Text())
+	assert.Nil(t, err)
+	assert.Equal(t, "first@example.com", email)
+
+	assert.NotNil(t, v.Send("first@example.com\r\nBcc: x@example.com"))
+}
+
+func TestSMTPSender_StalledServer_ResultedTimeout(t *testing.T) {
+
+	// the server accepts the connection but never greets
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer l.Close()
+	go func() {
+		conn, err := l.Accept()
+		if err == nil {
+			defer conn.Close()
+			time.Sleep(2 * time.Second)
+		}
+	}()
+
+	s := &SMTPSender{Addr: l.Addr().String(), From: "app-metadata@example.com", Timeout: 100 * time.Millisecond}
+	start := time.Now()
+	assert.NotNil(t, s.Send("first@example.com", "subject", "body"))
+	assert.True(t, time.Since(start) < time.Second)
+}
+
+func TestSMTPSender_InternationalAddress_ResultedRefused(t *testing.T) {
+
+	// the fake server doesn't advertise SMTPUTF8
+	addr, _ := fakeSMTPServer(t)
+	s := &SMTPSender{Addr: addr, From: "app-metadata@example.com"}
+	err := s.Send("prénom@example.com", "subject", "body")
+	assert.EqualError(t, err, "smtp: server doesn't support SMTPUTF8, required by prénom@example.com")
+}
+
+// blockingSender records the messages once unblocked
+type blockingSender struct {
+	unblock chan struct{}
+	sent    chan string
+}
+
+func (bs *blockingSender) Send(to, subject, body string) error {
+	<-bs.unblock
+	bs.sent <- to
+	return nil
+}
+
+func TestQueue_Send_ResultedBounded(t *testing.T) {
+
+	sender := &blockingSender{unblock: make(chan struct{}), sent: make(chan string, 2)}
+	q := NewQueue(sender, 1)
+	assert.Nil(t, q.Send("first@example.com", "subject", "body"))
+	assert.Equal(t, ErrQueueFull, q.Send("second@example.com", "subject", "body"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go q.Run(ctx)
+	close(sender.unblock)
+	select {
+	case to := <-sender.sent:
+		assert.Equal(t, "first@example.com", to)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message sent")
+	}
+}